@@ -1,79 +1,2091 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
+	"web-tester/internal/artifacts"
+	"web-tester/internal/audit"
+	"web-tester/internal/bodystore"
 	"web-tester/internal/browser"
+	"web-tester/internal/cli"
 	"web-tester/internal/config"
+	"web-tester/internal/crawl"
 	"web-tester/internal/database"
+	"web-tester/internal/diff"
+	"web-tester/internal/egress"
+	"web-tester/internal/explore"
+	"web-tester/internal/har"
+	"web-tester/internal/issues"
+	"web-tester/internal/logging"
+	"web-tester/internal/notify"
+	"web-tester/internal/openapi"
+	"web-tester/internal/redact"
+	"web-tester/internal/replay"
+	"web-tester/internal/report"
+	"web-tester/internal/rpc"
+	"web-tester/internal/runner"
+	"web-tester/internal/scheduler"
+	"web-tester/internal/scope"
+	"web-tester/internal/server"
+	"web-tester/internal/signing"
+	"web-tester/internal/sink"
+	"web-tester/internal/telemetry"
+	"web-tester/internal/tui"
 
-	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/google/uuid"
 )
 
 // main is the entry point of the web-tester application. It performs the following tasks:
-// 1. Initializes a logger with JSON output and info level logging.
-// 2. Creates a new browser client for the specified URL and ensures it is properly canceled on exit.
-// 3. Loads the database configuration and initializes the database connection.
-// 4. Sets up channels and structures to handle browser events, requests, and responses.
-// 5. Listens to browser events and runs the browser for a specified duration.
-// 6. Watches for event finishers and logs the successful run of the browser.
-// 7. Iterates over the captured requests and responses, inserting them into the database.
+// 1. Initializes the logger with the level, format, file and module levels of the LOG_* variables or --log-* flags.
+// 2. Prints the shell completion script or the JSON schema of the CLI for the "completion" and "schema" commands,
+// and with "login <url>" opens a browser window to log in manually, saving the session.
+// 3. Streams captured events to an NDJSON output when OUTPUT_NDJSON is set.
+// 4. Loads the database configuration and initializes the database connection unless DB_ENABLED is false.
+// 5. Loads the assertions declared in ASSERTIONS_FILE, the journey scenario in SCENARIO_FILE, the audit checks configuration and, with AIR_GAPPED,
+// restricts the checks' own network calls to the target and the hosts in EGRESS_ALLOW. The browser is
+// killed, failing the test, when it exceeds BROWSER_MEMORY_LIMIT_MB or BROWSER_CPU_LIMIT_SECONDS, and the
+// stored events are sampled according to the SAMPLE_* variables. Response bodies above BODY_MAX_DB_BYTES
+// are written to BODY_STORE_DIR instead of the database, and events are filtered with the CAPTURE_* variables.
+// 6. With the "serve" argument, serves the REST API triggering tests on demand, with "tui <test-id>"
+// opens a terminal UI to inspect a stored run, with "diff <base> <head>" compares two stored runs, and with
+// "report <test-id>" renders the report of a stored run, and with "suite <suite-id>" aggregates the runs of a suite.
+// Otherwise runs a test against the target, or each URL of the shard of the URL list, which captures the traffic, runs the audit checks
+// and evaluates the assertions, exiting with the cli exit code of the class of failure, if any.
 //
 // If any errors occur during database initialization, browser execution, or database insertion,
 // they are logged appropriately.
 func main() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	// the log flags apply to every command, so they are taken out of the arguments before the commands see them
+	args, logFlagCfg, logFlagErr := logFlags(os.Args[1:])
+	os.Args = append(os.Args[:1], args...)
 
-	client := browser.New("https://google.com")
+	// the configuration file sets the defaults of the environment, so it is applied before anything is loaded,
+	// except for the config command which inspects it
+	var fileCfg config.File
+	var fileOverrides []string
+	var fileErr error
+	if path := os.Getenv("CONFIG_FILE"); path != "" && (len(os.Args) < 2 || os.Args[1] != "config") {
+		if fileCfg, fileErr = config.ReadFile(path); fileErr == nil {
+			if fileErr = fileCfg.Validate(); fileErr == nil {
+				fileOverrides = fileCfg.Overrides()
+				fileErr = fileCfg.Apply()
+			}
+		}
+	}
+
+	outputConfig := &config.OutputConfig{}
+	outputCfg := outputConfig.Load()
+	writerConfig := &config.WriterConfig{}
+	writerCfg := writerConfig.Load()
+
+	// keep stdout clean for the event stream or the JSON-RPC responses when they are written there
+	rpcMode := len(os.Args) > 1 && os.Args[1] == "rpc"
+	stdoutSink := writerCfg.Sink == database.SinkStdout
+	logOutput := os.Stdout
+	if outputCfg.NDJSONPath == "-" || rpcMode || stdoutSink {
+		logOutput = os.Stderr
+	}
+	logConfig := &config.LogConfig{}
+	logCfg := logConfig.Load()
+	if logFlagCfg.Level != "" {
+		logCfg.Level = logFlagCfg.Level
+	}
+	if logFlagCfg.Format != "" {
+		logCfg.Format = logFlagCfg.Format
+	}
+	if logFlagCfg.File != "" {
+		logCfg.File = logFlagCfg.File
+	}
+	if len(logFlagCfg.Modules) > 0 {
+		logCfg.Modules = logFlagCfg.Modules
+	}
+	logger, err := logging.New(logCfg, logOutput)
+	if err == nil {
+		err = logFlagErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid log configuration: %v\n", err)
+		os.Exit(cli.ExitConfig)
+	}
+	if fileErr != nil {
+		logger.Error("invalid CONFIG_FILE", "error", fileErr)
+		os.Exit(cli.ExitConfig)
+	}
+	if len(fileOverrides) > 0 {
+		logger.Info("config file settings overridden by the environment", "variables", fileOverrides)
+	}
+	if rpcMode && (outputCfg.NDJSONPath == "-" || stdoutSink) {
+		logger.Error("the rpc command writes its responses to stdout, OUTPUT_NDJSON or EVENT_SINK cannot be written there too")
+		os.Exit(cli.ExitConfig)
+	}
+	if outputCfg.NDJSONPath == "-" && stdoutSink {
+		logger.Error("OUTPUT_NDJSON and EVENT_SINK cannot both be written to stdout")
+		os.Exit(cli.ExitConfig)
+	}
+	switch writerCfg.Sink {
+	case database.SinkPostgres, database.SinkFile, database.SinkStdout, database.SinkNone:
+	case database.SinkMongoDB:
+		if writerCfg.MongoURI == "" {
+			logger.Error("MONGODB_URI must be set for the mongodb event sink")
+			os.Exit(cli.ExitConfig)
+		}
+	default:
+		logger.Error("invalid EVENT_SINK", "sink", writerCfg.Sink)
+		os.Exit(cli.ExitConfig)
+	}
+
+	// commands describing the CLI need neither the database nor the browser
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "completion":
+			completion(logger, os.Args[2:])
+			return
+		case "schema":
+			schema, err := cli.SchemaJSON()
+			if err != nil {
+				logger.Error("failed to generate schema", "error", err)
+				os.Exit(cli.ExitConfig)
+			}
+			fmt.Println(string(schema))
+			return
+		case "sign":
+			sign(logger, os.Args[2:])
+			return
+		case "verify":
+			verify(logger, os.Args[2:])
+			return
+		case "login":
+			logIn(logger, os.Args[2:])
+			return
+		case "config":
+			configCommand(logger, os.Args[2:])
+			return
+		}
+	}
+
+	var db *sql.DB
+	var dbErr error
+	dbConfig := &config.DBConfig{}
+	if dbCfg := dbConfig.Load(); dbCfg.Enabled {
+		db, dbErr = database.Init(logger, dbCfg)
+		if dbErr != nil {
+			logger.Error("failed to initialize database", "error", dbErr)
+		}
+	}
+
+	assertions, err := fileCfg.LoadAssertions()
+	if err != nil {
+		logger.Error("failed to load assertions", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+
+	auditConfig := &config.AuditConfig{}
+	r := runner.New(logger, db, assertions, auditConfig.Load())
+
+	scenario, err := config.LoadScenario()
+	if err != nil {
+		logger.Error("failed to load scenario", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	r.Journey(scenario)
+	uploadConfig := &config.UploadConfig{}
+	r.AllowUploads(uploadConfig.Load().Dir)
+	redactionConfig := &config.RedactionConfig{}
+	redactionCfg, err := redactionConfig.Load()
+	if err != nil {
+		logger.Error("invalid redaction rules", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	redaction := redact.Rules{Headers: redactionCfg.Headers, Fields: redactionCfg.Fields, Pattern: redactionCfg.Pattern}
+	r.Redact(redaction)
+	exploreConfig := &config.ExploreConfig{}
+	exploreCfg, err := exploreConfig.Load()
+	if err != nil {
+		logger.Error("invalid exploration settings", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	r.Explore(explore.Budget{Interactions: exploreCfg.MaxInteractions, Scrolls: exploreCfg.MaxScrolls, Delay: exploreCfg.Delay,
+		Skip: exploreCfg.Skip})
+	scrollConfig := &config.ScrollConfig{}
+	scrollCfg := scrollConfig.Load()
+	r.InfiniteScroll(explore.Scroll{MaxIterations: scrollCfg.MaxIterations, Delay: scrollCfg.Delay, Stable: scrollCfg.Stable})
+
+	browserConfig := &config.BrowserConfig{}
+	browserCfg := browserConfig.Load()
+	r.LimitBrowser(browser.Limits{
+		MemoryBytes: int64(browserCfg.MemoryLimitMB) << 20,
+		CPUTime:     time.Duration(browserCfg.CPULimitSeconds) * time.Second,
+	})
+
+	captureConfig := &config.CaptureConfig{}
+	captureCfg := captureConfig.Load()
+	r.Filter(browser.Filter{
+		IncludeTypes: captureCfg.IncludeTypes, ExcludeTypes: captureCfg.ExcludeTypes,
+		IncludeMIME: captureCfg.IncludeMIME, ExcludeMIME: captureCfg.ExcludeMIME,
+	})
+
+	r.BatchWrites(writerCfg)
+
+	samplingConfig := &config.SamplingConfig{}
+	r.Sample(samplingConfig.Load())
+
+	egressConfig := &config.EgressConfig{}
+	egressCfg := egressConfig.Load()
+	if egressCfg.AirGapped {
+		r.Restrict(egressCfg)
+	}
+
+	store, err := artifactStore(logger, egressCfg)
+	if err != nil {
+		logger.Error("failed to open artifact store", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	if store != nil {
+		r.Archive(store)
+	}
+	downloadConfig := &config.DownloadConfig{}
+	if downloadCfg := downloadConfig.Load(); downloadCfg.Dir != "" {
+		r.CaptureDownloads(downloadCfg.Dir)
+	}
+
+	// large bodies go to the artifact store when set, or to the body store directory
+	bodyConfig := &config.BodyConfig{}
+	bodyCfg := bodyConfig.Load()
+	var bodies *bodystore.Store
+	switch {
+	case store != nil:
+		bodies = bodystore.New(store, "bodies")
+	case bodyCfg.StoreDir != "":
+		dir, err := artifacts.NewDir(bodyCfg.StoreDir)
+		if err != nil {
+			logger.Error("failed to open body store", "error", err)
+			os.Exit(cli.ExitConfig)
+		}
+		bodies = bodystore.New(dir, "")
+	}
+	r.LimitBodies(bodyCfg.MaxDBBytes, bodies)
+
+	authConfig := &config.AuthConfig{}
+	authCfg := authConfig.Load()
+	var basic *browser.Credentials
+	if authCfg.BasicUsername != "" {
+		basic = &browser.Credentials{Username: authCfg.BasicUsername, Password: authCfg.BasicPassword}
+	}
+	var login *browser.Login
+	if authCfg.LoginURL != "" {
+		login = &browser.Login{URL: authCfg.LoginURL, UsernameSelector: authCfg.UsernameSelector, PasswordSelector: authCfg.PasswordSelector,
+			SubmitSelector: authCfg.SubmitSelector, SuccessSelector: authCfg.SuccessSelector, SuccessURL: authCfg.SuccessURL,
+			Credentials: browser.Credentials{Username: authCfg.LoginUsername, Password: authCfg.LoginPassword}}
+		if err := login.Validate(); err != nil {
+			logger.Error("invalid login", "error", err)
+			os.Exit(cli.ExitConfig)
+		}
+	}
+	r.Authenticate(basic, login)
+
+	consentConfig := &config.ConsentConfig{}
+	if consentCfg := consentConfig.Load(); consentCfg.Policy != "" {
+		consent := &browser.Consent{Policy: consentCfg.Policy, Selectors: consentCfg.Selectors, Timeout: consentCfg.Timeout}
+		if err := consent.Validate(); err != nil {
+			logger.Error("invalid consent handling", "error", err)
+			os.Exit(cli.ExitConfig)
+		}
+		r.HandleConsent(consent)
+	}
+
+	mockConfigs, err := config.LoadMocks()
+	if err != nil {
+		logger.Error("failed to load mocks", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	mocks := make([]browser.Mock, 0, len(mockConfigs))
+	for _, m := range mockConfigs {
+		mock, err := browser.NewMock(m.URL, m.Status, m.Headers, m.Body)
+		if err != nil {
+			logger.Error("invalid mock", "url", m.URL, "error", err)
+			os.Exit(cli.ExitConfig)
+		}
+		mocks = append(mocks, mock)
+	}
+	r.Mock(mocks)
+
+	faultConfigs, err := config.LoadFaults()
+	if err != nil {
+		logger.Error("failed to load chaos faults", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	faults := make([]browser.Fault, 0, len(faultConfigs))
+	for _, f := range faultConfigs {
+		fault, err := browser.NewFault(f.URL, f.Action, time.Duration(f.DelayMS)*time.Millisecond, f.Reason, f.Status)
+		if err != nil {
+			logger.Error("invalid chaos fault", "url", f.URL, "error", err)
+			os.Exit(cli.ExitConfig)
+		}
+		faults = append(faults, fault)
+	}
+	r.InjectFaults(faults)
+
+	issuesConfig := &config.IssuesConfig{}
+	if trackers := issueTrackers(logger, issuesConfig.Load(), egressCfg); len(trackers) > 0 {
+		r.OpenIssues(trackers, issuesConfig.MinSeverity)
+	}
+
+	// in air-gapped mode, the webhooks must be in the egress allow-list like the issue trackers
+	notifyConfig := &config.NotifyConfig{}
+	if notifyCfg := notifyConfig.Load(); len(notifyCfg.URLs) > 0 {
+		client := &http.Client{Timeout: 10 * time.Second}
+		if egressCfg.AirGapped {
+			client.Transport = egress.New(logger, nil, egressCfg.Allow...)
+		}
+		r.Notify(notify.New(client, notifyCfg.URLs, notifyCfg.Secret))
+	}
+
+	// running without a subcommand, or with only flags, is the same as "run"
+	command, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	scopeConfig := &config.ScopeConfig{}
+	scopeCfg := scopeConfig.Load()
+	shardConfig := &config.ShardConfig{}
+	shardCfg := shardConfig.Load()
+	crawlConfig := &config.CrawlConfig{}
+	crawlCfg := crawlConfig.Load()
+	timeoutConfig := &config.TimeoutConfig{}
+	timeoutCfg := timeoutConfig.Load()
+	var flags runFlags
+	if command == "run" || command == "resume" {
+		flags = parseRunFlags(logger, args, &scopeCfg, &shardCfg, &crawlCfg, &timeoutCfg)
+	}
+	r.Timeout(browser.Timeouts{Run: timeoutCfg.Run, Navigation: timeoutCfg.Navigation, BodyFetch: timeoutCfg.BodyFetch, Idle: timeoutCfg.Idle})
+	rules, err := scope.Parse(scopeCfg.Include, scopeCfg.Exclude)
+	if err != nil {
+		logger.Error("failed to parse scope rules", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	r.Scope(rules)
+
+	var sinks sink.Multi
+	if outputCfg.NDJSONPath != "" {
+		ndjson, err := sink.NewNDJSON(outputCfg.NDJSONPath)
+		if err != nil {
+			logger.Error("failed to open ndjson output", "error", err)
+		} else {
+			sinks = append(sinks, ndjson)
+		}
+	}
+	if outputCfg.NATSURL != "" {
+		nats, err := sink.NewNATS(outputCfg.NATSURL, outputCfg.NATSSubject)
+		if err != nil {
+			logger.Error("failed to connect to nats", "error", err)
+			os.Exit(cli.ExitConfig)
+		}
+		sinks = append(sinks, nats)
+	}
+	// in air-gapped mode, the REST proxy must be in the egress allow-list like the issue trackers
+	if outputCfg.KafkaRESTURL != "" {
+		client := &http.Client{Timeout: 30 * time.Second}
+		if egressCfg.AirGapped {
+			client.Transport = egress.New(logger, nil, egressCfg.Allow...)
+		}
+		sinks = append(sinks, sink.NewKafka(client, outputCfg.KafkaRESTURL, outputCfg.KafkaTopic))
+	}
+	// in air-gapped mode, the collector must be in the egress allow-list like the issue trackers
+	telemetryConfig := &config.TelemetryConfig{}
+	var tracer *telemetry.Tracer
+	if telemetryCfg := telemetryConfig.Load(); telemetryCfg.Endpoint != "" {
+		client := &http.Client{Timeout: 10 * time.Second}
+		if egressCfg.AirGapped {
+			client.Transport = egress.New(logger, nil, egressCfg.Allow...)
+		}
+		tracer = telemetry.New(logger, client, telemetryCfg.Endpoint, telemetryCfg.Service, telemetryCfg.Headers)
+		r.Instrument(tracer)
+	}
+
+	// the streams are closed before exiting, publishing the records still queued, and the pending spans exported
+	closeStreams := sync.OnceFunc(func() {
+		if err := sinks.Close(); err != nil {
+			logger.Error("failed to close event streams", "error", err)
+		}
+		tracer.Shutdown()
+	})
+	defer closeStreams()
+	if len(sinks) > 0 {
+		r.StreamTo(sinks)
+	}
+
+	switch command {
+	case "serve":
+		serve(logger, db, r, store, bodies)
+		return
+	case "rpc":
+		serveRPC(logger, db, r)
+		return
+	case "tui":
+		inspect(logger, db, args)
+		return
+	case "diff":
+		compare(logger, db, args)
+		return
+	case "report":
+		render(logger, db, args)
+		return
+	case "openapi":
+		specify(logger, db, args)
+		return
+	case "replay":
+		replayRequests(logger, db, egressCfg, redaction, args)
+		return
+	case "suite":
+		aggregate(logger, db, args)
+		return
+	case "review":
+		review(logger, db, args)
+		return
+	case "results":
+		results(logger, db, args)
+		return
+	case "prune":
+		prune(logger, db, store, bodies, args)
+		return
+	case "run", "resume":
+	default:
+		logger.Error("unknown command", "command", command)
+		os.Exit(cli.ExitUsage)
+	}
+
+	targets := []string{"https://google.com"}
+	if len(fileCfg.Targets) > 0 {
+		targets = fileCfg.Targets
+	}
+	if shardCfg.URLsFile != "" {
+		urls, err := config.LoadURLs(shardCfg.URLsFile)
+		if err != nil {
+			logger.Error("failed to load urls", "error", err)
+			os.Exit(cli.ExitConfig)
+		}
+		if shardCfg.ShardTotal > 1 && shardCfg.SuiteID == "" {
+			logger.Error("a suite ID is required to shard the urls, set --suite or SUITE_ID")
+			os.Exit(cli.ExitUsage)
+		}
+		if targets, err = config.Shard(urls, shardCfg.ShardIndex, shardCfg.ShardTotal); err != nil {
+			logger.Error("invalid shard", "error", err)
+			os.Exit(cli.ExitUsage)
+		}
+		logger.Info("testing the urls of the shard", "urls", len(targets), "shard", shardCfg.ShardIndex, "total", shardCfg.ShardTotal)
+	}
+
+	opts := runner.Options{CompareCache: flags.compareCache, Coverage: flags.coverage, CPUThrottle: flags.cpuThrottle, Scroll: flags.scroll,
+		Explore: flags.explore, SaveState: flags.saveState, UserAgent: flags.userAgent, Locale: flags.locale, Timezone: flags.timezone}
+	if flags.geolocation != "" {
+		geo, err := browser.ParseGeolocation(flags.geolocation)
+		if err != nil {
+			logger.Error("invalid geolocation", "error", err)
+			os.Exit(cli.ExitUsage)
+		}
+		opts.Geolocation = &geo
+	}
+	if flags.loadState != "" && flags.loadSession != "" || flags.saveState != "" && flags.saveSession != "" {
+		logger.Error("the storage state and session flags are exclusive, use either --load-state and --save-state or --load-session and --save-session")
+		os.Exit(cli.ExitUsage)
+	}
+	if flags.loadState != "" {
+		state, err := browser.LoadState(flags.loadState)
+		if err != nil {
+			logger.Error("failed to load storage state", "error", err)
+			os.Exit(cli.ExitConfig)
+		}
+		opts.State = &state
+	}
+	if flags.loadSession != "" {
+		if opts.State, err = browser.LoadSession(flags.loadSession); err != nil {
+			logger.Error("failed to load session", "error", err)
+			os.Exit(cli.ExitConfig)
+		}
+		if opts.State == nil {
+			logger.Info("no session saved yet, starting logged out", "path", flags.loadSession)
+		}
+	}
+	if flags.saveSession != "" {
+		opts.SaveState = flags.saveSession
+	}
+	if flags.device != "" {
+		if opts.Device, err = browser.LookupDevice(flags.device); err != nil {
+			logger.Error("invalid device", "error", err)
+			os.Exit(cli.ExitUsage)
+		}
+	}
+	if flags.trace {
+		traceConfig := &config.TraceConfig{}
+		opts.TraceDir = traceConfig.Load().Dir
+	}
+	if flags.video {
+		videoConfig := &config.VideoConfig{}
+		videoCfg := videoConfig.Load()
+		opts.VideoDir, opts.VideoFPS = videoCfg.Dir, videoCfg.FPS
+	}
+	if flags.pdf {
+		pdfConfig := &config.PDFConfig{}
+		opts.PDFDir = pdfConfig.Load().Dir
+	}
+	opts.SuiteID, opts.ShardIndex, opts.ShardTotal = shardCfg.SuiteID, shardCfg.ShardIndex, shardCfg.ShardTotal
+
+	if command == "resume" {
+		exit := resumeCrawl(logger, db, r, opts, crawlCfg, flags.netlog, flags.args)
+		closeStreams()
+		os.Exit(exit)
+	}
+
+	exit := cli.ExitOK
+	for _, target := range targets {
+		opts.Target = target
+		if flags.crawl {
+			exit = worseExit(exit, crawlSite(logger, db, r, opts, crawlCfg, flags.netlog))
+			continue
+		}
+		_, code := runTarget(logger, r, opts, flags.netlog)
+		exit = worseExit(exit, code)
+	}
+	if exit == cli.ExitOK && dbErr != nil {
+		exit = cli.ExitStorage
+	}
+	closeStreams()
+	os.Exit(exit)
+}
+
+// runTarget tests a target in a browser of its own, returning the result and exit code of the run.
+func runTarget(logger *slog.Logger, r *runner.Runner, opts runner.Options, netlog bool) (runner.Result, int) {
+	testID, err := uuid.NewV7()
+	if err != nil {
+		logger.Error("failed to create test ID", "error", err)
+		return runner.Result{}, cli.ExitConfig
+	}
+	var launch []chromedp.ExecAllocatorOption
+	if netlog {
+		netLogConfig := &config.NetLogConfig{}
+		netLogCfg := netLogConfig.Load()
+		netLogPath := filepath.Join(netLogCfg.Dir, "netlog-"+testID.String()+".json")
+		netLog, err := browser.NetLog(netLogPath, netLogCfg.CaptureMode)
+		if err != nil {
+			logger.Error("failed to set up netlog", "error", err)
+			return runner.Result{TestID: testID}, cli.ExitConfig
+		}
+		logger.Info("recording the network log", "path", netLogPath)
+		launch = append(launch, netLog)
+	}
+	client := browser.NewWithTestID(opts.Target, testID, launch...)
+	defer client.Cancel()
+
+	result, err := r.Run(client, opts)
+	switch {
+	case errors.Is(err, browser.ErrNavigation):
+		return result, cli.ExitNavigation
+	case err != nil:
+		return result, cli.ExitBrowser
+	case len(result.FailedAssertions) > 0:
+		return result, cli.ExitAssertion
+	case result.StorageErrors > 0:
+		return result, cli.ExitStorage
+	}
+	return result, cli.ExitOK
+}
+
+// artifactStore opens the artifact store of ARTIFACT_STORE, or returns nil when none is set. In air-gapped mode,
+// the object storage must be in the egress allow-list like the issue trackers.
+func artifactStore(logger *slog.Logger, egressCfg config.EgressConfig) (artifacts.Store, error) {
+	artifactConfig := &config.ArtifactConfig{}
+	artifactCfg := artifactConfig.Load()
+	switch artifactCfg.Store {
+	case "":
+		return nil, nil
+	case "s3":
+		client := &http.Client{Timeout: 5 * time.Minute}
+		if egressCfg.AirGapped {
+			client.Transport = egress.New(logger, nil, egressCfg.Allow...)
+		}
+		return artifacts.NewS3(client, artifactCfg)
+	}
+	return nil, fmt.Errorf("unknown artifact store %q", artifactCfg.Store)
+}
+
+// readScreenshots reads the images of the screenshots uploaded to the artifact store, for the report to embed them.
+func readScreenshots(logger *slog.Logger, screenshots []browser.Screenshot) error {
+	var store artifacts.Store
+	opened := false
+	for i := range screenshots {
+		if screenshots[i].Ref == "" {
+			continue
+		}
+		if !opened {
+			egressConfig := &config.EgressConfig{}
+			var err error
+			if store, err = artifactStore(logger, egressConfig.Load()); err != nil {
+				return err
+			}
+			opened = true
+		}
+		image, err := artifacts.Read(store, screenshots[i].Ref)
+		if err != nil {
+			return err
+		}
+		screenshots[i].Image = image
+	}
+	return nil
+}
+
+// linkCheckers is the number of links checked at a time once a crawl is done.
+const linkCheckers = 8
+
+// crawlSite crawls the site of the target, testing each of its pages like a target of its own, then checks
+// the links found on every page and stores them along with their status. The frontier of the crawl is stored
+// as it goes, so an interrupted crawl can be resumed with resumeCrawl. The pages are tagged with the suite ID
+// of the run, or a new one when none is set, to be aggregated with the suite command.
+func crawlSite(logger *slog.Logger, db *sql.DB, r *runner.Runner, opts runner.Options, crawlCfg config.CrawlConfig, netlog bool) int {
+	frontier, err := crawl.NewFrontier(opts.Target, crawlCfg.MaxPages, crawlCfg.MaxDepth)
+	if err != nil {
+		logger.Error("failed to start crawl", "error", err)
+		return cli.ExitUsage
+	}
+	crawlID, err := uuid.NewV7()
+	if err != nil {
+		logger.Error("failed to create crawl ID", "error", err)
+		return cli.ExitConfig
+	}
+	if opts.SuiteID == "" {
+		opts.SuiteID = crawlID.String()
+	}
+	c := database.Crawl{CrawlID: crawlID, Root: opts.Target, SuiteID: opts.SuiteID, MaxPages: crawlCfg.MaxPages, MaxDepth: crawlCfg.MaxDepth}
+	if err = database.InsertCrawl(logger, db, c); err != nil {
+		logger.Error("failed to insert crawl into database", "error", err)
+		return cli.ExitStorage
+	}
+	root, _ := crawl.Normalize(opts.Target)
+	if err = database.InsertCrawlPage(logger, db, crawlID, crawl.Page{URL: root}); err != nil {
+		logger.Error("failed to insert crawl page into database", "error", err)
+		return cli.ExitStorage
+	}
+
+	logger.Info("crawling", "crawl_id", crawlID, "root", opts.Target, "suite", opts.SuiteID, "max_pages", crawlCfg.MaxPages,
+		"max_depth", crawlCfg.MaxDepth)
+	return runCrawl(logger, db, r, opts, crawlCfg, netlog, crawlID, frontier, nil)
+}
+
+// resumeCrawl resumes the crawl with the given ID, or the crawl one of whose pages was the test with that ID,
+// from its stored frontier: the pages left to crawl and the pages that failed to load are crawled, and the links
+// of the pages that were not checked yet are checked. The bounds and suite of the crawl are the ones it started
+// with, the other options are those of the resume command.
+func resumeCrawl(logger *slog.Logger, db *sql.DB, r *runner.Runner, opts runner.Options, crawlCfg config.CrawlConfig, netlog bool, args []string) int {
+	if len(args) < 1 {
+		logger.Error("usage: web-tester resume [run flags] <test-id>")
+		return cli.ExitUsage
+	}
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		logger.Error("invalid test id", "error", err)
+		return cli.ExitUsage
+	}
+	if db == nil {
+		logger.Error("resume reads the crawl from the database, which is not available")
+		return cli.ExitStorage
+	}
+	c, err := database.GetCrawl(db, id)
+	if err != nil {
+		logger.Error("failed to get crawl", "id", id, "error", err)
+		return cli.ExitStorage
+	}
+	pages, err := database.GetCrawlPages(db, c.CrawlID)
+	if err != nil {
+		logger.Error("failed to get crawl frontier", "error", err)
+		return cli.ExitStorage
+	}
+
+	frontier, err := crawl.NewFrontier(c.Root, c.MaxPages, c.MaxDepth)
+	if err != nil {
+		logger.Error("failed to resume crawl", "error", err)
+		return cli.ExitConfig
+	}
+	var seen, queued []crawl.Page
+	var visited []database.CrawlPage
+	for _, page := range pages {
+		seen = append(seen, page.Page)
+		if page.State == crawl.StateVisited {
+			visited = append(visited, page)
+		} else {
+			queued = append(queued, page.Page)
+		}
+	}
+	frontier.Restore(seen, queued, len(visited))
+	crawlCfg.MaxPages, crawlCfg.MaxDepth = c.MaxPages, c.MaxDepth
+	opts.SuiteID = c.SuiteID
+
+	logger.Info("resuming crawl", "crawl_id", c.CrawlID, "root", c.Root, "status", c.Status, "visited", len(visited), "queued", len(queued))
+	return runCrawl(logger, db, r, opts, crawlCfg, netlog, c.CrawlID, frontier, visited)
+}
+
+// crawledPage is a page crawled by runCrawl, or restored from the frontier of the crawl it resumes.
+type crawledPage struct {
+	page         crawl.Page
+	result       runner.Result
+	code         int
+	linksChecked bool
+}
+
+// runCrawl crawls the pages of the frontier, storing the frontier as it goes, then checks the links found on
+// the pages crawled and on the visited pages restored from an interrupted crawl. The pages are loaded and the
+// links checked keeping to the politeness limits of the crawl towards their host.
+func runCrawl(logger *slog.Logger, db *sql.DB, r *runner.Runner, opts runner.Options, crawlCfg config.CrawlConfig, netlog bool,
+	crawlID uuid.UUID, frontier *crawl.Frontier, visited []database.CrawlPage) int {
+	logger.Info("crawl politeness", "concurrency", crawlCfg.Concurrency, "host_concurrency", crawlCfg.HostConcurrency,
+		"host_rate", crawlCfg.HostRate, "jitter", crawlCfg.Jitter)
+	hosts := crawl.NewHosts(crawl.Politeness{HostConcurrency: crawlCfg.HostConcurrency, HostRate: crawlCfg.HostRate, Jitter: crawlCfg.Jitter})
+	checker := crawl.NewChecker(crawlCfg.LinkTimeout, hosts)
+	opts.Links = true
+	exit := cli.ExitOK
+
+	var pages []crawledPage
+	for _, page := range visited {
+		result := runner.Result{TestID: page.TestID, PageStatus: page.Status, Links: page.Links}
+		pages = append(pages, crawledPage{page: page.Page, result: result, linksChecked: page.LinksChecked})
+		if page.Status > 0 {
+			checker.Visited(page.URL, page.Status, nil)
+		}
+	}
+
+	// the frontier is only used by this goroutine, the pages are loaded by up to Concurrency others
+	crawled := make(chan crawledPage)
+	inFlight := 0
+	for {
+		for inFlight < max(crawlCfg.Concurrency, 1) {
+			page, ok := frontier.Next()
+			if !ok {
+				break
+			}
+			inFlight++
+			go func(opts runner.Options) {
+				release, _ := hosts.Acquire(context.Background(), page.URL)
+				defer release()
+				opts.Target = page.URL
+				logger.Info("crawling page", "url", page.URL, "depth", page.Depth)
+				result, code := runTarget(logger, r, opts, netlog)
+				crawled <- crawledPage{page: page, result: result, code: code}
+			}(opts)
+		}
+		if inFlight == 0 {
+			break
+		}
+		done := <-crawled
+		inFlight--
+		exit = worseExit(exit, done.code)
+		// the pages the browser could not load are broken links, the others are checked like any link
+		switch {
+		case done.code == cli.ExitNavigation:
+			checker.Visited(done.page.URL, 0, browser.ErrNavigation)
+		case done.result.PageStatus > 0:
+			checker.Visited(done.page.URL, done.result.PageStatus, nil)
+		}
+
+		// the pages found are stored before the page is marked visited, so they are not lost when the crawl is
+		// interrupted in between
+		for _, page := range frontier.Add(done.page, done.result.Links) {
+			if err := database.InsertCrawlPage(logger, db, crawlID, page); err != nil {
+				logger.Error("failed to insert crawl page into database", "error", err)
+				exit = worseExit(exit, cli.ExitStorage)
+			}
+		}
+		state := crawl.StateVisited
+		if done.code == cli.ExitNavigation || done.code == cli.ExitBrowser || done.code == cli.ExitConfig {
+			state = crawl.StateFailed
+		}
+		crawlPage := database.CrawlPage{Page: done.page, State: state, TestID: done.result.TestID, Status: done.result.PageStatus, Links: done.result.Links}
+		if err := database.SetCrawlPage(logger, db, crawlID, crawlPage); err != nil {
+			logger.Error("failed to update crawl page in database", "error", err)
+			exit = worseExit(exit, cli.ExitStorage)
+		}
+		pages = append(pages, done)
+	}
+
+	// every link is checked once, however many pages link to it
+	var unique []string
+	seen := map[string]bool{}
+	for _, page := range pages {
+		if page.linksChecked {
+			continue
+		}
+		for _, link := range page.result.Links {
+			if normalized, ok := crawl.Normalize(link); ok && !seen[normalized] {
+				seen[normalized] = true
+				unique = append(unique, normalized)
+			}
+		}
+	}
+	logger.Info("checking links", "links", len(unique))
+	var checks sync.WaitGroup
+	checkers := make(chan struct{}, linkCheckers)
+	for _, link := range unique {
+		checks.Add(1)
+		checkers <- struct{}{}
+		go func() {
+			defer checks.Done()
+			defer func() { <-checkers }()
+			checker.Check(context.Background(), link)
+		}()
+	}
+	checks.Wait()
+
+	broken := 0
+	for _, page := range pages {
+		if page.linksChecked {
+			continue
+		}
+		for _, href := range page.result.Links {
+			normalized, ok := crawl.Normalize(href)
+			if !ok {
+				continue
+			}
+			link := checker.Check(context.Background(), normalized)
+			if link.Broken() {
+				broken++
+				logger.Warn("broken link", "test_id", page.result.TestID, "url", link.URL, "status", link.Status, "reason", link.Reason())
+			}
+			if err := database.InsertLink(logger, db, page.result.TestID, link); err != nil {
+				logger.Error("failed to insert link into database", "error", err)
+				exit = worseExit(exit, cli.ExitStorage)
+			}
+		}
+		if err := database.SetLinksChecked(logger, db, crawlID, page.page.URL); err != nil {
+			logger.Error("failed to update crawl page in database", "error", err)
+			exit = worseExit(exit, cli.ExitStorage)
+		}
+	}
+
+	if err := database.FinishCrawl(logger, db, crawlID); err != nil {
+		logger.Error("failed to finish crawl in database", "error", err)
+		exit = worseExit(exit, cli.ExitStorage)
+	}
+	logger.Info("crawl finished", "crawl_id", crawlID, "suite", opts.SuiteID, "pages", len(pages), "links", len(unique), "broken", broken)
+	return exit
+}
+
+// exitSeverity orders the exit codes of the runs from the least to the most severe failure.
+var exitSeverity = []int{cli.ExitOK, cli.ExitStorage, cli.ExitAssertion, cli.ExitTampered, cli.ExitNavigation,
+	cli.ExitBrowser, cli.ExitConfig, cli.ExitUsage}
+
+// worseExit returns the most severe of two exit codes, so a run of several URLs exits with its worst failure. A code
+// missing from exitSeverity is ranked above all the others rather than taken for a success.
+func worseExit(a, b int) int {
+	if exitRank(b) > exitRank(a) {
+		return b
+	}
+	return a
+}
+
+// exitRank returns the rank of an exit code in exitSeverity, past its end when it is missing.
+func exitRank(code int) int {
+	if i := slices.Index(exitSeverity, code); i >= 0 {
+		return i
+	}
+	return len(exitSeverity)
+}
+
+// listFlag is a flag that can be repeated, collecting its values.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runFlags are the flags of the run command besides the scope rules.
+type runFlags struct {
+	compareCache bool
+	trace        bool
+	video        bool
+	pdf          bool
+	crawl        bool
+	coverage     bool
+	scroll       bool
+	explore      bool
+	netlog       bool
+	device       string
+	cpuThrottle  float64
+	loadState    string
+	saveState    string
+	loadSession  string
+	saveSession  string
+	userAgent    string
+	locale       string
+	geolocation  string
+	timezone     string
+	// args are the arguments left after the flags
+	args []string
+}
+
+// parseRunFlags parses the flags of the run command, adding the --include-url and --exclude-url rules
+// to the scope rules of the environment and overriding its URL list and shard with --urls, --shard-index,
+// --shard-total and --suite, its crawl politeness limits with the --crawl-* flags, and its timeouts with the
+// --*-timeout flags.
+func parseRunFlags(logger *slog.Logger, args []string, scopeCfg *config.ScopeConfig, shardCfg *config.ShardConfig, crawlCfg *config.CrawlConfig,
+	timeoutCfg *config.TimeoutConfig) runFlags {
+	var parsed runFlags
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	include, exclude := listFlag(scopeCfg.Include), listFlag(scopeCfg.Exclude)
+	flags.Var(&include, "include-url", "only persist the requests to URLs matching this glob, or regexp with re:, can be repeated")
+	flags.Var(&exclude, "exclude-url", "do not persist the requests to URLs matching this glob, or regexp with re:, can be repeated")
+	flags.BoolVar(&parsed.coverage, "coverage", false, "measure the unused bytes of every script and stylesheet of the page")
+	flags.StringVar(&parsed.device, "device", "", "emulate a device preset: iphone-14, pixel-7, ipad or desktop-1080p")
+	flags.Float64Var(&parsed.cpuThrottle, "cpu-throttle", 0, "slow down the CPU of the page by this factor, e.g. 4 for a low-end mobile device")
+	flags.StringVar(&parsed.userAgent, "user-agent", "", "send this user agent instead of the browser's or the device's")
+	flags.StringVar(&parsed.locale, "locale", "", "prefer this language, e.g. fr-FR, in Accept-Language, navigator.language and Intl")
+	flags.StringVar(&parsed.geolocation, "geolocation", "", "place the browser at latitude,longitude[,accuracy in meters]")
+	flags.StringVar(&parsed.timezone, "timezone", "", "run the browser in this IANA timezone, e.g. Europe/Paris")
+	flags.StringVar(&parsed.loadState, "load-state", "", "start from the cookies and localStorage of this storage state file")
+	flags.StringVar(&parsed.saveState, "save-state", "", "save the cookies and localStorage to this file once the page and journey ran")
+	flags.StringVar(&parsed.loadSession, "load-session", "", "start from the cookies, localStorage and sessionStorage of this session file, when it exists")
+	flags.StringVar(&parsed.saveSession, "save-session", "", "save the cookies, localStorage and sessionStorage to this session file once the page and journey ran")
+	flags.BoolVar(&parsed.netlog, "netlog", false, "record the Chrome network log of the run in NETLOG_DIR")
+	flags.BoolVar(&parsed.trace, "trace", false, "record a Chrome trace of the page load in TRACE_DIR")
+	flags.BoolVar(&parsed.video, "video", false, "record a video of the session in VIDEO_DIR")
+	flags.BoolVar(&parsed.pdf, "pdf", false, "print the page to PDF in PDF_DIR once loaded and once the journey ran")
+	flags.BoolVar(&parsed.scroll, "scroll", false, "scroll the page to its end once loaded until its lazy-loaded content and infinite lists stop growing")
+	flags.BoolVar(&parsed.explore, "explore", false, "scroll the page and click its buttons and menu toggles once the journey ran, within the EXPLORE_* budget")
+	flags.StringVar(&shardCfg.URLsFile, "urls", shardCfg.URLsFile, "test every URL of this file, one per line, instead of the default target")
+	flags.IntVar(&shardCfg.ShardIndex, "shard-index", shardCfg.ShardIndex, "test only the URLs of this shard, from 0 to --shard-total - 1")
+	flags.IntVar(&shardCfg.ShardTotal, "shard-total", shardCfg.ShardTotal, "split the URLs deterministically across this many shards")
+	flags.StringVar(&shardCfg.SuiteID, "suite", shardCfg.SuiteID, "tag the runs with this suite ID, aggregated across shards with the suite command")
+	flags.BoolVar(&parsed.crawl, "crawl", false, "crawl the pages of the target's origin, up to CRAWL_MAX_PAGES pages CRAWL_MAX_DEPTH links away, and check their links")
+	flags.IntVar(&crawlCfg.Concurrency, "crawl-concurrency", crawlCfg.Concurrency, "crawl this many pages at a time")
+	flags.IntVar(&crawlCfg.HostConcurrency, "crawl-host-concurrency", crawlCfg.HostConcurrency, "load or check at most this many pages of a host at a time, unlimited when 0")
+	flags.Float64Var(&crawlCfg.HostRate, "crawl-host-rate", crawlCfg.HostRate, "load or check at most this many pages of a host per second, unlimited when 0")
+	flags.DurationVar(&crawlCfg.Jitter, "crawl-jitter", crawlCfg.Jitter, "wait a random delay of up to this duration, e.g. 500ms, before loading or checking a page")
+	flags.DurationVar(&timeoutCfg.Run, "run-timeout", timeoutCfg.Run, "kill the browser and fail the run once it ran this long, e.g. 2m")
+	flags.DurationVar(&timeoutCfg.Navigation, "navigation-timeout", timeoutCfg.Navigation, "fail the run when the target takes longer than this to load, unbounded when 0")
+	flags.DurationVar(&timeoutCfg.BodyFetch, "body-fetch-timeout", timeoutCfg.BodyFetch, "give up fetching a response body after this long, unbounded when 0")
+	flags.DurationVar(&timeoutCfg.Idle, "idle-timeout", timeoutCfg.Idle, "after the wait time, wait up to this long for the network to be idle, not at all when 0")
+	flags.BoolVar(&parsed.compareCache, "compare-cache", false, "load the target a second time with a warm cache and compare it with the cold load")
+	if err := flags.Parse(args); err != nil {
+		logger.Error("invalid run flags", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+	scopeCfg.Include, scopeCfg.Exclude = include, exclude
+	parsed.args = flags.Args()
+	return parsed
+}
+
+// serveRPC runs the tests submitted over the JSON-RPC protocol on stdin until it is closed, answering on stdout.
+func serveRPC(logger *slog.Logger, db *sql.DB, r *runner.Runner) {
+	serverConfig := &config.ServerConfig{}
+	serverCfg := serverConfig.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := rpc.New(logger, db, r, serverCfg.Workers).Serve(ctx, os.Stdin, os.Stdout); err != nil {
+		logger.Error("failed to serve json-rpc", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+}
+
+// serve runs the REST API, along with the schedules configured in SCHEDULES_FILE, until the process is interrupted.
+func serve(logger *slog.Logger, db *sql.DB, r *runner.Runner, store artifacts.Store, bodies *bodystore.Store) {
+	serverConfig := &config.ServerConfig{}
+	serverCfg := serverConfig.Load()
+	retentionConfig := &config.RetentionConfig{}
+	retentionCfg := retentionConfig.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if retentionCfg.Days > 0 && db != nil {
+		go retain(ctx, logger, db, store, bodies, time.Duration(retentionCfg.Days)*24*time.Hour)
+	}
+
+	s := server.New(logger, db, r, serverCfg.QueueSize)
+	s.Start(ctx, serverCfg.Workers)
+
+	schedules, err := config.LoadSchedules()
+	if err != nil {
+		logger.Error("failed to load schedules", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	if len(schedules) > 0 {
+		sched, err := scheduler.New(logger, s, schedules)
+		if err != nil {
+			logger.Error("failed to create scheduler", "error", err)
+			os.Exit(cli.ExitConfig)
+		}
+		go sched.Run(ctx)
+	}
+
+	if err := s.ListenAndServe(ctx, serverCfg.Addr); err != nil {
+		logger.Error("failed to serve api", "error", err)
+	}
+}
+
+// retentionInterval is how often serve mode prunes the runs past the retention period.
+const retentionInterval = time.Hour
+
+// retain prunes the runs older than maxAge, along with their artifacts, every retentionInterval until ctx is done.
+func retain(ctx context.Context, logger *slog.Logger, db *sql.DB, store artifacts.Store, bodies *bodystore.Store, maxAge time.Duration) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		if pruned, err := pruneRuns(logger, db, store, bodies, time.Now().Add(-maxAge)); err != nil {
+			logger.Error("failed to prune runs", "error", err)
+		} else if pruned.Runs > 0 || pruned.Crawls > 0 {
+			logger.Info("pruned runs past the retention period", "runs", pruned.Runs, "crawls", pruned.Crawls)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune deletes the runs, with their events and artifacts, older than --older-than, e.g. 30d or 12h, printing
+// what was deleted as JSON.
+func prune(logger *slog.Logger, db *sql.DB, store artifacts.Store, bodies *bodystore.Store, args []string) {
+	flags := flag.NewFlagSet("prune", flag.ContinueOnError)
+	olderThan := flags.String("older-than", "", "delete the runs started longer ago than this, e.g. 30d or 12h")
+	if err := flags.Parse(args); err != nil || *olderThan == "" || flags.NArg() > 0 {
+		logger.Error("usage: web-tester prune --older-than <age>")
+		os.Exit(cli.ExitUsage)
+	}
+	maxAge, err := parseAge(*olderThan)
+	if err != nil {
+		logger.Error("invalid age", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+	if db == nil {
+		logger.Error("prune deletes runs from the database, which is not available")
+		os.Exit(cli.ExitStorage)
+	}
+
+	pruned, err := pruneRuns(logger, db, store, bodies, time.Now().Add(-maxAge))
+	if err != nil {
+		logger.Error("failed to prune runs", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	output, err := json.MarshalIndent(pruned, "", "  ")
+	if err != nil {
+		logger.Error("failed to encode pruned runs", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	fmt.Println(string(output))
+}
+
+// pruneRuns deletes the runs started before the given time from the database, then their artifacts and the bodies
+// no remaining run refers to. An artifact that cannot be deleted is logged and left behind, as its run is gone already.
+func pruneRuns(logger *slog.Logger, db *sql.DB, store artifacts.Store, bodies *bodystore.Store, before time.Time) (database.Pruned, error) {
+	pruned, err := database.Prune(logger, db, before)
+	if err != nil {
+		return pruned, err
+	}
+	for _, ref := range pruned.Artifacts {
+		if err = artifacts.Remove(store, ref); err != nil {
+			logger.Error("failed to delete artifact", "ref", ref, "error", err)
+		}
+	}
+	deleted := pruned.Bodies[:0]
+	for _, ref := range pruned.Bodies {
+		// a body handed out to a run still in progress is kept, its events refer to it once stored
+		if bodies != nil && !bodies.Release(ref, pruned.Since) {
+			continue
+		}
+		if err = artifacts.Remove(store, ref); err != nil {
+			logger.Error("failed to delete body", "ref", ref, "error", err)
+		}
+		deleted = append(deleted, ref)
+	}
+	pruned.Bodies = deleted
+	return pruned, nil
+}
+
+// parseAge parses an age in days, e.g. 30d, or as a Go duration, e.g. 12h.
+func parseAge(age string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(age, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days %q", days)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(age)
+}
+
+// inspect opens the terminal UI on the run whose test ID is the first argument.
+func inspect(logger *slog.Logger, db *sql.DB, args []string) {
+	if len(args) < 1 {
+		logger.Error("usage: web-tester tui <test-id>")
+		os.Exit(cli.ExitUsage)
+	}
+	if db == nil {
+		logger.Error("the tui reads runs from the database, which is not available")
+		os.Exit(cli.ExitStorage)
+	}
+
+	testID, err := uuid.Parse(args[0])
+	if err != nil {
+		logger.Error("invalid test id", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+
+	events, err := database.GetEvents(db, testID)
+	if err != nil {
+		logger.Error("failed to get events", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	findings, err := database.GetFindings(db, testID)
+	if err != nil {
+		logger.Error("failed to get findings", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+
+	if err = tui.New(os.Stdout, events, findings).Run(os.Stdin); err != nil {
+		logger.Error("failed to read input", "error", err)
+	}
+}
+
+// completion prints the completion script for the shell given as the first argument.
+func completion(logger *slog.Logger, args []string) {
+	if len(args) < 1 {
+		logger.Error("usage: web-tester completion <bash|zsh|fish>")
+		os.Exit(cli.ExitUsage)
+	}
+	script, err := cli.Completion(args[0])
+	if err != nil {
+		logger.Error("failed to generate completion", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+	fmt.Print(script)
+}
+
+// compare prints the differences between the runs whose test IDs are the first two arguments,
+// exiting with cli.ExitAssertion when they differ.
+func compare(logger *slog.Logger, db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	harPath := flags.String("har", "", "compare the traffic of this HAR file, captured elsewhere, with the head run")
+	err := flags.Parse(args)
+	args = flags.Args()
+	if *harPath != "" {
+		// the HAR takes the place of the base run
+		args = append([]string{""}, args...)
+	}
+	if err != nil || len(args) < 2 {
+		logger.Error("usage: web-tester diff <base-test-id> <head-test-id> | --har <file> <head-test-id>")
+		os.Exit(cli.ExitUsage)
+	}
+	if db == nil {
+		logger.Error("diff reads runs from the database, which is not available")
+		os.Exit(cli.ExitStorage)
+	}
+
+	var runs [2]database.TestRun
+	var events [2][]database.StoredEvent
+	for i, arg := range args[:2] {
+		if arg == "" {
+			events[i] = readHAR(logger, *harPath)
+			continue
+		}
+		testID, err := uuid.Parse(arg)
+		if err != nil {
+			logger.Error("invalid test id", "error", err)
+			os.Exit(cli.ExitUsage)
+		}
+		if runs[i], err = database.GetTestRun(db, testID); err != nil {
+			logger.Error("failed to get test run", "test_id", testID, "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+		if events[i], err = database.GetEvents(db, testID); err != nil {
+			logger.Error("failed to get events", "test_id", testID, "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+	}
+
+	report := diff.Compare(events[0], events[1], runs[1].TargetURL)
+	base := fmt.Sprintf("%s (%s)", runs[0].TestID, runs[0].StartedAt.Format(time.RFC3339))
+	if *harPath != "" {
+		base = *harPath
+	}
+	fmt.Printf("diff %s -> %s (%s)\n", base, runs[1].TestID, runs[1].StartedAt.Format(time.RFC3339))
+	report.Write(os.Stdout)
+	if !report.Empty() {
+		os.Exit(cli.ExitAssertion)
+	}
+}
+
+// readHAR reads the events of a HAR file, exiting on failure.
+func readHAR(logger *slog.Logger, path string) []database.StoredEvent {
+	file, err := os.Open(path)
+	if err != nil {
+		logger.Error("failed to open HAR file", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+	defer file.Close()
+	events, err := har.Read(file)
+	if err != nil {
+		logger.Error("failed to read HAR file", "path", path, "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+	return events
+}
+
+// logIn opens a browser window on the URL given as argument for a person to log in, then saves the session
+// to the --save-session file once they press Enter, so later runs start from it with --load-session.
+func logIn(logger *slog.Logger, args []string) {
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	sessionPath := flags.String("save-session", "session.json", "save the cookies, localStorage and sessionStorage to this session file")
+	if err := flags.Parse(args); err != nil || flags.NArg() < 1 {
+		logger.Error("usage: web-tester login [--save-session file] <url>")
+		os.Exit(cli.ExitUsage)
+	}
+
+	client := browser.NewInteractive(flags.Arg(0))
 	defer client.Cancel()
+	if err := client.Open(); err != nil {
+		logger.Error("failed to open the login page", "error", err)
+		if errors.Is(err, browser.ErrNavigation) {
+			os.Exit(cli.ExitNavigation)
+		}
+		os.Exit(cli.ExitBrowser)
+	}
 
-	dbConfig := &config.DBConfig{}
-	db, err := database.Init(logger, dbConfig.Load())
+	fmt.Fprintln(os.Stderr, "Log in in the browser window, then press Enter here to save the session.")
+	if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("failed to read input", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+
+	state, err := client.ExportState()
+	if err != nil {
+		logger.Error("failed to export session", "error", err)
+		os.Exit(cli.ExitBrowser)
+	}
+	if err = browser.SaveState(*sessionPath, state); err != nil {
+		logger.Error("failed to save session", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	logger.Info("session saved", "path", *sessionPath, "cookies", len(state.Cookies))
+}
+
+// aggregate prints the JSON aggregate of the runs of the suite whose ID is the first argument, across
+// its shards, exiting with cli.ExitAssertion when a shard is missing or a run did not complete.
+func aggregate(logger *slog.Logger, db *sql.DB, args []string) {
+	if len(args) < 1 {
+		logger.Error("usage: web-tester suite <suite-id>")
+		os.Exit(cli.ExitUsage)
+	}
+	if db == nil {
+		logger.Error("suite reads runs from the database, which is not available")
+		os.Exit(cli.ExitStorage)
+	}
+
+	runs, err := database.GetSuiteRuns(db, args[0])
+	if err != nil {
+		logger.Error("failed to get suite runs", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	findings := map[string][]audit.Finding{}
+	for _, run := range runs {
+		if findings[run.TestID.String()], err = database.GetFindings(db, run.TestID); err != nil {
+			logger.Error("failed to get findings", "test_id", run.TestID, "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+	}
+
+	suite := report.NewSuite(args[0], runs, findings)
+	output, err := json.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		logger.Error("failed to encode suite", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	fmt.Println(string(output))
+	if !suite.Passed() {
+		os.Exit(cli.ExitAssertion)
+	}
+}
+
+// review lists the response bodies of the review queue waiting for a verdict as JSON, or with a review ID
+// and a verdict, records the verdict of the review along with an optional note.
+func review(logger *slog.Logger, db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("review", flag.ContinueOnError)
+	limit := flags.Int("limit", 20, "list at most this many pending reviews")
+	if err := flags.Parse(args); err != nil || flags.NArg() == 1 {
+		logger.Error("usage: web-tester review [--limit n] [<review-id> ok|bad [note]]")
+		os.Exit(cli.ExitUsage)
+	}
+	args = flags.Args()
+	if db == nil {
+		logger.Error("review reads the review queue from the database, which is not available")
+		os.Exit(cli.ExitStorage)
+	}
+
+	if len(args) == 0 {
+		items, err := database.GetPendingReviews(db, *limit)
+		if err != nil {
+			logger.Error("failed to get pending reviews", "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+		output, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			logger.Error("failed to encode reviews", "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+		fmt.Println(string(output))
+		return
+	}
+
+	reviewID, err := uuid.Parse(args[0])
+	if err != nil {
+		logger.Error("invalid review id", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+	verdict := args[1]
+	if verdict != database.VerdictOK && verdict != database.VerdictBad {
+		logger.Error("invalid verdict, expected ok or bad", "verdict", verdict)
+		os.Exit(cli.ExitUsage)
+	}
+	err = database.SetReviewVerdict(db, reviewID, verdict, strings.Join(args[2:], " "))
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Error("unknown review", "review_id", reviewID)
+		os.Exit(cli.ExitUsage)
+	}
+	if err != nil {
+		logger.Error("failed to record verdict", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	logger.Info("review recorded", "review_id", reviewID, "verdict", verdict)
+}
+
+// results prints the runs, a run, or the events of a run stored in the database as a table, or as JSON with --json,
+// or writes the body of the response to a request of a run to the standard output.
+func results(logger *slog.Logger, db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("results", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print JSON instead of a table")
+	limit := flags.Int("limit", 20, "list at most this many runs or events, all events when 0")
+	domain := flags.String("domain", "", "list only the events of this domain")
+	status := flags.Int("status", 0, "list only the events with this HTTP status")
+	eventType := flags.String("type", "", "list only the events of this type, request or response")
+	usage := "usage: web-tester results runs [--json] [--limit n] | show [--json] <test-id> | events [--json] [--limit n] [--domain d] [--status n] [--type t] <test-id> | body <test-id> <request-id>"
+	if len(args) < 1 {
+		logger.Error(usage)
+		os.Exit(cli.ExitUsage)
+	}
+	subcommand := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		logger.Error(usage)
+		os.Exit(cli.ExitUsage)
+	}
+	args = flags.Args()
+	wanted := map[string]int{"runs": 0, "show": 1, "events": 1, "body": 2}
+	if n, ok := wanted[subcommand]; !ok || len(args) != n {
+		logger.Error(usage)
+		os.Exit(cli.ExitUsage)
+	}
+	if db == nil {
+		logger.Error("results reads runs from the database, which is not available")
+		os.Exit(cli.ExitStorage)
+	}
+	var testID uuid.UUID
+	if len(args) > 0 {
+		var err error
+		if testID, err = uuid.Parse(args[0]); err != nil {
+			logger.Error("invalid test id", "error", err)
+			os.Exit(cli.ExitUsage)
+		}
+	}
+
+	var output interface{}
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	switch subcommand {
+	case "runs":
+		runs, err := database.ListTestRuns(db, *limit)
+		if err != nil {
+			logger.Error("failed to get runs", "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+		output = runs
+		fmt.Fprintln(table, "TEST ID\tSTARTED\tSTATUS\tREQUESTS\tRESPONSES\tTARGET")
+		for _, run := range runs {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%d\t%s\n", run.TestID, run.StartedAt.Format(time.RFC3339), run.Status,
+				run.RequestCount, run.ResponseCount, run.TargetURL)
+		}
+	case "show":
+		run, err := database.GetTestRun(db, testID)
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("unknown test", "test_id", testID)
+			os.Exit(cli.ExitUsage)
+		}
+		if err != nil {
+			logger.Error("failed to get run", "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+		findings, err := database.GetFindings(db, testID)
+		if err != nil {
+			logger.Error("failed to get findings", "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+		output = struct {
+			database.TestRun
+			Findings []audit.Finding `json:"findings"`
+		}{run, findings}
+		for _, field := range [][2]string{
+			{"Test ID", run.TestID.String()}, {"Target", run.TargetURL}, {"Status", run.Status},
+			{"Started", run.StartedAt.Format(time.RFC3339)}, {"Finished", run.FinishedAt.Format(time.RFC3339)},
+			{"Browser", run.BrowserVersion}, {"Tool", run.ToolVersion}, {"Requests", fmt.Sprint(run.RequestCount)},
+			{"Responses", fmt.Sprint(run.ResponseCount)}, {"Schedule", run.ScheduleID}, {"Suite", run.SuiteID}, {"Device", run.Device},
+		} {
+			if field[1] != "" {
+				fmt.Fprintf(table, "%s:\t%s\n", field[0], field[1])
+			}
+		}
+		if s := run.Statuses; s != nil {
+			fmt.Fprintf(table, "Statuses:\t1xx %d, 2xx %d, 3xx %d, 4xx %d, 5xx %d, failed %d\n",
+				s.Informational, s.Success, s.Redirect, s.ClientError, s.ServerError, s.Failed)
+			for _, slow := range s.Slowest {
+				fmt.Fprintf(table, "Slowest:\t%.0f ms %s\n", slow.TotalMS, slow.URL)
+			}
+			for _, large := range s.Largest {
+				fmt.Fprintf(table, "Largest:\t%.0f bytes %s\n", large.Bytes, large.URL)
+			}
+		}
+		if len(findings) > 0 {
+			fmt.Fprintln(table, "\nSEVERITY\tCHECK\tURL\tMESSAGE")
+			for _, f := range findings {
+				fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", f.Severity, f.Check, f.URL, f.Message)
+			}
+		}
+	case "events":
+		events, err := database.FindEvents(db, testID, database.EventFilter{Domain: *domain, Type: *eventType, Status: int64(*status), Limit: *limit})
+		if err != nil {
+			logger.Error("failed to get events", "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+		output = events
+		fmt.Fprintln(table, "CREATED\tTYPE\tSTATUS\tBYTES\tURL")
+		for _, e := range events {
+			fmt.Fprintf(table, "%s\t%s\t%d\t%.0f\t%s\n", e.CreatedAt.Format(time.RFC3339), e.Type, e.Status, e.EncodedBytes, e.URL)
+		}
+	case "body":
+		body, ref, err := database.GetResponseBody(db, testID, args[1])
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("no response to the request", "test_id", testID, "request_id", args[1])
+			os.Exit(cli.ExitUsage)
+		}
+		if err != nil {
+			logger.Error("failed to get body", "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+		// bodies too large for the database are read back from the body store
+		if ref != "" {
+			egressConfig := &config.EgressConfig{}
+			store, err := artifactStore(logger, egressConfig.Load())
+			if err != nil {
+				logger.Error("failed to open artifact store", "error", err)
+				os.Exit(cli.ExitConfig)
+			}
+			if body, err = artifacts.Read(store, ref); err != nil {
+				logger.Error("failed to read body", "ref", ref, "error", err)
+				os.Exit(cli.ExitStorage)
+			}
+		}
+		os.Stdout.Write(body)
+		return
+	}
+
+	if *asJSON {
+		encoded, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			logger.Error("failed to encode results", "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+		fmt.Println(string(encoded))
+		return
+	}
+	table.Flush()
+}
+
+// render prints the report of the run whose test ID is the first argument, in REPORT_FORMAT,
+// using the template in REPORT_TEMPLATE when it is set.
+func render(logger *slog.Logger, db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	consent := flags.String("consent", "", "render the consent report of the run for a regulation profile, gdpr or ccpa")
+	output := flags.String("output", "", "write the report to this file, signed when SIGNING_KEY_FILE is set")
+	if err := flags.Parse(args); err != nil || flags.NArg() < 1 {
+		logger.Error("usage: web-tester report [--consent gdpr|ccpa] [--output file] <test-id>")
+		os.Exit(cli.ExitUsage)
+	}
+	args = flags.Args()
+
+	var profile report.ConsentProfile
+	if *consent != "" {
+		var ok bool
+		if profile, ok = report.ConsentProfiles[*consent]; !ok {
+			logger.Error("unknown consent profile", "profile", *consent)
+			os.Exit(cli.ExitUsage)
+		}
+	}
+	if db == nil {
+		logger.Error("report reads runs from the database, which is not available")
+		os.Exit(cli.ExitStorage)
+	}
+
+	testID, err := uuid.Parse(args[0])
+	if err != nil {
+		logger.Error("invalid test id", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+
+	run, err := database.GetTestRun(db, testID)
+	if err != nil {
+		logger.Error("failed to get test run", "test_id", testID, "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	reportConfig := &config.ReportConfig{}
+	reportCfg := reportConfig.Load()
+	costConfig := &config.CostConfig{}
+	costCfg := costConfig.Load()
+	prices, err := report.ParsePrices(costCfg.Prices)
+	if err != nil {
+		logger.Error("failed to parse data prices", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+
+	out, file := io.Writer(os.Stdout), (*os.File)(nil)
+	if *output != "" {
+		if file, err = os.Create(*output); err != nil {
+			logger.Error("failed to create report file", "error", err)
+			os.Exit(cli.ExitUsage)
+		}
+		out = file
+	}
+
+	if *consent != "" {
+		renderConsent(logger, db, out, run, profile, reportCfg)
+		closeReport(logger, file)
+		return
+	}
+
+	events, err := database.GetEvents(db, testID)
+	if err != nil {
+		logger.Error("failed to get events", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	findings, err := database.GetFindings(db, testID)
+	if err != nil {
+		logger.Error("failed to get findings", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+
+	data := report.New(run, events, findings)
+	if data.Milestones, err = database.GetMilestones(db, testID); err != nil {
+		logger.Error("failed to get milestones", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	if data.Interactions, err = database.GetInteractions(db, testID); err != nil {
+		logger.Error("failed to get interactions", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	if data.CriticalChain, err = database.GetCriticalChain(db, testID); err != nil {
+		logger.Error("failed to get critical request chain", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	if data.Coverage, err = database.GetCoverage(db, testID); err != nil {
+		logger.Error("failed to get coverage", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	if data.Storage, err = database.GetStorageSnapshot(db, testID); err != nil {
+		logger.Error("failed to get web storage snapshot", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	if data.Faults, err = database.GetFaults(db, testID); err != nil {
+		logger.Error("failed to get faults", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	transactions, err := database.GetTransactions(db, testID)
 	if err != nil {
-		logger.Error("failed to initialize database", "error: ", err)
+		logger.Error("failed to get transactions", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	data.Waterfall = report.NewWaterfall(events, transactions)
+	if data.Screenshots, err = database.GetScreenshots(db, testID); err != nil {
+		logger.Error("failed to get screenshots", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	if err = readScreenshots(logger, data.Screenshots); err != nil {
+		logger.Error("failed to get screenshots", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	if data.BrokenLinks, err = database.GetBrokenLinks(db, testID); err != nil {
+		logger.Error("failed to get broken links", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	if data.Edge, err = database.GetEdgeTiming(db, testID); err != nil {
+		logger.Error("failed to get edge timing", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	comparison, compared, err := database.GetCacheComparison(db, testID)
+	if err != nil {
+		logger.Error("failed to get cache comparison", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	var warm *browser.LoadStats
+	if compared {
+		warm = &comparison.Warm
+	}
+	data.Cost = report.NewCost(events, warm, prices, costCfg.Currency)
+	if err = report.Render(out, reportCfg.Format, reportCfg.Template, data); err != nil {
+		logger.Error("failed to render report", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	closeReport(logger, file)
+}
+
+// specify writes the OpenAPI document synthesized from the API traffic of a stored run, for the origin with the
+// most API requests unless --origin names another one.
+func specify(logger *slog.Logger, db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	origin := flags.String("origin", "", "document the API served from this origin, e.g. https://api.example.com")
+	title := flags.String("title", "", "title of the document, the origin by default")
+	output := flags.String("output", "", "write the document to this file")
+	if err := flags.Parse(args); err != nil || flags.NArg() < 1 {
+		logger.Error("usage: web-tester openapi [--origin origin] [--title title] [--output file] <test-id>")
+		os.Exit(cli.ExitUsage)
+	}
+	args = flags.Args()
+	if db == nil {
+		logger.Error("openapi reads runs from the database, which is not available")
+		os.Exit(cli.ExitStorage)
 	}
 
-	var finisherChan = client.NewFinisherChannel()
-	var responses = browser.Responses{}
-	var requests = browser.Requests{}
+	testID, err := uuid.Parse(args[0])
+	if err != nil {
+		logger.Error("invalid test id", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+	endpoints, err := database.GetAPIEndpoints(db, testID)
+	if err != nil {
+		logger.Error("failed to get api endpoints", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	if len(endpoints) == 0 {
+		logger.Error("the run called no API", "test_id", testID)
+		os.Exit(cli.ExitUsage)
+	}
 
-	client.ListenToEvents(logger, &responses, &requests, &finisherChan)
+	requests := map[string]int{}
+	for _, e := range endpoints {
+		requests[e.Origin] += e.Requests
+	}
+	if *origin == "" {
+		for o, n := range requests {
+			if n > requests[*origin] || n == requests[*origin] && o < *origin {
+				*origin = o
+			}
+		}
+		for o, n := range requests {
+			if o != *origin {
+				logger.Info("left out API origin, document it with --origin", "origin", o, "requests", n)
+			}
+		}
+	} else if _, ok := requests[*origin]; !ok {
+		logger.Error("the run called no API on the origin", "origin", *origin)
+		os.Exit(cli.ExitUsage)
+	}
+	if *title == "" {
+		*title = *origin
+	}
 
-	err = client.Run(5 * time.Second)
+	events, err := database.GetEvents(db, testID)
 	if err != nil {
-		logger.Error("failed to run browser:", "error: ", err)
-		panic(err)
+		logger.Error("failed to get events", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	doc := openapi.Generate(*title, *origin, endpoints, openapi.Samples(events))
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			logger.Error("failed to create openapi file", "error", err)
+			os.Exit(cli.ExitUsage)
+		}
+		defer file.Close()
+		out = file
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		logger.Error("failed to write openapi document", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+}
+
+// replayRequests replays the requests of a stored run, or of the HAR given with --har, selected by the flags,
+// optionally mutated, stores the responses to those of a run, redacted, along with their comparison with the
+// captured ones, and writes the results as JSON.
+func replayRequests(logger *slog.Logger, db *sql.DB, egressCfg config.EgressConfig, redaction redact.Rules, args []string) {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	types := flags.String("types", "XHR,Fetch", "replay the requests of these comma separated resource types, all when empty")
+	method := flags.String("method", "", "only replay the requests of this method")
+	match := flags.String("match", "", "only replay the requests to URLs matching this regexp")
+	host := flags.String("host", "", "send the requests to this host instead, e.g. staging.example.com:8443")
+	var headers listFlag
+	flags.Var(&headers, "header", `set this header on the requests, e.g. "Authorization: Bearer token", or remove it when empty, e.g. "Cookie:", can be repeated`)
+	output := flags.String("output", "", "write the results to this file")
+	harPath := flags.String("har", "", "replay the requests of this HAR file instead of those of a stored run")
+	if err := flags.Parse(args); err != nil || (flags.NArg() < 1) == (*harPath == "") {
+		logger.Error("usage: web-tester replay [--types types] [--method method] [--match regexp] [--host host] [--header header]... [--output file] <test-id> | --har <file>")
+		os.Exit(cli.ExitUsage)
+	}
+	args = flags.Args()
+
+	filter := replay.Filter{Method: *method}
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter.Types = append(filter.Types, t)
+		}
+	}
+	if *match != "" {
+		var err error
+		if filter.Match, err = regexp.Compile(*match); err != nil {
+			logger.Error("invalid url pattern", "error", err)
+			os.Exit(cli.ExitUsage)
+		}
+	}
+	mutation := replay.Mutation{Host: *host, Headers: http.Header{}}
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			logger.Error("invalid header, use name: value", "header", h)
+			os.Exit(cli.ExitUsage)
+		}
+		mutation.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
 	}
 
-	client.WatchEventFinishers(logger, &finisherChan, &responses)
+	var testID uuid.UUID
+	var events []database.StoredEvent
+	var err error
+	if *harPath != "" {
+		events = readHAR(logger, *harPath)
+	} else {
+		if db == nil {
+			logger.Error("replay reads runs from the database, which is not available")
+			os.Exit(cli.ExitStorage)
+		}
+		if testID, err = uuid.Parse(args[0]); err != nil {
+			logger.Error("invalid test id", "error", err)
+			os.Exit(cli.ExitUsage)
+		}
+		if events, err = database.GetEvents(db, testID); err != nil {
+			logger.Error("failed to get events", "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+	}
+	transactions := replay.Select(database.Transactions(events), filter)
+	if len(transactions) == 0 {
+		logger.Error("no captured request to replay", "test_id", testID, "har", *harPath)
+		os.Exit(cli.ExitUsage)
+	}
 
-	logger.Info("browser ran successfully, starting database input")
+	replayConfig := &config.ReplayConfig{}
+	replayCfg := replayConfig.Load()
+	client := &http.Client{Timeout: replayCfg.Timeout,
+		// the browser captured every hop of the redirects, which are replayed on their own
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	if egressCfg.AirGapped {
+		client.Transport = egress.New(logger, nil, egressCfg.Allow...)
+	}
+	results := replay.Run(context.Background(), logger, client, transactions, mutation, redaction, replayCfg.MaxRequests)
+	changed := 0
+	for _, result := range results {
+		if result.Changed {
+			changed++
+		}
+		// the replays of a HAR belong to no run
+		if *harPath != "" {
+			continue
+		}
+		if err = database.InsertReplay(logger, db, testID, result); err != nil {
+			logger.Error("failed to insert replay into database", "error", err)
+			os.Exit(cli.ExitStorage)
+		}
+	}
+	if len(results) > 0 {
+		logger.Info("replayed requests", "replay_id", results[0].ReplayID, "requests", len(results), "changed", changed)
+	}
 
-	for _, r := range requests {
-		r.SetBody(client.GetCtx())
-		err = database.InsertIntoDB(logger, db, client.TestID(), struct {
-			RequestID network.RequestID
-			Type      string
-			URL       string
-			Content   interface{}
-			Body      []byte
-		}{RequestID: r.RequestID, Type: r.Type, URL: r.URL, Content: r.Content, Body: r.Body})
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
 		if err != nil {
-			logger.Error("failed to insert into database", "error: ", err)
+			logger.Error("failed to create replay file", "error", err)
+			os.Exit(cli.ExitUsage)
 		}
+		defer file.Close()
+		out = file
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		logger.Error("failed to write replay results", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+}
+
+// closeReport closes the file a report was written to, signing it when a signing key is configured.
+// Reports written to the standard output are not signed.
+func closeReport(logger *slog.Logger, file *os.File) {
+	if file == nil {
+		return
+	}
+	if err := file.Close(); err != nil {
+		logger.Error("failed to write report file", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+
+	signingConfig := &config.SigningConfig{}
+	signingCfg := signingConfig.Load()
+	if signingCfg.PrivateKeyFile == "" {
+		return
+	}
+	key, err := signing.LoadPrivateKey(signingCfg.PrivateKeyFile)
+	if err != nil {
+		logger.Error("failed to load signing key", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	sigPath, err := signing.SignFile(key, file.Name())
+	if err != nil {
+		logger.Error("failed to sign report", "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+	logger.Info("signed report", "path", file.Name(), "signature", sigPath)
+}
+
+// logFlags takes the --log-level, --log-format, --log-file and repeatable --log-module flags out of the arguments,
+// wherever they are, returning the other arguments and the settings of the flags. The flag values are given as
+// --log-level=debug or --log-level debug.
+func logFlags(args []string) ([]string, config.LogConfig, error) {
+	var cfg config.LogConfig
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || !strings.HasPrefix(name, "log-") {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return rest, cfg, fmt.Errorf("flag --%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "log-level":
+			cfg.Level = value
+		case "log-format":
+			cfg.Format = value
+		case "log-file":
+			cfg.File = value
+		case "log-module":
+			cfg.Modules = append(cfg.Modules, value)
+		default:
+			return rest, cfg, fmt.Errorf("unknown flag --%s", name)
+		}
+	}
+	return rest, cfg, nil
+}
+
+// configCommand runs the config subcommands: "validate [file]" checks the configuration file, CONFIG_FILE by
+// default, printing the report of its problems and the environment variables overriding it as JSON, and exiting
+// with cli.ExitConfig when it is invalid.
+func configCommand(logger *slog.Logger, args []string) {
+	if len(args) < 1 || args[0] != "validate" || len(args) > 2 {
+		logger.Error("usage: web-tester config validate [file]")
+		os.Exit(cli.ExitUsage)
+	}
+	path := os.Getenv("CONFIG_FILE")
+	if len(args) == 2 {
+		path = args[1]
+	}
+	if path == "" {
+		logger.Error("no config file to validate, pass its path or set CONFIG_FILE")
+		os.Exit(cli.ExitUsage)
+	}
+
+	report := struct {
+		Path      string   `json:"path"`
+		Valid     bool     `json:"valid"`
+		Errors    []string `json:"errors,omitempty"`
+		Overrides []string `json:"overrides,omitempty"`
+	}{Path: path}
+	f, err := config.ReadFile(path)
+	if err == nil {
+		err = f.Validate()
+		report.Overrides = f.Overrides()
+	}
+	if err != nil {
+		report.Errors = strings.Split(err.Error(), "\n")
+	}
+	report.Valid = err == nil
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logger.Error("failed to encode report", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+	fmt.Println(string(out))
+	if !report.Valid {
+		os.Exit(cli.ExitConfig)
+	}
+}
+
+// sign writes the detached signatures of the files given as arguments, e.g. NDJSON bundles, with the
+// key in SIGNING_KEY_FILE.
+func sign(logger *slog.Logger, args []string) {
+	if len(args) < 1 {
+		logger.Error("usage: web-tester sign <file>...")
+		os.Exit(cli.ExitUsage)
+	}
+	signingConfig := &config.SigningConfig{}
+	signingCfg := signingConfig.Load()
+	if signingCfg.PrivateKeyFile == "" {
+		logger.Error("signing requires a private key in SIGNING_KEY_FILE")
+		os.Exit(cli.ExitConfig)
+	}
+	key, err := signing.LoadPrivateKey(signingCfg.PrivateKeyFile)
+	if err != nil {
+		logger.Error("failed to load signing key", "error", err)
+		os.Exit(cli.ExitConfig)
 	}
 
-	for _, r := range responses.ResponseMap {
-		err = database.InsertIntoDB(logger, db, client.TestID(), struct {
-			RequestID network.RequestID
-			Type      string
-			URL       string
-			Content   interface{}
-			Body      []byte
-		}{RequestID: r.RequestID, Type: r.Type, URL: r.URL, Content: r.Content, Body: r.Body})
+	for _, path := range args {
+		sigPath, err := signing.SignFile(key, path)
 		if err != nil {
-			logger.Error("failed to insert into database: ", "error: ", err)
+			logger.Error("failed to sign file", "path", path, "error", err)
+			os.Exit(cli.ExitUsage)
+		}
+		logger.Info("signed file", "path", path, "signature", sigPath)
+	}
+}
+
+// verify checks the file given as argument against its detached signature, exiting with cli.ExitTampered
+// when it does not match. The public key trusted is the one of --public-key, SIGNING_PUBLIC_KEY_FILE or
+// the public key of SIGNING_KEY_FILE, in this order.
+func verify(logger *slog.Logger, args []string) {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	sigPath := flags.String("signature", "", "path of the detached signature, the file's path with "+signing.Extension+" appended by default")
+	publicKeyPath := flags.String("public-key", "", "PEM file of the trusted Ed25519 public key")
+	if err := flags.Parse(args); err != nil || flags.NArg() < 1 {
+		logger.Error("usage: web-tester verify [--signature file.sig] [--public-key key.pem] <file>")
+		os.Exit(cli.ExitUsage)
+	}
+	path := flags.Arg(0)
+	if *sigPath == "" {
+		*sigPath = path + signing.Extension
+	}
+
+	signingConfig := &config.SigningConfig{}
+	signingCfg := signingConfig.Load()
+	if *publicKeyPath == "" {
+		*publicKeyPath = signingCfg.PublicKeyFile
+	}
+	var trusted ed25519.PublicKey
+	var err error
+	switch {
+	case *publicKeyPath != "":
+		trusted, err = signing.LoadPublicKey(*publicKeyPath)
+	case signingCfg.PrivateKeyFile != "":
+		var key ed25519.PrivateKey
+		if key, err = signing.LoadPrivateKey(signingCfg.PrivateKeyFile); err == nil {
+			trusted = key.Public().(ed25519.PublicKey)
 		}
+	default:
+		logger.Error("verifying requires a trusted public key, in --public-key or SIGNING_PUBLIC_KEY_FILE")
+		os.Exit(cli.ExitConfig)
+	}
+	if err != nil {
+		logger.Error("failed to load trusted key", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+
+	sig, err := signing.VerifyFile(trusted, path, *sigPath)
+	switch {
+	case errors.Is(err, signing.ErrInvalid):
+		logger.Error("verification failed", "path", path, "error", err)
+		os.Exit(cli.ExitTampered)
+	case err != nil:
+		logger.Error("failed to verify file", "path", path, "error", err)
+		os.Exit(cli.ExitUsage)
+	}
+	logger.Info("signature valid", "path", path, "sha256", sig.SHA256, "signed_at", sig.SignedAt)
+}
+
+// renderConsent prints the consent report of a stored run for the regulation profile.
+func renderConsent(logger *slog.Logger, db *sql.DB, out io.Writer, run database.TestRun, profile report.ConsentProfile, reportCfg config.ReportConfig) {
+	cookies, err := database.GetCookies(db, run.TestID)
+	if err != nil {
+		logger.Error("failed to get cookies", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	storage, err := database.GetStorageItems(db, run.TestID)
+	if err != nil {
+		logger.Error("failed to get web storage", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+	domains, err := database.GetDomains(db, run.TestID)
+	if err != nil {
+		logger.Error("failed to get domains", "error", err)
+		os.Exit(cli.ExitStorage)
+	}
+
+	data := report.NewConsent(run, profile, cookies, storage, domains)
+	if err = report.RenderConsent(out, reportCfg.Format, reportCfg.Template, data); err != nil {
+		logger.Error("failed to render consent report", "error", err)
+		os.Exit(cli.ExitConfig)
+	}
+}
+
+// issueTrackers creates the issue trackers enabled in the configuration. In air-gapped mode, their hosts
+// must be in the egress allow-list.
+func issueTrackers(logger *slog.Logger, issuesCfg config.IssuesConfig, egressCfg config.EgressConfig) []issues.Tracker {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if egressCfg.AirGapped {
+		client.Transport = egress.New(logger, nil, egressCfg.Allow...)
+	}
+
+	var trackers []issues.Tracker
+	if issuesCfg.GitHubRepo != "" {
+		trackers = append(trackers, issues.NewGitHub(client, issuesCfg.GitHubAPI, issuesCfg.GitHubRepo, issuesCfg.GitHubToken))
+	}
+	if issuesCfg.JiraProject != "" {
+		trackers = append(trackers, issues.NewJira(client, issuesCfg.JiraURL, issuesCfg.JiraProject, issuesCfg.JiraIssueType, issuesCfg.JiraUser, issuesCfg.JiraToken))
 	}
+	return trackers
 }
@@ -0,0 +1,134 @@
+// Package artifacts stores the artifacts of the runs, e.g. large response bodies, screenshots, PDFs, videos and
+// traces, in a directory or an S3-compatible object storage, so the database only keeps references to them.
+package artifacts
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store keeps artifacts under keys, e.g. screenshots/<test-id>-loaded.jpg. Put returns the reference to the
+// stored artifact, kept in the database in place of its content, and Get reads an artifact back from its
+// reference, which Delete deletes. PutFile stores the file at a path like Put, without reading it into memory.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Put(key, contentType string, data []byte) (string, error)
+	PutFile(key, contentType, path string) (string, error)
+	Get(ref string) ([]byte, error)
+	Delete(ref string) error
+}
+
+// Dir is a Store keeping the artifacts in a directory, referenced by their path.
+type Dir struct {
+	dir string
+}
+
+// NewDir creates a Dir in dir, creating the directory when it does not exist.
+func NewDir(dir string) (*Dir, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %v", err)
+	}
+	return &Dir{dir: dir}, nil
+}
+
+// Put writes the artifact to the file of its key in the directory, returning its path.
+func (d *Dir) Put(key, contentType string, data []byte) (string, error) {
+	return d.write(key, bytes.NewReader(data))
+}
+
+// PutFile copies the file at path to the file of its key in the directory, returning its path.
+func (d *Dir) PutFile(key, contentType, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read artifact: %v", err)
+	}
+	defer f.Close()
+	return d.write(key, f)
+}
+
+// write writes the artifact read from r to the file of its key in the directory, returning its path.
+func (d *Dir) write(key string, r io.Reader) (string, error) {
+	path := filepath.Join(d.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create artifact directory: %v", err)
+	}
+
+	// write to a temporary file first so a partially written artifact is never found under its key
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create artifact file: %v", err)
+	}
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write artifact file: %v", err)
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write artifact file: %v", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to store artifact file: %v", err)
+	}
+	return path, nil
+}
+
+// Get reads the artifact at a path.
+func (d *Dir) Get(ref string) ([]byte, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %v", err)
+	}
+	return data, nil
+}
+
+// Delete deletes the artifact at a path, unless it is gone already.
+func (d *Dir) Delete(ref string) error {
+	if err := os.Remove(ref); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete artifact: %v", err)
+	}
+	return nil
+}
+
+// Upload stores the file at path in the store under key, returning its reference.
+func Upload(store Store, path, key, contentType string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read artifact: %v", err)
+	}
+	return store.Put(key, contentType, data)
+}
+
+// Read reads an artifact from its reference: an s3:// URL from the store, which must then be set, or a path.
+func Read(store Store, ref string) ([]byte, error) {
+	if strings.HasPrefix(ref, "s3://") {
+		if store == nil {
+			return nil, fmt.Errorf("no artifact store to read %s from", ref)
+		}
+		return store.Get(ref)
+	}
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %v", err)
+	}
+	return data, nil
+}
+
+// Remove deletes an artifact from its reference: an s3:// URL from the store, which must then be set, or a path.
+func Remove(store Store, ref string) error {
+	if strings.HasPrefix(ref, "s3://") {
+		if store == nil {
+			return fmt.Errorf("no artifact store to delete %s from", ref)
+		}
+		return store.Delete(ref)
+	}
+	if err := os.Remove(ref); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete artifact: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package artifacts
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"web-tester/internal/config"
+)
+
+// S3 is a Store keeping the artifacts in a bucket of an S3-compatible object storage, e.g. AWS S3, MinIO or
+// Cloudflare R2, referenced by their s3://<bucket>/<key> URL. Requests are signed with AWS Signature Version 4.
+type S3 struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+	token     string
+	pathStyle bool
+}
+
+// NewS3 creates an S3 store from the artifact configuration, sending its requests with client.
+func NewS3(client *http.Client, artifactCfg config.ArtifactConfig) (*S3, error) {
+	if artifactCfg.S3Bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET is not set")
+	}
+	endpoint := artifactCfg.S3Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + artifactCfg.S3Region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	return &S3{
+		client: client, endpoint: u, bucket: artifactCfg.S3Bucket, prefix: strings.Trim(artifactCfg.S3Prefix, "/"),
+		region: artifactCfg.S3Region, accessKey: artifactCfg.S3AccessKeyID, secretKey: artifactCfg.S3SecretAccessKey,
+		token: artifactCfg.S3SessionToken, pathStyle: artifactCfg.S3PathStyle,
+	}, nil
+}
+
+// Put uploads the artifact to the key under the prefix of the store, returning its s3:// URL.
+func (s *S3) Put(key, contentType string, data []byte) (string, error) {
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+	sum := sha256.Sum256(data)
+	resp, err := s.do(http.MethodPut, key, contentType, bytes.NewReader(data), int64(len(data)), hex.EncodeToString(sum[:]))
+	if err != nil {
+		return "", fmt.Errorf("failed to upload artifact: %v", err)
+	}
+	resp.Body.Close()
+	return "s3://" + s.bucket + "/" + key, nil
+}
+
+// PutFile uploads the file at path to the key under the prefix of the store, returning its s3:// URL. The file is
+// read twice, to sign its hash and to send it, instead of being held in memory.
+func (s *S3) PutFile(key, contentType, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read artifact: %v", err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read artifact: %v", err)
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read artifact: %v", err)
+	}
+
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+	// the body is wrapped so the client does not close the file, closed once uploaded
+	resp, err := s.do(http.MethodPut, key, contentType, io.NopCloser(f), size, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return "", fmt.Errorf("failed to upload artifact: %v", err)
+	}
+	resp.Body.Close()
+	return "s3://" + s.bucket + "/" + key, nil
+}
+
+// Get downloads the artifact of an s3:// URL of the bucket of the store.
+func (s *S3) Get(ref string) ([]byte, error) {
+	key, ok := strings.CutPrefix(ref, "s3://"+s.bucket+"/")
+	if !ok {
+		return nil, fmt.Errorf("artifact %s is not in bucket %s", ref, s.bucket)
+	}
+	resp, err := s.do(http.MethodGet, key, "", nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifact: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifact: %v", err)
+	}
+	return data, nil
+}
+
+// Delete deletes the artifact of an s3:// URL of the bucket of the store. Deleting an artifact that is gone
+// already succeeds.
+func (s *S3) Delete(ref string) error {
+	key, ok := strings.CutPrefix(ref, "s3://"+s.bucket+"/")
+	if !ok {
+		return fmt.Errorf("artifact %s is not in bucket %s", ref, s.bucket)
+	}
+	resp, err := s.do(http.MethodDelete, key, "", nil, 0, emptyPayloadHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete artifact: %v", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// emptyPayloadHash is the hex SHA-256 hash of an empty body, signed for the requests without one.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// do sends a signed request for the object of the key, with a body of size bytes whose hex SHA-256 hash is
+// payloadHash, returning the response when successful.
+func (s *S3) do(method, key, contentType string, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	u := *s.endpoint
+	path := "/" + key
+	if s.pathStyle {
+		path = "/" + s.bucket + path
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	base := strings.TrimSuffix(s.endpoint.Path, "/")
+	u.Path, u.RawPath = base+path, base+escapePath(path)
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, payloadHash, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("object storage answered %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// sign adds the AWS Signature Version 4 of the request, whose body has the hex SHA-256 hash payloadHash, to its
+// headers.
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.token != "" {
+		req.Header.Set("X-Amz-Security-Token", s.token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonicalHeaders.String(), signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// escapePath escapes a path as S3 expects it in the canonical request: every byte but the unreserved characters
+// and the slashes is percent-encoded.
+func escapePath(path string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
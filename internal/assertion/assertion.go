@@ -0,0 +1,111 @@
+// Package assertion evaluates the expectations declared by the user against a captured run.
+package assertion
+
+import (
+	"fmt"
+	"regexp"
+	"web-tester/internal/browser"
+	"web-tester/internal/config"
+)
+
+// Result is the outcome of evaluating a single assertion.
+type Result struct {
+	Name    string
+	Passed  bool
+	Message string
+}
+
+// Run holds the captured data assertions are evaluated against.
+type Run struct {
+	Title         string
+	Requests      []browser.Request
+	Responses     []browser.Response
+	ConsoleErrors []browser.ConsoleMessage
+}
+
+// Evaluate checks every declared assertion against the run and returns one result per assertion.
+func Evaluate(assertions config.Assertions, run Run) []Result {
+	var results []Result
+
+	if assertions.TitleMatches != "" {
+		results = append(results, titleMatches(assertions.TitleMatches, run.Title))
+	}
+	for _, pattern := range assertions.RequestOccurred {
+		results = append(results, requestOccurred(pattern, run.Requests))
+	}
+	if assertions.No5xx {
+		results = append(results, no5xx(run.Responses))
+	}
+	if assertions.NoConsoleErrors {
+		results = append(results, noConsoleErrors(run.ConsoleErrors))
+	}
+	if assertions.MaxPageWeight > 0 {
+		results = append(results, maxPageWeight(assertions.MaxPageWeight, run.Responses))
+	}
+
+	return results
+}
+
+// Failed returns the results of the assertions that did not pass.
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if !r.Passed {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+func titleMatches(pattern, title string) Result {
+	name := "title_matches"
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Result{Name: name, Message: fmt.Sprintf("invalid pattern %q: %v", pattern, err)}
+	}
+	if !re.MatchString(title) {
+		return Result{Name: name, Message: fmt.Sprintf("title %q does not match %q", title, pattern)}
+	}
+	return Result{Name: name, Passed: true, Message: fmt.Sprintf("title %q matches %q", title, pattern)}
+}
+
+func requestOccurred(pattern string, requests []browser.Request) Result {
+	name := "request_occurred"
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Result{Name: name, Message: fmt.Sprintf("invalid pattern %q: %v", pattern, err)}
+	}
+	for _, r := range requests {
+		if re.MatchString(r.URL) {
+			return Result{Name: name, Passed: true, Message: fmt.Sprintf("request to %s matches %q", r.URL, pattern)}
+		}
+	}
+	return Result{Name: name, Message: fmt.Sprintf("no request matches %q", pattern)}
+}
+
+func no5xx(responses []browser.Response) Result {
+	name := "no_5xx"
+	for _, r := range responses {
+		if r.Status >= 500 && r.Status < 600 {
+			return Result{Name: name, Message: fmt.Sprintf("%s returned status %d", r.URL, r.Status)}
+		}
+	}
+	return Result{Name: name, Passed: true, Message: "no 5xx responses"}
+}
+
+func noConsoleErrors(errors []browser.ConsoleMessage) Result {
+	name := "no_console_errors"
+	if len(errors) > 0 {
+		return Result{Name: name, Message: fmt.Sprintf("%d console errors, first: %s", len(errors), errors[0].Text)}
+	}
+	return Result{Name: name, Passed: true, Message: "no console errors"}
+}
+
+func maxPageWeight(limit float64, responses []browser.Response) Result {
+	name := "max_page_weight"
+	weight := browser.Summarize(responses).TotalBytes
+	if weight > limit {
+		return Result{Name: name, Message: fmt.Sprintf("page weight %.0f bytes exceeds %.0f bytes", weight, limit)}
+	}
+	return Result{Name: name, Passed: true, Message: fmt.Sprintf("page weight %.0f bytes is within %.0f bytes", weight, limit)}
+}
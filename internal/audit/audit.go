@@ -0,0 +1,83 @@
+// Package audit provides checks that run against the traffic captured by the browser.
+package audit
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Severity levels used by findings.
+const (
+	SeverityInfo   = "info"
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// Finding is a single issue reported by a check. URL is the affected resource and Pages lists the
+// pages it was found on. Remediation is set by Enrich.
+type Finding struct {
+	Check       string       `json:"check"`
+	Severity    string       `json:"severity"`
+	URL         string       `json:"url"`
+	Message     string       `json:"message"`
+	Pages       []string     `json:"pages,omitempty"`
+	Remediation *Remediation `json:"remediation,omitempty"`
+}
+
+// OnPage sets the page the findings were found on.
+func OnPage(findings []Finding, page string) []Finding {
+	for i := range findings {
+		findings[i].Pages = []string{page}
+	}
+	return findings
+}
+
+// Dedup merges identical findings (same check, resource and message) reported on different pages
+// into a single finding listing every affected page, keeping the order in which they were first seen.
+func Dedup(findings []Finding) []Finding {
+	var deduped []Finding
+	index := map[[3]string]int{}
+
+	for _, f := range findings {
+		key := [3]string{f.Check, f.URL, f.Message}
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(deduped)
+			f.Pages = append([]string{}, f.Pages...)
+			deduped = append(deduped, f)
+			continue
+		}
+		for _, page := range f.Pages {
+			if !contains(deduped[i].Pages, page) {
+				deduped[i].Pages = append(deduped[i].Pages, page)
+			}
+		}
+	}
+
+	return deduped
+}
+
+// contains reports whether values contains value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// targetDomain returns the host of the target URL without its "www." prefix, or an empty string if it is invalid.
+func targetDomain(target string) string {
+	t, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(t.Hostname(), "www.")
+}
+
+// sameDomain reports whether host is the domain itself or one of its subdomains.
+func sameDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"web-tester/internal/browser"
+)
+
+// CheckCacheValidators is the name of the caching replay check.
+const CheckCacheValidators = "cache-validators"
+
+// ValidateCaching re-requests cacheable responses with If-None-Match/If-Modified-Since headers
+// derived from the first response, and reports endpoints that do not answer with a 304.
+//
+// Only successful GET responses carrying an ETag or Last-Modified header, and not marked no-store,
+// are re-requested.
+func ValidateCaching(ctx context.Context, logger *slog.Logger, client *http.Client, responses []browser.Response) []Finding {
+	var findings []Finding
+
+	for _, r := range responses {
+		etag, lastModified := r.Header("ETag"), r.Header("Last-Modified")
+		if r.Status != http.StatusOK || (etag == "" && lastModified == "") {
+			continue
+		}
+		if strings.Contains(strings.ToLower(r.Header("Cache-Control")), "no-store") {
+			continue
+		}
+		if !strings.HasPrefix(r.URL, "http://") && !strings.HasPrefix(r.URL, "https://") {
+			continue
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+		if err != nil {
+			logger.Error("failed to build conditional request", "url", r.URL, "error", err)
+			continue
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			logger.Error("failed to send conditional request", "url", r.URL, "error", err)
+			continue
+		}
+		resp.Body.Close()
+
+		logger.Debug("conditional request", "url", r.URL, "status", resp.StatusCode)
+		if resp.StatusCode != http.StatusNotModified {
+			findings = append(findings, Finding{
+				Check:    CheckCacheValidators,
+				Severity: SeverityLow,
+				URL:      r.URL,
+				Message:  fmt.Sprintf("expected 304 for conditional request (ETag %q, Last-Modified %q), got %d", etag, lastModified, resp.StatusCode),
+			})
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"web-tester/internal/browser"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// cspDirectives maps the resource types of the captured requests to the CSP directive allowing them.
+var cspDirectives = map[network.ResourceType]string{
+	network.ResourceTypeScript:             "script-src",
+	network.ResourceTypeStylesheet:         "style-src",
+	network.ResourceTypeImage:              "img-src",
+	network.ResourceTypeFont:               "font-src",
+	network.ResourceTypeMedia:              "media-src",
+	network.ResourceTypeManifest:           "manifest-src",
+	network.ResourceTypeXHR:                "connect-src",
+	network.ResourceTypeFetch:              "connect-src",
+	network.ResourceTypeEventSource:        "connect-src",
+	network.ResourceTypeWebSocket:          "connect-src",
+	network.ResourceTypePing:               "connect-src",
+	network.ResourceTypeCSPViolationReport: "connect-src",
+	network.ResourceTypeDocument:           "frame-src",
+}
+
+// cspOrder is the order of the directives in a generated policy.
+var cspOrder = []string{"default-src", "script-src", "style-src", "img-src", "font-src", "media-src", "manifest-src", "connect-src", "frame-src"}
+
+// GenerateCSP returns a candidate Content-Security-Policy allowing exactly the origins the page loaded
+// each kind of resource from, with 'self' for the target's origin. The first document request is the page
+// itself, later ones are its frames. Inline scripts and styles are not visible in the traffic, so pages
+// relying on them need nonces or hashes added to the policy.
+func GenerateCSP(target string, requests []browser.Request) string {
+	self := cspSource(target)
+	sources := map[string]map[string]bool{"default-src": {"'self'": true}}
+
+	page := true
+	for _, r := range requests {
+		ev, ok := r.Content.(*network.EventRequestWillBeSent)
+		if !ok {
+			continue
+		}
+		if ev.Type == network.ResourceTypeDocument && page {
+			page = false
+			continue
+		}
+		directive, ok := cspDirectives[ev.Type]
+		if !ok {
+			continue
+		}
+		source := cspSource(r.URL)
+		if source == "" {
+			continue
+		}
+		if source == self {
+			source = "'self'"
+		}
+		if sources[directive] == nil {
+			sources[directive] = map[string]bool{}
+		}
+		sources[directive][source] = true
+	}
+
+	var policy []string
+	for _, directive := range cspOrder {
+		if sources[directive] == nil {
+			continue
+		}
+		var list []string
+		for source := range sources[directive] {
+			list = append(list, source)
+		}
+		// 'self' first, then the other origins alphabetically
+		sort.Slice(list, func(i, j int) bool {
+			if (list[i] == "'self'") != (list[j] == "'self'") {
+				return list[i] == "'self'"
+			}
+			return list[i] < list[j]
+		})
+		policy = append(policy, directive+" "+strings.Join(list, " "))
+	}
+	return strings.Join(policy, "; ")
+}
+
+// cspSource returns the CSP source expression of a URL: its origin, or its scheme for data: and blob: URLs.
+func cspSource(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" {
+		return ""
+	}
+	if u.Host == "" {
+		return u.Scheme + ":"
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"web-tester/internal/browser"
+)
+
+// CheckDNS is the name of the dangling CNAME and NXDOMAIN check.
+const CheckDNS = "dns-takeover"
+
+// takeoverServices are CNAME suffixes of hosting services known to allow claiming unbound names.
+var takeoverServices = []string{
+	".s3.amazonaws.com", ".cloudfront.net", ".herokuapp.com", ".herokudns.com", ".github.io",
+	".azurewebsites.net", ".cloudapp.net", ".trafficmanager.net", ".blob.core.windows.net",
+	".pantheonsite.io", ".surge.sh", ".netlify.app", ".fastly.net", ".ghost.io", ".myshopify.com",
+}
+
+// domainRefs counts how a third-party domain was referenced during the run.
+type domainRefs struct {
+	requests int
+	hints    int
+}
+
+// CheckDanglingDNS resolves every third-party domain contacted during the run or referenced by resource hints,
+// and reports NXDOMAIN references and CNAMEs pointing to names that no longer resolve (subdomain takeover risk).
+func CheckDanglingDNS(ctx context.Context, logger *slog.Logger, resolver *net.Resolver, target string, requests []browser.Request, hints []string) []Finding {
+	domain := targetDomain(target)
+	refs := map[string]*domainRefs{}
+	add := func(rawURL string, hint bool) {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil || sameDomain(u.Hostname(), domain) {
+			return
+		}
+		if refs[u.Hostname()] == nil {
+			refs[u.Hostname()] = &domainRefs{}
+		}
+		if hint {
+			refs[u.Hostname()].hints++
+		} else {
+			refs[u.Hostname()].requests++
+		}
+	}
+	for _, r := range requests {
+		add(r.URL, false)
+	}
+	for _, h := range hints {
+		add(h, true)
+	}
+
+	hosts := make([]string, 0, len(refs))
+	for host := range refs {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var findings []Finding
+	for _, host := range hosts {
+		ref := refs[host]
+		usage := fmt.Sprintf("referenced by %d requests and %d resource hints", ref.requests, ref.hints)
+
+		cname, cnameErr := resolver.LookupCNAME(ctx, host)
+		_, hostErr := resolver.LookupHost(ctx, host)
+		logger.Debug("resolved third-party domain", "host", host, "cname", cname)
+
+		if hostErr == nil {
+			continue
+		}
+		var dnsErr *net.DNSError
+		if !errors.As(hostErr, &dnsErr) || !dnsErr.IsNotFound {
+			logger.Error("failed to resolve third-party domain", "host", host, "error", hostErr)
+			continue
+		}
+
+		cname = strings.TrimSuffix(cname, ".")
+		if cnameErr == nil && cname != "" && cname != host {
+			severity := SeverityMedium
+			if takeoverProne(cname) {
+				severity = SeverityHigh
+			}
+			findings = append(findings, Finding{Check: CheckDNS, Severity: severity, URL: "https://" + host,
+				Message: fmt.Sprintf("dangling CNAME: %s points to %s which does not resolve (%s)", host, cname, usage)})
+			continue
+		}
+
+		findings = append(findings, Finding{Check: CheckDNS, Severity: SeverityMedium, URL: "https://" + host,
+			Message: fmt.Sprintf("NXDOMAIN: %s does not resolve (%s)", host, usage)})
+	}
+
+	return findings
+}
+
+// takeoverProne reports whether the CNAME target belongs to a hosting service known for subdomain takeovers.
+func takeoverProne(cname string) bool {
+	for _, suffix := range takeoverServices {
+		if strings.HasSuffix(cname, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"web-tester/internal/browser"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// CheckFuzz is the name of the active parameter fuzzing check.
+const CheckFuzz = "fuzz"
+
+// maxFuzzBody is the maximum number of bytes of a response body read when comparing replays.
+const maxFuzzBody = 1 << 20
+
+// fuzzCanary marks the injected payloads so their reflection can be told apart from the page's own content.
+const fuzzCanary = "wtcanary"
+
+// FuzzPayload is a value a parameter is replaced with. Reflected is the string whose presence in the
+// response shows the payload was reflected unsafely, when it applies.
+type FuzzPayload struct {
+	Name      string
+	Value     string
+	Reflected string
+}
+
+// FuzzPayloads are the boundary values and injection canaries the parameters are replaced with.
+var FuzzPayloads = []FuzzPayload{
+	{Name: "empty", Value: ""},
+	{Name: "zero", Value: "0"},
+	{Name: "negative", Value: "-1"},
+	{Name: "int-overflow", Value: "2147483648"},
+	{Name: "long", Value: strings.Repeat("A", 4096)},
+	{Name: "quote", Value: `'"` + fuzzCanary},
+	{Name: "markup", Value: "<" + fuzzCanary + ">", Reflected: "<" + fuzzCanary + ">"},
+	{Name: "template", Value: "{{7*7}}" + fuzzCanary, Reflected: "49" + fuzzCanary},
+	{Name: "traversal", Value: "../../" + fuzzCanary},
+}
+
+// FuzzResult is the outcome of replaying a request with a parameter replaced by a payload,
+// compared to the replay of the original request.
+type FuzzResult struct {
+	URL            string
+	Method         string
+	Parameter      string
+	Payload        string
+	BaselineStatus int
+	Status         int
+	BaselineLength int
+	Length         int
+	Reflected      bool
+}
+
+// fuzzTarget is a captured API request whose parameters are fuzzed.
+type fuzzTarget struct {
+	method  string
+	url     *url.URL
+	headers http.Header
+	body    []byte
+	// json and form tell how the body parameters are encoded, if any
+	json, form bool
+}
+
+// fuzzTargets returns the XHR and fetch requests to the target's domain, once per method, endpoint and
+// set of parameters.
+func fuzzTargets(target string, requests []browser.Request) []fuzzTarget {
+	domain := targetDomain(target)
+	seen := map[string]bool{}
+	var targets []fuzzTarget
+	for _, r := range requests {
+		ev, ok := r.Content.(*network.EventRequestWillBeSent)
+		if !ok || ev.Request == nil || (ev.Type != network.ResourceTypeXHR && ev.Type != network.ResourceTypeFetch) {
+			continue
+		}
+		u, err := url.Parse(r.URL)
+		if err != nil || !sameDomain(u.Hostname(), domain) {
+			continue
+		}
+
+		t := fuzzTarget{method: ev.Request.Method, url: u, headers: http.Header{}, body: r.Body}
+		for name, value := range ev.Request.Headers {
+			// pseudo and framing headers are set by the client
+			if strings.HasPrefix(name, ":") || strings.EqualFold(name, "Content-Length") || strings.EqualFold(name, "Host") {
+				continue
+			}
+			t.headers.Set(name, fmt.Sprint(value))
+		}
+		contentType := strings.ToLower(t.headers.Get("Content-Type"))
+		t.json = strings.Contains(contentType, "json") && len(t.body) > 0
+		t.form = strings.Contains(contentType, "x-www-form-urlencoded")
+
+		params := t.params()
+		if len(params) == 0 {
+			continue
+		}
+		key := t.method + " " + u.Scheme + "://" + u.Host + u.Path + " " + strings.Join(params, ",")
+		if !seen[key] {
+			seen[key] = true
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// params returns the sorted names of the query and body parameters of the request. Body parameters are
+// prefixed with "body." to tell them apart from query parameters of the same name.
+func (t fuzzTarget) params() []string {
+	var params []string
+	for name := range t.url.Query() {
+		params = append(params, name)
+	}
+	switch {
+	case t.json:
+		var fields map[string]interface{}
+		if json.Unmarshal(t.body, &fields) == nil {
+			for name := range fields {
+				params = append(params, "body."+name)
+			}
+		}
+	case t.form:
+		if values, err := url.ParseQuery(string(t.body)); err == nil {
+			for name := range values {
+				params = append(params, "body."+name)
+			}
+		}
+	}
+	sort.Strings(params)
+	return params
+}
+
+// mutate returns the URL and body of the request with the parameter set to value.
+func (t fuzzTarget) mutate(param, value string) (string, []byte) {
+	u, body := *t.url, t.body
+	name, inBody := strings.CutPrefix(param, "body.")
+	switch {
+	case !inBody:
+		query := u.Query()
+		query.Set(name, value)
+		u.RawQuery = query.Encode()
+	case t.json:
+		var fields map[string]interface{}
+		if json.Unmarshal(t.body, &fields) == nil {
+			fields[name] = value
+			body, _ = json.Marshal(fields)
+		}
+	case t.form:
+		values, _ := url.ParseQuery(string(t.body))
+		values.Set(name, value)
+		body = []byte(values.Encode())
+	}
+	return u.String(), body
+}
+
+// send replays the request with the given URL and body, returning the response status and body.
+func (t fuzzTarget) send(ctx context.Context, client *http.Client, rawURL string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, t.method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header = t.headers.Clone()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxFuzzBody))
+	return resp.StatusCode, respBody, err
+}
+
+// Fuzz replays the captured API requests to the target's domain with each of their query and body parameters
+// replaced by the FuzzPayloads, and compares the responses with the replay of the original request. It sends
+// at most maxRequests requests, and must only be run against non-production targets.
+//
+// Payloads reflected unescaped in the response, and server errors the original request did not cause,
+// are reported as findings.
+func Fuzz(ctx context.Context, logger *slog.Logger, client *http.Client, target string, requests []browser.Request, maxRequests int) ([]FuzzResult, []Finding) {
+	var results []FuzzResult
+	var findings []Finding
+	sent := 0
+
+	for _, t := range fuzzTargets(target, requests) {
+		if sent >= maxRequests {
+			logger.Info("fuzzing request budget exhausted", "max_requests", maxRequests)
+			break
+		}
+		baselineStatus, baselineBody, err := t.send(ctx, client, t.url.String(), t.body)
+		sent++
+		if err != nil {
+			logger.Error("failed to replay request", "url", t.url.String(), "error", err)
+			continue
+		}
+
+		for _, param := range t.params() {
+			for _, payload := range FuzzPayloads {
+				if sent >= maxRequests {
+					break
+				}
+				rawURL, body := t.mutate(param, payload.Value)
+				status, respBody, err := t.send(ctx, client, rawURL, body)
+				sent++
+				if err != nil {
+					logger.Error("failed to send fuzzed request", "url", rawURL, "error", err)
+					continue
+				}
+
+				result := FuzzResult{
+					URL: t.url.String(), Method: t.method, Parameter: param, Payload: payload.Name,
+					BaselineStatus: baselineStatus, Status: status, BaselineLength: len(baselineBody), Length: len(respBody),
+					Reflected: payload.Reflected != "" && bytes.Contains(respBody, []byte(payload.Reflected)),
+				}
+				results = append(results, result)
+
+				if result.Reflected {
+					findings = append(findings, Finding{Check: CheckFuzz, Severity: SeverityHigh, URL: result.URL,
+						Message: fmt.Sprintf("%s %s parameter %q reflects the %s payload unescaped", t.method, t.url.Path, param, payload.Name)})
+				}
+				if status >= 500 && baselineStatus < 500 {
+					findings = append(findings, Finding{Check: CheckFuzz, Severity: SeverityMedium, URL: result.URL,
+						Message: fmt.Sprintf("%s %s parameter %q answers %d to the %s payload, %d otherwise", t.method, t.url.Path, param, status, payload.Name, baselineStatus)})
+				}
+			}
+		}
+	}
+
+	return results, findings
+}
@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"web-tester/internal/browser"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// CheckSecurityHeaders is the name of the security header audit.
+const CheckSecurityHeaders = "security-headers"
+
+// minHSTSMaxAge is the minimum HSTS max-age, in seconds, not reported as too short (six months).
+const minHSTSMaxAge = 15768000
+
+// SecurityHeaders are the response headers, in lower case, that harden a document against common attacks.
+var SecurityHeaders = []string{
+	"content-security-policy",
+	"strict-transport-security",
+	"x-frame-options",
+	"x-content-type-options",
+	"referrer-policy",
+	"permissions-policy",
+}
+
+// severityPenalty is the number of points a problem of each severity takes off the security header score.
+var severityPenalty = map[string]int{
+	SeverityHigh:   30,
+	SeverityMedium: 20,
+	SeverityLow:    10,
+	SeverityInfo:   5,
+}
+
+// HeaderGrade is the grade of the security headers of a document, from A to F, along with its score out of 100.
+type HeaderGrade struct {
+	URL     string
+	Grade   string
+	Score   int
+	Missing []string
+}
+
+// MainDocument returns the response of the main document, i.e. the first document response received.
+func MainDocument(responses []browser.Response) (browser.Response, bool) {
+	var main browser.Response
+	var first *network.EventResponseReceived
+	for _, r := range responses {
+		ev, ok := r.Content.(*network.EventResponseReceived)
+		if !ok || ev.Type != network.ResourceTypeDocument {
+			continue
+		}
+		if first == nil || ev.Timestamp.Time().Before(first.Timestamp.Time()) {
+			main, first = r, ev
+		}
+	}
+	return main, first != nil
+}
+
+// AuditSecurityHeaders evaluates the security headers of the main document response, returning a finding
+// for each missing or weak header along with the resulting grade.
+func AuditSecurityHeaders(doc browser.Response) ([]Finding, HeaderGrade) {
+	var findings []Finding
+	grade := HeaderGrade{URL: doc.URL, Score: 100}
+	report := func(severity, message string) {
+		findings = append(findings, Finding{Check: CheckSecurityHeaders, Severity: severity, URL: doc.URL, Message: message})
+		grade.Score -= severityPenalty[severity]
+	}
+	missing := func(header, severity string) {
+		grade.Missing = append(grade.Missing, header)
+		report(severity, "missing "+header+" header")
+	}
+
+	csp := strings.ToLower(doc.Header("Content-Security-Policy"))
+	switch {
+	case csp == "":
+		missing("content-security-policy", SeverityHigh)
+	case strings.Contains(csp, "'unsafe-inline'") || strings.Contains(csp, "'unsafe-eval'"):
+		report(SeverityMedium, "content-security-policy allows 'unsafe-inline' or 'unsafe-eval'")
+	}
+
+	if strings.HasPrefix(doc.URL, "https://") {
+		hsts := doc.Header("Strict-Transport-Security")
+		if hsts == "" {
+			missing("strict-transport-security", SeverityMedium)
+		} else if maxAge := hstsMaxAge(hsts); maxAge < minHSTSMaxAge {
+			report(SeverityLow, fmt.Sprintf("strict-transport-security max-age %d is below %d", maxAge, minHSTSMaxAge))
+		}
+	}
+
+	// frame-ancestors supersedes X-Frame-Options
+	switch xfo := strings.ToUpper(strings.TrimSpace(doc.Header("X-Frame-Options"))); {
+	case strings.Contains(csp, "frame-ancestors"):
+	case xfo == "":
+		missing("x-frame-options", SeverityMedium)
+	case xfo != "DENY" && xfo != "SAMEORIGIN":
+		report(SeverityLow, fmt.Sprintf("x-frame-options %q is neither DENY nor SAMEORIGIN", xfo))
+	}
+
+	switch xcto := strings.ToLower(strings.TrimSpace(doc.Header("X-Content-Type-Options"))); xcto {
+	case "nosniff":
+	case "":
+		missing("x-content-type-options", SeverityLow)
+	default:
+		report(SeverityLow, fmt.Sprintf("x-content-type-options %q is not nosniff", xcto))
+	}
+
+	switch rp := strings.ToLower(strings.TrimSpace(doc.Header("Referrer-Policy"))); rp {
+	case "":
+		missing("referrer-policy", SeverityLow)
+	case "unsafe-url", "no-referrer-when-downgrade":
+		report(SeverityLow, fmt.Sprintf("referrer-policy %q leaks the full URL to other origins", rp))
+	}
+
+	if doc.Header("Permissions-Policy") == "" {
+		missing("permissions-policy", SeverityInfo)
+	}
+
+	grade.Score = max(grade.Score, 0)
+	switch {
+	case grade.Score >= 90:
+		grade.Grade = "A"
+	case grade.Score >= 80:
+		grade.Grade = "B"
+	case grade.Score >= 70:
+		grade.Grade = "C"
+	case grade.Score >= 60:
+		grade.Grade = "D"
+	default:
+		grade.Grade = "F"
+	}
+	findings = append(findings, Finding{Check: CheckSecurityHeaders, Severity: SeverityInfo, URL: doc.URL,
+		Message: fmt.Sprintf("security headers grade %s (score %d/100)", grade.Grade, grade.Score)})
+
+	return findings, grade
+}
+
+// hstsMaxAge returns the max-age directive of a Strict-Transport-Security header, or -1 when it is missing.
+func hstsMaxAge(header string) int {
+	for _, directive := range strings.Split(header, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			if v, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				return v
+			}
+		}
+	}
+	return -1
+}
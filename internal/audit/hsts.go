@@ -0,0 +1,186 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"web-tester/internal/browser"
+)
+
+// Names of the HSTS preload and certificate transparency checks.
+const (
+	CheckHSTSPreload = "hsts-preload"
+	CheckCT          = "certificate-transparency"
+)
+
+// minPreloadMaxAge is the minimum HSTS max-age, in seconds, accepted by the preload list.
+const minPreloadMaxAge = 31536000
+
+// HSTSPreloadAPI and CTSearchAPI are the endpoints queried for preload list status and CT log entries.
+var (
+	HSTSPreloadAPI = "https://hstspreload.org/api/v2/status?domain="
+	CTSearchAPI    = "https://crt.sh/?output=json&q="
+)
+
+// TargetOrigins returns the hosts of the HTTPS responses that belong to the target's domain,
+// i.e. the target host itself and its subdomains.
+func TargetOrigins(target string, responses []browser.Response) []string {
+	domain := targetDomain(target)
+	if domain == "" {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var origins []string
+	for _, r := range responses {
+		u, err := url.Parse(r.URL)
+		if err != nil || u.Scheme != "https" || seen[u.Hostname()] {
+			continue
+		}
+		host := u.Hostname()
+		if sameDomain(host, domain) {
+			seen[host] = true
+			origins = append(origins, host)
+		}
+	}
+	return origins
+}
+
+// CheckHSTS verifies each origin serves a Strict-Transport-Security header eligible for the preload list
+// (max-age of at least a year, includeSubDomains and preload) and queries the preload list for its inclusion.
+func CheckHSTS(ctx context.Context, logger *slog.Logger, client *http.Client, origins []string, responses []browser.Response) []Finding {
+	var findings []Finding
+
+	for _, origin := range origins {
+		header := ""
+		for _, r := range responses {
+			if u, err := url.Parse(r.URL); err == nil && u.Hostname() == origin && r.Header("Strict-Transport-Security") != "" {
+				header = r.Header("Strict-Transport-Security")
+				break
+			}
+		}
+
+		originURL := "https://" + origin
+		for _, problem := range hstsProblems(header) {
+			findings = append(findings, Finding{Check: CheckHSTSPreload, Severity: SeverityMedium, URL: originURL, Message: problem})
+		}
+
+		var status struct {
+			Status string `json:"status"`
+		}
+		if err := getJSON(ctx, client, HSTSPreloadAPI+url.QueryEscape(origin), &status); err != nil {
+			logger.Error("failed to query hsts preload status", "origin", origin, "error", err)
+			continue
+		}
+		if status.Status != "preloaded" {
+			findings = append(findings, Finding{Check: CheckHSTSPreload, Severity: SeverityInfo, URL: originURL,
+				Message: fmt.Sprintf("origin is not on the HSTS preload list (status %q)", status.Status)})
+		}
+	}
+
+	return findings
+}
+
+// hstsProblems returns the reasons a Strict-Transport-Security header is not eligible for preloading.
+func hstsProblems(header string) []string {
+	if header == "" {
+		return []string{"no Strict-Transport-Security header"}
+	}
+
+	var problems []string
+	maxAge, includeSubDomains, preload := -1, false, false
+	for _, directive := range strings.Split(header, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "max-age":
+			if v, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				maxAge = v
+			}
+		case "includesubdomains":
+			includeSubDomains = true
+		case "preload":
+			preload = true
+		}
+	}
+
+	if maxAge < minPreloadMaxAge {
+		problems = append(problems, fmt.Sprintf("HSTS max-age %d is below the %d required for preloading", maxAge, minPreloadMaxAge))
+	}
+	if !includeSubDomains {
+		problems = append(problems, "HSTS header is missing includeSubDomains")
+	}
+	if !preload {
+		problems = append(problems, "HSTS header is missing the preload directive")
+	}
+	return problems
+}
+
+// CheckCertificateTransparency queries CT logs for certificates issued to each origin in the last recentDays days
+// and reports the ones not issued by one of the expected issuers. Without expected issuers every recent certificate
+// is reported for review.
+func CheckCertificateTransparency(ctx context.Context, logger *slog.Logger, client *http.Client, origins []string, expectedIssuers []string, recentDays int) []Finding {
+	var findings []Finding
+	since := time.Now().AddDate(0, 0, -recentDays)
+
+	for _, origin := range origins {
+		var entries []struct {
+			ID         int64  `json:"id"`
+			IssuerName string `json:"issuer_name"`
+			NameValue  string `json:"name_value"`
+			NotBefore  string `json:"not_before"`
+		}
+		if err := getJSON(ctx, client, CTSearchAPI+url.QueryEscape(origin), &entries); err != nil {
+			logger.Error("failed to query ct logs", "origin", origin, "error", err)
+			continue
+		}
+
+		for _, e := range entries {
+			issued, err := time.Parse("2006-01-02T15:04:05", e.NotBefore)
+			if err != nil || issued.Before(since) || issuerExpected(e.IssuerName, expectedIssuers) {
+				continue
+			}
+			severity := SeverityInfo
+			if len(expectedIssuers) > 0 {
+				severity = SeverityHigh
+			}
+			findings = append(findings, Finding{Check: CheckCT, Severity: severity, URL: "https://" + origin,
+				Message: fmt.Sprintf("certificate %d for %q issued %s by %q", e.ID, e.NameValue, issued.Format(time.DateOnly), e.IssuerName)})
+		}
+	}
+
+	return findings
+}
+
+// issuerExpected reports whether the issuer contains one of the expected issuer names.
+func issuerExpected(issuer string, expected []string) bool {
+	for _, e := range expected {
+		if strings.Contains(strings.ToLower(issuer), strings.ToLower(e)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getJSON sends a GET request to u and decodes the JSON response into v.
+func getJSON(ctx context.Context, client *http.Client, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
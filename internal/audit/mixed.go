@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"web-tester/internal/browser"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// CheckMixedContent is the name of the mixed content check.
+const CheckMixedContent = "mixed-content"
+
+// passiveMixedContent are the resource types whose insecure loads cannot alter the page besides their own area,
+// and are reported with a lower severity than active content such as scripts, stylesheets and frames.
+var passiveMixedContent = map[network.ResourceType]bool{
+	network.ResourceTypeImage: true,
+	network.ResourceTypeMedia: true,
+}
+
+// CheckMixedContentRequests reports the plain HTTP subresources requested by an HTTPS document.
+func CheckMixedContentRequests(doc browser.Response, requests []browser.Request) []Finding {
+	if !strings.HasPrefix(doc.URL, "https://") {
+		return nil
+	}
+
+	var findings []Finding
+	for _, r := range requests {
+		if !strings.HasPrefix(r.URL, "http://") {
+			continue
+		}
+		resourceType := network.ResourceTypeOther
+		if ev, ok := r.Content.(*network.EventRequestWillBeSent); ok {
+			resourceType = ev.Type
+		}
+
+		severity := SeverityHigh
+		if passiveMixedContent[resourceType] {
+			severity = SeverityMedium
+		}
+		findings = append(findings, Finding{Check: CheckMixedContent, Severity: severity, URL: r.URL,
+			Message: fmt.Sprintf("%s loaded over HTTP from the HTTPS page %s", strings.ToLower(resourceType.String()), doc.URL)})
+	}
+	return findings
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"web-tester/internal/browser"
+)
+
+// CheckPWA is the name of the favicon, manifest and PWA installability check.
+const CheckPWA = "pwa"
+
+// manifest holds the web app manifest members validated by CheckPWAStatus.
+type manifest struct {
+	Name      string `json:"name"`
+	ShortName string `json:"short_name"`
+	StartURL  string `json:"start_url"`
+	Display   string `json:"display"`
+	Icons     []struct {
+		Src   string `json:"src"`
+		Sizes string `json:"sizes"`
+	} `json:"icons"`
+}
+
+// CheckPWAStatus validates the manifest, icons and service worker state collected from the page,
+// fetching every favicon and manifest icon to make sure it resolves, and reports installability problems.
+func CheckPWAStatus(ctx context.Context, logger *slog.Logger, client *http.Client, pageURL string, status browser.PWAStatus) []Finding {
+	var findings []Finding
+	report := func(severity, u, format string, args ...interface{}) {
+		findings = append(findings, Finding{Check: CheckPWA, Severity: severity, URL: u, Message: fmt.Sprintf(format, args...)})
+	}
+
+	icons := append([]string{}, status.Favicons...)
+	if len(status.Favicons) == 0 {
+		report(SeverityInfo, pageURL, "page does not declare a favicon")
+	}
+
+	if status.ManifestURL == "" {
+		report(SeverityInfo, pageURL, "page does not link a web app manifest")
+	} else {
+		for _, e := range status.ManifestErrors {
+			report(SeverityLow, status.ManifestURL, "manifest error: %s", e)
+		}
+
+		m := manifest{}
+		if err := json.Unmarshal([]byte(status.Manifest), &m); err != nil {
+			report(SeverityLow, status.ManifestURL, "manifest is not valid JSON: %v", err)
+		}
+		if m.Name == "" && m.ShortName == "" {
+			report(SeverityLow, status.ManifestURL, "manifest has neither name nor short_name")
+		}
+		if m.StartURL == "" {
+			report(SeverityInfo, status.ManifestURL, "manifest has no start_url")
+		}
+		if len(m.Icons) == 0 {
+			report(SeverityLow, status.ManifestURL, "manifest declares no icons")
+		}
+
+		base, _ := url.Parse(status.ManifestURL)
+		for _, icon := range m.Icons {
+			if ref, err := url.Parse(icon.Src); err == nil && base != nil {
+				icons = append(icons, base.ResolveReference(ref).String())
+			}
+		}
+	}
+
+	for _, e := range status.InstallabilityErrors {
+		report(SeverityInfo, pageURL, "not installable: %s", e)
+	}
+	if len(status.ServiceWorkers) == 0 {
+		report(SeverityInfo, pageURL, "no service worker is registered")
+	}
+
+	for _, icon := range icons {
+		if u, err := url.Parse(icon); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, icon, nil)
+		if err != nil {
+			logger.Error("failed to build icon request", "url", icon, "error", err)
+			continue
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			report(SeverityLow, icon, "icon could not be fetched: %v", err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			report(SeverityLow, icon, "icon returned status %d", resp.StatusCode)
+		}
+	}
+
+	return findings
+}
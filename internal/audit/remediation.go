@@ -0,0 +1,127 @@
+package audit
+
+// Remediation is the guidance attached to the findings of a check, so reports tell developers how to fix them.
+// CWE and OWASP are the identifiers of the weakness and of the OWASP Top 10 category, when they apply.
+type Remediation struct {
+	Summary    string   `json:"summary"`
+	References []string `json:"references,omitempty"`
+	CWE        string   `json:"cwe,omitempty"`
+	OWASP      string   `json:"owasp,omitempty"`
+}
+
+// Remediations maps the name of each built-in check to the guidance for its findings.
+var Remediations = map[string]Remediation{
+	CheckCacheValidators: {
+		Summary: "Answer conditional requests carrying If-None-Match or If-Modified-Since with 304 Not Modified when the " +
+			"resource did not change, so clients revalidate their cache without downloading it again.",
+		References: []string{
+			"https://www.rfc-editor.org/rfc/rfc9110#name-conditional-requests",
+			"https://developer.mozilla.org/en-US/docs/Web/HTTP/Conditional_requests",
+		},
+	},
+	CheckPWA: {
+		Summary: "Serve a favicon and a valid web app manifest linked from the document, and register a service worker " +
+			"if the site should be installable.",
+		References: []string{
+			"https://web.dev/articles/install-criteria",
+			"https://developer.mozilla.org/en-US/docs/Web/Manifest",
+		},
+	},
+	CheckHSTSPreload: {
+		Summary: "Send Strict-Transport-Security with a max-age of at least a year, includeSubDomains and preload on every " +
+			"HTTPS response, then submit the domain to the HSTS preload list.",
+		References: []string{
+			"https://hstspreload.org/",
+			"https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Strict-Transport-Security",
+		},
+		CWE:   "CWE-319",
+		OWASP: "A05:2021 Security Misconfiguration",
+	},
+	CheckCT: {
+		Summary: "Review the certificates issued for the domain. Revoke the unexpected ones, and restrict the CAs allowed " +
+			"to issue for the domain with CAA records.",
+		References: []string{
+			"https://certificate.transparency.dev/",
+			"https://www.rfc-editor.org/rfc/rfc8659",
+		},
+		CWE:   "CWE-295",
+		OWASP: "A02:2021 Cryptographic Failures",
+	},
+	CheckCertExpiry: {
+		Summary:    "Renew the certificate before it expires, ideally with automated issuance such as ACME.",
+		References: []string{"https://www.rfc-editor.org/rfc/rfc8555"},
+		CWE:        "CWE-324",
+		OWASP:      "A02:2021 Cryptographic Failures",
+	},
+	CheckDNS: {
+		Summary: "Remove the DNS records pointing to services that are no longer provisioned, or claim the resource " +
+			"again, before someone else takes it over.",
+		References: []string{
+			"https://owasp.org/www-project-web-security-testing-guide/latest/4-Web_Application_Security_Testing/02-Configuration_and_Deployment_Management_Testing/10-Test_for_Subdomain_Takeover",
+		},
+		CWE:   "CWE-672",
+		OWASP: "A05:2021 Security Misconfiguration",
+	},
+	CheckSecurityHeaders: {
+		Summary: "Send the missing or weak headers on the document: a Content-Security-Policy without unsafe-inline or " +
+			"unsafe-eval, Strict-Transport-Security, X-Frame-Options or frame-ancestors, X-Content-Type-Options: nosniff, " +
+			"a strict Referrer-Policy and a Permissions-Policy.",
+		References: []string{
+			"https://owasp.org/www-project-secure-headers/",
+			"https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers#security",
+		},
+		CWE:   "CWE-693",
+		OWASP: "A05:2021 Security Misconfiguration",
+	},
+	CheckMixedContent: {
+		Summary: "Load every subresource over HTTPS, or send Content-Security-Policy: upgrade-insecure-requests " +
+			"while migrating.",
+		References: []string{
+			"https://developer.mozilla.org/en-US/docs/Web/Security/Mixed_content",
+			"https://www.w3.org/TR/mixed-content/",
+		},
+		CWE:   "CWE-319",
+		OWASP: "A02:2021 Cryptographic Failures",
+	},
+	CheckFuzz: {
+		Summary: "Validate and encode every parameter on the server: escape the values reflected in responses for " +
+			"their context, never evaluate them as templates, and reject unexpected values with a 4xx instead of failing.",
+		References: []string{
+			"https://cheatsheetseries.owasp.org/cheatsheets/Cross_Site_Scripting_Prevention_Cheat_Sheet.html",
+			"https://cheatsheetseries.owasp.org/cheatsheets/Input_Validation_Cheat_Sheet.html",
+		},
+		CWE:   "CWE-20",
+		OWASP: "A03:2021 Injection",
+	},
+	CheckSecrets: {
+		Summary: "Keep secrets on the server: revoke and rotate the exposed credentials, move them out of the code, " +
+			"URLs and responses sent to the browser, and only send the personal data the page needs, never in URLs.",
+		References: []string{
+			"https://cheatsheetseries.owasp.org/cheatsheets/Secrets_Management_Cheat_Sheet.html",
+			"https://owasp.org/www-project-top-ten/2017/A3_2017-Sensitive_Data_Exposure",
+		},
+		CWE:   "CWE-200",
+		OWASP: "A01:2021 Broken Access Control",
+	},
+	CheckScriptChange: {
+		Summary: "Review the stored diff of the script. Self-host third-party scripts or pin them with Subresource " +
+			"Integrity, and restrict script sources with a Content-Security-Policy, so a compromised provider cannot " +
+			"inject code such as card skimmers.",
+		References: []string{
+			"https://developer.mozilla.org/en-US/docs/Web/Security/Subresource_Integrity",
+			"https://owasp.org/www-community/attacks/Magecart",
+		},
+		CWE:   "CWE-829",
+		OWASP: "A08:2021 Software and Data Integrity Failures",
+	},
+}
+
+// Enrich attaches the remediation guidance of their check to the findings.
+func Enrich(findings []Finding) []Finding {
+	for i := range findings {
+		if r, ok := Remediations[findings[i].Check]; ok {
+			findings[i].Remediation = &r
+		}
+	}
+	return findings
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"web-tester/internal/browser"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// CheckScriptChange is the name of the third-party script change check.
+const CheckScriptChange = "third-party-script-change"
+
+// Script is a third-party script loaded by a run. URL identifies it across runs and excludes the query
+// string, which often only busts caches.
+type Script struct {
+	URL     string
+	Hash    string
+	Content string
+}
+
+// ThirdPartyScripts returns the scripts loaded from outside of the target's domain whose body was captured,
+// with the hex encoded SHA-256 hash of their content, sorted by URL.
+func ThirdPartyScripts(target string, responses []browser.Response) []Script {
+	domain := targetDomain(target)
+	byURL := map[string]Script{}
+	for _, r := range responses {
+		ev, ok := r.Content.(*network.EventResponseReceived)
+		if !ok || ev.Type != network.ResourceTypeScript || len(r.Body) == 0 {
+			continue
+		}
+		u, err := url.Parse(r.URL)
+		if err != nil || u.Hostname() == "" || sameDomain(u.Hostname(), domain) {
+			continue
+		}
+		u.RawQuery, u.Fragment = "", ""
+
+		sum := sha256.Sum256(r.Body)
+		byURL[u.String()] = Script{URL: u.String(), Hash: hex.EncodeToString(sum[:]), Content: string(r.Body)}
+	}
+
+	scripts := make([]Script, 0, len(byURL))
+	for _, s := range byURL {
+		scripts = append(scripts, s)
+	}
+	sort.Slice(scripts, func(i, j int) bool { return scripts[i].URL < scripts[j].URL })
+	return scripts
+}
+
+// ScriptChanged returns the finding reporting that the content of a third-party script changed since a previous run.
+func ScriptChanged(script Script, previousHash string) Finding {
+	return Finding{Check: CheckScriptChange, Severity: SeverityMedium, URL: script.URL,
+		Message: fmt.Sprintf("third-party script content changed since the previous run (sha256 %.12s -> %.12s)", previousHash, script.Hash)}
+}
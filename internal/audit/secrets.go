@@ -0,0 +1,220 @@
+package audit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+	"web-tester/internal/browser"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// CheckSecrets is the name of the sensitive data check.
+const CheckSecrets = "secrets"
+
+// maxSecretBody is the maximum number of bytes of a body scanned for secrets.
+const maxSecretBody = 2 << 20
+
+// SecretDetector finds a kind of secret or personal data. Group is the group of Pattern holding the value, the
+// whole match when 0. A value is only reported when its entropy reaches MinEntropy and Valid accepts it, when set.
+// Markup tells whether the detector also scans the documents, scripts and stylesheets, where values of its kind
+// are usually public, e.g. the contact address of a page.
+type SecretDetector struct {
+	Name       string
+	Severity   string
+	Pattern    *regexp.Regexp
+	Group      int
+	MinEntropy float64
+	Valid      func(value string) bool
+	Markup     bool
+}
+
+// SecretDetectors are the detectors run over the captured traffic.
+var SecretDetectors = []SecretDetector{
+	{Name: "private-key", Severity: SeverityHigh, Markup: true,
+		Pattern: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |ENCRYPTED )?PRIVATE KEY-----`)},
+	{Name: "aws-access-key-id", Severity: SeverityHigh, Markup: true,
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "aws-secret-access-key", Severity: SeverityHigh, Markup: true, Group: 1, MinEntropy: 4,
+		Pattern: regexp.MustCompile(`(?i)aws.{0,20}secret.{0,20}?["'=:\s]+([A-Za-z0-9/+]{40})\b`)},
+	{Name: "github-token", Severity: SeverityHigh, Markup: true,
+		Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{Name: "slack-token", Severity: SeverityHigh, Markup: true,
+		Pattern: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{Name: "stripe-secret-key", Severity: SeverityHigh, Markup: true,
+		Pattern: regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{20,}\b`)},
+	// the Google API keys of the frontends are public, restricted by referrer, so they are only worth a review
+	{Name: "google-api-key", Severity: SeverityLow, Markup: true,
+		Pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{Name: "generic-secret", Severity: SeverityMedium, Markup: true, Group: 1, MinEntropy: 3.5,
+		Pattern: regexp.MustCompile(`(?i)(?:api[_-]?key|secret|passw(?:or)?d|access[_-]?token|auth[_-]?token)["']?\s*[:=]\s*["']([^"'\s]{12,})["']`)},
+	{Name: "jwt", Severity: SeverityLow,
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{Name: "email", Severity: SeverityLow,
+		Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{Name: "credit-card", Severity: SeverityHigh, Valid: isCardNumber,
+		Pattern: regexp.MustCompile(`\b(?:\d{4}[ -]?){3}\d{1,4}\b|\b\d{4}[ -]?\d{6}[ -]?\d{5}\b`)},
+}
+
+// credentialHeaders are the headers meant to carry credentials, which are not reported as leaking them.
+var credentialHeaders = map[string]bool{
+	"authorization": true, "proxy-authorization": true, "cookie": true, "set-cookie": true, "x-api-key": true,
+	"api-key": true, "x-auth-token": true, "x-access-token": true, "x-csrf-token": true, "x-xsrf-token": true,
+}
+
+// markupTypes are the resource types of the documents, scripts and stylesheets.
+var markupTypes = map[string]bool{
+	network.ResourceTypeDocument.String(): true, network.ResourceTypeScript.String(): true,
+	network.ResourceTypeStylesheet.String(): true,
+}
+
+// MaskSecret masks a secret, keeping its first and last 4 characters when it is long enough to stay hidden.
+func MaskSecret(value string) string {
+	if len(value) <= 12 {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:4] + strings.Repeat("*", len(value)-8) + value[len(value)-4:]
+}
+
+// findSecrets runs the SecretDetectors over the first maxSecretBody bytes of the text, calling found with the
+// text scanned and the bounds of every value found. Markup tells whether the text is a document, script or
+// stylesheet.
+func findSecrets(text string, markup bool, found func(d SecretDetector, text string, start, end int)) {
+	// binary bodies would only yield chance matches
+	if !utf8.ValidString(text) {
+		return
+	}
+	if len(text) > maxSecretBody {
+		text = text[:maxSecretBody]
+	}
+	for _, d := range SecretDetectors {
+		if markup && !d.Markup {
+			continue
+		}
+		for _, m := range d.Pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[2*d.Group], m[2*d.Group+1]
+			if start < 0 {
+				continue
+			}
+			value := text[start:end]
+			if entropy(value) < d.MinEntropy || (d.Valid != nil && !d.Valid(value)) {
+				continue
+			}
+			found(d, text, start, end)
+		}
+	}
+}
+
+// maskSecrets returns the text with the values found by the SecretDetectors masked.
+func maskSecrets(text string, markup bool) string {
+	var values []string
+	findSecrets(text, markup, func(_ SecretDetector, text string, start, end int) {
+		values = append(values, text[start:end])
+	})
+	for _, value := range values {
+		text = strings.ReplaceAll(text, value, MaskSecret(value))
+	}
+	return text
+}
+
+// ScanSecrets runs the SecretDetectors over the URLs, headers and bodies of the requests and responses of the
+// transactions, besides the headers meant to carry credentials, and reports every value found once per resource and
+// location. The values are masked in the findings and the logs, the URLs included.
+func ScanSecrets(logger *slog.Logger, transactions []browser.Transaction) []Finding {
+	var findings []Finding
+	seen := map[string]bool{}
+	for _, t := range transactions {
+		markup := markupTypes[t.ResourceType]
+		url := maskSecrets(t.URL, markup)
+		scan := func(location, text string) {
+			findSecrets(text, markup, func(d SecretDetector, text string, start, end int) {
+				value := text[start:end]
+				where := location
+				if strings.HasSuffix(location, "body") {
+					where = fmt.Sprintf("%s, line %d", location, strings.Count(text[:start], "\n")+1)
+				}
+				key := t.URL + " " + d.Name + " " + location + " " + value
+				if seen[key] {
+					return
+				}
+				seen[key] = true
+				logger.Warn("sensitive data found in captured traffic", "detector", d.Name, "url", url, "location", where,
+					"value", MaskSecret(value))
+				findings = append(findings, Finding{Check: CheckSecrets, Severity: d.Severity, URL: url,
+					Message: fmt.Sprintf("%s found in the %s: %s", d.Name, where, MaskSecret(value))})
+			})
+		}
+
+		scan("url", t.URL)
+		if ev, ok := t.Request.Content.(*network.EventRequestWillBeSent); ok && ev.Request != nil {
+			scanHeaders(scan, "request", ev.Request.Headers)
+		}
+		scan("request body", string(t.Request.Body))
+		if t.Response != nil {
+			scanHeaders(scan, "response", t.Response.Headers)
+			scan("response body", string(t.Response.Body))
+		}
+	}
+	return findings
+}
+
+// scanHeaders scans the headers besides those meant to carry credentials, in order of name.
+func scanHeaders(scan func(location, text string), side string, headers network.Headers) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		if !credentialHeaders[strings.ToLower(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		scan(side+" header "+name, fmt.Sprint(headers[name]))
+	}
+}
+
+// entropy returns the Shannon entropy of a value, in bits per character.
+func entropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	for _, c := range value {
+		counts[c]++
+	}
+	n := float64(len([]rune(value)))
+	e := 0.0
+	for _, count := range counts {
+		p := float64(count) / n
+		e -= p * math.Log2(p)
+	}
+	return e
+}
+
+// isCardNumber tells whether a value is the number of a Visa, Mastercard, American Express or Discover card, with a
+// valid Luhn checksum.
+func isCardNumber(value string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(value)
+	switch {
+	case strings.HasPrefix(digits, "4") && (len(digits) == 13 || len(digits) == 16 || len(digits) == 19):
+	case len(digits) == 16 && (digits[:2] >= "51" && digits[:2] <= "55" || digits[:4] >= "2221" && digits[:4] <= "2720"):
+	case len(digits) == 15 && (strings.HasPrefix(digits, "34") || strings.HasPrefix(digits, "37")):
+	case len(digits) == 16 && (strings.HasPrefix(digits, "6011") || strings.HasPrefix(digits, "65")):
+	default:
+		return false
+	}
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
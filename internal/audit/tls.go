@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+	"web-tester/internal/browser"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// CheckCertExpiry is the name of the certificate expiry check.
+const CheckCertExpiry = "certificate-expiry"
+
+// TLSDetails are the TLS connection and certificate details of an origin contacted during a run.
+type TLSDetails struct {
+	Origin    string
+	Protocol  string
+	Cipher    string
+	Subject   string
+	Issuer    string
+	SANs      []string
+	ValidFrom time.Time
+	ValidTo   time.Time
+}
+
+// CollectTLS returns the TLS details of each origin among the responses, as reported by the browser
+// for the first response received from it, sorted by origin.
+func CollectTLS(responses []browser.Response) []TLSDetails {
+	byOrigin := map[string]TLSDetails{}
+	for _, r := range responses {
+		ev, ok := r.Content.(*network.EventResponseReceived)
+		if !ok || ev.Response == nil || ev.Response.SecurityDetails == nil {
+			continue
+		}
+		u, err := url.Parse(r.URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		origin := u.Scheme + "://" + u.Host
+		if _, ok := byOrigin[origin]; ok {
+			continue
+		}
+
+		sd := ev.Response.SecurityDetails
+		details := TLSDetails{Origin: origin, Protocol: sd.Protocol, Cipher: sd.Cipher, Subject: sd.SubjectName, Issuer: sd.Issuer, SANs: sd.SanList}
+		if sd.ValidFrom != nil {
+			details.ValidFrom = sd.ValidFrom.Time()
+		}
+		if sd.ValidTo != nil {
+			details.ValidTo = sd.ValidTo.Time()
+		}
+		byOrigin[origin] = details
+	}
+
+	details := make([]TLSDetails, 0, len(byOrigin))
+	for _, d := range byOrigin {
+		details = append(details, d)
+	}
+	sort.Slice(details, func(i, j int) bool { return details[i].Origin < details[j].Origin })
+	return details
+}
+
+// CheckCertificateExpiry reports the certificates that expired or expire within warnDays days of now.
+func CheckCertificateExpiry(details []TLSDetails, warnDays int, now time.Time) []Finding {
+	var findings []Finding
+	for _, d := range details {
+		if d.ValidTo.IsZero() {
+			continue
+		}
+		left := d.ValidTo.Sub(now)
+		switch {
+		case left <= 0:
+			findings = append(findings, Finding{Check: CheckCertExpiry, Severity: SeverityHigh, URL: d.Origin,
+				Message: fmt.Sprintf("certificate for %s expired on %s", d.Subject, d.ValidTo.Format(time.DateOnly))})
+		case left <= time.Duration(warnDays)*24*time.Hour:
+			findings = append(findings, Finding{Check: CheckCertExpiry, Severity: SeverityMedium, URL: d.Origin,
+				Message: fmt.Sprintf("certificate for %s expires on %s, in %d days", d.Subject, d.ValidTo.Format(time.DateOnly), int(left.Hours()/24))})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// maxEndpointContent is the maximum number of bytes kept from a well-known endpoint's body.
+const maxEndpointContent = 64 * 1024
+
+// WellKnownPaths are the standard endpoints probed on the target's origin.
+var WellKnownPaths = []struct {
+	Name string
+	Path string
+}{
+	{"security.txt", "/.well-known/security.txt"},
+	{"security.txt (legacy)", "/security.txt"},
+	{"change-password", "/.well-known/change-password"},
+	{"robots.txt", "/robots.txt"},
+	{"sitemap", "/sitemap.xml"},
+}
+
+// Endpoint is the result of probing a well-known endpoint on the target.
+type Endpoint struct {
+	Name    string
+	URL     string
+	Status  int
+	Present bool
+	Content string
+}
+
+// DiscoverWellKnown probes the standard well-known endpoints on the target's origin and records
+// whether each one is present along with its content. Redirects are followed, which is how
+// change-password is expected to behave.
+func DiscoverWellKnown(ctx context.Context, logger *slog.Logger, client *http.Client, target string) ([]Endpoint, error) {
+	base, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []Endpoint
+	for _, wk := range WellKnownPaths {
+		u := url.URL{Scheme: base.Scheme, Host: base.Host, Path: wk.Path}
+		endpoint := Endpoint{Name: wk.Name, URL: u.String()}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.URL, nil)
+		if err != nil {
+			logger.Error("failed to build well-known request", "url", endpoint.URL, "error", err)
+			continue
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			logger.Error("failed to probe well-known endpoint", "url", endpoint.URL, "error", err)
+			endpoints = append(endpoints, endpoint)
+			continue
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxEndpointContent))
+		resp.Body.Close()
+		if err != nil {
+			logger.Error("failed to read well-known endpoint", "url", endpoint.URL, "error", err)
+		}
+
+		endpoint.Status = resp.StatusCode
+		endpoint.Present = resp.StatusCode >= 200 && resp.StatusCode < 300
+		if endpoint.Present {
+			endpoint.Content = string(body)
+		}
+		logger.Debug("probed well-known endpoint", "url", endpoint.URL, "status", resp.StatusCode)
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, nil
+}
@@ -0,0 +1,114 @@
+// Package bodystore stores response bodies too large for the database in a content-addressed artifact store,
+// where each body is written once under its SHA-256 hash.
+package bodystore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+	"web-tester/internal/artifacts"
+)
+
+// Store is a content-addressed store of bodies in an artifact store, under a prefix.
+type Store struct {
+	artifacts artifacts.Store
+	prefix    string
+	mu        sync.Mutex
+	// stored holds the bodies stored already, by hash
+	stored map[string]storedBody
+}
+
+// storedBody is the reference of a stored body and when it was last handed out by Put.
+type storedBody struct {
+	ref  string
+	used time.Time
+}
+
+// New creates a Store keeping the bodies in store under prefix.
+func New(store artifacts.Store, prefix string) *Store {
+	return &Store{artifacts: store, prefix: prefix, stored: map[string]storedBody{}}
+}
+
+// Put writes the body to the store, unless a body with the same content was stored already, and returns
+// its reference and hex encoded SHA-256 hash.
+func (s *Store) Put(body []byte) (ref, hash string, err error) {
+	sum := sha256.Sum256(body)
+	hash = hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	if b, ok := s.stored[hash]; ok {
+		s.stored[hash] = storedBody{ref: b.ref, used: time.Now()}
+		s.mu.Unlock()
+		return b.ref, hash, nil
+	}
+	s.mu.Unlock()
+
+	// fan out on the first byte of the hash to keep directories small
+	if ref, err = s.artifacts.Put(path.Join(s.prefix, hash[:2], hash), "application/octet-stream", body); err != nil {
+		return "", "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stored[hash] = storedBody{ref: ref, used: time.Now()}
+	return ref, hash, nil
+}
+
+// PutStream writes the body read from r to the store like Put, without holding it in memory: it is spooled to a
+// temporary file while hashed, then stored from that file. It returns the size of the body along with its reference
+// and hash.
+func (s *Store) PutStream(r io.Reader) (ref, hash string, size int64, err error) {
+	tmp, err := os.CreateTemp("", "web-tester-body-*")
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to create body file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	sum := sha256.New()
+	size, err = io.Copy(io.MultiWriter(tmp, sum), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to write body file: %v", err)
+	}
+	hash = hex.EncodeToString(sum.Sum(nil))
+
+	s.mu.Lock()
+	if b, ok := s.stored[hash]; ok {
+		s.stored[hash] = storedBody{ref: b.ref, used: time.Now()}
+		s.mu.Unlock()
+		return b.ref, hash, size, nil
+	}
+	s.mu.Unlock()
+
+	if ref, err = s.artifacts.PutFile(path.Join(s.prefix, hash[:2], hash), "application/octet-stream", tmp.Name()); err != nil {
+		return "", "", 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stored[hash] = storedBody{ref: ref, used: time.Now()}
+	return ref, hash, size, nil
+}
+
+// Release forgets the body of ref so it is written again the next time it is put, and reports whether it may be
+// deleted: a body handed out by Put at or after since, e.g. to a run still in progress that did not store its
+// reference yet, is kept.
+func (s *Store) Release(ref string, since time.Time) bool {
+	hash := filepath.Base(ref)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.stored[hash]
+	if !ok || b.ref != ref {
+		return true
+	}
+	if !b.used.Before(since) {
+		return false
+	}
+	delete(s.stored, hash)
+	return true
+}
@@ -0,0 +1,114 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/chromedp"
+)
+
+// loginTimeout bounds the form login, from loading the login page to its success check.
+const loginTimeout = 30 * time.Second
+
+// Credentials are the username and password of an authenticated visitor.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Login is a form login run before the capture begins: the login page is loaded, the credentials typed in
+// the username and password fields and the form submitted with the submit button. The login succeeded once
+// the element matching SuccessSelector is visible, or the page URL contains SuccessURL.
+type Login struct {
+	URL              string
+	UsernameSelector string
+	PasswordSelector string
+	SubmitSelector   string
+	SuccessSelector  string
+	SuccessURL       string
+	Credentials
+}
+
+// Validate checks that the login has a page, its fields and a success check.
+func (l Login) Validate() error {
+	switch {
+	case l.URL == "":
+		return errors.New("the login has no URL")
+	case l.UsernameSelector == "" || l.PasswordSelector == "" || l.SubmitSelector == "":
+		return errors.New("the login needs the username, password and submit selectors")
+	case l.SuccessSelector == "" && l.SuccessURL == "":
+		return errors.New("the login needs a success selector or URL to check it succeeded")
+	}
+	return nil
+}
+
+// Authenticate makes Run answer the HTTP authentication challenges of the target's origin with the basic
+// credentials, and log in with the form login before loading the target, when they are set. Challenges of
+// other origins and of proxies are left to the browser, so the credentials are never sent elsewhere.
+func (b *Browser) Authenticate(basic *Credentials, login *Login) {
+	b.basic, b.login = basic, login
+}
+
+// authResponse answers an authentication challenge with the basic credentials when it comes from the
+// target's origin, leaving the others to the browser.
+func (b *Browser) authResponse(challenge *fetch.AuthChallenge) *fetch.AuthChallengeResponse {
+	origin := ""
+	if u, err := url.Parse(b.target); err == nil {
+		origin = u.Scheme + "://" + u.Host
+	}
+	if b.basic == nil || challenge == nil || challenge.Source == fetch.AuthChallengeSourceProxy || !strings.EqualFold(challenge.Origin, origin) {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+	}
+	return &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: b.basic.Username,
+		Password: b.basic.Password,
+	}
+}
+
+// logIn runs the form login, without capturing its events, so the test starts from the logged in session.
+func (b *Browser) logIn() error {
+	b.paused.Store(true)
+	defer b.paused.Store(false)
+
+	ctx, cancel := context.WithTimeout(b.ctx, loginTimeout)
+	defer cancel()
+
+	l := b.login
+	err := chromedp.Run(ctx,
+		chromedp.Navigate(l.URL),
+		chromedp.WaitVisible(l.UsernameSelector),
+		chromedp.SendKeys(l.UsernameSelector, l.Username),
+		chromedp.SendKeys(l.PasswordSelector, l.Password),
+		chromedp.Click(l.SubmitSelector),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to submit login form: %w", err)
+	}
+
+	if l.SuccessSelector != "" {
+		if err := chromedp.Run(ctx, chromedp.WaitVisible(l.SuccessSelector)); err != nil {
+			return fmt.Errorf("login did not succeed, %s is not visible: %w", l.SuccessSelector, err)
+		}
+		return nil
+	}
+	for {
+		var location string
+		if err := chromedp.Run(ctx, chromedp.Location(&location)); err != nil {
+			return fmt.Errorf("login did not succeed, the page did not reach %s: %w", l.SuccessURL, err)
+		}
+		if strings.Contains(location, l.SuccessURL) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("login did not succeed, the page is still at %s", location)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"web-tester/internal/bodystore"
+
+	"github.com/chromedp/cdproto/cdp"
+	cdpio "github.com/chromedp/cdproto/io"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Body fetch statuses, telling whether the body of a response was fetched and, when it was not, why.
+const (
+	// BodyFetched is a body kept with its response
+	BodyFetched = "fetched"
+	// BodyStored is a body above the body limit, moved to the body store
+	BodyStored = "stored"
+	// BodyTooLarge is a body above the body limit, dropped without a body store
+	BodyTooLarge = "too_large"
+	// BodyEvicted is a body the browser no longer had, e.g. evicted from its buffer or cleared by a navigation
+	BodyEvicted = "evicted"
+	// BodyClosed is a body the browser, or the worker or iframe of the request, was closed before it was fetched
+	BodyClosed = "closed"
+	// BodyFailed is a body that could not be fetched for another reason, every attempt having failed
+	BodyFailed = "failed"
+	// BodyDropped is a body left out because the fetch queue was full, or the browser stopped before it was fetched
+	BodyDropped = "dropped"
+	// BodyLate is a body whose response finished loading after the bodies were collected
+	BodyLate = "late"
+	// BodyTimedOut is a body not fetched within the body fetch timeout
+	BodyTimedOut = "timeout"
+)
+
+// The network buffers of the browser, which keep the bodies of the responses until they are fetched. They are
+// larger than the browser's own defaults so fewer bodies are evicted before they are fetched on busy pages.
+const (
+	networkBufferBytes  = 256 << 20
+	resourceBufferBytes = 64 << 20
+)
+
+// bodyFetchAttempts bounds the attempts to fetch a body, bodyFetchBackoff being the wait before the second
+// attempt, doubled before every next one.
+const (
+	bodyFetchAttempts = 4
+	bodyFetchBackoff  = 100 * time.Millisecond
+)
+
+// enlargeBuffers enlarges the network buffers of the target of ctx.
+func enlargeBuffers(ctx context.Context) error {
+	return chromedp.Run(ctx, network.Enable().WithMaxTotalBufferSize(networkBufferBytes).WithMaxResourceBufferSize(resourceBufferBytes))
+}
+
+// fetchBody fetches the body of the response to the request from the target of ctx, retrying with an exponential
+// backoff unless the body is gone for good or the target closed.
+func fetchBody(ctx context.Context, requestID network.RequestID) ([]byte, error) {
+	backoff := bodyFetchBackoff
+	for attempt := 1; ; attempt++ {
+		var body []byte
+		err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			body, err = network.GetResponseBody(requestID).Do(ctx)
+			return err
+		}))
+		if err == nil {
+			return body, nil
+		}
+		if attempt == bodyFetchAttempts || bodyFetchStatus(ctx, err) != BodyFailed {
+			return nil, err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// bodyChunkBytes is the size of the chunks a body streamed from the browser is read in.
+const bodyChunkBytes = 1 << 20
+
+// streamBody loads the resource of a GET request again in the frame of the target of ctx, or in the worker when
+// frameID is empty, and streams its body to store chunk by chunk instead of fetching it whole: the browser only
+// streams the bodies of the responses it intercepted or loaded itself. It returns the reference, hash and size of
+// the body stored.
+func streamBody(ctx context.Context, store *bodystore.Store, frameID cdp.FrameID, url string) (ref, hash string, size int64, err error) {
+	err = chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		load := network.LoadNetworkResource(url, &network.LoadNetworkResourceOptions{IncludeCredentials: true})
+		if frameID != "" {
+			load = load.WithFrameID(frameID)
+		}
+		resource, err := load.Do(ctx)
+		if err != nil {
+			return err
+		}
+		if !resource.Success {
+			return fmt.Errorf("failed to load resource: %s, status %d", resource.NetErrorName, int(resource.HTTPStatusCode))
+		}
+		defer cdpio.Close(resource.Stream).Do(ctx)
+		ref, hash, size, err = store.PutStream(&streamReader{ctx: ctx, handle: resource.Stream})
+		return err
+	}))
+	return ref, hash, size, err
+}
+
+// streamReader reads a stream of the browser chunk by chunk, decoding the chunks encoded in base64.
+type streamReader struct {
+	ctx    context.Context
+	handle cdpio.StreamHandle
+	buf    []byte
+	eof    bool
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		// the command is executed directly, IO.read's Do leaving out whether the chunk is encoded
+		var chunk cdpio.ReadReturns
+		if err := cdp.Execute(r.ctx, cdpio.CommandRead, cdpio.Read(r.handle).WithSize(bodyChunkBytes), &chunk); err != nil {
+			return 0, err
+		}
+		r.eof, r.buf = chunk.EOF, []byte(chunk.Data)
+		if chunk.Base64encoded {
+			decoded, err := base64.StdEncoding.DecodeString(chunk.Data)
+			if err != nil {
+				return 0, fmt.Errorf("failed to decode stream chunk: %v", err)
+			}
+			r.buf = decoded
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// bodyFetchStatus returns the status of a body whose fetch failed with err.
+func bodyFetchStatus(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return BodyTimedOut
+	case ctx.Err() != nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return BodyClosed
+	case strings.Contains(err.Error(), "No resource with given identifier"), strings.Contains(err.Error(), "No data found for resource"):
+		return BodyEvicted
+	}
+	return BodyFailed
+}
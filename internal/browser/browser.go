@@ -3,41 +3,190 @@ package browser
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
+	"web-tester/internal/bodystore"
+	"web-tester/internal/redact"
+	"web-tester/internal/sink"
+	"web-tester/internal/telemetry"
 
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
 	"github.com/google/uuid"
 )
 
+// ErrLaunch and ErrNavigation tell the errors returned by Run for a browser that could not be started
+// from a target that could not be loaded.
+var (
+	ErrLaunch     = errors.New("failed to launch browser")
+	ErrNavigation = errors.New("failed to navigate")
+)
+
 // Browser represents a browser instance with a target URL, context, and cancel function.
 type Browser struct {
 	target string
 	ctx    context.Context
 	cancel context.CancelFunc
 	testID uuid.UUID
+	sink   sink.Sink
+	// redaction masks the credentials and personal data of the events streamed to sink and of the bodies stored
+	redaction redact.Rules
+	limits    Limits
+	// bodyLimit is the size above which response bodies are moved to bodies, or dropped without a store
+	bodyLimit int
+	bodies    *bodystore.Store
+	filter    Filter
+	// tracer records a trace of the page load when set
+	tracer *tracer
+	// spans records a span for every response body fetched, children of the span of spanCtx, when set
+	spans   *telemetry.Tracer
+	spanCtx context.Context
+	// coverage tracks the usage of scripts and stylesheets when set
+	coverage *coverage
+	// recorder records a screencast of the session when set
+	recorder *recorder
+	// device is the device emulated when set
+	device *Device
+	// cpuThrottle is the slowdown factor of the CPU, applied when above 1
+	cpuThrottle float64
+	// state is the storage state the browser starts from when set
+	state *State
+	// userAgent and locale override the browser's own when set
+	userAgent string
+	locale    string
+	// geolocation and timezone override the machine's when set
+	geolocation *Geolocation
+	timezone    string
+	// basic answers the authentication challenges of the target and login logs in before loading it when set
+	basic *Credentials
+	login *Login
+	// mocks serve the requests matching them from fixtures, mocked counting the requests they served
+	mocks  []Mock
+	mocked atomic.Int64
+	// faults are injected into the requests matching them
+	faults []Fault
+	// sent counts the requests sent by the page, along with their redirects
+	sent atomic.Int64
+	// paused stops capturing events, e.g. while ReloadWarm measures a second load
+	paused atomic.Bool
+	// logger logs the messages of chromedp, the one given to Run once it was called
+	logger atomic.Pointer[slog.Logger]
+	// finishers fetches the bodies of the responses as they finish loading, once the events are listened to
+	finishers *finishers
+	// streamer writes the events to sink in order, once the events are listened to
+	streamer *streamer
+	// deadline kills the browser once the run is over its deadline
+	deadline *time.Timer
+	// downloads keeps the files the page downloads when set
+	downloads *downloads
+	// consent dismisses the consent banner of the page once the target loaded when set
+	consent *Consent
+
+	mu sync.Mutex
+	// err is the error met discovering the browser executable or enforcing its limits, returned by Run
+	err error
+	// filtered holds the IDs of the requests left out by the filter
+	filtered map[network.RequestID]bool
+	// injected holds the faults injected into the requests
+	injected []InjectedFault
+	// targets holds the contexts of the workers and iframes that sent requests, by request ID
+	targets map[network.RequestID]context.Context
+	// frames is the frame tree of the page, by frame ID
+	frames map[cdp.FrameID]Frame
+	// timeouts bound the steps of the run
+	timeouts Timeouts
+	// inflight holds the IDs of the requests in flight and lastActivity is when the network was last active
+	inflight     map[network.RequestID]bool
+	lastActivity time.Time
+	// consentButton is the button clicked to dismiss the consent banner
+	consentButton string
 }
 
 // New creates a new Browser instance with the specified target URL.
 // It initializes a chromedp context with logging and sets a timeout of 60 seconds to prevent infinite wait loops.
-func New(target string) *Browser {
-	// create context
-	ctx, _ := chromedp.NewContext(
-		context.Background(),
-		chromedp.WithLogf(log.Printf),
-	)
+// The launch options, e.g. NetLog, are added to the default flags of the browser.
+func New(target string, launch ...chromedp.ExecAllocatorOption) *Browser {
+	id, err := uuid.NewV7()
+	if err != nil {
+		log.Fatalf("failed to create test ID: %v", err)
+	}
+
+	return NewWithTestID(target, id, launch...)
+}
+
+// NewWithTestID creates a new Browser instance like New, identifying the test with the given test ID.
+// This lets callers hand out the test ID before the browser is created.
+func NewWithTestID(target string, id uuid.UUID, launch ...chromedp.ExecAllocatorOption) *Browser {
+	return newBrowser(target, id, DefaultRunTimeout, launch...)
+}
+
+// interactiveTimeout bounds the sessions of the browsers created with NewInteractive.
+const interactiveTimeout = 30 * time.Minute
 
+// NewInteractive creates a new Browser instance showing its window, for a person to interact with the target,
+// e.g. to log in manually before the session is exported with ExportState. It is open for up to 30 minutes.
+func NewInteractive(target string) *Browser {
 	id, err := uuid.NewV7()
 	if err != nil {
 		log.Fatalf("failed to create test ID: %v", err)
 	}
 
-	// create a timeout as a safety net to prevent any infinite wait loops
-	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
-	return &Browser{target: target, ctx: ctx, cancel: cancel, testID: id}
+	return newBrowser(target, id, interactiveTimeout, chromedp.Flag("headless", false))
+}
+
+// newBrowser creates a new Browser instance killed once the timeout passed, unless SetTimeouts sets another deadline.
+func newBrowser(target string, id uuid.UUID, timeout time.Duration, launch ...chromedp.ExecAllocatorOption) *Browser {
+	// find the browser to run, keeping chromedp's own lookup when none is found so Run reports the error
+	allocCtx, allocCancel := context.Background(), context.CancelFunc(func() {})
+	execPath, err := FindExecPath()
+	if err == nil {
+		opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(execPath))
+		allocCtx, allocCancel = chromedp.NewExecAllocator(allocCtx, append(opts, launch...)...)
+	}
+
+	// create context
+	b := &Browser{target: target, testID: id, err: err}
+	ctx, _ := chromedp.NewContext(
+		allocCtx,
+		chromedp.WithLogf(b.logf),
+		chromedp.WithErrorf(b.errorf),
+	)
+
+	// create a deadline as a safety net to prevent any infinite wait loops, a timer rather than a context
+	// deadline so SetTimeouts can move it once the browser is created
+	ctx, cancel := context.WithCancel(ctx)
+	b.ctx = ctx
+	b.cancel = func() { b.deadline.Stop(); cancel(); allocCancel() }
+	b.deadline = time.AfterFunc(timeout, b.timeOut)
+	return b
+}
+
+// chromedpLogger returns the logger of the messages of chromedp: the one given to Run, or the default logger
+// before it was called.
+func (b *Browser) chromedpLogger() *slog.Logger {
+	if logger := b.logger.Load(); logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+// logf logs a message of chromedp, e.g. about an event it could not decode.
+func (b *Browser) logf(format string, args ...interface{}) {
+	b.chromedpLogger().Info("chromedp message", "message", fmt.Sprintf(format, args...))
+}
+
+// errorf logs an error of chromedp, e.g. a target it could not attach to.
+func (b *Browser) errorf(format string, args ...interface{}) {
+	b.chromedpLogger().Error("chromedp error", "error", fmt.Sprintf(format, args...))
 }
 
 // TestID returns the browser's test ID.
@@ -45,6 +194,67 @@ func (b *Browser) TestID() uuid.UUID {
 	return b.testID
 }
 
+// Version returns the product name and version of the running browser, e.g. "HeadlessChrome/129.0.6668.58".
+func (b *Browser) Version() (string, error) {
+	var product string
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		_, product, _, _, _, err = cdpbrowser.GetVersion().Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return "", fmt.Errorf("failed to get browser version: %v", err)
+	}
+	return product, nil
+}
+
+// StreamTo makes the browser write every captured request, response, loading failed and loading finished event
+// to the sink as it happens, in the order received, in addition to the collections passed to ListenToEvents. It
+// must be called before ListenToEvents, and DrainStream once the test is done for the queued events to be written.
+func (b *Browser) StreamTo(s sink.Sink) {
+	b.sink = s
+}
+
+// Redact makes the browser mask the credentials and personal data of the events it streams and of the bodies it
+// moves to the body store with the rules.
+func (b *Browser) Redact(rules redact.Rules) {
+	b.redaction = rules
+}
+
+// Instrument makes the browser record a span for every response body it fetches, children of the span of ctx.
+func (b *Browser) Instrument(ctx context.Context, tracer *telemetry.Tracer) {
+	b.spans, b.spanCtx = tracer, ctx
+}
+
+// LimitBodies keeps response bodies larger than limit bytes out of memory, writing them to the store
+// instead, or dropping them when store is nil. A limit of 0 keeps every body.
+func (b *Browser) LimitBodies(limit int, store *bodystore.Store) {
+	b.bodyLimit, b.bodies = limit, store
+}
+
+// SetFilter sets the filter selecting the requests whose events and bodies are captured.
+func (b *Browser) SetFilter(filter Filter) {
+	b.filter = filter
+}
+
+// filterOut reports whether the events of the request are left out, marking it as such when drop is set.
+func (b *Browser) filterOut(requestID network.RequestID, drop bool) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if drop {
+		if b.filtered == nil {
+			b.filtered = map[network.RequestID]bool{}
+		}
+		b.filtered[requestID] = true
+	}
+	return b.filtered[requestID]
+}
+
+// RequestCount returns the number of requests captured so far.
+func (b *Browser) RequestCount() int {
+	return int(b.sent.Load())
+}
+
 // Cancel cancels the browser's context, stopping any ongoing operations.
 func (b *Browser) Cancel() {
 	b.cancel()
@@ -56,118 +266,352 @@ func (b *Browser) GetCtx() context.Context {
 }
 
 // ListenToEvents sets up listeners for various browser events and processes them accordingly.
-// It listens for network request, response, and loading finished events, and logs the events
-// using the provided logger. The events are also added to the respective Requests and Responses
-// collections, and the loading finished events are sent to the finisher channel.
+// It listens for network request, response, loading finished and loading failed events, and logs the events
+// using the provided logger. The requests, responses and loading failed events are also added to the event
+// store as they are received, in order, and the loading finished events complete the timing of their response
+// and queue it for its body to be fetched by the finisher pipeline, drained by DrainFinishers. The events of the
+// requests left out by the browser's filter are ignored, so neither they nor their bodies are captured.
+// The events of the workers and out-of-process iframes of the page are captured too, the requests and
+// responses of workers telling the type of the worker in Source. Every request and response tells the frame
+// it was sent from, and the frame tree is kept for Frames.
 //
 // Parameters:
 //   - logger: A pointer to an slog.Logger used for logging event information.
-//   - responses: A pointer to a Responses collection where response events are added.
-//   - requests: A pointer to a Requests collection where request events are added.
-//   - finisherChan: A pointer to a channel where loading finished events are sent.
-func (b *Browser) ListenToEvents(logger *slog.Logger, responses *Responses, requests *Requests, finisherChan *chan network.EventLoadingFinished) {
+//   - events: The EventStore where requests, responses and failures are added.
+func (b *Browser) ListenToEvents(logger *slog.Logger, events *EventStore) {
+	b.startFinishers(logger, events)
+	b.startStreamer(logger)
 	// listen for events
-	chromedp.ListenTarget(b.ctx, func(ev interface{}) {
+	chromedp.ListenTarget(b.ctx, b.eventHandler(b.ctx, logger, "", events))
+	b.captureWorkers(logger, events)
+}
+
+// eventHandler returns the listener capturing the network and frame events of the target of ctx: the page,
+// one of its out-of-process iframes, or else the worker of type source.
+//
+// The listener is called synchronously for every event, so it keeps the events in order and queues them for the
+// streamer, which writes them to the sink in that order.
+func (b *Browser) eventHandler(ctx context.Context, logger *slog.Logger, source string, events *EventStore) func(ev interface{}) {
+	return func(ev interface{}) {
+		if b.paused.Load() {
+			return
+		}
+		b.trackActivity(ev)
 		switch ev := ev.(type) {
-		// case *page.EventFrameNavigated:
-		// 	fmt.Printf("frame navigated: %s\n", ev.Frame.URL)
+		case *page.EventFrameAttached, *page.EventFrameNavigated:
+			b.trackFrame(ev)
+
 		case *network.EventRequestWillBeSent:
-			go func() {
-				logger.Info("EventRequestWillBeSent: ", "requestID: ", ev.RequestID)
-				requests.Add(Request{RequestID: ev.RequestID, Type: "request", URL: ev.Request.URL, Content: ev})
-			}()
+			if b.filterOut(ev.RequestID, !b.filter.AllowsType(ev.Type.String())) {
+				return
+			}
+			if ctx != b.ctx {
+				b.addTargetRequest(ev.RequestID, ctx)
+			}
+			b.sent.Add(1)
+			logger.Info("EventRequestWillBeSent", "request_id", ev.RequestID)
+			events.AddRequest(Request{RequestID: ev.RequestID, Type: "request", URL: ev.Request.URL, Content: ev, Source: source,
+				FrameID: ev.FrameID, FrameURL: b.frameURL(ev.FrameID, ev.Type, ev.Request.URL)})
+			b.stream("request", ev.RequestID, ev.Request.URL, ev)
 
 		case *network.EventResponseReceived:
-			go func() {
-				logger.Info("EventResponseReceived:", "requestID: ", ev.RequestID)
-				responses.Add(Response{RequestID: ev.RequestID, Type: "response", URL: ev.Response.URL, Content: ev})
-			}()
+			if b.filterOut(ev.RequestID, !b.filter.AllowsType(ev.Type.String()) || !b.filter.AllowsMIME(ev.Response.MimeType)) {
+				logger.Debug("response left out by the filter", "request_id", ev.RequestID, "mime_type", ev.Response.MimeType)
+				return
+			}
+			logger.Info("EventResponseReceived", "request_id", ev.RequestID)
+			response := Response{RequestID: ev.RequestID, Type: "response", URL: ev.Response.URL, Content: ev, Source: source,
+				FrameID: ev.FrameID, FrameURL: b.frameURL(ev.FrameID, ev.Type, ev.Response.URL)}
+			response.setTransferInfo(ev.Response)
+			response.setTiming(ev.Response.Timing)
+			events.AddResponse(response)
+			b.stream("response", ev.RequestID, ev.Response.URL, ev)
+
+		case *network.EventLoadingFailed:
+			if b.filterOut(ev.RequestID, false) {
+				return
+			}
+			logger.Info("EventLoadingFailed", "request_id", ev.RequestID, "error", ev.ErrorText)
+			events.AddFailure(Failure{RequestID: ev.RequestID, ErrorText: ev.ErrorText, Canceled: ev.Canceled, BlockedReason: ev.BlockedReason.String()})
+			b.stream("failed", ev.RequestID, "", ev)
 
 		case *network.EventLoadingFinished:
-			go func() {
-				logger.Info("EventLoadingFinished:", "requestID: ", ev.RequestID)
-				*finisherChan <- *ev
-			}()
+			// the bodies of filtered out responses are not fetched
+			if b.filterOut(ev.RequestID, false) {
+				return
+			}
+			logger.Info("EventLoadingFinished", "request_id", ev.RequestID)
+			var resp Response
+			if events.UpdateResponse(ev.RequestID, func(r *Response) {
+				r.finishTiming(*ev)
+				resp = *r
+			}) {
+				b.finishers.enqueue(resp)
+			} else {
+				// e.g. a preflight or a data URL, which have no response to fetch the body of
+				logger.Debug("loading finished without a response", "request_id", ev.RequestID)
+			}
+			b.stream("finished", ev.RequestID, "", ev)
 
 		}
-	})
+	}
 }
 
 // Run navigates the browser to the target URL specified in the Browser struct.
 // It uses the chromedp package to perform the navigation.
-// Returns an error if the navigation fails.
-func (b *Browser) Run(waitTime time.Duration) error {
-	// navigate to the target URL
-	if err := chromedp.Run(b.ctx, chromedp.Navigate(b.target)); err != nil {
-		return err
+// Returns an error if no browser executable was found, the navigation fails or the browser was killed
+// for exceeding its resource limits or its deadline. Errors starting the browser wrap ErrLaunch and errors loading
+// the target wrap ErrNavigation. Once loaded, the page is given the wait time, then up to the idle timeout for its
+// network to be idle. The failures of the optional measurements, which do not fail the test, are logged with logger.
+func (b *Browser) Run(logger *slog.Logger, waitTime time.Duration) error {
+	b.logger.Store(logger)
+	if err := b.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrLaunch, err)
+	}
+
+	// start the browser, then watch its resources while it runs
+	if err := chromedp.Run(b.ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrLaunch, err)
+	}
+	// the bodies are still fetched with the default buffers, only more of them may be evicted
+	if err := enlargeBuffers(b.ctx); err != nil {
+		logger.Warn("failed to enlarge network buffers", "error", err)
+	}
+	// the downloads are kept when possible, but a page that downloads nothing is still tested
+	if b.downloads != nil {
+		err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			return b.startDownloads(ctx, logger)
+		}))
+		if err != nil {
+			logger.Warn("failed to capture downloads", "error", err)
+			b.downloads = nil
+		}
+	}
+	if b.limits != (Limits{}) {
+		go b.watchLimits()
+	}
+
+	// the vitals are observed from the start of the navigation, but a page without them is still tested
+	if err := chromedp.Run(b.ctx, chromedp.ActionFunc(b.observeVitals)); err != nil {
+		logger.Warn("failed to observe web vitals", "error", err)
+	}
+
+	// like the vitals, the trace is optional to the test
+	if b.tracer != nil {
+		if err := chromedp.Run(b.ctx, chromedp.ActionFunc(b.startTracing)); err != nil {
+			logger.Warn("failed to trace page load", "error", err)
+			b.tracer = nil
+		}
+	}
+
+	// unlike the measurements, a test of a device must not silently load the target as another one
+	if b.device != nil {
+		if err := chromedp.Run(b.ctx, chromedp.ActionFunc(b.emulateDevice)); err != nil {
+			return fmt.Errorf("failed to emulate device %s: %w", b.device.Name, err)
+		}
+	}
+	if b.userAgent != "" || b.locale != "" || (b.device != nil && b.device.UserAgent != "") {
+		if err := chromedp.Run(b.ctx, chromedp.ActionFunc(b.overrideIdentity)); err != nil {
+			return err
+		}
+	}
+	if b.geolocation != nil || b.timezone != "" {
+		if err := chromedp.Run(b.ctx, chromedp.ActionFunc(b.overrideLocation)); err != nil {
+			return err
+		}
+	}
+	if b.cpuThrottle > 1 {
+		if err := chromedp.Run(b.ctx, chromedp.ActionFunc(b.throttleCPU)); err != nil {
+			return err
+		}
+	}
+
+	if b.recorder != nil {
+		err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			return b.startRecording(ctx, logger)
+		}))
+		if err != nil {
+			logger.Warn("failed to record video", "error", err)
+			b.recorder = nil
+		}
 	}
 
-	// wait for the specified duration
-	chromedp.Sleep(waitTime)
+	if b.coverage != nil {
+		if err := chromedp.Run(b.ctx, chromedp.ActionFunc(b.startCoverage)); err != nil {
+			logger.Warn("failed to measure coverage", "error", err)
+			b.coverage = nil
+		}
+	}
+
+	// like the device, a test of an authenticated area must not silently run logged out
+	var restored page.ScriptIdentifier
+	if b.state != nil {
+		err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			restored, err = b.restoreState(ctx)
+			return err
+		}))
+		if err != nil {
+			return fmt.Errorf("failed to restore storage state: %w", err)
+		}
+	}
 
+	// like the state, the credentials, fixtures and faults must not silently be ignored
+	if b.basic != nil || len(b.mocks) > 0 || len(b.faults) > 0 {
+		err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			return b.intercept(ctx, logger)
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	if b.login != nil {
+		if err := b.logIn(); err != nil {
+			if limitErr := b.Err(); limitErr != nil {
+				return limitErr
+			}
+			return fmt.Errorf("%w: %w", ErrNavigation, err)
+		}
+	}
+
+	// navigate to the target URL, which aborts the navigation when the target is a file the page downloads
+	if err := b.navigate(); err != nil && !b.downloadStarted() {
+		if limitErr := b.Err(); limitErr != nil {
+			return limitErr
+		}
+		return fmt.Errorf("%w: %w", ErrNavigation, err)
+	}
+
+	// the restored web storage must not overwrite what the page stores in the documents it loads next
+	if restored != "" {
+		if err := chromedp.Run(b.ctx, page.RemoveScriptToEvaluateOnNewDocument(restored)); err != nil {
+			logger.Warn("failed to remove web storage script", "error", err)
+		}
+	}
+
+	// the banner holds traffic back until dismissed, but a page whose banner could not be dismissed is still tested
+	if b.consent != nil {
+		if err := b.dismissConsent(); err != nil {
+			logger.Warn("failed to dismiss consent banner", "error", err)
+		}
+	}
+
+	// wait for the specified duration, then for the page to settle
+	if err := chromedp.Run(b.ctx, chromedp.Sleep(waitTime)); err != nil {
+		if limitErr := b.Err(); limitErr != nil {
+			return limitErr
+		}
+		return fmt.Errorf("%w: %w", ErrNavigation, err)
+	}
+	if !b.waitIdle() {
+		logger.Warn("network still busy after the idle timeout", "in_flight", b.inflightCount())
+	}
+
+	if b.tracer != nil {
+		if err := b.stopTracing(); err != nil {
+			logger.Warn("failed to write trace", "error", err)
+		}
+	}
+
+	return b.Err()
+}
+
+// Open loads the target without capturing its events, e.g. for a person to log in on a browser created
+// with NewInteractive.
+func (b *Browser) Open() error {
+	if err := b.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrLaunch, err)
+	}
+	if err := b.navigate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrNavigation, err)
+	}
 	return nil
 }
 
-// GetResponseBody retrieves the response body for a given request and updates the response map.
+// GetResponseBody retrieves the response body for a given request and updates its response in the event store,
+// along with the status of the fetch. The fetch is retried with a backoff unless the body is gone for good. A body
+// whose bytes received already exceed the body limit is never fetched whole: see storeLargeBody.
 // It logs the initial and final lengths of the response body at various stages of the process.
 //
 // Parameters:
 // - logger: A structured logger for logging information and errors.
 // - r: A pointer to the Response struct containing the request ID and body.
-// - responses: A pointer to the Responses struct containing a map of responses and a mutex for synchronization.
+// - events: The EventStore holding the response.
 //
 // Returns:
 // - error: An error if the response body could not be retrieved or updated.
-func (b *Browser) GetResponseBody(logger *slog.Logger, r *Response, responses *Responses) error {
-	logger.Info("initial response body length: ", "len: ", len(r.Body))
+func (b *Browser) GetResponseBody(logger *slog.Logger, r *Response, events *EventStore) error {
+	logger.Info("initial response body length", "len", len(r.Body))
+	_, span := b.spans.Start(b.spanCtx, "browser.fetch_body", telemetry.String("url", r.URL), telemetry.String("request_id", string(r.RequestID)))
+	defer span.End()
 
-	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
-		body, err := network.GetResponseBody(r.RequestID).Do(ctx)
+	ctx, cancel := b.withTimeout(b.ContextOf(r.RequestID), func(t Timeouts) time.Duration { return t.BodyFetch })
+	defer cancel()
+	if b.bodyLimit > 0 && r.Timing.EncodedBytes > float64(b.bodyLimit) {
+		b.storeLargeBody(ctx, logger, r, events)
+	} else {
+		body, err := fetchBody(ctx, r.RequestID)
 		if err != nil {
-			logger.Error("failed to get response body: ", "error: ", err)
-			return fmt.Errorf("failed to get response body: %v", err)
+			r.BodyStatus = bodyFetchStatus(ctx, err)
+			logger.Error("failed to get response body", "request_id", r.RequestID, "status", r.BodyStatus, "error", err)
+			events.UpdateResponse(r.RequestID, func(resp *Response) { resp.BodyStatus = r.BodyStatus })
+			span.RecordError(err)
+			span.SetAttributes(telemetry.String("status", r.BodyStatus))
+			return fmt.Errorf("could not get response body: %v", err)
 		}
-		r.Body = body
-		return nil
-	}))
 
-	if err != nil {
-		return fmt.Errorf("could not get response body: %v", err)
+		r.Body, r.BodySize, r.BodyStatus = body, len(body), BodyFetched
+		r.setParts()
+		// the body was compressed below the limit, so it is only known to be above it once fetched
+		if b.bodyLimit > 0 && len(body) > b.bodyLimit {
+			r.Body, r.BodyStatus = nil, BodyTooLarge
+			if b.bodies == nil {
+				logger.Info("dropping response body above the limit", "url", r.URL, "size", len(body))
+			} else if r.BodyPath, r.BodyHash, err = b.bodies.Put(b.redaction.Body(body)); err != nil {
+				logger.Error("failed to store response body", "url", r.URL, "error", err)
+			} else {
+				r.BodyStatus = BodyStored
+			}
+		}
 	}
+	span.SetAttributes(telemetry.Int("bytes", r.BodySize), telemetry.String("status", r.BodyStatus))
 
-	// Lock the mutex before updating the map
-	responses.mu.Lock()
-	defer responses.mu.Unlock()
-	responses.ResponseMap[r.RequestID] = *r
-
+	// only the body is updated, the response may have changed since it was read
+	events.UpdateResponse(r.RequestID, func(resp *Response) {
+		resp.Body, resp.BodySize, resp.Parts, resp.BodyPath, resp.BodyHash = r.Body, r.BodySize, r.Parts, r.BodyPath, r.BodyHash
+		resp.BodyStatus = r.BodyStatus
+	})
 	return nil
 }
 
-// NewFinisherChannel creates and returns a new channel for network.EventLoadingFinished events.
-// This channel can be used to receive notifications when a network loading event has finished.
-func (b *Browser) NewFinisherChannel() chan network.EventLoadingFinished {
-	return make(chan network.EventLoadingFinished)
-}
-
-// WatchEventFinishers listens for network loading finished events and processes the responses.
-// It logs the event details and retrieves the response body for each event.
-//
-// Parameters:
-//   - logger: A pointer to an slog.Logger instance for logging event details.
-//   - f: A pointer to a channel of network.EventLoadingFinished events to watch.
-//   - responses: A pointer to a Responses struct containing the response map and mutex.
-func (b *Browser) WatchEventFinishers(logger *slog.Logger, f *chan network.EventLoadingFinished, responses *Responses) {
-	log.Printf("Watching for event finishers")
-	go func(responses *Responses) {
-		for event := range *f {
-			logger.Info("EventLoadingFinished, getting body:", "requestID: ", event.RequestID)
-
-			// Lock the mutex before reading from the map
-			responses.mu.Lock()
-			resp := responses.ResponseMap[event.RequestID]
-			responses.mu.Unlock()
-
-			b.GetResponseBody(logger, &resp, responses)
-		}
-	}(responses)
+// storeLargeBody handles the body of a response whose bytes received exceed the body limit without fetching it into
+// memory: it is streamed to the body store, loaded again by the browser, or else dropped, the size being the bytes
+// received then. The bodies of the requests other than GET requests, which must not be sent again, are dropped too,
+// and so are all of them with redaction rules, a body streamed not being redacted.
+func (b *Browser) storeLargeBody(ctx context.Context, logger *slog.Logger, r *Response, events *EventStore) {
+	r.Body, r.BodySize, r.BodyStatus = nil, int(r.Timing.EncodedBytes), BodyTooLarge
+	method := ""
+	if req, ok := events.Request(r.RequestID); ok {
+		if ev, ok := req.Content.(*network.EventRequestWillBeSent); ok && ev.Request != nil {
+			method = ev.Request.Method
+		}
+	}
+	switch {
+	case b.bodies == nil:
+		logger.Info("dropping response body above the limit without fetching it", "url", r.URL, "size", r.BodySize)
+	case !b.redaction.Empty():
+		logger.Info("dropping response body above the limit, which cannot be redacted without fetching it", "url", r.URL,
+			"size", r.BodySize)
+	case method != http.MethodGet:
+		logger.Info("dropping response body above the limit of a request that cannot be sent again", "url", r.URL,
+			"method", method, "size", r.BodySize)
+	default:
+		path, hash, size, err := streamBody(ctx, b.bodies, r.FrameID, r.URL)
+		if err != nil {
+			r.BodyStatus = bodyFetchStatus(ctx, err)
+			logger.Error("failed to stream response body", "url", r.URL, "status", r.BodyStatus, "error", err)
+			return
+		}
+		r.BodyPath, r.BodyHash, r.BodySize, r.BodyStatus = path, hash, int(size), BodyStored
+	}
 }
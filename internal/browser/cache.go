@@ -0,0 +1,88 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// loadTimeScript reads the load event end of the page's navigation, in milliseconds.
+const loadTimeScript = `(performance.getEntriesByType('navigation')[0] || {}).loadEventEnd || 0`
+
+// LoadStats summarizes a page load: the requests it sent, how many of them the browser's caches served,
+// the bytes transferred over the network and the load event end, in milliseconds.
+type LoadStats struct {
+	Requests  int     `json:"requests"`
+	FromCache int     `json:"from_cache"`
+	Bytes     float64 `json:"bytes"`
+	Load      float64 `json:"load_ms"`
+}
+
+// fromCache reports whether a response was served by the disk, prefetch or service worker cache.
+func fromCache(resp *network.Response) bool {
+	return resp.FromDiskCache || resp.FromPrefetchCache || resp.FromServiceWorker
+}
+
+// SummarizeLoad summarizes the load captured by Run from its requests and responses, with the load event
+// end measured by the vitals.
+func SummarizeLoad(requests []Request, responses []Response, load float64) LoadStats {
+	stats := LoadStats{Requests: len(requests), Load: load}
+	for _, resp := range responses {
+		if ev, ok := resp.Content.(*network.EventResponseReceived); ok && fromCache(ev.Response) {
+			stats.FromCache++
+		}
+		stats.Bytes += resp.Timing.EncodedBytes
+	}
+	return stats
+}
+
+// ReloadWarm navigates to the target again in the same browser context, so the caches filled by the first
+// load are used, and returns the stats of the load. The events of this load are not captured.
+func (b *Browser) ReloadWarm(waitTime time.Duration) (LoadStats, error) {
+	b.paused.Store(true)
+	defer b.paused.Store(false)
+
+	var mu sync.Mutex
+	stats := LoadStats{}
+	cached := map[network.RequestID]bool{}
+	listenCtx, stop := context.WithCancel(b.ctx)
+	defer stop()
+	chromedp.ListenTarget(listenCtx, func(ev interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			stats.Requests++
+		case *network.EventRequestServedFromCache:
+			cached[ev.RequestID] = true
+		case *network.EventResponseReceived:
+			if fromCache(ev.Response) {
+				cached[ev.RequestID] = true
+			}
+		case *network.EventLoadingFinished:
+			stats.Bytes += ev.EncodedDataLength
+		}
+	})
+
+	if err := b.navigate(); err != nil {
+		return stats, fmt.Errorf("failed to reload target: %v", err)
+	}
+	if err := chromedp.Run(b.ctx, chromedp.Sleep(waitTime)); err != nil {
+		return stats, fmt.Errorf("failed to reload target: %v", err)
+	}
+	stop()
+
+	var load float64
+	if err := chromedp.Run(b.ctx, chromedp.Evaluate(loadTimeScript, &load)); err != nil {
+		return stats, fmt.Errorf("failed to get load time: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	stats.FromCache, stats.Load = len(cached), load
+	return stats, nil
+}
@@ -0,0 +1,121 @@
+package browser
+
+import (
+	"sort"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// ChainNode is a request of the critical request chain. Depth is 0 for the document, and Start and End are
+// in milliseconds from the start of the document request.
+type ChainNode struct {
+	URL          string  `json:"url"`
+	ResourceType string  `json:"resource_type"`
+	Depth        int     `json:"depth"`
+	Start        float64 `json:"start_ms"`
+	End          float64 `json:"end_ms"`
+	Bytes        float64 `json:"bytes"`
+}
+
+// criticalTypes are the resource types that can block the first render.
+var criticalTypes = map[network.ResourceType]bool{
+	network.ResourceTypeDocument:   true,
+	network.ResourceTypeStylesheet: true,
+	network.ResourceTypeScript:     true,
+	network.ResourceTypeFont:       true,
+}
+
+// criticalPriorities are the priorities the browser gives to render-blocking requests.
+var criticalPriorities = map[network.ResourcePriority]bool{
+	network.ResourcePriorityHigh:     true,
+	network.ResourcePriorityVeryHigh: true,
+}
+
+// CriticalChain returns the critical request chain of a page load: the tree of requests leading from the
+// document to the resources blocking its first render, in depth-first order. Like Lighthouse, a request is
+// critical when it is a high priority document, stylesheet, script or font that is not a preload, and it is
+// linked to its parent by its initiator. Requests started after firstRender, in milliseconds from the start
+// of the document request, are left out unless firstRender is zero.
+func CriticalChain(transactions []Transaction, firstRender float64) []ChainNode {
+	var document *Transaction
+	for i := range transactions {
+		if transactions[i].ResourceType == network.ResourceTypeDocument.String() {
+			document = &transactions[i]
+			break
+		}
+	}
+	if document == nil || document.Response == nil {
+		return nil
+	}
+	origin := document.Response.Timing.requestTime
+
+	byURL := map[string]*Transaction{}
+	children := map[network.RequestID][]*Transaction{}
+	for i := range transactions {
+		byURL[transactions[i].URL] = &transactions[i]
+	}
+	for i := range transactions {
+		t := &transactions[i]
+		if t == document || !critical(t) {
+			continue
+		}
+		if firstRender > 0 && t.Response != nil && (t.Response.Timing.requestTime-origin)*1000 > firstRender {
+			continue
+		}
+		parent, ok := byURL[initiatorURL(t)]
+		if !ok {
+			continue
+		}
+		children[parent.RequestID] = append(children[parent.RequestID], t)
+	}
+
+	var chain []ChainNode
+	var visit func(t *Transaction, depth int)
+	visit = func(t *Transaction, depth int) {
+		node := ChainNode{URL: t.URL, ResourceType: t.ResourceType, Depth: depth}
+		if t.Response != nil {
+			node.Start = (t.Response.Timing.requestTime - origin) * 1000
+			node.End, node.Bytes = node.Start+t.Response.Timing.Total, t.Response.Timing.EncodedBytes
+		}
+		chain = append(chain, node)
+
+		next := children[t.RequestID]
+		sort.SliceStable(next, func(i, j int) bool {
+			return requestTime(next[i].Request).Before(requestTime(next[j].Request))
+		})
+		// every request has a single parent and the document none, so the chain is a tree
+		for _, child := range next {
+			visit(child, depth+1)
+		}
+	}
+	visit(document, 0)
+	return chain
+}
+
+// critical reports whether a request can block the first render.
+func critical(t *Transaction) bool {
+	ev, ok := t.Request.Content.(*network.EventRequestWillBeSent)
+	if !ok || ev.Request == nil || t.Failed() {
+		return false
+	}
+	return criticalTypes[ev.Type] && criticalPriorities[ev.Request.InitialPriority] && !ev.Request.IsLinkPreload
+}
+
+// initiatorURL returns the URL of the document or script that initiated a request.
+func initiatorURL(t *Transaction) string {
+	ev, ok := t.Request.Content.(*network.EventRequestWillBeSent)
+	if !ok || ev.Initiator == nil {
+		return ""
+	}
+	if ev.Initiator.URL != "" {
+		return ev.Initiator.URL
+	}
+	for stack := ev.Initiator.Stack; stack != nil; stack = stack.Parent {
+		for _, frame := range stack.CallFrames {
+			if frame.URL != "" {
+				return frame.URL
+			}
+		}
+	}
+	return ev.DocumentURL
+}
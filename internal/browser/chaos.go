@@ -0,0 +1,160 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"web-tester/internal/scope"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Fault actions.
+const (
+	// FaultFail fails the request with a network error, or answers it with an error status when one is set
+	FaultFail = "fail"
+	// FaultDelay holds the request for a delay before sending it
+	FaultDelay = "delay"
+	// FaultCorrupt truncates the body of the response to half its size
+	FaultCorrupt = "corrupt"
+)
+
+// Fault is a failure injected into the requests to the URLs matching its pattern, to observe how the page
+// degrades. Patterns are scope rules: globs, or regular expressions prefixed with "re:".
+type Fault struct {
+	Pattern string
+	Action  string
+	// Delay is how long FaultDelay holds the requests
+	Delay time.Duration
+	// Reason is the network error FaultFail fails the requests with, unless Status is set
+	Reason network.ErrorReason
+	Status int
+	rules  scope.Rules
+}
+
+// InjectedFault is a fault injected into a request of the page.
+type InjectedFault struct {
+	URL    string `json:"url"`
+	Action string `json:"action"`
+	Detail string `json:"detail"`
+}
+
+// NewFault compiles the pattern of a fault and checks its action. The reason of a failure defaults to Failed.
+func NewFault(pattern, action string, delay time.Duration, reason string, status int) (Fault, error) {
+	rules, err := scope.Parse([]string{pattern}, nil)
+	if err != nil {
+		return Fault{}, fmt.Errorf("invalid fault pattern: %v", err)
+	}
+	fault := Fault{Pattern: pattern, Action: action, Delay: delay, Reason: network.ErrorReason(reason), Status: status, rules: rules}
+	switch action {
+	case FaultFail:
+		if reason == "" {
+			fault.Reason = network.ErrorReasonFailed
+		} else if err := fault.Reason.UnmarshalJSON([]byte(strconv.Quote(reason))); err != nil {
+			// the reasons are the network errors of the browser, e.g. TimedOut or ConnectionReset
+			return Fault{}, fmt.Errorf("invalid fault reason %q", reason)
+		}
+	case FaultDelay:
+		if delay <= 0 {
+			return Fault{}, fmt.Errorf("the delay fault of %s has no delay", pattern)
+		}
+	case FaultCorrupt:
+	default:
+		return Fault{}, fmt.Errorf("invalid fault action %q, expected %s, %s or %s", action, FaultFail, FaultDelay, FaultCorrupt)
+	}
+	return fault, nil
+}
+
+// InjectFaults makes Run inject the faults into the requests matching them, the first matching fault
+// winning. The injected faults are read with Faults.
+func (b *Browser) InjectFaults(faults []Fault) {
+	b.faults = faults
+}
+
+// Faults returns the faults injected into the requests of the page so far.
+func (b *Browser) Faults() []InjectedFault {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]InjectedFault(nil), b.injected...)
+}
+
+// faultFor returns the first fault matching the URL, or nil.
+func (b *Browser) faultFor(url string) *Fault {
+	for i := range b.faults {
+		if b.faults[i].rules.InScope(url) {
+			return &b.faults[i]
+		}
+	}
+	return nil
+}
+
+// recordFault records a fault injected into a request.
+func (b *Browser) recordFault(url, action, detail string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.injected = append(b.injected, InjectedFault{URL: url, Action: action, Detail: detail})
+}
+
+// injectFault injects the fault into a request paused before it is sent. Delayed requests are passed on
+// to pass once the delay elapsed, and corrupted ones paused again once their response is received.
+func (b *Browser) injectFault(logger *slog.Logger, fault *Fault, ev *fetch.EventRequestPaused, pass func(*fetch.EventRequestPaused)) {
+	url := ev.Request.URL
+	switch fault.Action {
+	case FaultFail:
+		if fault.Status > 0 {
+			b.recordFault(url, FaultFail, fmt.Sprintf("status %d", fault.Status))
+			b.continueFetch(logger, fetch.FulfillRequest(ev.RequestID, int64(fault.Status)).WithBody(""))
+			return
+		}
+		b.recordFault(url, FaultFail, fault.Reason.String())
+		b.continueFetch(logger, fetch.FailRequest(ev.RequestID, fault.Reason))
+	case FaultDelay:
+		b.recordFault(url, FaultDelay, fault.Delay.String())
+		select {
+		case <-time.After(fault.Delay):
+			pass(ev)
+		case <-b.ctx.Done():
+		}
+	case FaultCorrupt:
+		b.continueFetch(logger, fetch.ContinueRequest(ev.RequestID).WithInterceptResponse(true))
+	}
+}
+
+// corruptResponse truncates the body of a response paused once received to half its size. Redirects and
+// failed responses, which have no body, are continued as is.
+func (b *Browser) corruptResponse(logger *slog.Logger, ev *fetch.EventRequestPaused) {
+	if ev.ResponseErrorReason != "" || ev.ResponseStatusCode >= 300 && ev.ResponseStatusCode < 400 {
+		b.continueFetch(logger, fetch.ContinueResponse(ev.RequestID))
+		return
+	}
+
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		body, err := fetch.GetResponseBody(ev.RequestID).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get response body: %v", err)
+		}
+		// the body is decoded, so the headers describing its encoding no longer apply
+		var headers []*fetch.HeaderEntry
+		for _, h := range ev.ResponseHeaders {
+			if name := strings.ToLower(h.Name); name != "content-length" && name != "content-encoding" {
+				headers = append(headers, h)
+			}
+		}
+		truncated := body[:len(body)/2]
+		b.recordFault(ev.Request.URL, FaultCorrupt, fmt.Sprintf("truncated from %d to %d bytes", len(body), len(truncated)))
+		return fetch.FulfillRequest(ev.RequestID, ev.ResponseStatusCode).
+			WithResponseHeaders(headers).
+			WithBody(base64.StdEncoding.EncodeToString(truncated)).
+			Do(ctx)
+	}))
+	if err != nil && b.ctx.Err() == nil {
+		logger.Warn("failed to corrupt response", "url", ev.Request.URL, "error", err)
+		b.continueFetch(logger, fetch.ContinueResponse(ev.RequestID))
+	}
+}
@@ -0,0 +1,140 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Consent policies, the choice clicked in the consent banners.
+const (
+	ConsentAccept = "accept"
+	ConsentReject = "reject"
+)
+
+// consentSelectors are the buttons of the common consent management platforms, by policy: OneTrust, Cookiebot,
+// Didomi, TrustArc and Quantcast.
+var consentSelectors = map[string][]string{
+	ConsentAccept: {
+		"#onetrust-accept-btn-handler",
+		"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll",
+		"#CybotCookiebotDialogBodyButtonAccept",
+		"#didomi-notice-agree-button",
+		"#truste-consent-button",
+		".qc-cmp2-summary-buttons button[mode=primary]",
+	},
+	ConsentReject: {
+		"#onetrust-reject-all-handler",
+		"#CybotCookiebotDialogBodyButtonDecline",
+		"#didomi-notice-disagree-button",
+		"#truste-consent-required",
+		".qc-cmp2-summary-buttons button[mode=secondary]",
+	},
+}
+
+// consentLabels match the labels of the generic consent buttons, by policy, for the banners of no known platform.
+var consentLabels = map[string]string{
+	ConsentAccept: `^(accept|accept all|accept all cookies|accept cookies|allow all|allow all cookies|agree|i agree|got it|ok)$`,
+	ConsentReject: `^(reject|reject all|reject all cookies|decline|decline all|deny|refuse|refuse all|only necessary|necessary only|use necessary cookies only)$`,
+}
+
+// consentScript clicks the first visible element matching the selectors, or else the first visible button whose
+// label matches the pattern, and returns a description of it, or an empty string when the page has none.
+const consentScript = `((selectors, pattern) => {
+	const visible = el => {
+		const rect = el.getBoundingClientRect();
+		const style = getComputedStyle(el);
+		return rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+	};
+	for (const selector of selectors) {
+		const el = Array.from(document.querySelectorAll(selector)).find(visible);
+		if (el) {
+			el.click();
+			return selector;
+		}
+	}
+	const label = new RegExp(pattern, 'i');
+	for (const el of document.querySelectorAll('button, [role=button], input[type=button], input[type=submit], a')) {
+		const text = (el.innerText || el.value || el.getAttribute('aria-label') || '').trim().replace(/\s+/g, ' ');
+		if (label.test(text) && visible(el)) {
+			el.click();
+			return el.tagName.toLowerCase() + ' "' + text + '"';
+		}
+	}
+	return '';
+})(%s, %s)`
+
+// Consent is the handling of the consent banners of the page: once the target loaded, the banner is looked for
+// until Timeout and dismissed with the choice of Policy, accept or reject. Selectors are tried before the buttons
+// of the known consent management platforms and the generic buttons labelled with the choice.
+type Consent struct {
+	Policy    string
+	Selectors []string
+	Timeout   time.Duration
+}
+
+// Validate checks the policy of the consent handling.
+func (c Consent) Validate() error {
+	if _, ok := consentSelectors[c.Policy]; !ok {
+		return fmt.Errorf("unknown consent policy %q, use %s or %s", c.Policy, ConsentAccept, ConsentReject)
+	}
+	return nil
+}
+
+// HandleConsent makes Run dismiss the consent banner of the page once the target loaded, before the wait time,
+// so the traffic the banner held back until a choice was made is captured.
+func (b *Browser) HandleConsent(c *Consent) {
+	b.consent = c
+}
+
+// ConsentDismissed returns the button clicked to dismiss the consent banner of the page, empty when no banner
+// was found.
+func (b *Browser) ConsentDismissed() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.consentButton
+}
+
+// dismissConsent looks for the consent banner of the page until the timeout of the consent handling, clicking the
+// button of its policy once found. A page without a banner is not an error.
+func (b *Browser) dismissConsent() error {
+	c := b.consent
+	selectors, err := json.Marshal(append(append([]string{}, c.Selectors...), consentSelectors[c.Policy]...))
+	if err != nil {
+		return err
+	}
+	pattern, err := json.Marshal(consentLabels[c.Policy])
+	if err != nil {
+		return err
+	}
+	script := fmt.Sprintf(consentScript, selectors, pattern)
+
+	ctx, cancel := context.WithTimeout(b.ctx, c.Timeout)
+	defer cancel()
+	// the consent management platforms usually show their banner once their own script loaded
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		var clicked string
+		if err := chromedp.Run(ctx, chromedp.Evaluate(script, &clicked)); err != nil {
+			if ctx.Err() != nil && b.ctx.Err() == nil {
+				return nil
+			}
+			return err
+		}
+		if clicked != "" {
+			b.mu.Lock()
+			b.consentButton = clicked
+			b.mu.Unlock()
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
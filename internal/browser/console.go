@@ -0,0 +1,88 @@
+package browser
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// ConsoleMessage is a message logged to the page's console, or an uncaught exception.
+type ConsoleMessage struct {
+	Level string
+	Text  string
+	URL   string
+}
+
+// ConsoleMessages holds the console messages captured during a run.
+type ConsoleMessages struct {
+	mu       sync.Mutex
+	Messages []ConsoleMessage
+}
+
+// Add appends a console message to the collection.
+func (c *ConsoleMessages) Add(message ConsoleMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Messages = append(c.Messages, message)
+}
+
+// Errors returns the captured messages with the error level.
+func (c *ConsoleMessages) Errors() []ConsoleMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errors []ConsoleMessage
+	for _, m := range c.Messages {
+		if m.Level == "error" {
+			errors = append(errors, m)
+		}
+	}
+	return errors
+}
+
+// ListenToConsole captures console API calls, browser log entries and uncaught exceptions into messages.
+func (b *Browser) ListenToConsole(logger *slog.Logger, messages *ConsoleMessages) {
+	chromedp.ListenTarget(b.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			var args []string
+			for _, arg := range ev.Args {
+				if arg.Value != nil {
+					args = append(args, strings.Trim(string(arg.Value), `"`))
+				} else {
+					args = append(args, arg.Description)
+				}
+			}
+			level := string(ev.Type)
+			if ev.Type == runtime.APITypeAssert {
+				level = "error"
+			}
+			messages.Add(ConsoleMessage{Level: level, Text: strings.Join(args, " ")})
+
+		case *cdplog.EventEntryAdded:
+			messages.Add(ConsoleMessage{Level: string(ev.Entry.Level), Text: ev.Entry.Text, URL: ev.Entry.URL})
+
+		case *runtime.EventExceptionThrown:
+			text := ev.ExceptionDetails.Text
+			if ev.ExceptionDetails.Exception != nil && ev.ExceptionDetails.Exception.Description != "" {
+				text = ev.ExceptionDetails.Exception.Description
+			}
+			logger.Info("EventExceptionThrown", "text", text)
+			messages.Add(ConsoleMessage{Level: "error", Text: text, URL: ev.ExceptionDetails.URL})
+		}
+	})
+}
+
+// Title returns the title of the loaded page.
+func (b *Browser) Title() (string, error) {
+	var title string
+	if err := chromedp.Run(b.ctx, chromedp.Title(&title)); err != nil {
+		return "", fmt.Errorf("failed to get page title: %v", err)
+	}
+	return title, nil
+}
@@ -0,0 +1,177 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/chromedp/cdproto/css"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/profiler"
+	"github.com/chromedp/chromedp"
+)
+
+// ResourceCoverage is how much of a script or stylesheet was used by the page. Sizes are in characters of
+// the source, which match bytes for ASCII sources. Inline scripts and styles are counted under the URL of
+// their document.
+type ResourceCoverage struct {
+	URL   string  `json:"url"`
+	Type  string  `json:"type"`
+	Total float64 `json:"total"`
+	Used  float64 `json:"used"`
+}
+
+// Unused returns the characters of the resource that were never executed or matched.
+func (c ResourceCoverage) Unused() float64 {
+	return c.Total - c.Used
+}
+
+// coverage holds the stylesheets added to the page while their rule usage is tracked.
+type coverage struct {
+	mu          sync.Mutex
+	stylesheets map[css.StyleSheetID]*css.StyleSheetHeader
+}
+
+// MeasureCoverage makes Run track which parts of the scripts and stylesheets of the page are used, read
+// with Coverage once the page is loaded.
+func (b *Browser) MeasureCoverage() {
+	b.coverage = &coverage{stylesheets: map[css.StyleSheetID]*css.StyleSheetHeader{}}
+}
+
+// startCoverage starts the precise script coverage and the CSS rule usage tracking.
+func (b *Browser) startCoverage(ctx context.Context) error {
+	c := b.coverage
+	chromedp.ListenTarget(b.ctx, func(ev interface{}) {
+		if ev, ok := ev.(*css.EventStyleSheetAdded); ok && ev.Header != nil {
+			c.mu.Lock()
+			c.stylesheets[ev.Header.StyleSheetID] = ev.Header
+			c.mu.Unlock()
+		}
+	})
+
+	if err := profiler.Enable().Do(ctx); err != nil {
+		return fmt.Errorf("failed to enable profiler: %v", err)
+	}
+	if _, err := profiler.StartPreciseCoverage().WithCallCount(false).WithDetailed(true).Do(ctx); err != nil {
+		return fmt.Errorf("failed to start script coverage: %v", err)
+	}
+	// the CSS domain requires the DOM domain
+	if err := dom.Enable().Do(ctx); err != nil {
+		return fmt.Errorf("failed to enable dom: %v", err)
+	}
+	if err := css.Enable().Do(ctx); err != nil {
+		return fmt.Errorf("failed to enable css: %v", err)
+	}
+	if err := css.StartRuleUsageTracking().Do(ctx); err != nil {
+		return fmt.Errorf("failed to start css coverage: %v", err)
+	}
+	return nil
+}
+
+// Coverage returns the coverage of every script and stylesheet loaded by the page, sorted by unused size,
+// largest first. It must be called after Run with MeasureCoverage, while the page is still loaded.
+func (b *Browser) Coverage() ([]ResourceCoverage, error) {
+	if b.coverage == nil {
+		return nil, nil
+	}
+
+	var scripts []*profiler.ScriptCoverage
+	var rules []*css.RuleUsage
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		if scripts, _, err = profiler.TakePreciseCoverage().Do(ctx); err != nil {
+			return fmt.Errorf("failed to take script coverage: %v", err)
+		}
+		if rules, err = css.StopRuleUsageTracking().Do(ctx); err != nil {
+			return fmt.Errorf("failed to take css coverage: %v", err)
+		}
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	byURL := map[string]*ResourceCoverage{}
+	add := func(url, kind string, used []bool) {
+		if url == "" {
+			// scripts evaluated from strings and constructed stylesheets have no resource to blame
+			return
+		}
+		key := kind + " " + url
+		c, ok := byURL[key]
+		if !ok {
+			c = &ResourceCoverage{URL: url, Type: kind}
+			byURL[key] = c
+		}
+		c.Total += float64(len(used))
+		for _, u := range used {
+			if u {
+				c.Used++
+			}
+		}
+	}
+
+	for _, script := range scripts {
+		add(script.URL, "script", scriptUsage(script))
+	}
+
+	b.coverage.mu.Lock()
+	usage := map[css.StyleSheetID][]bool{}
+	for id, header := range b.coverage.stylesheets {
+		usage[id] = make([]bool, int(header.Length))
+	}
+	for _, rule := range rules {
+		used, ok := usage[rule.StyleSheetID]
+		if !ok || !rule.Used {
+			continue
+		}
+		for i := max(int(rule.StartOffset), 0); i < min(int(rule.EndOffset), len(used)); i++ {
+			used[i] = true
+		}
+	}
+	for id, header := range b.coverage.stylesheets {
+		add(header.SourceURL, "stylesheet", usage[id])
+	}
+	b.coverage.mu.Unlock()
+
+	resources := make([]ResourceCoverage, 0, len(byURL))
+	for _, c := range byURL {
+		resources = append(resources, *c)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Unused() != resources[j].Unused() {
+			return resources[i].Unused() > resources[j].Unused()
+		}
+		return resources[i].URL < resources[j].URL
+	})
+	return resources, nil
+}
+
+// scriptUsage marks the characters of a script that were executed. Block coverage ranges nest, the
+// innermost range telling whether its characters ran, so they are applied from the outermost in. The
+// script's top level function spans the whole source, giving its length.
+func scriptUsage(script *profiler.ScriptCoverage) []bool {
+	var ranges []*profiler.CoverageRange
+	var length int64
+	for _, f := range script.Functions {
+		for _, r := range f.Ranges {
+			ranges = append(ranges, r)
+			length = max(length, r.EndOffset)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].StartOffset != ranges[j].StartOffset {
+			return ranges[i].StartOffset < ranges[j].StartOffset
+		}
+		return ranges[i].EndOffset > ranges[j].EndOffset
+	})
+
+	used := make([]bool, length)
+	for _, r := range ranges {
+		for i := max(r.StartOffset, 0); i < r.EndOffset; i++ {
+			used[i] = r.Count > 0
+		}
+	}
+	return used
+}
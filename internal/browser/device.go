@@ -0,0 +1,89 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+)
+
+// Device is an emulated device: its viewport in CSS pixels, device scale factor, whether it is a mobile
+// device with a touch screen, and the user agent it sends. An empty UserAgent keeps the browser's own.
+type Device struct {
+	Name        string  `json:"name"`
+	Width       int64   `json:"width"`
+	Height      int64   `json:"height"`
+	ScaleFactor float64 `json:"scale_factor"`
+	Mobile      bool    `json:"mobile"`
+	Touch       bool    `json:"touch"`
+	UserAgent   string  `json:"user_agent,omitempty"`
+}
+
+// Devices are the device presets selectable by name.
+var Devices = map[string]Device{
+	"iphone-14": {
+		Name: "iphone-14", Width: 390, Height: 844, ScaleFactor: 3, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	},
+	"pixel-7": {
+		Name: "pixel-7", Width: 412, Height: 915, ScaleFactor: 2.625, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36",
+	},
+	"ipad": {
+		Name: "ipad", Width: 810, Height: 1080, ScaleFactor: 2, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPad; CPU OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	},
+	"desktop-1080p": {Name: "desktop-1080p", Width: 1920, Height: 1080, ScaleFactor: 1},
+}
+
+// LookupDevice returns the device preset with the given name, ignoring case.
+func LookupDevice(name string) (Device, error) {
+	device, ok := Devices[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(Devices))
+		for n := range Devices {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Device{}, fmt.Errorf("unknown device %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return device, nil
+}
+
+// Emulate makes Run load the target as the given device.
+func (b *Browser) Emulate(device Device) {
+	b.device = &device
+}
+
+// throttleCPU applies the CPU throttling rate to the page.
+func (b *Browser) throttleCPU(ctx context.Context) error {
+	if err := emulation.SetCPUThrottlingRate(b.cpuThrottle).Do(ctx); err != nil {
+		return fmt.Errorf("failed to throttle cpu: %v", err)
+	}
+	return nil
+}
+
+// emulateDevice overrides the viewport and touch support of the page with the device's.
+func (b *Browser) emulateDevice(ctx context.Context) error {
+	d := b.device
+	if err := emulation.SetDeviceMetricsOverride(d.Width, d.Height, d.ScaleFactor, d.Mobile).Do(ctx); err != nil {
+		return fmt.Errorf("failed to override device metrics: %v", err)
+	}
+	touch := emulation.SetTouchEmulationEnabled(d.Touch)
+	if d.Touch {
+		touch = touch.WithMaxTouchPoints(5)
+	}
+	if err := touch.Do(ctx); err != nil {
+		return fmt.Errorf("failed to emulate touch: %v", err)
+	}
+	// the user agent of the device is sent by overrideIdentity, unless another one is set explicitly
+	return nil
+}
+
+// ThrottleCPU makes Run slow down the CPU of the page by rate, e.g. 4 for a low-end mobile device four times
+// slower than the machine running the test. A rate of 1 or less leaves the CPU as is.
+func (b *Browser) ThrottleCPU(rate float64) {
+	b.cpuThrottle = rate
+}
@@ -0,0 +1,85 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// execPathEnv is the environment variable overriding the browser discovery with an explicit executable path.
+const execPathEnv = "CHROME_PATH"
+
+// downloadGuidance is appended to discovery errors to help users install a supported browser.
+const downloadGuidance = "install Google Chrome (https://www.google.com/chrome/), Chromium (https://www.chromium.org/getting-involved/download-chromium/) " +
+	"or Microsoft Edge (https://www.microsoft.com/edge), or set " + execPathEnv + " to the path of a Chromium based browser"
+
+// execCandidates returns the executable names and paths of Chromium based browsers commonly found on the
+// current platform, in order of preference. Paths are built from environment variables where possible so
+// they also resolve on ARM64 Windows, where Program Files and the per-user folders can live elsewhere.
+func execCandidates() []string {
+	switch runtime.GOOS {
+	case "windows":
+		var candidates []string
+		for _, dir := range []string{os.Getenv("ProgramFiles"), os.Getenv("ProgramFiles(x86)"), os.Getenv("ProgramW6432"), os.Getenv("LOCALAPPDATA")} {
+			if dir == "" {
+				continue
+			}
+			candidates = append(candidates,
+				filepath.Join(dir, "Google", "Chrome", "Application", "chrome.exe"),
+				filepath.Join(dir, "Chromium", "Application", "chrome.exe"),
+				filepath.Join(dir, "Microsoft", "Edge", "Application", "msedge.exe"),
+			)
+		}
+		return append(candidates, "chrome.exe", "msedge.exe")
+
+	case "darwin":
+		var candidates []string
+		apps := []string{
+			"Google Chrome.app/Contents/MacOS/Google Chrome",
+			"Chromium.app/Contents/MacOS/Chromium",
+			"Google Chrome Canary.app/Contents/MacOS/Google Chrome Canary",
+			"Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+		}
+		home, _ := os.UserHomeDir()
+		for _, dir := range []string{"/Applications", filepath.Join(home, "Applications")} {
+			for _, app := range apps {
+				candidates = append(candidates, filepath.Join(dir, app))
+			}
+		}
+		return candidates
+
+	default:
+		// the same names cover amd64 and arm64 distributions, which mostly ship chromium on arm64
+		return []string{
+			"headless_shell", "headless-shell",
+			"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "microsoft-edge", "microsoft-edge-stable", "chrome",
+			"/opt/google/chrome/chrome", "/usr/lib/chromium/chromium", "/usr/lib/chromium-browser/chromium-browser",
+			"/snap/bin/chromium", "/opt/microsoft/msedge/msedge",
+		}
+	}
+}
+
+// FindExecPath returns the path of the browser executable to run. The path set in CHROME_PATH takes
+// precedence over the platform's common install locations. The returned error explains how to install
+// a supported browser when none is found.
+func FindExecPath() (string, error) {
+	if path, ok := os.LookupEnv(execPathEnv); ok && path != "" {
+		found, err := exec.LookPath(path)
+		if err != nil {
+			return "", fmt.Errorf("browser set in %s was not found at %q: %v", execPathEnv, path, err)
+		}
+		return found, nil
+	}
+
+	candidates := execCandidates()
+	for _, candidate := range candidates {
+		if found, err := exec.LookPath(candidate); err == nil {
+			return found, nil
+		}
+	}
+	return "", fmt.Errorf("no Chromium based browser found on %s/%s (looked for %s): %s",
+		runtime.GOOS, runtime.GOARCH, strings.Join(candidates, ", "), downloadGuidance)
+}
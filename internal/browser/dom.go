@@ -0,0 +1,51 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/domsnapshot"
+	"github.com/chromedp/chromedp"
+)
+
+// snapshotStyles are the computed styles kept for every node of a DOM snapshot, enough to tell what was
+// visible and how it was laid out.
+var snapshotStyles = []string{"display", "visibility", "opacity", "position", "z-index", "color", "background-color", "font-size"}
+
+// RenderedHTML returns the HTML of the document as rendered by the page, after its scripts ran, with
+// its doctype.
+func (b *Browser) RenderedHTML() (string, error) {
+	var html string
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		root, err := dom.GetDocument().Do(ctx)
+		if err != nil {
+			return err
+		}
+		html, err = dom.GetOuterHTML().WithNodeID(root.NodeID).Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return "", fmt.Errorf("failed to get rendered html: %v", err)
+	}
+	return html, nil
+}
+
+// DOMSnapshot returns the DOM snapshot of the page as JSON: the documents of its frames with their nodes,
+// layout boxes and the computed styles in snapshotStyles, along with the strings table they index.
+func (b *Browser) DOMSnapshot() ([]byte, error) {
+	var snapshot struct {
+		Documents []*domsnapshot.DocumentSnapshot `json:"documents"`
+		Strings   []string                        `json:"strings"`
+	}
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		snapshot.Documents, snapshot.Strings, err = domsnapshot.CaptureSnapshot(snapshotStyles).WithIncludeDOMRects(true).Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture dom snapshot: %v", err)
+	}
+	return json.Marshal(snapshot)
+}
@@ -0,0 +1,209 @@
+package browser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+)
+
+// downloadWait bounds the wait for the downloads still in progress once the page and journey ran.
+const downloadWait = 30 * time.Second
+
+// Download states, as reported by the browser.
+const (
+	DownloadInProgress = "inProgress"
+	DownloadCompleted  = "completed"
+	DownloadCanceled   = "canceled"
+)
+
+// Download is a file the page downloaded, e.g. from a link clicked by a journey or a navigation to a file. Path
+// is where the file was written, once completed, and Bytes and SHA256 its size and hash.
+type Download struct {
+	GUID     string `json:"guid"`
+	URL      string `json:"url"`
+	FrameID  string `json:"frame_id"`
+	Filename string `json:"filename"`
+	State    string `json:"state"`
+	Path     string `json:"path,omitempty"`
+	Bytes    int64  `json:"bytes"`
+	SHA256   string `json:"sha256,omitempty"`
+}
+
+// downloads keeps the downloads of the page, written to dir.
+type downloads struct {
+	dir string
+	// hashing counts the completed downloads still being hashed
+	hashing sync.WaitGroup
+
+	mu     sync.Mutex
+	byGUID map[string]*Download
+	order  []string
+}
+
+// CaptureDownloads makes Run let the page download files to dir, instead of the browser discarding them. The
+// downloads are hashed as they complete and returned by Downloads.
+func (b *Browser) CaptureDownloads(dir string) {
+	b.downloads = &downloads{dir: dir, byGUID: map[string]*Download{}}
+}
+
+// startDownloads allows the downloads of the browser to the download directory, listening to their progress.
+func (b *Browser) startDownloads(ctx context.Context, logger *slog.Logger) error {
+	d := b.downloads
+	dir, err := filepath.Abs(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve download directory: %v", err)
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create download directory: %v", err)
+	}
+	d.dir = dir
+
+	chromedp.ListenTarget(b.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *cdpbrowser.EventDownloadWillBegin:
+			d.begin(ev)
+		case *cdpbrowser.EventDownloadProgress:
+			d.progress(logger, ev)
+		}
+	})
+	// the files are named by the GUID of their download, renamed after their suggested name once completed
+	return cdpbrowser.SetDownloadBehavior(cdpbrowser.SetDownloadBehaviorBehaviorAllowAndName).
+		WithDownloadPath(dir).WithEventsEnabled(true).Do(ctx)
+}
+
+// begin records a download the page started.
+func (d *downloads) begin(ev *cdpbrowser.EventDownloadWillBegin) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.byGUID[ev.GUID]; ok {
+		return
+	}
+	d.byGUID[ev.GUID] = &Download{GUID: ev.GUID, URL: ev.URL, FrameID: string(ev.FrameID), Filename: ev.SuggestedFilename,
+		State: DownloadInProgress}
+	d.order = append(d.order, ev.GUID)
+}
+
+// progress updates the state of a download, hashing its file once completed. The hash is computed off the event
+// loop of the browser, so a large file does not hold the other events back.
+func (d *downloads) progress(logger *slog.Logger, ev *cdpbrowser.EventDownloadProgress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dl, ok := d.byGUID[ev.GUID]
+	if !ok || dl.State != DownloadInProgress {
+		return
+	}
+	dl.Bytes = int64(ev.ReceivedBytes)
+	switch ev.State {
+	case cdpbrowser.DownloadProgressStateCompleted:
+		dl.State = DownloadCompleted
+		d.hashing.Add(1)
+		go d.finish(logger, ev.GUID)
+	case cdpbrowser.DownloadProgressStateCanceled:
+		dl.State = DownloadCanceled
+	}
+}
+
+// finish renames the file of a completed download after its suggested name and hashes it.
+func (d *downloads) finish(logger *slog.Logger, guid string) {
+	defer d.hashing.Done()
+	d.mu.Lock()
+	name := d.byGUID[guid].Filename
+	d.mu.Unlock()
+
+	path := filepath.Join(d.dir, guid)
+	// the suggested name comes from the page, only its base is kept so it cannot point out of the directory
+	if base := filepath.Base(name); base != "." && base != string(filepath.Separator) && !strings.HasPrefix(base, "..") {
+		renamed := filepath.Join(d.dir, guid+"-"+base)
+		if err := os.Rename(path, renamed); err == nil {
+			path = renamed
+		}
+	}
+	size, hash, err := hashFile(path)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dl := d.byGUID[guid]
+	dl.Path = path
+	if err != nil {
+		logger.Warn("failed to hash download", "path", path, "error", err)
+		return
+	}
+	dl.Bytes, dl.SHA256 = size, hash
+}
+
+// hashFile returns the size and the hex SHA-256 hash of the file at path.
+func hashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Downloads waits up to 30 seconds for the downloads in progress to complete, then returns the downloads of
+// the page in the order they began, those still in progress included. The download directory is removed when
+// the page downloaded nothing.
+func (b *Browser) Downloads() []Download {
+	d := b.downloads
+	if d == nil {
+		return nil
+	}
+
+	deadline := time.Now().Add(downloadWait)
+	for time.Now().Before(deadline) && b.ctx.Err() == nil && d.inProgress() {
+		time.Sleep(100 * time.Millisecond)
+	}
+	d.hashing.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.order) == 0 {
+		os.Remove(d.dir)
+		return nil
+	}
+	list := make([]Download, 0, len(d.order))
+	for _, guid := range d.order {
+		list = append(list, *d.byGUID[guid])
+	}
+	return list
+}
+
+// inProgress tells whether a download has not completed nor been canceled yet.
+func (d *downloads) inProgress() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, dl := range d.byGUID {
+		if dl.State == DownloadInProgress {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadStarted tells whether the page started a download, e.g. when the navigation to the target was
+// aborted because the target is a file.
+func (b *Browser) downloadStarted() bool {
+	if b.downloads == nil {
+		return false
+	}
+	b.downloads.mu.Lock()
+	defer b.downloads.mu.Unlock()
+	return len(b.downloads.order) > 0
+}
@@ -2,15 +2,112 @@ package browser
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
 	"sync"
 
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
 )
 
-type Requests []Request
-type Responses struct {
-	mu          sync.Mutex
-	ResponseMap map[network.RequestID]Response
+// EventStore holds the requests, responses and failures captured by a run. The event listeners add to it
+// concurrently as the events are received, so it is only accessed through its methods, which return copies.
+type EventStore struct {
+	mu        sync.Mutex
+	requests  []Request
+	responses map[network.RequestID]Response
+	// order holds the request IDs of the responses in the order they were received
+	order    []network.RequestID
+	failures []Failure
+}
+
+// NewEventStore creates an empty EventStore.
+func NewEventStore() *EventStore {
+	return &EventStore{responses: map[network.RequestID]Response{}}
+}
+
+// AddRequest adds a request.
+func (s *EventStore) AddRequest(request Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, request)
+}
+
+// AddResponse adds a response, replacing the response of the same request ID, if any.
+func (s *EventStore) AddResponse(response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.responses[response.RequestID]; !ok {
+		s.order = append(s.order, response.RequestID)
+	}
+	s.responses[response.RequestID] = response
+}
+
+// UpdateResponse updates the response of the request ID with update, reporting whether there was one.
+func (s *EventStore) UpdateResponse(requestID network.RequestID, update func(*Response)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp, ok := s.responses[requestID]
+	if !ok {
+		return false
+	}
+	update(&resp)
+	s.responses[requestID] = resp
+	return true
+}
+
+// AddFailure adds a request that failed to load.
+func (s *EventStore) AddFailure(failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure)
+}
+
+// Requests returns the requests captured so far, in the order they were added.
+func (s *EventStore) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Response returns the response of the request ID, if it was captured.
+func (s *EventStore) Response(requestID network.RequestID) (Response, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp, ok := s.responses[requestID]
+	return resp, ok
+}
+
+// Request returns the last request of the request ID, the final one of a redirect chain, if it was captured.
+func (s *EventStore) Request(requestID network.RequestID) (Request, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.requests) - 1; i >= 0; i-- {
+		if s.requests[i].RequestID == requestID {
+			return s.requests[i], true
+		}
+	}
+	return Request{}, false
+}
+
+// Responses returns the responses captured so far, in the order they were received.
+func (s *EventStore) Responses() []Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	responses := make([]Response, 0, len(s.order))
+	for _, requestID := range s.order {
+		responses = append(responses, s.responses[requestID])
+	}
+	return responses
+}
+
+// Failures returns the requests that failed to load so far.
+func (s *EventStore) Failures() []Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Failure(nil), s.failures...)
 }
 
 type Request struct {
@@ -19,35 +116,83 @@ type Request struct {
 	URL       string
 	Content   interface{}
 	Body      []byte
+	// Source is the type of the worker that sent the request, e.g. service_worker, or empty for the page
+	Source string
+	// FrameID and FrameURL are the frame that sent the request and the URL of its document
+	FrameID  cdp.FrameID
+	FrameURL string
 }
 
 type Response struct {
-	RequestID network.RequestID
-	Type      string
-	URL       string
-	Content   interface{}
-	Body      []byte
+	RequestID    network.RequestID
+	Type         string
+	URL          string
+	Content      interface{}
+	Body         []byte
+	Status       int64
+	Headers      network.Headers
+	MimeType     string
+	Range        string
+	ContentRange string
+	Chunked      bool
+	Parts        []Part
+	Timing       Timing
+	// Source is the type of the worker that received the response, or empty for the page
+	Source string
+	// FrameID and FrameURL are the frame that received the response and the URL of its document
+	FrameID  cdp.FrameID
+	FrameURL string
+	// BodySize is the size of the body, which is kept out of Body when it exceeds the browser's body limit,
+	// in which case BodyPath and BodyHash locate it in the body store, if any
+	BodySize int
+	BodyPath string
+	BodyHash string
+	// BodyStatus is the status of the fetch of the body, e.g. fetched or evicted, empty until it was attempted
+	BodyStatus  string
+	contentType string
+	// finished is set once the loading finished event of the response is received
+	finished bool
 }
 
-func (r *Responses) Add(response Response) {
-	if r.ResponseMap == nil {
-		r.ResponseMap = make(map[network.RequestID]Response)
+// SetBody sets the request body from the post data entries of the request event, decoding each
+// entry so binary and multipart bodies are kept intact. When the entries are missing or shorter than
+// the declared Content-Length, the post data is fetched from the browser instead, so it must be called
+// before the browser context is canceled.
+func (r *Request) SetBody(ctx context.Context) error {
+	if r.Type != "request" {
+		return nil
+	}
+	req := r.Content.(*network.EventRequestWillBeSent).Request
+	if !req.HasPostData {
+		return nil
 	}
-	r.ResponseMap[response.RequestID] = response
-}
-
-func (rqs *Requests) Add(request Request) {
-	*rqs = append(*rqs, request)
-}
 
-func (r *Request) SetBody(ctx context.Context) {
-	if r.Type != "request" {
-		return
+	var body []byte
+	for _, entry := range req.PostDataEntries {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Bytes)
+		if err != nil {
+			decoded = []byte(entry.Bytes)
+		}
+		body = append(body, decoded...)
 	}
-	postDataEntries := r.Content.(*network.EventRequestWillBeSent).Request.PostDataEntries
-	var postData string
-	for _, entry := range postDataEntries {
-		postData += entry.Bytes
+
+	contentLength, _ := strconv.Atoi(headerValue(req.Headers, "Content-Length"))
+	if len(body) == 0 || len(body) < contentLength {
+		var postData string
+		err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			postData, err = network.GetRequestPostData(r.RequestID).Do(ctx)
+			return err
+		}))
+		if err != nil {
+			r.Body = body
+			return fmt.Errorf("failed to get request post data: %v", err)
+		}
+		if len(postData) > len(body) {
+			body = []byte(postData)
+		}
 	}
-	r.Body = []byte(postData)
+
+	r.Body = body
+	return nil
 }
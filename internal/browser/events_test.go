@@ -0,0 +1,95 @@
+package browser
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// TestEventStoreConcurrentIngestion adds requests, responses and failures from many goroutines while others read
+// them, the way the event listeners and the finishers use the store. Run it with -race.
+func TestEventStoreConcurrentIngestion(t *testing.T) {
+	const writers, events = 8, 200
+
+	store := NewEventStore()
+	done := make(chan struct{})
+
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, resp := range store.Responses() {
+					_ = resp.Status
+				}
+				_ = len(store.Requests())
+				_ = len(store.Failures())
+				store.Response("0-0")
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < events; i++ {
+				requestID := network.RequestID(fmt.Sprintf("%d-%d", w, i))
+				store.AddRequest(Request{RequestID: requestID, Type: "request"})
+				store.AddResponse(Response{RequestID: requestID, Type: "response", Status: 200})
+				if !store.UpdateResponse(requestID, func(resp *Response) {
+					resp.BodySize, resp.finished = i, true
+				}) {
+					t.Errorf("response %s was not found to update", requestID)
+				}
+				if i%10 == 0 {
+					store.AddFailure(Failure{RequestID: requestID, ErrorText: "net::ERR_FAILED"})
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	if got := len(store.Requests()); got != writers*events {
+		t.Errorf("got %d requests, want %d", got, writers*events)
+	}
+	responses := store.Responses()
+	if len(responses) != writers*events {
+		t.Errorf("got %d responses, want %d", len(responses), writers*events)
+	}
+	for _, resp := range responses {
+		if !resp.finished {
+			t.Errorf("response %s was not updated", resp.RequestID)
+		}
+	}
+	if got := len(store.Failures()); got != writers*events/10 {
+		t.Errorf("got %d failures, want %d", got, writers*events/10)
+	}
+}
+
+// TestEventStoreResponseReplaced checks that adding the response of a request ID twice keeps its place in the order.
+func TestEventStoreResponseReplaced(t *testing.T) {
+	store := NewEventStore()
+	store.AddResponse(Response{RequestID: "1", Status: 301})
+	store.AddResponse(Response{RequestID: "2", Status: 200})
+	store.AddResponse(Response{RequestID: "1", Status: 200})
+
+	responses := store.Responses()
+	if len(responses) != 2 || responses[0].RequestID != "1" || responses[0].Status != 200 || responses[1].RequestID != "2" {
+		t.Errorf("got responses %+v, want 1 replaced before 2", responses)
+	}
+	if store.UpdateResponse("3", func(*Response) {}) {
+		t.Error("updated the response of an unknown request ID")
+	}
+}
@@ -0,0 +1,57 @@
+package browser
+
+import "strings"
+
+// Filter selects the events captured by resource type (e.g. "Image", "Font", "XHR") and by response
+// content type (e.g. "image/*", "application/json"). Empty include lists allow everything, and
+// exclusions take precedence over inclusions. Types are matched case-insensitively.
+type Filter struct {
+	IncludeTypes []string `json:"include_types,omitempty"`
+	ExcludeTypes []string `json:"exclude_types,omitempty"`
+	IncludeMIME  []string `json:"include_mime,omitempty"`
+	ExcludeMIME  []string `json:"exclude_mime,omitempty"`
+}
+
+// Empty reports whether the filter allows every event.
+func (f Filter) Empty() bool {
+	return len(f.IncludeTypes) == 0 && len(f.ExcludeTypes) == 0 && len(f.IncludeMIME) == 0 && len(f.ExcludeMIME) == 0
+}
+
+// AllowsType reports whether events of the resource type are captured.
+func (f Filter) AllowsType(resourceType string) bool {
+	return allows(f.IncludeTypes, f.ExcludeTypes, resourceType, strings.EqualFold)
+}
+
+// AllowsMIME reports whether responses of the content type, parameters included or not, are captured.
+func (f Filter) AllowsMIME(mimeType string) bool {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	return allows(f.IncludeMIME, f.ExcludeMIME, strings.TrimSpace(mimeType), mimeMatches)
+}
+
+// allows reports whether value is in include, or include is empty, and is not in exclude.
+func allows(include, exclude []string, value string, match func(pattern, value string) bool) bool {
+	for _, pattern := range exclude {
+		if match(pattern, value) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// mimeMatches reports whether the content type matches the pattern, which is either a full content type
+// or a type followed by "/*", e.g. "image/*".
+func mimeMatches(pattern, mimeType string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		t, _, _ := strings.Cut(mimeType, "/")
+		return strings.EqualFold(prefix, t)
+	}
+	return strings.EqualFold(pattern, mimeType)
+}
@@ -0,0 +1,107 @@
+package browser
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// The bounds of the pipeline fetching the response bodies: finisherWorkers fetch them at a time, and up to
+// finisherQueue responses wait for a worker, the bodies of the responses finished beyond that being dropped
+// instead of blocking the event listeners.
+const (
+	finisherWorkers = 4
+	finisherQueue   = 1024
+)
+
+// FinisherStats counts the responses whose body was not fetched: Dropped when the queue of the pipeline was full
+// or the browser stopped before a worker fetched it, and Late when it finished loading after the pipeline drained.
+type FinisherStats struct {
+	Dropped int
+	Late    int
+}
+
+// finishers is the pipeline fetching the bodies of the responses as they finish loading, tied to the context
+// of the browser: its workers stop once the browser does.
+type finishers struct {
+	events *EventStore
+	queue  chan Response
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	drained bool
+	stats   FinisherStats
+}
+
+// startFinishers starts the workers of the pipeline fetching the bodies of the responses of the event store.
+func (b *Browser) startFinishers(logger *slog.Logger, events *EventStore) {
+	f := &finishers{events: events, queue: make(chan Response, finisherQueue)}
+	for i := 0; i < finisherWorkers; i++ {
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			for {
+				select {
+				case resp, ok := <-f.queue:
+					if !ok {
+						return
+					}
+					b.GetResponseBody(logger, &resp, events)
+				case <-b.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	b.finishers = f
+}
+
+// enqueue queues the response for its body to be fetched, without blocking.
+func (f *finishers) enqueue(resp Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.drained {
+		f.stats.Late++
+		f.setStatus(resp.RequestID, BodyLate)
+		return
+	}
+	select {
+	case f.queue <- resp:
+	default:
+		f.stats.Dropped++
+		f.setStatus(resp.RequestID, BodyDropped)
+	}
+}
+
+// setStatus records the status of the body of the response to the request.
+func (f *finishers) setStatus(requestID network.RequestID, status string) {
+	f.events.UpdateResponse(requestID, func(r *Response) { r.BodyStatus = status })
+}
+
+// DrainFinishers stops queuing the bodies of the responses that finish loading from now on, waits for the bodies
+// queued already to be fetched, and returns the counts of the bodies that were not. It must be called before the
+// context of the browser is canceled for the queued bodies to be fetched, and the bodies of the responses still
+// loading are counted as late once it returned.
+func (b *Browser) DrainFinishers() FinisherStats {
+	f := b.finishers
+	if f == nil {
+		return FinisherStats{}
+	}
+	f.mu.Lock()
+	if !f.drained {
+		f.drained = true
+		close(f.queue)
+	}
+	f.mu.Unlock()
+	f.wg.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// the workers stop with the browser, leaving the responses still queued
+	for resp := range f.queue {
+		f.stats.Dropped++
+		f.setStatus(resp.RequestID, BodyDropped)
+	}
+	return f.stats
+}
@@ -0,0 +1,78 @@
+package browser
+
+import (
+	"sort"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+)
+
+// Frame is a frame of the page: the main frame, without a parent, or an iframe embedded in its parent frame.
+// URL is the last document the frame navigated to.
+type Frame struct {
+	ID       cdp.FrameID `json:"id"`
+	ParentID cdp.FrameID `json:"parent_id,omitempty"`
+	URL      string      `json:"url"`
+}
+
+// Main tells whether the frame is the main frame of the page.
+func (f Frame) Main() bool {
+	return f.ParentID == ""
+}
+
+// trackFrame keeps the frame tree up to date with the frame events. Detached frames are kept, so the
+// requests they sent can still be attributed.
+func (b *Browser) trackFrame(ev interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.frames == nil {
+		b.frames = make(map[cdp.FrameID]Frame)
+	}
+	switch ev := ev.(type) {
+	case *page.EventFrameAttached:
+		frame := b.frames[ev.FrameID]
+		frame.ID, frame.ParentID = ev.FrameID, ev.ParentFrameID
+		b.frames[ev.FrameID] = frame
+	case *page.EventFrameNavigated:
+		frame := b.frames[ev.Frame.ID]
+		frame.ID, frame.URL = ev.Frame.ID, ev.Frame.URL
+		if ev.Frame.ParentID != "" {
+			frame.ParentID = ev.Frame.ParentID
+		}
+		b.frames[ev.Frame.ID] = frame
+	}
+}
+
+// frameURL returns the URL of the document of the frame sending a request: the URL of the request itself
+// when it loads the frame's document, otherwise the last document the frame navigated to, if any yet.
+func (b *Browser) frameURL(frameID cdp.FrameID, resourceType network.ResourceType, requestURL string) string {
+	if resourceType == network.ResourceTypeDocument {
+		return requestURL
+	}
+	return b.FrameURL(frameID)
+}
+
+// FrameURL returns the last document the frame navigated to, or an empty string for an unknown frame.
+func (b *Browser) FrameURL(frameID cdp.FrameID) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.frames[frameID].URL
+}
+
+// Frames returns the frames of the page seen during the run, the main frame first.
+func (b *Browser) Frames() []Frame {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	frames := make([]Frame, 0, len(b.frames))
+	for _, frame := range b.frames {
+		frames = append(frames, frame)
+	}
+	sort.Slice(frames, func(i, j int) bool {
+		if frames[i].Main() != frames[j].Main() {
+			return frames[i].Main()
+		}
+		return frames[i].ID < frames[j].ID
+	})
+	return frames
+}
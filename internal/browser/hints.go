@@ -0,0 +1,20 @@
+package browser
+
+import (
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+)
+
+// hintsScript collects the URLs referenced by dns-prefetch, preconnect and prefetch resource hints.
+const hintsScript = `Array.from(document.querySelectorAll('link[rel~="dns-prefetch"], link[rel~="preconnect"], link[rel~="prefetch"]')).map(l => l.href)`
+
+// ResourceHints returns the URLs referenced by the page's dns-prefetch, preconnect and prefetch hints.
+// These domains may never be requested during the run, so they are collected from the DOM.
+func (b *Browser) ResourceHints() ([]string, error) {
+	var hints []string
+	if err := chromedp.Run(b.ctx, chromedp.Evaluate(hintsScript, &hints)); err != nil {
+		return nil, fmt.Errorf("failed to evaluate resource hints script: %v", err)
+	}
+	return hints, nil
+}
@@ -0,0 +1,57 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/chromedp"
+)
+
+// intercept pauses the requests of the page to inject the faults, serve the mocked ones from their fixtures
+// and answer the authentication challenges with the basic credentials. The other requests are continued as is.
+func (b *Browser) intercept(ctx context.Context, logger *slog.Logger) error {
+	pass := func(ev *fetch.EventRequestPaused) {
+		b.pass(logger, ev)
+	}
+	chromedp.ListenTarget(b.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			// only the responses of the requests to corrupt are paused once received
+			if ev.ResponseStatusCode != 0 || ev.ResponseErrorReason != "" {
+				go b.corruptResponse(logger, ev)
+				return
+			}
+			if fault := b.faultFor(ev.Request.URL); fault != nil {
+				go b.injectFault(logger, fault, ev, pass)
+				return
+			}
+			go pass(ev)
+		case *fetch.EventAuthRequired:
+			go b.continueFetch(logger, fetch.ContinueWithAuth(ev.RequestID, b.authResponse(ev.AuthChallenge)))
+		}
+	})
+
+	if err := fetch.Enable().WithHandleAuthRequests(b.basic != nil).Do(ctx); err != nil {
+		return fmt.Errorf("failed to intercept requests: %v", err)
+	}
+	return nil
+}
+
+// pass serves the paused request from its mock, if any, or sends it.
+func (b *Browser) pass(logger *slog.Logger, ev *fetch.EventRequestPaused) {
+	if mock := b.mockFor(ev.Request.URL); mock != nil {
+		b.mocked.Add(1)
+		b.continueFetch(logger, mock.fulfill(ev.RequestID))
+		return
+	}
+	b.continueFetch(logger, fetch.ContinueRequest(ev.RequestID))
+}
+
+// continueFetch resumes a request paused by intercept.
+func (b *Browser) continueFetch(logger *slog.Logger, action chromedp.Action) {
+	if err := chromedp.Run(b.ctx, action); err != nil && b.ctx.Err() == nil {
+		logger.Warn("failed to continue paused request", "error", err)
+	}
+}
@@ -0,0 +1,80 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// limitsInterval is how often the resource usage of the browser is sampled.
+const limitsInterval = 500 * time.Millisecond
+
+// ErrLimitExceeded is returned when the browser was killed for exceeding its resource limits.
+var ErrLimitExceeded = errors.New("browser resource limit exceeded")
+
+// Limits are the resources the browser, along with its child processes, may use. Zero values are unlimited.
+type Limits struct {
+	MemoryBytes int64
+	CPUTime     time.Duration
+}
+
+// usage is the resource usage of a process tree.
+type usage struct {
+	memoryBytes int64
+	cpuTime     time.Duration
+}
+
+// SetLimits sets the resource limits enforced once the browser runs.
+func (b *Browser) SetLimits(limits Limits) {
+	b.limits = limits
+}
+
+// Err returns the error that stopped the browser, such as exceeding its resource limits, or nil.
+func (b *Browser) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// watchLimits samples the resource usage of the browser processes until the browser is cancelled, killing it
+// when it exceeds its limits so a runaway page fails the run instead of destabilizing the host.
+func (b *Browser) watchLimits() {
+	c := chromedp.FromContext(b.ctx)
+	if c == nil || c.Browser == nil || c.Browser.Process() == nil {
+		return
+	}
+	pid := c.Browser.Process().Pid
+
+	ticker := time.NewTicker(limitsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		u, err := processTreeUsage(pid)
+		if err != nil {
+			return
+		}
+
+		var exceeded error
+		switch {
+		case b.limits.MemoryBytes > 0 && u.memoryBytes > b.limits.MemoryBytes:
+			exceeded = fmt.Errorf("%w: memory %d bytes over %d", ErrLimitExceeded, u.memoryBytes, b.limits.MemoryBytes)
+		case b.limits.CPUTime > 0 && u.cpuTime > b.limits.CPUTime:
+			exceeded = fmt.Errorf("%w: cpu time %s over %s", ErrLimitExceeded, u.cpuTime, b.limits.CPUTime)
+		}
+		if exceeded != nil {
+			b.mu.Lock()
+			b.err = exceeded
+			b.mu.Unlock()
+			// cancelling the browser context kills the browser process and its children
+			b.cancel()
+			return
+		}
+	}
+}
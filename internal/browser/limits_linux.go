@@ -0,0 +1,69 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the USER_HZ the CPU times in /proc are expressed in, which is 100 on every supported architecture.
+const clockTicks = 100
+
+// processTreeUsage returns the memory (resident set size) and CPU time used by a process and its descendants,
+// read from /proc.
+func processTreeUsage(pid int) (usage, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return usage{}, fmt.Errorf("failed to list processes: %v", err)
+	}
+
+	type stat struct {
+		ppid          int
+		ticks, rssPgs int64
+	}
+	stats := map[int]stat{}
+	for _, e := range entries {
+		p, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile("/proc/" + e.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// the command name is in parentheses and may contain spaces, the other fields follow it
+		end := strings.LastIndexByte(string(data), ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(string(data[end+1:]))
+		if len(fields) < 22 {
+			continue
+		}
+		ppid, _ := strconv.Atoi(fields[1])
+		utime, _ := strconv.ParseInt(fields[11], 10, 64)
+		stime, _ := strconv.ParseInt(fields[12], 10, 64)
+		rss, _ := strconv.ParseInt(fields[21], 10, 64)
+		stats[p] = stat{ppid: ppid, ticks: utime + stime, rssPgs: rss}
+	}
+	if _, ok := stats[pid]; !ok {
+		return usage{}, fmt.Errorf("process %d not found", pid)
+	}
+
+	children := map[int][]int{}
+	for p, s := range stats {
+		children[s.ppid] = append(children[s.ppid], p)
+	}
+
+	var u usage
+	pageSize := int64(os.Getpagesize())
+	for queue := []int{pid}; len(queue) > 0; queue = queue[1:] {
+		s := stats[queue[0]]
+		u.memoryBytes += s.rssPgs * pageSize
+		u.cpuTime += time.Duration(s.ticks) * time.Second / clockTicks
+		queue = append(queue, children[queue[0]]...)
+	}
+	return u, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package browser
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// processTreeUsage is only implemented on Linux, resource limits are not enforced on other platforms.
+func processTreeUsage(pid int) (usage, error) {
+	return usage{}, fmt.Errorf("resource limits are not supported on %s", runtime.GOOS)
+}
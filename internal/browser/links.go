@@ -0,0 +1,28 @@
+package browser
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Links returns the absolute URLs of the links of the page, i.e. the href of its anchors and image map areas,
+// deduplicated and sorted.
+func (b *Browser) Links() ([]string, error) {
+	var hrefs []string
+	if err := chromedp.Run(b.ctx, chromedp.Evaluate(`Array.from(document.links, link => link.href)`, &hrefs)); err != nil {
+		return nil, fmt.Errorf("failed to collect links: %v", err)
+	}
+	seen := map[string]bool{}
+	links := make([]string, 0, len(hrefs))
+	for _, href := range hrefs {
+		if href == "" || seen[href] {
+			continue
+		}
+		seen[href] = true
+		links = append(links, href)
+	}
+	sort.Strings(links)
+	return links, nil
+}
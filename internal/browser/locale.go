@@ -0,0 +1,116 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/emulation"
+)
+
+// Identify makes Run send the user agent and prefer the locale, a BCP 47 language tag such as "fr-FR", in
+// the Accept-Language header, navigator.language and the Intl APIs. Empty values keep the browser's own,
+// and an explicit user agent replaces the one of the emulated device.
+func (b *Browser) Identify(userAgent, locale string) {
+	b.userAgent, b.locale = userAgent, locale
+}
+
+// acceptLanguage returns the Accept-Language header preferring the locale, falling back to its language,
+// e.g. "fr-FR,fr;q=0.9".
+func acceptLanguage(locale string) string {
+	if language, _, ok := strings.Cut(locale, "-"); ok {
+		return locale + "," + language + ";q=0.9"
+	}
+	return locale
+}
+
+// overrideIdentity overrides the user agent and locale of the page. The user agent is the explicit one,
+// otherwise the one of the emulated device, otherwise the browser's own, which must be sent along with the
+// Accept-Language header.
+func (b *Browser) overrideIdentity(ctx context.Context) error {
+	userAgent := b.userAgent
+	if userAgent == "" && b.device != nil {
+		userAgent = b.device.UserAgent
+	}
+	if userAgent == "" {
+		var err error
+		if _, _, _, userAgent, _, err = cdpbrowser.GetVersion().Do(ctx); err != nil {
+			return fmt.Errorf("failed to get browser user agent: %v", err)
+		}
+	}
+
+	override := emulation.SetUserAgentOverride(userAgent)
+	if b.locale != "" {
+		override = override.WithAcceptLanguage(acceptLanguage(b.locale))
+	}
+	if err := override.Do(ctx); err != nil {
+		return fmt.Errorf("failed to override user agent: %v", err)
+	}
+
+	if b.locale != "" {
+		// the Intl APIs take ICU locales, e.g. "fr_FR"
+		if err := emulation.SetLocaleOverride().WithLocale(strings.ReplaceAll(b.locale, "-", "_")).Do(ctx); err != nil {
+			return fmt.Errorf("failed to override locale: %v", err)
+		}
+	}
+	return nil
+}
+
+// Geolocation is an emulated position, in degrees, with its accuracy in meters.
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy,omitempty"`
+}
+
+// ParseGeolocation parses a position given as "latitude,longitude" or "latitude,longitude,accuracy".
+// The accuracy defaults to 100 meters.
+func ParseGeolocation(s string) (Geolocation, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Geolocation{}, fmt.Errorf("invalid geolocation %q, expected latitude,longitude[,accuracy]", s)
+	}
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return Geolocation{}, fmt.Errorf("invalid geolocation %q: %v", s, err)
+		}
+		values[i] = value
+	}
+	geo := Geolocation{Latitude: values[0], Longitude: values[1], Accuracy: 100}
+	if len(values) == 3 {
+		geo.Accuracy = values[2]
+	}
+	if geo.Latitude < -90 || geo.Latitude > 90 || geo.Longitude < -180 || geo.Longitude > 180 || geo.Accuracy < 0 {
+		return Geolocation{}, fmt.Errorf("invalid geolocation %q: out of range", s)
+	}
+	return geo, nil
+}
+
+// Locate makes Run place the browser at the position, granting the pages the geolocation permission, and in
+// the timezone, an IANA name such as "Europe/Paris". A nil position or an empty timezone keeps the machine's.
+func (b *Browser) Locate(geo *Geolocation, timezone string) {
+	b.geolocation, b.timezone = geo, timezone
+}
+
+// overrideLocation overrides the position and timezone of the page.
+func (b *Browser) overrideLocation(ctx context.Context) error {
+	if geo := b.geolocation; geo != nil {
+		if err := cdpbrowser.GrantPermissions([]cdpbrowser.PermissionType{cdpbrowser.PermissionTypeGeolocation}).Do(ctx); err != nil {
+			return fmt.Errorf("failed to grant geolocation permission: %v", err)
+		}
+		err := emulation.SetGeolocationOverride().WithLatitude(geo.Latitude).WithLongitude(geo.Longitude).WithAccuracy(geo.Accuracy).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to override geolocation: %v", err)
+		}
+	}
+	if b.timezone != "" {
+		if err := emulation.SetTimezoneOverride(b.timezone).Do(ctx); err != nil {
+			return fmt.Errorf("failed to override timezone: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package browser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"web-tester/internal/scope"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/chromedp"
+)
+
+// Mock serves the requests to the URLs matching its pattern from a fixture instead of the network, e.g. to
+// test a page whose third parties are flaky or unavailable. Patterns are scope rules: globs, or regular
+// expressions prefixed with "re:".
+type Mock struct {
+	Pattern string
+	Status  int
+	Headers map[string]string
+	Body    []byte
+	rules   scope.Rules
+}
+
+// NewMock compiles the pattern of a mock. The status defaults to 200.
+func NewMock(pattern string, status int, headers map[string]string, body []byte) (Mock, error) {
+	rules, err := scope.Parse([]string{pattern}, nil)
+	if err != nil {
+		return Mock{}, fmt.Errorf("invalid mock pattern: %v", err)
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return Mock{Pattern: pattern, Status: status, Headers: headers, Body: body, rules: rules}, nil
+}
+
+// Mock makes Run serve the requests matching the mocks from their fixtures, the first matching mock
+// winning. The mocked responses are captured like the others.
+func (b *Browser) Mock(mocks []Mock) {
+	b.mocks = mocks
+}
+
+// Mocked returns the number of requests served from fixtures so far.
+func (b *Browser) Mocked() int {
+	return int(b.mocked.Load())
+}
+
+// mockFor returns the first mock matching the URL, or nil.
+func (b *Browser) mockFor(url string) *Mock {
+	for i := range b.mocks {
+		if b.mocks[i].rules.InScope(url) {
+			return &b.mocks[i]
+		}
+	}
+	return nil
+}
+
+// fulfill returns the action responding to the paused request with the fixture.
+func (m *Mock) fulfill(requestID fetch.RequestID) chromedp.Action {
+	headers := make([]*fetch.HeaderEntry, 0, len(m.Headers))
+	for name, value := range m.Headers {
+		headers = append(headers, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	return fetch.FulfillRequest(requestID, int64(m.Status)).
+		WithResponseHeaders(headers).
+		WithBody(base64.StdEncoding.EncodeToString(m.Body))
+}
@@ -0,0 +1,126 @@
+package browser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/textproto"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// Part describes a single part of a multipart or partial content response.
+// Start and End are byte offsets of the part's body within the captured response body.
+type Part struct {
+	ContentType  string `json:"contentType,omitempty"`
+	ContentRange string `json:"contentRange,omitempty"`
+	Start        int    `json:"start"`
+	End          int    `json:"end"`
+}
+
+// headerValue returns the value of the given header, matching the name case-insensitively.
+func headerValue(headers network.Headers, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			if s, ok := v.(string); ok {
+				return s
+			}
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// Header returns the value of the named response header, matching the name case-insensitively.
+func (r *Response) Header(name string) string {
+	return headerValue(r.Headers, name)
+}
+
+// Header returns the value of the named request header, matching the name case-insensitively, empty when the
+// request has no request event.
+func (r *Request) Header(name string) string {
+	ev, ok := r.Content.(*network.EventRequestWillBeSent)
+	if !ok {
+		return ""
+	}
+	return headerValue(ev.Request.Headers, name)
+}
+
+// setTransferInfo records the status, range and transfer encoding details of a response
+// so partial content and chunked transfers can be told apart from regular responses.
+func (r *Response) setTransferInfo(resp *network.Response) {
+	r.Status = resp.Status
+	r.Headers = resp.Headers
+	r.MimeType = resp.MimeType
+	r.ContentRange = headerValue(resp.Headers, "Content-Range")
+	r.Range = headerValue(resp.RequestHeaders, "Range")
+	r.Chunked = strings.Contains(strings.ToLower(headerValue(resp.Headers, "Transfer-Encoding")), "chunked")
+	r.contentType = headerValue(resp.Headers, "Content-Type")
+}
+
+// setParts splits a multipart response body into its parts, recording the boundaries of each one.
+// Single range (206) responses are recorded as one part covering the whole body.
+func (r *Response) setParts() {
+	r.Parts = nil
+
+	mediaType, params, err := mime.ParseMediaType(r.contentType)
+	if err == nil && strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		r.Parts = splitParts(r.Body, params["boundary"])
+		return
+	}
+
+	if r.ContentRange != "" {
+		r.Parts = []Part{{ContentType: r.contentType, ContentRange: r.ContentRange, Start: 0, End: len(r.Body)}}
+	}
+}
+
+// splitParts walks a multipart body delimited by boundary and returns the parts found.
+// Parts that are truncated or have malformed headers end the walk.
+func splitParts(body []byte, boundary string) []Part {
+	delim := []byte("--" + boundary)
+	var parts []Part
+
+	idx := bytes.Index(body, delim)
+	for idx >= 0 {
+		start := idx + len(delim)
+		if bytes.HasPrefix(body[start:], []byte("--")) {
+			break
+		}
+
+		next := bytes.Index(body[start:], delim)
+		if next < 0 {
+			break
+		}
+		end := start + next
+
+		section := body[start:end]
+		headerEnd := bytes.Index(section, []byte("\r\n\r\n"))
+		if headerEnd < 0 {
+			break
+		}
+
+		headerBlock := io.MultiReader(bytes.NewReader(bytes.TrimLeft(section[:headerEnd], "\r\n")), strings.NewReader("\r\n\r\n"))
+		header, err := textproto.NewReader(bufio.NewReader(headerBlock)).ReadMIMEHeader()
+		if err != nil {
+			break
+		}
+
+		bodyStart, bodyEnd := start+headerEnd+4, end
+		if bytes.HasSuffix(body[bodyStart:bodyEnd], []byte("\r\n")) {
+			bodyEnd -= 2
+		}
+		parts = append(parts, Part{
+			ContentType:  header.Get("Content-Type"),
+			ContentRange: header.Get("Content-Range"),
+			Start:        bodyStart,
+			End:          bodyEnd,
+		})
+
+		idx = end
+	}
+
+	return parts
+}
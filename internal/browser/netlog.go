@@ -0,0 +1,29 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/chromedp/chromedp"
+)
+
+// NetLogCaptureModes are the capture modes of the NetLog: Default leaves out cookies, credentials and bytes,
+// IncludeSensitive adds cookies and credentials and Everything adds the bytes sent and received.
+var NetLogCaptureModes = map[string]bool{"Default": true, "IncludeSensitive": true, "Everything": true}
+
+// NetLog returns the launch option making the browser write its network log to path, in the JSON format
+// loadable in the NetLog viewer. It gives socket, DNS, proxy and QUIC events the DevTools protocol does not
+// report. The log is complete once the browser is cancelled.
+func NetLog(path, captureMode string) (chromedp.ExecAllocatorOption, error) {
+	if !NetLogCaptureModes[captureMode] {
+		return nil, fmt.Errorf("unknown netlog capture mode %q", captureMode)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create netlog directory: %v", err)
+	}
+	return func(a *chromedp.ExecAllocator) {
+		chromedp.Flag("log-net-log", path)(a)
+		chromedp.Flag("net-log-capture-mode", captureMode)(a)
+	}, nil
+}
@@ -0,0 +1,34 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// PrintPDF renders the page as it is displayed to a PDF file at path, with its backgrounds, returning the URL
+// of the printed page. Chrome only prints pages to PDF in headless mode.
+func (b *Browser) PrintPDF(path string) (string, error) {
+	var location string
+	var pdf []byte
+	err := chromedp.Run(b.ctx, chromedp.Location(&location), chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		pdf, _, err = page.PrintToPDF().WithPrintBackground(true).WithGenerateTaggedPDF(true).Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return "", fmt.Errorf("failed to print page to pdf: %v", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create pdf directory: %v", err)
+	}
+	if err = os.WriteFile(path, pdf, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write pdf file: %v", err)
+	}
+	return location, nil
+}
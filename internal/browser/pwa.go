@@ -0,0 +1,73 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// pwaScript collects the page's favicon links and the scopes of its registered service workers.
+const pwaScript = `(async () => {
+	const icons = Array.from(document.querySelectorAll('link[rel~="icon"], link[rel="apple-touch-icon"]')).map(l => l.href);
+	let serviceWorkers = [];
+	if (navigator.serviceWorker) {
+		serviceWorkers = (await navigator.serviceWorker.getRegistrations()).map(r => r.scope);
+	}
+	return {icons, serviceWorkers};
+})()`
+
+// PWAStatus holds the web app manifest, icons and service worker state of the loaded page.
+type PWAStatus struct {
+	ManifestURL          string
+	Manifest             string
+	ManifestErrors       []string
+	InstallabilityErrors []string
+	Favicons             []string
+	ServiceWorkers       []string
+}
+
+// PWAStatus collects the page's manifest, favicons, service worker registrations and the installability
+// errors reported by the browser. It must be called after Run, while the page is still loaded.
+func (b *Browser) PWAStatus() (PWAStatus, error) {
+	status := PWAStatus{}
+
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		url, manifestErrors, data, _, err := page.GetAppManifest().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get app manifest: %v", err)
+		}
+		status.ManifestURL, status.Manifest = url, data
+		for _, e := range manifestErrors {
+			status.ManifestErrors = append(status.ManifestErrors, fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Line, e.Column))
+		}
+
+		installabilityErrors, err := page.GetInstallabilityErrors().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get installability errors: %v", err)
+		}
+		for _, e := range installabilityErrors {
+			status.InstallabilityErrors = append(status.InstallabilityErrors, e.ErrorID)
+		}
+		return nil
+	}))
+	if err != nil {
+		return status, err
+	}
+
+	var res struct {
+		Icons          []string `json:"icons"`
+		ServiceWorkers []string `json:"serviceWorkers"`
+	}
+	err = chromedp.Run(b.ctx, chromedp.Evaluate(pwaScript, &res, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	}))
+	if err != nil {
+		return status, fmt.Errorf("failed to evaluate pwa script: %v", err)
+	}
+	status.Favicons, status.ServiceWorkers = res.Icons, res.ServiceWorkers
+
+	return status, nil
+}
@@ -0,0 +1,36 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// screenshotQuality is the JPEG quality of the screenshots, small enough to embed them in the reports.
+const screenshotQuality = 80
+
+// Screenshot is a JPEG screenshot of the viewport of the page, labelled with the step of the run it was taken at.
+type Screenshot struct {
+	Label    string `json:"label"`
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Image    []byte `json:"image"`
+	// Ref is the reference of the image in the artifact store when it was uploaded there instead of stored with the screenshot
+	Ref string `json:"ref,omitempty"`
+}
+
+// Screenshot takes a screenshot of the viewport of the page as it is displayed.
+func (b *Browser) Screenshot(label string) (Screenshot, error) {
+	screenshot := Screenshot{Label: label, MimeType: "image/jpeg"}
+	err := chromedp.Run(b.ctx, chromedp.Location(&screenshot.URL), chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		screenshot.Image, err = page.CaptureScreenshot().WithFormat(page.CaptureScreenshotFormatJpeg).WithQuality(screenshotQuality).Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return Screenshot{}, fmt.Errorf("failed to take screenshot: %v", err)
+	}
+	return screenshot, nil
+}
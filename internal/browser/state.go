@@ -0,0 +1,185 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// State is the storage state of the browser: its cookies and the localStorage and sessionStorage of the
+// origins it visited. Its JSON format is the storage state format of Playwright, which ignores the
+// sessionStorage, so states can be exchanged with it.
+type State struct {
+	Cookies []StateCookie `json:"cookies"`
+	Origins []OriginState `json:"origins"`
+}
+
+// StateCookie is a cookie of a storage state, with its value. Expires is in seconds since the epoch, -1 for
+// session cookies.
+type StateCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"`
+	HTTPOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	SameSite string  `json:"sameSite,omitempty"`
+}
+
+// OriginState is the localStorage and sessionStorage of an origin.
+type OriginState struct {
+	Origin         string      `json:"origin"`
+	LocalStorage   []NameValue `json:"localStorage"`
+	SessionStorage []NameValue `json:"sessionStorage,omitempty"`
+}
+
+// NameValue is a key of a web storage with its value.
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// webStorageScript reads the localStorage and sessionStorage of the page's origin, with their values.
+const webStorageScript = `(() => {
+	const read = (storage) => {
+		const items = [];
+		try {
+			for (let i = 0; i < storage.length; i++) {
+				const name = storage.key(i);
+				items.push({name: name, value: storage.getItem(name) || ""});
+			}
+		} catch (e) {}
+		return items;
+	};
+	return {origin: location.origin, localStorage: read(localStorage), sessionStorage: read(sessionStorage)};
+})()`
+
+// restoreScript fills the localStorage and sessionStorage of the documents whose origin has a state, given
+// as the JSON encoded origins.
+const restoreScript = `(() => {
+	const state = (%s || []).find(o => o.origin === location.origin);
+	if (!state) return;
+	try {
+		for (const item of state.localStorage || []) localStorage.setItem(item.name, item.value);
+		for (const item of state.sessionStorage || []) sessionStorage.setItem(item.name, item.value);
+	} catch (e) {}
+})()`
+
+// LoadState reads a storage state file.
+func LoadState(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err = json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse state file: %v", err)
+	}
+	return state, nil
+}
+
+// LoadSession reads a session file, a storage state file saved by a previous run. It returns nil when the
+// file does not exist yet, e.g. on the first of a series of runs saving their session to it.
+func LoadSession(path string) (*State, error) {
+	state, err := LoadState(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// SaveState writes a storage state file. It holds the session of the visitor, so only its owner may read it.
+func SaveState(path string, state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %v", err)
+	}
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	return nil
+}
+
+// StartFrom makes Run start from the storage state, e.g. one exported after logging in, setting its cookies
+// and the localStorage and sessionStorage of its origins before loading the target.
+func (b *Browser) StartFrom(state State) {
+	b.state = &state
+}
+
+// restoreState sets the cookies of the state, and installs the script filling the web storage of its
+// origins in the documents loaded next, returning the script's identifier.
+func (b *Browser) restoreState(ctx context.Context) (page.ScriptIdentifier, error) {
+	var cookies []*network.CookieParam
+	for _, c := range b.state.Cookies {
+		cookie := &network.CookieParam{Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path,
+			Secure: c.Secure, HTTPOnly: c.HTTPOnly, SameSite: network.CookieSameSite(c.SameSite)}
+		if c.Expires > 0 {
+			expires := cdp.TimeSinceEpoch(time.Unix(int64(c.Expires), 0))
+			cookie.Expires = &expires
+		}
+		cookies = append(cookies, cookie)
+	}
+	if len(cookies) > 0 {
+		if err := storage.SetCookies(cookies).Do(ctx); err != nil {
+			return "", fmt.Errorf("failed to set cookies: %v", err)
+		}
+	}
+
+	origins, err := json.Marshal(b.state.Origins)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal origins: %v", err)
+	}
+	id, err := page.AddScriptToEvaluateOnNewDocument(fmt.Sprintf(restoreScript, origins)).Do(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to install web storage script: %v", err)
+	}
+	return id, nil
+}
+
+// ExportState returns the storage state of the browser: every cookie, and the localStorage and
+// sessionStorage of the page's origin. It must be called after Run, e.g. once a scenario logged in.
+func (b *Browser) ExportState() (State, error) {
+	state := State{Cookies: []StateCookie{}, Origins: []OriginState{}}
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		cookies, err := storage.GetCookies().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get cookies: %v", err)
+		}
+		for _, c := range cookies {
+			cookie := StateCookie{Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path, Expires: -1,
+				HTTPOnly: c.HTTPOnly, Secure: c.Secure, SameSite: c.SameSite.String()}
+			if !c.Session {
+				cookie.Expires = c.Expires
+			}
+			state.Cookies = append(state.Cookies, cookie)
+		}
+		return nil
+	}))
+	if err != nil {
+		return state, err
+	}
+
+	var origin OriginState
+	if err = chromedp.Run(b.ctx, chromedp.Evaluate(webStorageScript, &origin)); err != nil {
+		return state, fmt.Errorf("failed to evaluate web storage script: %v", err)
+	}
+	// pages without an origin, e.g. about:blank, have the opaque origin "null"
+	if origin.Origin != "" && origin.Origin != "null" {
+		state.Origins = append(state.Origins, origin)
+	}
+	return state, nil
+}
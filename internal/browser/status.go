@@ -0,0 +1,79 @@
+package browser
+
+import (
+	"sort"
+)
+
+// summaryTop is the number of slowest and largest responses kept in a StatusSummary.
+const summaryTop = 5
+
+// StatusSummary is the distribution of the statuses of the responses of a run, by class, along with its slowest
+// and largest responses and the errors its failed requests failed with, counted by error text.
+type StatusSummary struct {
+	Informational int            `json:"1xx"`
+	Success       int            `json:"2xx"`
+	Redirect      int            `json:"3xx"`
+	ClientError   int            `json:"4xx"`
+	ServerError   int            `json:"5xx"`
+	Failed        int            `json:"failed"`
+	Errors        map[string]int `json:"errors,omitempty"`
+	Slowest       []ResponseStat `json:"slowest,omitempty"`
+	Largest       []ResponseStat `json:"largest,omitempty"`
+}
+
+// ResponseStat is a response of a StatusSummary.
+type ResponseStat struct {
+	URL     string  `json:"url"`
+	Status  int64   `json:"status"`
+	TotalMS float64 `json:"total_ms"`
+	Bytes   float64 `json:"bytes"`
+}
+
+// SummarizeStatuses computes the status summary of the responses and failures of a run.
+func SummarizeStatuses(responses []Response, failures []Failure) StatusSummary {
+	var s StatusSummary
+	stats := make([]ResponseStat, 0, len(responses))
+	for _, r := range responses {
+		switch r.Status / 100 {
+		case 1:
+			s.Informational++
+		case 2:
+			s.Success++
+		case 3:
+			s.Redirect++
+		case 4:
+			s.ClientError++
+		case 5:
+			s.ServerError++
+		}
+		stats = append(stats, ResponseStat{URL: r.URL, Status: r.Status, TotalMS: r.Timing.Total, Bytes: r.Timing.EncodedBytes})
+	}
+	for _, f := range failures {
+		if s.Errors == nil {
+			s.Errors = map[string]int{}
+		}
+		s.Failed++
+		s.Errors[f.ErrorText]++
+	}
+
+	s.Slowest = top(stats, func(r ResponseStat) float64 { return r.TotalMS })
+	s.Largest = top(stats, func(r ResponseStat) float64 { return r.Bytes })
+	return s
+}
+
+// top returns the summaryTop responses with the highest non-zero value, highest first.
+func top(stats []ResponseStat, value func(ResponseStat) float64) []ResponseStat {
+	var ranked []ResponseStat
+	for _, r := range stats {
+		if value(r) > 0 {
+			ranked = append(ranked, r)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if value(ranked[i]) != value(ranked[j]) {
+			return value(ranked[i]) > value(ranked[j])
+		}
+		return ranked[i].URL < ranked[j].URL
+	})
+	return ranked[:min(summaryTop, len(ranked))]
+}
@@ -0,0 +1,180 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// Cookie is a cookie set in the browser during a run. Its value is left out, as it often identifies the visitor.
+type Cookie struct {
+	Name     string    `json:"name"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires,omitempty"`
+	Session  bool      `json:"session"`
+	Secure   bool      `json:"secure"`
+	HTTPOnly bool      `json:"http_only"`
+	SameSite string    `json:"same_site,omitempty"`
+	Size     int       `json:"size"`
+}
+
+// StorageItem is a key set in the localStorage or sessionStorage of the page's origin during a run.
+// Kind is "local" or "session". Like cookies, values are left out.
+type StorageItem struct {
+	Origin string `json:"origin"`
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+	Size   int    `json:"size"`
+}
+
+// storageScript lists the keys of the page's localStorage and sessionStorage with the size of their values.
+const storageScript = `(() => {
+	const items = [];
+	for (const [kind, store] of [["local", window.localStorage], ["session", window.sessionStorage]]) {
+		try {
+			for (let i = 0; i < store.length; i++) {
+				const key = store.key(i);
+				items.push({origin: location.origin, kind: kind, key: key, size: (store.getItem(key) || "").length});
+			}
+		} catch (e) {}
+	}
+	return items;
+})()`
+
+// Cookies returns every cookie set in the browser, first and third-party.
+func (b *Browser) Cookies() ([]Cookie, error) {
+	var cookies []Cookie
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		all, err := storage.GetCookies().Do(ctx)
+		if err != nil {
+			return err
+		}
+		for _, c := range all {
+			cookie := Cookie{Name: c.Name, Domain: c.Domain, Path: c.Path, Session: c.Session, Secure: c.Secure,
+				HTTPOnly: c.HTTPOnly, SameSite: c.SameSite.String(), Size: int(c.Size)}
+			if !c.Session && c.Expires > 0 {
+				cookie.Expires = time.Unix(int64(c.Expires), 0)
+			}
+			cookies = append(cookies, cookie)
+		}
+		return nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cookies: %v", err)
+	}
+	return cookies, nil
+}
+
+// WebStorage returns the keys set in the localStorage and sessionStorage of the page's origin.
+func (b *Browser) WebStorage() ([]StorageItem, error) {
+	var items []StorageItem
+	if err := chromedp.Run(b.ctx, chromedp.Evaluate(storageScript, &items)); err != nil {
+		return nil, fmt.Errorf("failed to evaluate web storage script: %v", err)
+	}
+	return items, nil
+}
+
+// StorageEntry is a key of the localStorage or sessionStorage of an origin, with its value. Kind is "local"
+// or "session". Looks tells what the value looks like when it is recognized: "jwt", "uuid" or "json".
+type StorageEntry struct {
+	Origin string `json:"origin"`
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Looks  string `json:"looks,omitempty"`
+}
+
+// snapshotScript reads the localStorage and sessionStorage of the frame's origin, with their values.
+const snapshotScript = `(() => {
+	const entries = [];
+	for (const [kind, store] of [["local", window.localStorage], ["session", window.sessionStorage]]) {
+		try {
+			for (let i = 0; i < store.length; i++) {
+				const key = store.key(i);
+				entries.push({origin: location.origin, kind: kind, key: key, value: store.getItem(key) || ""});
+			}
+		} catch (e) {}
+	}
+	return entries;
+})()`
+
+var (
+	jwtPattern  = regexp.MustCompile(`^eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*$`)
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// looks tells what a storage value looks like, e.g. a token or an identifier, or returns an empty string.
+func looks(value string) string {
+	value = strings.TrimSpace(value)
+	switch {
+	case jwtPattern.MatchString(value):
+		return "jwt"
+	case uuidPattern.MatchString(value):
+		return "uuid"
+	case (strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[")) && json.Valid([]byte(value)):
+		return "json"
+	}
+	return ""
+}
+
+// SnapshotStorage returns the contents of the localStorage and sessionStorage of every origin of the page's
+// frames, read in an isolated world of one frame per origin so the page's scripts cannot tamper with it.
+// Unlike WebStorage, it includes the values, which may identify the visitor.
+func (b *Browser) SnapshotStorage() ([]StorageEntry, error) {
+	var entries []StorageEntry
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		tree, err := page.GetFrameTree().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get frame tree: %v", err)
+		}
+
+		seen := map[string]bool{}
+		var walk func(*page.FrameTree) error
+		walk = func(t *page.FrameTree) error {
+			// frames without an origin, e.g. about:blank, have the opaque origin "null"
+			if origin := t.Frame.SecurityOrigin; origin != "" && origin != "null" && !seen[origin] {
+				seen[origin] = true
+				world, err := page.CreateIsolatedWorld(t.Frame.ID).WithWorldName("web-tester-storage").Do(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to create isolated world for %s: %v", origin, err)
+				}
+				result, exception, err := runtime.Evaluate(snapshotScript).WithContextID(world).WithReturnByValue(true).Do(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to evaluate storage snapshot script for %s: %v", origin, err)
+				}
+				if exception != nil {
+					return fmt.Errorf("failed to evaluate storage snapshot script for %s: %s", origin, exception.Text)
+				}
+				var frameEntries []StorageEntry
+				if err = json.Unmarshal(result.Value, &frameEntries); err != nil {
+					return fmt.Errorf("failed to parse storage snapshot of %s: %v", origin, err)
+				}
+				entries = append(entries, frameEntries...)
+			}
+			for _, child := range t.ChildFrames {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return walk(tree)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		entries[i].Looks = looks(entries[i].Value)
+	}
+	return entries, nil
+}
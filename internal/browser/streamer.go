@@ -0,0 +1,86 @@
+package browser
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"web-tester/internal/sink"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// streamQueue is the number of events waiting to be written to the sink, the events received beyond that being
+// dropped instead of blocking the event listeners while a slow sink catches up.
+const streamQueue = 4096
+
+// streamer is the pipeline writing the captured events to the sink of the browser. A single worker writes them,
+// so the sink receives them in the order they were received.
+type streamer struct {
+	queue chan sink.Record
+	done  chan struct{}
+
+	mu      sync.Mutex
+	drained bool
+	dropped int
+}
+
+// startStreamer starts the worker writing the events streamed to the sink of the browser, when one is set.
+func (b *Browser) startStreamer(logger *slog.Logger) {
+	if b.sink == nil {
+		return
+	}
+	s := &streamer{queue: make(chan sink.Record, streamQueue), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		for record := range s.queue {
+			// the events are redacted off the listeners, which only queue them
+			record.URL, record.Content = b.redaction.URL(record.URL), b.redaction.Content(record.Content)
+			if err := b.sink.Write(record); err != nil {
+				logger.Error("failed to stream event", "request_id", record.RequestID, "error", err)
+			}
+		}
+	}()
+	b.streamer = s
+}
+
+// stream queues a captured event for the sink of the browser, if one is set, without blocking.
+func (b *Browser) stream(eventType string, requestID network.RequestID, url string, content interface{}) {
+	s := b.streamer
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.drained {
+		s.dropped++
+		return
+	}
+	select {
+	case s.queue <- sink.Record{TestID: b.testID, Type: eventType, RequestID: string(requestID), URL: url, Time: time.Now(),
+		Content: content}:
+	default:
+		s.dropped++
+	}
+}
+
+// DrainStream stops streaming the events received from now on, waits for the events queued already to be written
+// to the sink, and returns the number of events that were not, because the queue was full or they were received
+// once drained. It does nothing without a sink.
+func (b *Browser) DrainStream() int {
+	s := b.streamer
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	if !s.drained {
+		s.drained = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
@@ -0,0 +1,136 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultRunTimeout is the deadline of the browsers created with New and NewWithTestID when none is set.
+const DefaultRunTimeout = 60 * time.Second
+
+// ErrRunTimeout is returned when the browser was killed for running past the deadline of the run.
+var ErrRunTimeout = fmt.Errorf("%w: run deadline exceeded", ErrNavigation)
+
+// idleQuiet is how long the network must be quiet, with no request in flight, for the page to be idle.
+const idleQuiet = 500 * time.Millisecond
+
+// Timeouts bound the steps of a run, each disabled when zero. Run is the deadline of the whole run, after which
+// the browser is killed, Navigation bounds the loads of the target, BodyFetch the fetch of every response body,
+// with its retries, and Idle the wait for the network to be idle once the wait time after the load passed.
+type Timeouts struct {
+	Run        time.Duration
+	Navigation time.Duration
+	BodyFetch  time.Duration
+	Idle       time.Duration
+}
+
+// SetTimeouts sets the timeouts of the browser. A non-zero Run replaces the deadline the browser was created
+// with, counted from now.
+func (b *Browser) SetTimeouts(t Timeouts) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timeouts = t
+	if t.Run > 0 {
+		b.deadline.Reset(t.Run)
+	}
+}
+
+// timeOut kills the browser once the deadline of the run passed.
+func (b *Browser) timeOut() {
+	b.mu.Lock()
+	if b.err == nil {
+		b.err = ErrRunTimeout
+	}
+	b.mu.Unlock()
+	b.cancel()
+}
+
+// withTimeout returns the context of the browser bounded by the timeout, unbounded when zero.
+func (b *Browser) withTimeout(ctx context.Context, timeout func(Timeouts) time.Duration) (context.Context, context.CancelFunc) {
+	b.mu.Lock()
+	d := timeout(b.timeouts)
+	b.mu.Unlock()
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// navigate loads the target, bounded by the navigation timeout.
+func (b *Browser) navigate() error {
+	ctx, cancel := b.withTimeout(b.ctx, func(t Timeouts) time.Duration { return t.Navigation })
+	defer cancel()
+	err := chromedp.Run(ctx, chromedp.Navigate(b.target))
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && b.ctx.Err() == nil {
+		return fmt.Errorf("navigation timed out: %w", err)
+	}
+	return err
+}
+
+// trackActivity keeps the requests of the page in flight and the time of its last network activity, for
+// waitIdle. It tracks the requests left out by the filter too, which keep the page busy all the same.
+func (b *Browser) trackActivity(ev interface{}) {
+	var id network.RequestID
+	done := true
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		id, done = ev.RequestID, false
+	case *network.EventLoadingFinished:
+		id = ev.RequestID
+	case *network.EventLoadingFailed:
+		id = ev.RequestID
+	default:
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.inflight == nil {
+		b.inflight = map[network.RequestID]bool{}
+	}
+	if done {
+		delete(b.inflight, id)
+	} else {
+		b.inflight[id] = true
+	}
+	b.lastActivity = time.Now()
+}
+
+// inflightCount returns the number of requests of the page in flight.
+func (b *Browser) inflightCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.inflight)
+}
+
+// waitIdle waits for the network of the page to be idle, no request being in flight for idleQuiet, up to the
+// idle timeout. It returns false when the page was still busy once the timeout passed, and right away when the
+// idle timeout is zero.
+func (b *Browser) waitIdle() bool {
+	ctx, cancel := b.withTimeout(b.ctx, func(t Timeouts) time.Duration { return t.Idle })
+	defer cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		return true
+	}
+
+	ticker := time.NewTicker(idleQuiet / 5)
+	defer ticker.Stop()
+	for {
+		b.mu.Lock()
+		idle := len(b.inflight) == 0 && time.Since(b.lastActivity) >= idleQuiet
+		b.mu.Unlock()
+		if idle {
+			return true
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package browser
+
+import (
+	"sort"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+)
+
+// Timing holds the timing metrics of a single request, in milliseconds.
+// Phases that did not happen for the request (e.g. DNS on a reused connection) are left at zero.
+type Timing struct {
+	DNS          float64
+	Connect      float64
+	TLS          float64
+	TTFB         float64
+	Download     float64
+	Total        float64
+	EncodedBytes float64
+
+	// request start and headers received, in seconds on the browser's monotonic clock
+	requestTime float64
+	headersEnd  float64
+}
+
+// Stats holds aggregate timing metrics for a set of responses.
+type Stats struct {
+	Count      int
+	P50        float64
+	P95        float64
+	TotalBytes float64
+}
+
+// phase returns the duration between start and end, or zero if the phase did not happen.
+func phase(start, end float64) float64 {
+	if start < 0 || end < start {
+		return 0
+	}
+	return end - start
+}
+
+// setTiming records the connection and time to first byte phases from the response timing data.
+func (r *Response) setTiming(t *network.ResourceTiming) {
+	if t == nil {
+		return
+	}
+	r.Timing.DNS = phase(t.DNSStart, t.DNSEnd)
+	r.Timing.Connect = phase(t.ConnectStart, t.ConnectEnd)
+	r.Timing.TLS = phase(t.SslStart, t.SslEnd)
+
+	headersStart := t.ReceiveHeadersStart
+	if headersStart <= 0 {
+		headersStart = t.ReceiveHeadersEnd
+	}
+	r.Timing.TTFB = phase(t.SendEnd, headersStart)
+
+	r.Timing.requestTime = t.RequestTime
+	r.Timing.headersEnd = t.RequestTime + t.ReceiveHeadersEnd/1000
+}
+
+// finishTiming records the download and total durations using the timestamp of the loading finished event.
+func (r *Response) finishTiming(ev network.EventLoadingFinished) {
+	r.Timing.EncodedBytes, r.finished = ev.EncodedDataLength, true
+	if ev.Timestamp == nil || cdp.MonotonicTimeEpoch == nil || r.Timing.requestTime == 0 {
+		return
+	}
+
+	finished := time.Time(*ev.Timestamp).Sub(*cdp.MonotonicTimeEpoch).Seconds()
+	r.Timing.Download = phase(r.Timing.headersEnd, finished) * 1000
+	r.Timing.Total = phase(r.Timing.requestTime, finished) * 1000
+}
+
+// percentile returns the p-th percentile of the sorted values using the nearest-rank method.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// Summarize computes the p50/p95 total latency and the total bytes transferred of the responses.
+// Responses whose loading never finished are left out of the latency percentiles.
+func Summarize(responses []Response) Stats {
+	var latencies []float64
+	stats := Stats{}
+	for _, r := range responses {
+		stats.TotalBytes += r.Timing.EncodedBytes
+		if r.Timing.Total > 0 {
+			latencies = append(latencies, r.Timing.Total)
+		}
+	}
+	sort.Float64s(latencies)
+
+	stats.Count = len(latencies)
+	stats.P50 = percentile(latencies, 50)
+	stats.P95 = percentile(latencies, 95)
+	return stats
+}
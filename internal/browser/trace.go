@@ -0,0 +1,94 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/tracing"
+	"github.com/chromedp/chromedp"
+)
+
+// traceCategories are the trace categories recorded by the performance panel of the DevTools.
+var traceCategories = []string{
+	"-*", "devtools.timeline", "disabled-by-default-devtools.timeline", "disabled-by-default-devtools.timeline.frame",
+	"disabled-by-default-devtools.timeline.stack", "disabled-by-default-v8.cpu_profiler", "toplevel", "loading",
+	"navigation", "latencyInfo", "blink.console", "blink.user_timing", "v8.execute",
+}
+
+// traceTimeout bounds the wait for the browser to flush the trace once tracing is stopped.
+const traceTimeout = 30 * time.Second
+
+// tracer collects the trace events reported by the browser until tracing completes.
+type tracer struct {
+	path   string
+	mu     sync.Mutex
+	events []json.RawMessage
+	done   chan struct{}
+}
+
+// Trace makes Run record a Chrome trace of the page load, written to path in the JSON trace format
+// loadable in chrome://tracing or Perfetto.
+func (b *Browser) Trace(path string) {
+	b.tracer = &tracer{path: path}
+}
+
+// startTracing starts recording the trace, collecting its events as they are reported.
+func (b *Browser) startTracing(ctx context.Context) error {
+	t := b.tracer
+	t.done = make(chan struct{})
+	chromedp.ListenTarget(b.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *tracing.EventDataCollected:
+			t.mu.Lock()
+			for _, value := range ev.Value {
+				t.events = append(t.events, json.RawMessage(value))
+			}
+			t.mu.Unlock()
+		case *tracing.EventTracingComplete:
+			close(t.done)
+		}
+	})
+
+	err := tracing.Start().
+		WithTransferMode(tracing.TransferModeReportEvents).
+		WithTraceConfig(&tracing.TraceConfig{RecordMode: tracing.RecordModeRecordAsMuchAsPossible, IncludedCategories: traceCategories}).
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start tracing: %v", err)
+	}
+	return nil
+}
+
+// stopTracing stops recording the trace and writes it once the browser has flushed its events.
+func (b *Browser) stopTracing() error {
+	t := b.tracer
+	if err := chromedp.Run(b.ctx, tracing.End()); err != nil {
+		return fmt.Errorf("failed to stop tracing: %v", err)
+	}
+	select {
+	case <-t.done:
+	case <-time.After(traceTimeout):
+		return fmt.Errorf("timed out waiting for the trace")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, err := json.Marshal(struct {
+		TraceEvents []json.RawMessage `json:"traceEvents"`
+	}{t.events})
+	if err != nil {
+		return fmt.Errorf("failed to marshal trace: %v", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create trace directory: %v", err)
+	}
+	if err = os.WriteFile(t.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write trace: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"sort"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// Failure is a request that failed to load, from its loading failed event.
+type Failure struct {
+	RequestID     network.RequestID
+	ErrorText     string
+	Canceled      bool
+	BlockedReason string
+}
+
+// Redirect is a 3xx hop of a redirect chain: the URL that was requested, its status and Location header.
+type Redirect struct {
+	URL      string `json:"url"`
+	Status   int64  `json:"status"`
+	Location string `json:"location"`
+}
+
+// Transaction is a full HTTP transaction: a request with the response, loading finished and loading failed
+// events of its request ID. The browser reuses the request ID when following a redirect, so a transaction
+// ends with the last hop, and Redirects holds the earlier hops in order.
+type Transaction struct {
+	RequestID    network.RequestID
+	Method       string
+	URL          string
+	ResourceType string
+	Redirects    []Redirect
+	Request      Request
+	// Response is nil when no response was received, e.g. when the request failed or was filtered out
+	Response *Response
+	// Finished is set when the response finished loading
+	Finished bool
+	Failure  *Failure
+}
+
+// Failed reports whether the transaction failed to load.
+func (t Transaction) Failed() bool {
+	return t.Failure != nil
+}
+
+// Correlate merges the requests, responses and failures captured by a run into transactions keyed by
+// request ID, in the order their first request was sent.
+func Correlate(requests []Request, responses []Response, failures []Failure) []Transaction {
+	var transactions []Transaction
+	index := map[network.RequestID]int{}
+	// requests are added as their events are handled, which may be out of order, so follow their timestamps
+	sorted := append([]Request(nil), requests...)
+	sort.SliceStable(sorted, func(i, j int) bool { return requestTime(sorted[i]).Before(requestTime(sorted[j])) })
+
+	for _, req := range sorted {
+		i, ok := index[req.RequestID]
+		if !ok {
+			index[req.RequestID] = len(transactions)
+			transactions = append(transactions, Transaction{RequestID: req.RequestID})
+			i = len(transactions) - 1
+		}
+		t := &transactions[i]
+		ev, _ := req.Content.(*network.EventRequestWillBeSent)
+		// a later request with the same ID follows a redirect from the current one, whose response it carries
+		if ok {
+			redirect := Redirect{URL: t.URL}
+			if ev != nil && ev.RedirectResponse != nil {
+				redirect.URL, redirect.Status = ev.RedirectResponse.URL, ev.RedirectResponse.Status
+				redirect.Location = headerValue(ev.RedirectResponse.Headers, "Location")
+			}
+			t.Redirects = append(t.Redirects, redirect)
+		}
+		t.URL, t.Request = req.URL, req
+		if ev != nil {
+			t.Method, t.ResourceType = ev.Request.Method, ev.Type.String()
+		}
+	}
+
+	for _, resp := range responses {
+		if i, ok := index[resp.RequestID]; ok {
+			resp := resp
+			transactions[i].Response, transactions[i].Finished = &resp, resp.finished
+		}
+	}
+
+	for _, f := range failures {
+		if i, ok := index[f.RequestID]; ok {
+			failure := f
+			transactions[i].Failure = &failure
+		}
+	}
+	return transactions
+}
+
+// requestTime returns the time a request was sent, or the zero time when its event has no timestamp.
+func requestTime(req Request) time.Time {
+	ev, ok := req.Content.(*network.EventRequestWillBeSent)
+	if !ok || ev.Timestamp == nil {
+		return time.Time{}
+	}
+	return ev.Timestamp.Time()
+}
@@ -0,0 +1,92 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"web-tester/internal/video"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// recorder collects the frames of the screencast of the page.
+type recorder struct {
+	path string
+	fps  int
+	mu   sync.Mutex
+	// frames are the frames received, in order, and stopped is set once the screencast is stopped
+	frames  []video.Frame
+	stopped bool
+}
+
+// Record makes Run record a screencast of the session from before the navigation, written to path as a
+// Motion JPEG video of fps frames per second by StopRecording.
+func (b *Browser) Record(path string, fps int) {
+	b.recorder = &recorder{path: path, fps: fps}
+}
+
+// startRecording starts the screencast, keeping its frames as they are received. Each frame must be
+// acknowledged for the next one to be sent.
+func (b *Browser) startRecording(ctx context.Context, logger *slog.Logger) error {
+	rec := b.recorder
+	chromedp.ListenTarget(b.ctx, func(ev interface{}) {
+		frame, ok := ev.(*page.EventScreencastFrame)
+		if !ok {
+			return
+		}
+		go func() {
+			if err := chromedp.Run(b.ctx, page.ScreencastFrameAck(frame.SessionID)); err != nil && b.ctx.Err() == nil {
+				logger.Warn("failed to acknowledge screencast frame", "error", err)
+			}
+		}()
+
+		data, err := base64.StdEncoding.DecodeString(frame.Data)
+		if err != nil {
+			logger.Warn("failed to decode screencast frame", "error", err)
+			return
+		}
+		captured := time.Now()
+		if frame.Metadata != nil && frame.Metadata.Timestamp != nil {
+			captured = frame.Metadata.Timestamp.Time()
+		}
+		rec.mu.Lock()
+		if !rec.stopped {
+			rec.frames = append(rec.frames, video.Frame{JPEG: data, Time: captured})
+		}
+		rec.mu.Unlock()
+	})
+
+	if err := page.StartScreencast().WithFormat(page.ScreencastFormatJpeg).WithQuality(70).Do(ctx); err != nil {
+		return fmt.Errorf("failed to start screencast: %v", err)
+	}
+	return nil
+}
+
+// StopRecording stops the screencast and writes the video, returning the number of frames it holds. It
+// does nothing without Record, and can be called after a failed Run so the video shows the failure.
+func (b *Browser) StopRecording(logger *slog.Logger) (int, error) {
+	rec := b.recorder
+	if rec == nil {
+		return 0, nil
+	}
+	// the browser may be gone, e.g. killed for exceeding its limits, leaving the frames received so far
+	if err := chromedp.Run(b.ctx, page.StopScreencast()); err != nil && b.ctx.Err() == nil {
+		logger.Warn("failed to stop screencast", "error", err)
+	}
+
+	rec.mu.Lock()
+	rec.stopped = true
+	frames := rec.frames
+	rec.mu.Unlock()
+	if len(frames) == 0 {
+		return 0, fmt.Errorf("no screencast frame was received")
+	}
+	if err := video.WriteFile(rec.path, frames, rec.fps); err != nil {
+		return 0, err
+	}
+	return len(frames), nil
+}
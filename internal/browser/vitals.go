@@ -0,0 +1,141 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/performance"
+	"github.com/chromedp/chromedp"
+)
+
+// vitalsObserverScript is evaluated in every document before its own scripts, observing the largest
+// contentful paint, layout shifts, first input and interactions as they happen.
+const vitalsObserverScript = `(() => {
+	const v = window.__webTesterVitals = {lcp: 0, cls: 0, fid: 0, inp: 0};
+	const observe = (type, callback, options) => {
+		try {
+			new PerformanceObserver(list => list.getEntries().forEach(callback)).observe(Object.assign({type, buffered: true}, options));
+		} catch (e) {}
+	};
+	observe('largest-contentful-paint', e => { v.lcp = e.startTime; });
+	observe('layout-shift', e => { if (!e.hadRecentInput) v.cls += e.value; });
+	observe('first-input', e => { v.fid = e.processingStart - e.startTime; });
+	observe('event', e => { if (e.interactionId) v.inp = Math.max(v.inp, e.duration); }, {durationThreshold: 16});
+})()`
+
+// vitalsScript reads the navigation timing of the page along with the vitals observed in it.
+const vitalsScript = `(() => {
+	const v = window.__webTesterVitals || {};
+	const nav = performance.getEntriesByType('navigation')[0] || {};
+	const fcp = performance.getEntriesByName('first-contentful-paint')[0];
+	return {
+		url: location.href,
+		ttfb: nav.responseStart || 0,
+		fcp: fcp ? fcp.startTime : 0,
+		dom_content_loaded: nav.domContentLoadedEventEnd || 0,
+		load: nav.loadEventEnd || 0,
+		lcp: v.lcp || 0,
+		cls: v.cls || 0,
+		fid: v.fid || 0,
+		inp: v.inp || 0,
+	};
+})()`
+
+// Vitals holds the navigation timing and Core Web Vitals of a page, in milliseconds from the start of the
+// navigation, along with the main thread and heap metrics of the performance domain. CLS sums every layout
+// shift without recent input, and INP is the longest interaction, so both approximate the field metrics.
+// FID and INP are zero when nothing interacted with the page.
+type Vitals struct {
+	URL              string  `json:"url"`
+	TTFB             float64 `json:"ttfb"`
+	FCP              float64 `json:"fcp"`
+	DOMContentLoaded float64 `json:"dom_content_loaded"`
+	Load             float64 `json:"load"`
+	LCP              float64 `json:"lcp"`
+	CLS              float64 `json:"cls"`
+	FID              float64 `json:"fid"`
+	INP              float64 `json:"inp"`
+	ScriptDuration   float64 `json:"script_duration"`
+	TaskDuration     float64 `json:"task_duration"`
+	LayoutDuration   float64 `json:"layout_duration"`
+	JSHeapUsedSize   float64 `json:"js_heap_used_size"`
+}
+
+// vitalsThresholds are the good and poor thresholds of the Core Web Vitals and their diagnostic metrics,
+// as published on web.dev.
+var vitalsThresholds = []struct {
+	name       string
+	good, poor float64
+	value      func(Vitals) float64
+}{
+	{"ttfb", 800, 1800, func(v Vitals) float64 { return v.TTFB }},
+	{"fcp", 1800, 3000, func(v Vitals) float64 { return v.FCP }},
+	{"lcp", 2500, 4000, func(v Vitals) float64 { return v.LCP }},
+	{"cls", 0.1, 0.25, func(v Vitals) float64 { return v.CLS }},
+	{"fid", 100, 300, func(v Vitals) float64 { return v.FID }},
+	{"inp", 200, 500, func(v Vitals) float64 { return v.INP }},
+}
+
+// Ratings rates each vital as "good", "needs-improvement" or "poor". Vitals that were not measured are left out.
+func (v Vitals) Ratings() map[string]string {
+	ratings := map[string]string{}
+	for _, t := range vitalsThresholds {
+		value := t.value(v)
+		switch {
+		case value <= 0 && t.name != "cls":
+		case value <= t.good:
+			ratings[t.name] = "good"
+		case value <= t.poor:
+			ratings[t.name] = "needs-improvement"
+		default:
+			ratings[t.name] = "poor"
+		}
+	}
+	return ratings
+}
+
+// observeVitals enables the performance domain and installs the vitals observers in the documents loaded next.
+func (b *Browser) observeVitals(ctx context.Context) error {
+	if err := performance.Enable().Do(ctx); err != nil {
+		return fmt.Errorf("failed to enable performance metrics: %v", err)
+	}
+	if _, err := page.AddScriptToEvaluateOnNewDocument(vitalsObserverScript).Do(ctx); err != nil {
+		return fmt.Errorf("failed to install vitals observers: %v", err)
+	}
+	return nil
+}
+
+// WebVitals returns the navigation timing and Core Web Vitals of the loaded page. It must be called after
+// Run, while the page is still loaded.
+func (b *Browser) WebVitals() (Vitals, error) {
+	var vitals Vitals
+	if err := chromedp.Run(b.ctx, chromedp.Evaluate(vitalsScript, &vitals)); err != nil {
+		return vitals, fmt.Errorf("failed to evaluate vitals script: %v", err)
+	}
+
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		metrics, err := performance.GetMetrics().Do(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range metrics {
+			// durations are reported in seconds
+			switch m.Name {
+			case "ScriptDuration":
+				vitals.ScriptDuration = m.Value * 1000
+			case "TaskDuration":
+				vitals.TaskDuration = m.Value * 1000
+			case "LayoutDuration":
+				vitals.LayoutDuration = m.Value * 1000
+			case "JSHeapUsedSize":
+				vitals.JSHeapUsedSize = m.Value
+			}
+		}
+		return nil
+	}))
+	if err != nil {
+		return vitals, fmt.Errorf("failed to get performance metrics: %v", err)
+	}
+	return vitals, nil
+}
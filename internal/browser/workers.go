@@ -0,0 +1,84 @@
+package browser
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// workerTypes are the types of the targets whose requests are captured along with the page's: the
+// service workers, shared workers and dedicated workers the page starts.
+var workerTypes = map[string]bool{
+	"service_worker": true,
+	"shared_worker":  true,
+	"worker":         true,
+}
+
+// iframeType is the type of the targets of the out-of-process iframes, e.g. of third-party origins, whose
+// requests are not seen by the page.
+const iframeType = "iframe"
+
+// captureWorkers attaches to the workers and out-of-process iframes of the page as they are created or
+// attached by the page, and captures their network events like the page's. The requests a target sends before
+// it is attached, e.g. while it starts, are missed, and they are not intercepted, so neither mocked nor faulted.
+func (b *Browser) captureWorkers(logger *slog.Logger, events *EventStore) {
+	attached := map[target.ID]bool{}
+	chromedp.ListenTarget(b.ctx, func(ev interface{}) {
+		var info *target.Info
+		switch ev := ev.(type) {
+		case *target.EventTargetCreated:
+			info = ev.TargetInfo
+		case *target.EventAttachedToTarget:
+			info = ev.TargetInfo
+		}
+		// the listeners are called in order, so attached needs no lock
+		if info == nil || !(workerTypes[info.Type] || info.Type == iframeType) || attached[info.TargetID] {
+			return
+		}
+		attached[info.TargetID] = true
+		source := info.Type
+		if source == iframeType {
+			source = ""
+		}
+
+		// attaching runs commands, which must not block the listener
+		go func() {
+			ctx, _ := chromedp.NewContext(b.ctx, chromedp.WithTargetID(info.TargetID))
+			chromedp.ListenTarget(ctx, b.eventHandler(ctx, logger, source, events))
+			if err := chromedp.Run(ctx); err != nil {
+				if b.ctx.Err() == nil {
+					logger.Warn("failed to attach to target", "type", info.Type, "url", info.URL, "error", err)
+				}
+				return
+			}
+			if err := enlargeBuffers(ctx); err != nil {
+				logger.Debug("failed to enlarge network buffers", "type", info.Type, "error", err)
+			}
+			logger.Info("capturing target requests", "type", info.Type, "url", info.URL)
+		}()
+	})
+}
+
+// ContextOf returns the context of the target that sent the request, to fetch its post data or response
+// body: the worker's or iframe's for their requests, the page's otherwise.
+func (b *Browser) ContextOf(requestID network.RequestID) context.Context {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ctx, ok := b.targets[requestID]; ok {
+		return ctx
+	}
+	return b.ctx
+}
+
+// addTargetRequest records the context of the worker or iframe the request was sent from.
+func (b *Browser) addTargetRequest(requestID network.RequestID, ctx context.Context) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.targets == nil {
+		b.targets = make(map[network.RequestID]context.Context)
+	}
+	b.targets[requestID] = ctx
+}
@@ -0,0 +1,31 @@
+package config
+
+// ArtifactConfig selects the store the artifacts of the runs are uploaded to. Store is empty to keep them on
+// disk, or s3 to upload the large bodies, screenshots, PDFs, videos and traces to S3Bucket, under S3Prefix, of
+// the S3-compatible object storage at S3Endpoint, AWS S3 in S3Region when empty. S3PathStyle addresses the bucket
+// in the path of the URLs instead of the host, as MinIO needs.
+type ArtifactConfig struct {
+	Store             string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3Prefix          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3SessionToken    string
+	S3PathStyle       bool
+}
+
+func (a *ArtifactConfig) Load() ArtifactConfig {
+	a.Store = getEnv("ARTIFACT_STORE", "")
+	a.S3Endpoint = getEnv("S3_ENDPOINT", "")
+	a.S3Region = getEnv("S3_REGION", "us-east-1")
+	a.S3Bucket = getEnv("S3_BUCKET", "")
+	a.S3Prefix = getEnv("S3_PREFIX", "")
+	a.S3AccessKeyID = getEnv("S3_ACCESS_KEY_ID", getEnv("AWS_ACCESS_KEY_ID", ""))
+	a.S3SecretAccessKey = getEnv("S3_SECRET_ACCESS_KEY", getEnv("AWS_SECRET_ACCESS_KEY", ""))
+	a.S3SessionToken = getEnv("S3_SESSION_TOKEN", getEnv("AWS_SESSION_TOKEN", ""))
+	a.S3PathStyle = getEnv("S3_PATH_STYLE", "false") == "true"
+
+	return *a
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Assertions holds the expectations a run is checked against once the capture finishes.
+// Unset expectations are not evaluated.
+type Assertions struct {
+	TitleMatches    string   `json:"title_matches"`
+	RequestOccurred []string `json:"request_occurred"`
+	No5xx           bool     `json:"no_5xx"`
+	NoConsoleErrors bool     `json:"no_console_errors"`
+	MaxPageWeight   float64  `json:"max_page_weight"`
+}
+
+// LoadAssertions reads the assertions from the JSON file set in ASSERTIONS_FILE.
+// It returns empty assertions when the variable is not set.
+func LoadAssertions() (Assertions, error) {
+	assertions := Assertions{}
+
+	path := getEnv("ASSERTIONS_FILE", "")
+	if path == "" {
+		return assertions, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return assertions, fmt.Errorf("failed to read assertions file: %v", err)
+	}
+	if err = json.Unmarshal(data, &assertions); err != nil {
+		return assertions, fmt.Errorf("failed to parse assertions file: %v", err)
+	}
+	return assertions, nil
+}
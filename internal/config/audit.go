@@ -0,0 +1,72 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+type AuditConfig struct {
+	Dedup             bool
+	CTExpectedIssuers []string
+	CTRecentDays      int
+	MatchTrackers     bool
+	CertExpiryDays    int
+	Fuzz              bool
+	FuzzMaxRequests   int
+	// SecretScan scans the captured URLs, headers and bodies for secrets and personal data
+	SecretScan bool
+	// StorageSnapshot stores the values of the web storage of every origin of the page, not only its keys
+	StorageSnapshot bool
+	// RenderedHTML stores the HTML of the page as rendered after its scripts ran, and DOMSnapshot its DOM
+	// snapshot with the layout and computed styles of every node
+	RenderedHTML bool
+	DOMSnapshot  bool
+	// Screenshots stores a screenshot of the page once loaded, and once the journey ran
+	Screenshots bool
+}
+
+// getEnvList returns the comma separated values of an environment variable, or nil when it is not set.
+func getEnvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(getEnv(key, ""), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// getEnvInt returns the integer value of an environment variable, or defaultValue when it is not set or invalid.
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat returns the float value of an environment variable, or defaultValue when it is not set or invalid.
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func (a *AuditConfig) Load() AuditConfig {
+	a.Dedup = getEnv("FINDINGS_DEDUP", "true") == "true"
+	a.CTExpectedIssuers = getEnvList("CT_EXPECTED_ISSUERS")
+	a.CTRecentDays = getEnvInt("CT_RECENT_DAYS", 30)
+	a.MatchTrackers = getEnv("TRACKER_MATCH", "true") == "true"
+	a.CertExpiryDays = getEnvInt("CERT_EXPIRY_WARN_DAYS", 30)
+	a.Fuzz = getEnv("FUZZ_ENABLED", "false") == "true"
+	a.FuzzMaxRequests = getEnvInt("FUZZ_MAX_REQUESTS", 200)
+	a.SecretScan = getEnv("SECRET_SCAN", "true") == "true"
+	a.StorageSnapshot = getEnv("STORAGE_SNAPSHOT", "false") == "true"
+	a.RenderedHTML = getEnv("RENDERED_HTML", "true") == "true"
+	a.DOMSnapshot = getEnv("DOM_SNAPSHOT", "false") == "true"
+	a.Screenshots = getEnv("SCREENSHOTS", "true") == "true"
+
+	return *a
+}
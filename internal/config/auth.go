@@ -0,0 +1,32 @@
+package config
+
+// AuthConfig holds the credentials of the authenticated areas under test: the HTTP Basic credentials
+// answering the authentication challenges of the target, and the form login run before the capture.
+type AuthConfig struct {
+	BasicUsername string
+	BasicPassword string
+	// LoginURL enables the form login when set
+	LoginURL         string
+	LoginUsername    string
+	LoginPassword    string
+	UsernameSelector string
+	PasswordSelector string
+	SubmitSelector   string
+	SuccessSelector  string
+	SuccessURL       string
+}
+
+func (a *AuthConfig) Load() AuthConfig {
+	a.BasicUsername = getEnv("AUTH_BASIC_USERNAME", "")
+	a.BasicPassword = getEnv("AUTH_BASIC_PASSWORD", "")
+	a.LoginURL = getEnv("AUTH_LOGIN_URL", "")
+	a.LoginUsername = getEnv("AUTH_LOGIN_USERNAME", "")
+	a.LoginPassword = getEnv("AUTH_LOGIN_PASSWORD", "")
+	a.UsernameSelector = getEnv("AUTH_USERNAME_SELECTOR", "input[type=email], input[name=username]")
+	a.PasswordSelector = getEnv("AUTH_PASSWORD_SELECTOR", "input[type=password]")
+	a.SubmitSelector = getEnv("AUTH_SUBMIT_SELECTOR", "[type=submit]")
+	a.SuccessSelector = getEnv("AUTH_SUCCESS_SELECTOR", "")
+	a.SuccessURL = getEnv("AUTH_SUCCESS_URL", "")
+
+	return *a
+}
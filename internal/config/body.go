@@ -0,0 +1,15 @@
+package config
+
+// BodyConfig bounds the response bodies kept in memory and in the database. Bodies above MaxDBBytes are
+// written to the content-addressed store in StoreDir, or dropped when it is empty. A zero MaxDBBytes keeps every body.
+type BodyConfig struct {
+	MaxDBBytes int
+	StoreDir   string
+}
+
+func (b *BodyConfig) Load() BodyConfig {
+	b.MaxDBBytes = getEnvInt("BODY_MAX_DB_BYTES", 5<<20)
+	b.StoreDir = getEnv("BODY_STORE_DIR", "")
+
+	return *b
+}
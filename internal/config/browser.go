@@ -0,0 +1,14 @@
+package config
+
+// BrowserConfig holds the resource limits of the browser, where zero means unlimited.
+type BrowserConfig struct {
+	MemoryLimitMB   int
+	CPULimitSeconds int
+}
+
+func (b *BrowserConfig) Load() BrowserConfig {
+	b.MemoryLimitMB = getEnvInt("BROWSER_MEMORY_LIMIT_MB", 0)
+	b.CPULimitSeconds = getEnvInt("BROWSER_CPU_LIMIT_SECONDS", 0)
+
+	return *b
+}
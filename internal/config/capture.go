@@ -0,0 +1,19 @@
+package config
+
+// CaptureConfig holds the default filter of the captured events, as comma separated lists of
+// resource types (e.g. Image,Font,Media) and content types (e.g. image/*,application/json).
+type CaptureConfig struct {
+	IncludeTypes []string
+	ExcludeTypes []string
+	IncludeMIME  []string
+	ExcludeMIME  []string
+}
+
+func (c *CaptureConfig) Load() CaptureConfig {
+	c.IncludeTypes = getEnvList("CAPTURE_INCLUDE_TYPES")
+	c.ExcludeTypes = getEnvList("CAPTURE_EXCLUDE_TYPES")
+	c.IncludeMIME = getEnvList("CAPTURE_INCLUDE_MIME")
+	c.ExcludeMIME = getEnvList("CAPTURE_EXCLUDE_MIME")
+
+	return *c
+}
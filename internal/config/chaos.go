@@ -0,0 +1,38 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Fault is a failure injected by the chaos mode into the requests to the URLs matching URL, a scope rule.
+// Action is "fail", failing the requests with the network error Reason, e.g. "TimedOut", or answering them
+// with Status when it is set, "delay", holding them for DelayMS milliseconds, or "corrupt", truncating
+// their responses.
+type Fault struct {
+	URL     string `json:"url"`
+	Action  string `json:"action"`
+	DelayMS int    `json:"delay_ms,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Status  int    `json:"status,omitempty"`
+}
+
+// LoadFaults reads the faults of the chaos mode from the JSON file set in CHAOS_FILE.
+// It returns no faults when the variable is not set.
+func LoadFaults() ([]Fault, error) {
+	path := getEnv("CHAOS_FILE", "")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chaos file: %v", err)
+	}
+	var faults []Fault
+	if err = json.Unmarshal(data, &faults); err != nil {
+		return nil, fmt.Errorf("failed to parse chaos file: %v", err)
+	}
+	return faults, nil
+}
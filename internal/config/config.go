@@ -3,6 +3,7 @@ package config
 import "os"
 
 type DBConfig struct {
+	Enabled  bool
 	Host     string
 	Port     string
 	User     string
@@ -18,6 +19,7 @@ func getEnv(key, defaultValue string) string {
 }
 
 func (db *DBConfig) Load() DBConfig {
+	db.Enabled = getEnv("DB_ENABLED", "true") == "true"
 	db.Host = getEnv("DB_HOST", "localhost")
 	db.Port = getEnv("DB_PORT", "5432")
 	db.User = getEnv("DB_USER", "myuser")
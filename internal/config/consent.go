@@ -0,0 +1,20 @@
+package config
+
+import "time"
+
+// ConsentConfig holds the handling of the consent banners: the choice clicked, accept or reject, the handling
+// being disabled when empty, the selectors of the buttons tried before the built-in ones, and how long the banner
+// is looked for once the target loaded.
+type ConsentConfig struct {
+	Policy    string
+	Selectors []string
+	Timeout   time.Duration
+}
+
+func (c *ConsentConfig) Load() ConsentConfig {
+	c.Policy = getEnv("CONSENT_POLICY", "")
+	c.Selectors = getEnvList("CONSENT_SELECTORS")
+	c.Timeout = time.Duration(getEnvInt("CONSENT_TIMEOUT_SECONDS", 5)) * time.Second
+
+	return *c
+}
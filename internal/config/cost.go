@@ -0,0 +1,15 @@
+package config
+
+// CostConfig holds the data prices the cost to user of a page is estimated with, as label=price pairs of
+// the price of a gigabyte in Currency, e.g. "in/mobile=0.09".
+type CostConfig struct {
+	Prices   []string
+	Currency string
+}
+
+func (c *CostConfig) Load() CostConfig {
+	c.Prices = getEnvList("DATA_PRICES")
+	c.Currency = getEnv("DATA_PRICE_CURRENCY", "USD")
+
+	return *c
+}
@@ -0,0 +1,31 @@
+package config
+
+import "time"
+
+// CrawlConfig holds the bounds of the crawls started with --crawl: the pages crawled at most and how many
+// links away from the root page they may be, unlimited when 0, and the timeout of the requests checking
+// the links the crawl did not visit. Concurrency is the number of pages crawled at a time, and HostConcurrency,
+// HostRate and Jitter the politeness limits kept to towards every host: the page loads and link checks in
+// progress at a time, the number started per second, either unlimited when 0, and the random delay of up to
+// Jitter added before each of them.
+type CrawlConfig struct {
+	MaxPages        int
+	MaxDepth        int
+	LinkTimeout     time.Duration
+	Concurrency     int
+	HostConcurrency int
+	HostRate        float64
+	Jitter          time.Duration
+}
+
+func (c *CrawlConfig) Load() CrawlConfig {
+	c.MaxPages = getEnvInt("CRAWL_MAX_PAGES", 50)
+	c.MaxDepth = getEnvInt("CRAWL_MAX_DEPTH", 3)
+	c.LinkTimeout = time.Duration(getEnvInt("LINK_CHECK_TIMEOUT_SECONDS", 10)) * time.Second
+	c.Concurrency = getEnvInt("CRAWL_CONCURRENCY", 2)
+	c.HostConcurrency = getEnvInt("CRAWL_HOST_CONCURRENCY", 2)
+	c.HostRate = getEnvFloat("CRAWL_HOST_RATE", 1)
+	c.Jitter = time.Duration(getEnvInt("CRAWL_JITTER_MS", 500)) * time.Millisecond
+
+	return *c
+}
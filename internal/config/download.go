@@ -0,0 +1,13 @@
+package config
+
+// DownloadConfig holds the directory the files downloaded by the pages are written to, in a directory per test.
+// An empty Dir lets the browser discard them.
+type DownloadConfig struct {
+	Dir string
+}
+
+func (d *DownloadConfig) Load() DownloadConfig {
+	d.Dir = getEnv("DOWNLOAD_DIR", "downloads")
+
+	return *d
+}
@@ -0,0 +1,14 @@
+package config
+
+// EgressConfig configures the air-gapped mode, in which the tool only calls the target and the hosts in Allow.
+type EgressConfig struct {
+	AirGapped bool
+	Allow     []string
+}
+
+func (e *EgressConfig) Load() EgressConfig {
+	e.AirGapped = getEnv("AIR_GAPPED", "false") == "true"
+	e.Allow = getEnvList("EGRESS_ALLOW")
+
+	return *e
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// ExploreConfig holds the budget of the explorations started with --explore: the elements clicked and the
+// screens scrolled at most on every page, the delay waited after each of them for the traffic it triggers, and
+// the pattern of the labels of the elements never clicked.
+type ExploreConfig struct {
+	MaxInteractions int
+	MaxScrolls      int
+	Delay           time.Duration
+	Skip            *regexp.Regexp
+}
+
+func (e *ExploreConfig) Load() (ExploreConfig, error) {
+	e.MaxInteractions = getEnvInt("EXPLORE_MAX_INTERACTIONS", 20)
+	e.MaxScrolls = getEnvInt("EXPLORE_MAX_SCROLLS", 10)
+	e.Delay = time.Duration(getEnvInt("EXPLORE_DELAY_MS", 500)) * time.Millisecond
+
+	skip, err := regexp.Compile(getEnv("EXPLORE_SKIP_PATTERN", `(?i)log ?out|sign ?out|delete|remove|unsubscribe|cancel`))
+	if err != nil {
+		return *e, fmt.Errorf("invalid EXPLORE_SKIP_PATTERN: %v", err)
+	}
+	e.Skip = skip
+	return *e, nil
+}
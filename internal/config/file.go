@@ -0,0 +1,265 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// File is the configuration file set in CONFIG_FILE, in YAML, TOML or JSON. Its settings are the defaults of the
+// environment variables named in their env tags, so an environment variable that is set overrides the file.
+// Targets are the URLs tested when no URL list is set, and Assertions, when set, are used unless ASSERTIONS_FILE is.
+//
+// JSON files are read in full, but only the subset of YAML and TOML the settings need is: YAML mappings, block and
+// flow sequences of scalars, plain and quoted scalars and comments, and TOML tables, key = value pairs of strings,
+// numbers, booleans and single-line arrays of those, and comments. The other constructs, e.g. YAML anchors, block
+// scalars and sequences of mappings or TOML inline tables and arrays of tables, fail naming the construct.
+type File struct {
+	Targets    []string     `json:"targets"`
+	URLsFile   string       `json:"urls_file" env:"URLS_FILE"`
+	Scenario   string       `json:"scenario" env:"SCENARIO_FILE"`
+	Assertions *Assertions  `json:"assertions"`
+	Browser    FileBrowser  `json:"browser"`
+	Timeouts   FileTimeouts `json:"timeouts"`
+	Capture    FileCapture  `json:"capture"`
+	Sinks      FileSinks    `json:"sinks"`
+	Database   FileDatabase `json:"database"`
+	Log        FileLog      `json:"log"`
+}
+
+// FileBrowser holds the browser options of a configuration file.
+type FileBrowser struct {
+	ChromePath      string `json:"chrome_path" env:"CHROME_PATH"`
+	MemoryLimitMB   int    `json:"memory_limit_mb" env:"BROWSER_MEMORY_LIMIT_MB"`
+	CPULimitSeconds int    `json:"cpu_limit_seconds" env:"BROWSER_CPU_LIMIT_SECONDS"`
+}
+
+// FileTimeouts holds the timeouts of a configuration file, in seconds.
+type FileTimeouts struct {
+	Run        int `json:"run" env:"RUN_TIMEOUT_SECONDS"`
+	Navigation int `json:"navigation" env:"NAVIGATION_TIMEOUT_SECONDS"`
+	BodyFetch  int `json:"body_fetch" env:"BODY_FETCH_TIMEOUT_SECONDS"`
+	Idle       int `json:"idle" env:"IDLE_TIMEOUT_SECONDS"`
+}
+
+// FileCapture holds the capture filters of a configuration file.
+type FileCapture struct {
+	IncludeTypes []string `json:"include_types" env:"CAPTURE_INCLUDE_TYPES"`
+	ExcludeTypes []string `json:"exclude_types" env:"CAPTURE_EXCLUDE_TYPES"`
+	IncludeMIME  []string `json:"include_mime" env:"CAPTURE_INCLUDE_MIME"`
+	ExcludeMIME  []string `json:"exclude_mime" env:"CAPTURE_EXCLUDE_MIME"`
+}
+
+// FileSinks holds the event sink and the streaming outputs of a configuration file.
+type FileSinks struct {
+	Events          string `json:"events" env:"EVENT_SINK"`
+	EventsDir       string `json:"events_dir" env:"EVENT_SINK_DIR"`
+	MongoURI        string `json:"mongodb_uri" env:"MONGODB_URI"`
+	MongoDatabase   string `json:"mongodb_database" env:"MONGODB_DATABASE"`
+	MongoCollection string `json:"mongodb_collection" env:"MONGODB_COLLECTION"`
+	NDJSON          string `json:"ndjson" env:"OUTPUT_NDJSON"`
+	NATSURL         string `json:"nats_url" env:"STREAM_NATS_URL"`
+	NATSSubject     string `json:"nats_subject" env:"STREAM_NATS_SUBJECT"`
+	KafkaRESTURL    string `json:"kafka_rest_url" env:"STREAM_KAFKA_REST_URL"`
+	KafkaTopic      string `json:"kafka_topic" env:"STREAM_KAFKA_TOPIC"`
+}
+
+// FileDatabase holds the database settings of a configuration file.
+type FileDatabase struct {
+	Enabled  *bool  `json:"enabled" env:"DB_ENABLED"`
+	Host     string `json:"host" env:"DB_HOST"`
+	Port     int    `json:"port" env:"DB_PORT"`
+	User     string `json:"user" env:"DB_USER"`
+	Password string `json:"password" env:"DB_PASSWORD"`
+	Name     string `json:"name" env:"DB_NAME"`
+}
+
+// FileLog holds the logging settings of a configuration file.
+type FileLog struct {
+	Level   string   `json:"level" env:"LOG_LEVEL"`
+	Format  string   `json:"format" env:"LOG_FORMAT"`
+	File    string   `json:"file" env:"LOG_FILE"`
+	Modules []string `json:"modules" env:"LOG_MODULES"`
+}
+
+// eventSinks are the event sinks of the database package, which the configuration cannot import.
+var eventSinks = []string{"postgres", "file", "stdout", "mongodb", "none"}
+
+// ReadFile reads a configuration file, parsed as YAML, TOML or JSON by its extension. Unknown settings are an error.
+func ReadFile(path string) (File, error) {
+	var f File
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return f, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var tree interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		tree, err = parseYAML(data)
+	case ".toml":
+		tree, err = parseTOML(data)
+	case ".json":
+		err = json.Unmarshal(data, &tree)
+	default:
+		return f, fmt.Errorf("unsupported config file extension %q, use .yaml, .yml, .toml or .json", ext)
+	}
+	var unsupported unsupportedError
+	if errors.As(err, &unsupported) {
+		return f, fmt.Errorf("failed to parse config file: %v, only a subset of YAML and TOML is read, rewrite it or use JSON", err)
+	}
+	if err != nil {
+		return f, fmt.Errorf("failed to parse config file: %v", err)
+	}
+
+	// the parsed tree is decoded like JSON so the file has the same names and types in every format
+	if data, err = json.Marshal(tree); err != nil {
+		return f, fmt.Errorf("failed to parse config file: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&f); err != nil {
+		return f, fmt.Errorf("invalid config file: %v", err)
+	}
+	return f, nil
+}
+
+// Validate checks the settings of the file, returning every problem found joined in a single error.
+func (f File) Validate() error {
+	var errs []error
+	for _, target := range f.Targets {
+		if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("targets: %q is not an http or https URL", target))
+		}
+	}
+	for name, path := range map[string]string{"urls_file": f.URLsFile, "scenario": f.Scenario, "browser.chrome_path": f.Browser.ChromePath} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+		}
+	}
+	for name, value := range map[string]int{
+		"browser.memory_limit_mb": f.Browser.MemoryLimitMB, "browser.cpu_limit_seconds": f.Browser.CPULimitSeconds,
+		"timeouts.run": f.Timeouts.Run, "timeouts.navigation": f.Timeouts.Navigation,
+		"timeouts.body_fetch": f.Timeouts.BodyFetch, "timeouts.idle": f.Timeouts.Idle,
+	} {
+		if value < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative, got %d", name, value))
+		}
+	}
+	if f.Database.Port < 0 || f.Database.Port > 65535 {
+		errs = append(errs, fmt.Errorf("database.port: %d is not a port", f.Database.Port))
+	}
+	if s := f.Sinks.Events; s != "" {
+		if !contains(eventSinks, s) {
+			errs = append(errs, fmt.Errorf("sinks.events: %q is not one of %s", s, strings.Join(eventSinks, ", ")))
+		} else if s == "mongodb" && f.Sinks.MongoURI == "" && getEnv("MONGODB_URI", "") == "" {
+			errs = append(errs, errors.New("sinks.mongodb_uri: must be set for the mongodb event sink"))
+		}
+	}
+	if f.Log.Level != "" {
+		if _, err := ParseLogLevel(f.Log.Level); err != nil {
+			errs = append(errs, fmt.Errorf("log.level: %v", err))
+		}
+	}
+	if f.Log.Format != "" && f.Log.Format != "json" && f.Log.Format != "text" {
+		errs = append(errs, fmt.Errorf("log.format: %q is not json or text", f.Log.Format))
+	}
+	if _, err := ParseLogModules(f.Log.Modules); err != nil {
+		errs = append(errs, fmt.Errorf("log.modules: %v", err))
+	}
+	if f.Sinks.NDJSON == "-" && f.Sinks.Events == "stdout" {
+		errs = append(errs, errors.New("sinks: ndjson and events cannot both be written to stdout"))
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// contains tells whether values holds value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Env returns the environment variables the settings of the file stand for, by name. The settings left out of
+// the file are left out too.
+func (f File) Env() map[string]string {
+	env := map[string]string{}
+	collectEnv(reflect.ValueOf(f), env)
+	return env
+}
+
+// collectEnv adds the fields of v with an env tag and a non-zero value to env, descending into the nested structs.
+func collectEnv(v reflect.Value, env map[string]string) {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" {
+			if value.Kind() == reflect.Struct {
+				collectEnv(value, env)
+			}
+			continue
+		}
+		if value.IsZero() {
+			continue
+		}
+		switch value.Kind() {
+		case reflect.String:
+			env[name] = value.String()
+		case reflect.Int:
+			env[name] = strconv.FormatInt(value.Int(), 10)
+		case reflect.Pointer:
+			env[name] = strconv.FormatBool(value.Elem().Bool())
+		case reflect.Slice:
+			env[name] = strings.Join(value.Interface().([]string), ",")
+		}
+	}
+}
+
+// Overrides returns the names of the environment variables set that override settings of the file, sorted.
+func (f File) Overrides() []string {
+	var overrides []string
+	for name := range f.Env() {
+		if _, set := os.LookupEnv(name); set {
+			overrides = append(overrides, name)
+		}
+	}
+	sort.Strings(overrides)
+	return overrides
+}
+
+// Apply sets the environment variables the settings of the file stand for, except those already set.
+func (f File) Apply() error {
+	for name, value := range f.Env() {
+		if _, set := os.LookupEnv(name); set {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return fmt.Errorf("failed to set %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// LoadAssertions returns the assertions of the file, or those of ASSERTIONS_FILE when it is set or the file
+// has none.
+func (f File) LoadAssertions() (Assertions, error) {
+	if f.Assertions != nil && getEnv("ASSERTIONS_FILE", "") == "" {
+		return *f.Assertions, nil
+	}
+	return LoadAssertions()
+}
@@ -0,0 +1,29 @@
+package config
+
+// IssuesConfig configures the issue trackers issues are opened in for the findings of at least MinSeverity.
+// A tracker is enabled when its repository or project is set.
+type IssuesConfig struct {
+	MinSeverity   string
+	GitHubAPI     string
+	GitHubRepo    string
+	GitHubToken   string
+	JiraURL       string
+	JiraProject   string
+	JiraIssueType string
+	JiraUser      string
+	JiraToken     string
+}
+
+func (i *IssuesConfig) Load() IssuesConfig {
+	i.MinSeverity = getEnv("ISSUES_MIN_SEVERITY", "high")
+	i.GitHubAPI = getEnv("GITHUB_API_URL", "https://api.github.com")
+	i.GitHubRepo = getEnv("GITHUB_ISSUES_REPO", "")
+	i.GitHubToken = getEnv("GITHUB_TOKEN", "")
+	i.JiraURL = getEnv("JIRA_URL", "")
+	i.JiraProject = getEnv("JIRA_PROJECT", "")
+	i.JiraIssueType = getEnv("JIRA_ISSUE_TYPE", "Bug")
+	i.JiraUser = getEnv("JIRA_USER", "")
+	i.JiraToken = getEnv("JIRA_TOKEN", "")
+
+	return *i
+}
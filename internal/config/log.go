@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// LogConfig holds the logging settings: the Level of the records written, their Format, json or text, and the
+// File they are appended to, stdout or stderr when empty. Modules raise or lower the level of the records of
+// packages, as package=level pairs, e.g. browser=debug or database=warn.
+type LogConfig struct {
+	Level   string
+	Format  string
+	File    string
+	Modules []string
+}
+
+func (l *LogConfig) Load() LogConfig {
+	l.Level = getEnv("LOG_LEVEL", "info")
+	l.Format = getEnv("LOG_FORMAT", "json")
+	l.File = getEnv("LOG_FILE", "")
+	l.Modules = getEnvList("LOG_MODULES")
+
+	return *l
+}
+
+// ParseLogLevel parses a log level: debug, info, warn or error, case insensitive.
+func ParseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, fmt.Errorf("invalid log level %q, use debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
+// ParseLogModules parses the package=level pairs of the module levels, by package.
+func ParseLogModules(modules []string) (map[string]slog.Level, error) {
+	levels := map[string]slog.Level{}
+	for _, m := range modules {
+		name, value, ok := strings.Cut(m, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid log module %q, use package=level", m)
+		}
+		level, err := ParseLogLevel(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		levels[strings.TrimSpace(name)] = level
+	}
+	return levels, nil
+}
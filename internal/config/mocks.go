@@ -0,0 +1,54 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Mock serves the requests to the URLs matching URL, a scope rule, from the fixture file File, with the
+// status and headers given. Relative fixture paths are resolved from the directory of the mocks file.
+type Mock struct {
+	URL     string            `json:"url"`
+	File    string            `json:"file"`
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the content of the fixture file
+	Body []byte `json:"-"`
+}
+
+// LoadMocks reads the mocks from the JSON file set in MOCKS_FILE, along with their fixtures.
+// It returns no mocks when the variable is not set.
+func LoadMocks() ([]Mock, error) {
+	path := getEnv("MOCKS_FILE", "")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mocks file: %v", err)
+	}
+	var mocks []Mock
+	if err = json.Unmarshal(data, &mocks); err != nil {
+		return nil, fmt.Errorf("failed to parse mocks file: %v", err)
+	}
+
+	for i, mock := range mocks {
+		if mock.URL == "" {
+			return nil, fmt.Errorf("mock %d has no url", i)
+		}
+		if mock.File == "" {
+			continue
+		}
+		fixture := mock.File
+		if !filepath.IsAbs(fixture) {
+			fixture = filepath.Join(filepath.Dir(path), fixture)
+		}
+		if mocks[i].Body, err = os.ReadFile(fixture); err != nil {
+			return nil, fmt.Errorf("failed to read fixture of mock %s: %v", mock.URL, err)
+		}
+	}
+	return mocks, nil
+}
@@ -0,0 +1,15 @@
+package config
+
+// NetLogConfig holds the directory the Chrome network logs recorded with --netlog are written to, and
+// their capture mode.
+type NetLogConfig struct {
+	Dir         string
+	CaptureMode string
+}
+
+func (n *NetLogConfig) Load() NetLogConfig {
+	n.Dir = getEnv("NETLOG_DIR", "netlogs")
+	n.CaptureMode = getEnv("NETLOG_CAPTURE_MODE", "Default")
+
+	return *n
+}
@@ -0,0 +1,15 @@
+package config
+
+// NotifyConfig holds the webhooks the summary of every finished run is posted to, and the secret the
+// notifications are signed with, unsigned when empty.
+type NotifyConfig struct {
+	URLs   []string
+	Secret string
+}
+
+func (n *NotifyConfig) Load() NotifyConfig {
+	n.URLs = getEnvList("WEBHOOK_URLS")
+	n.Secret = getEnv("WEBHOOK_SECRET", "")
+
+	return *n
+}
@@ -0,0 +1,21 @@
+package config
+
+// OutputConfig sets the outputs the captured events are streamed to as they happen: an NDJSON file, a NATS
+// server publishing on subjects under NATSSubject, and a Kafka topic through a Kafka REST Proxy.
+type OutputConfig struct {
+	NDJSONPath   string
+	NATSURL      string
+	NATSSubject  string
+	KafkaRESTURL string
+	KafkaTopic   string
+}
+
+func (o *OutputConfig) Load() OutputConfig {
+	o.NDJSONPath = getEnv("OUTPUT_NDJSON", "")
+	o.NATSURL = getEnv("STREAM_NATS_URL", "")
+	o.NATSSubject = getEnv("STREAM_NATS_SUBJECT", "web-tester.events")
+	o.KafkaRESTURL = getEnv("STREAM_KAFKA_REST_URL", "")
+	o.KafkaTopic = getEnv("STREAM_KAFKA_TOPIC", "web-tester-events")
+
+	return *o
+}
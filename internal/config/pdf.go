@@ -0,0 +1,12 @@
+package config
+
+// PDFConfig holds the directory the PDFs of the pages printed with --pdf are written to.
+type PDFConfig struct {
+	Dir string
+}
+
+func (p *PDFConfig) Load() PDFConfig {
+	p.Dir = getEnv("PDF_DIR", "pdfs")
+
+	return *p
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// RedactionConfig holds the redaction rules applied to the captured traffic before it is stored or streamed: the
+// names of the headers and the paths of the JSON fields whose values are masked, and the pattern of the text masked
+// everywhere, nil when none is set.
+type RedactionConfig struct {
+	Headers []string
+	Fields  []string
+	Pattern *regexp.Regexp
+}
+
+func (r *RedactionConfig) Load() (RedactionConfig, error) {
+	r.Headers = getEnvList("REDACT_HEADERS")
+	r.Fields = getEnvList("REDACT_FIELDS")
+
+	if pattern := getEnv("REDACT_PATTERN", ""); pattern != "" {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return *r, fmt.Errorf("invalid REDACT_PATTERN: %v", err)
+		}
+		r.Pattern = compiled
+	}
+	return *r, nil
+}
@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+// ReplayConfig holds the limits of the replays of captured requests: the requests replayed at most by a replay, and
+// the timeout of each of them.
+type ReplayConfig struct {
+	MaxRequests int
+	Timeout     time.Duration
+}
+
+func (r *ReplayConfig) Load() ReplayConfig {
+	r.MaxRequests = getEnvInt("REPLAY_MAX_REQUESTS", 100)
+	r.Timeout = time.Duration(getEnvInt("REPLAY_TIMEOUT_SECONDS", 10)) * time.Second
+
+	return *r
+}
@@ -0,0 +1,14 @@
+package config
+
+// ReportConfig holds the format of the reports and the path of a custom template replacing the embedded one.
+type ReportConfig struct {
+	Format   string
+	Template string
+}
+
+func (r *ReportConfig) Load() ReportConfig {
+	r.Format = getEnv("REPORT_FORMAT", "html")
+	r.Template = getEnv("REPORT_TEMPLATE", "")
+
+	return *r
+}
@@ -0,0 +1,13 @@
+package config
+
+// RetentionConfig is the retention policy of serve mode: the runs older than Days days are pruned along with
+// their events and artifacts every hour. A zero Days keeps every run.
+type RetentionConfig struct {
+	Days int
+}
+
+func (r *RetentionConfig) Load() RetentionConfig {
+	r.Days = getEnvInt("RETENTION_DAYS", 0)
+
+	return *r
+}
@@ -0,0 +1,23 @@
+package config
+
+// SamplingConfig bounds the events stored per run. EveryN stores every Nth request, MaxPerType and
+// MaxPerDomain cap the requests stored per content type and per domain. Zero values disable an option.
+// ReviewBodies response bodies of every run, of the ReviewTypes media types or any when empty, are picked
+// at random for the review queue.
+type SamplingConfig struct {
+	EveryN       int
+	MaxPerType   int
+	MaxPerDomain int
+	ReviewBodies int
+	ReviewTypes  []string
+}
+
+func (s *SamplingConfig) Load() SamplingConfig {
+	s.EveryN = getEnvInt("SAMPLE_EVERY_N", 1)
+	s.MaxPerType = getEnvInt("SAMPLE_MAX_PER_TYPE", 0)
+	s.MaxPerDomain = getEnvInt("SAMPLE_MAX_PER_DOMAIN", 0)
+	s.ReviewBodies = getEnvInt("REVIEW_SAMPLE_BODIES", 0)
+	s.ReviewTypes = getEnvList("REVIEW_SAMPLE_TYPES")
+
+	return *s
+}
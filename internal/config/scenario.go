@@ -0,0 +1,48 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Scenario is a user journey run on the page once it has loaded, e.g. a checkout. Its milestone steps
+// mark the points of the journey the elapsed time and request counts are reported between.
+type Scenario struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+// Step is a single action of a scenario. Action is one of "navigate" (URL), "click" and "wait_visible"
+// (Selector), "type" (Selector and Value), "upload" (Selector of a file input and Files), "sleep" (Seconds) and
+// "milestone" (Name).
+type Step struct {
+	Action   string  `json:"action"`
+	Name     string  `json:"name,omitempty"`
+	URL      string  `json:"url,omitempty"`
+	Selector string  `json:"selector,omitempty"`
+	Value    string  `json:"value,omitempty"`
+	Seconds  float64 `json:"seconds,omitempty"`
+	// Files are the paths of the files an upload step sets, relative to the upload directory
+	Files []string `json:"files,omitempty"`
+}
+
+// LoadScenario reads the scenario from the JSON file set in SCENARIO_FILE.
+// It returns an empty scenario when the variable is not set.
+func LoadScenario() (Scenario, error) {
+	scenario := Scenario{}
+
+	path := getEnv("SCENARIO_FILE", "")
+	if path == "" {
+		return scenario, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return scenario, fmt.Errorf("failed to read scenario file: %v", err)
+	}
+	if err = json.Unmarshal(data, &scenario); err != nil {
+		return scenario, fmt.Errorf("failed to parse scenario file: %v", err)
+	}
+	return scenario, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Schedule is a target re-run in serve mode whenever its cron expression matches. StorageState is the path
+// of a storage state file the runs start from, e.g. to test an authenticated area without logging in.
+// Session is the path of a session file the runs start from, when it exists, and save their session to,
+// so a session refreshed by the site carries over to the next run.
+type Schedule struct {
+	ID           string  `json:"id"`
+	Target       string  `json:"target"`
+	Cron         string  `json:"cron"`
+	WaitSeconds  float64 `json:"wait_seconds"`
+	StorageState string  `json:"storage_state,omitempty"`
+	Session      string  `json:"session,omitempty"`
+}
+
+// LoadSchedules reads the schedules from the JSON file set in SCHEDULES_FILE.
+// It returns no schedules when the variable is not set.
+func LoadSchedules() ([]Schedule, error) {
+	path := getEnv("SCHEDULES_FILE", "")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules file: %v", err)
+	}
+	var schedules []Schedule
+	if err = json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules file: %v", err)
+	}
+	return schedules, nil
+}
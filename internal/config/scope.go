@@ -0,0 +1,14 @@
+package config
+
+// ScopeConfig holds the comma separated URL rules restricting the requests persisted by a run.
+type ScopeConfig struct {
+	Include []string
+	Exclude []string
+}
+
+func (s *ScopeConfig) Load() ScopeConfig {
+	s.Include = getEnvList("SCOPE_INCLUDE_URL")
+	s.Exclude = getEnvList("SCOPE_EXCLUDE_URL")
+
+	return *s
+}
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// ScrollConfig holds the strategy of the scrolls to the end of the page started with --scroll: the scrolls made at
+// most, the delay waited after every one of them, and the scrolls in a row the page must not grow for to be stable.
+type ScrollConfig struct {
+	MaxIterations int
+	Delay         time.Duration
+	Stable        int
+}
+
+func (s *ScrollConfig) Load() ScrollConfig {
+	s.MaxIterations = getEnvInt("SCROLL_MAX_ITERATIONS", 20)
+	s.Delay = time.Duration(getEnvInt("SCROLL_DELAY_MS", 1000)) * time.Millisecond
+	s.Stable = getEnvInt("SCROLL_STABLE_ITERATIONS", 2)
+
+	return *s
+}
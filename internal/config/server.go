@@ -0,0 +1,15 @@
+package config
+
+type ServerConfig struct {
+	Addr      string
+	Workers   int
+	QueueSize int
+}
+
+func (s *ServerConfig) Load() ServerConfig {
+	s.Addr = getEnv("SERVER_ADDR", ":8080")
+	s.Workers = getEnvInt("SERVER_WORKERS", 2)
+	s.QueueSize = getEnvInt("SERVER_QUEUE_SIZE", 100)
+
+	return *s
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ShardConfig holds the URL list a run tests and the shard of it this process runs. The URLs of the list
+// are split deterministically across ShardTotal processes, each running the URLs of its ShardIndex, and
+// the runs of every shard are tagged with SuiteID so they can be aggregated afterward.
+type ShardConfig struct {
+	URLsFile   string
+	ShardIndex int
+	ShardTotal int
+	SuiteID    string
+}
+
+func (s *ShardConfig) Load() ShardConfig {
+	s.URLsFile = getEnv("URLS_FILE", "")
+	s.ShardIndex = getEnvInt("SHARD_INDEX", 0)
+	s.ShardTotal = getEnvInt("SHARD_TOTAL", 1)
+	s.SuiteID = getEnv("SUITE_ID", "")
+
+	return *s
+}
+
+// LoadURLs reads the URL list file, one URL per line. Blank lines and lines starting with # are skipped,
+// and duplicates are dropped. The URLs are returned sorted so every shard sees the same order whatever
+// the order of the file.
+func LoadURLs(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read urls file: %v", err)
+	}
+	defer file.Close()
+
+	seen := map[string]bool{}
+	var urls []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read urls file: %v", err)
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
+
+// Shard returns the URLs of the given shard, dealing the URLs round-robin across the shards so they get
+// the same number of URLs, give or take one.
+func Shard(urls []string, index, total int) ([]string, error) {
+	if total < 1 {
+		return nil, fmt.Errorf("shard total must be at least 1, got %d", total)
+	}
+	if index < 0 || index >= total {
+		return nil, fmt.Errorf("shard index must be between 0 and %d, got %d", total-1, index)
+	}
+	var shard []string
+	for i, url := range urls {
+		if i%total == index {
+			shard = append(shard, url)
+		}
+	}
+	return shard, nil
+}
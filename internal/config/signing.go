@@ -0,0 +1,15 @@
+package config
+
+// SigningConfig holds the Ed25519 keys exported files are signed and verified with, as PEM files. Without
+// a public key, signatures are verified with the public key of the private key.
+type SigningConfig struct {
+	PrivateKeyFile string
+	PublicKeyFile  string
+}
+
+func (s *SigningConfig) Load() SigningConfig {
+	s.PrivateKeyFile = getEnv("SIGNING_KEY_FILE", "")
+	s.PublicKeyFile = getEnv("SIGNING_PUBLIC_KEY_FILE", "")
+
+	return *s
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// TelemetryConfig sets the OTLP collector the spans of the runs are exported to, from the standard variables
+// of the OpenTelemetry SDKs. Tracing is disabled when Endpoint is empty. Headers are sent with every export,
+// e.g. to authenticate.
+type TelemetryConfig struct {
+	Endpoint string
+	Headers  map[string]string
+	Service  string
+}
+
+func (t *TelemetryConfig) Load() TelemetryConfig {
+	t.Endpoint = getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Service = getEnv("OTEL_SERVICE_NAME", "web-tester")
+	t.Headers = map[string]string{}
+	for _, header := range getEnvList("OTEL_EXPORTER_OTLP_HEADERS") {
+		name, value, ok := strings.Cut(header, "=")
+		if !ok {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+		t.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	return *t
+}
@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// TimeoutConfig holds the timeouts of every run: Run is the deadline of the whole run, after which the browser
+// is killed, Navigation bounds the load of the target, BodyFetch the fetch of every response body and Idle the
+// wait for the network of the page to be idle once loaded. Every timeout but Run is disabled when 0.
+type TimeoutConfig struct {
+	Run        time.Duration
+	Navigation time.Duration
+	BodyFetch  time.Duration
+	Idle       time.Duration
+}
+
+func (t *TimeoutConfig) Load() TimeoutConfig {
+	t.Run = time.Duration(getEnvInt("RUN_TIMEOUT_SECONDS", 60)) * time.Second
+	t.Navigation = time.Duration(getEnvInt("NAVIGATION_TIMEOUT_SECONDS", 30)) * time.Second
+	t.BodyFetch = time.Duration(getEnvInt("BODY_FETCH_TIMEOUT_SECONDS", 10)) * time.Second
+	t.Idle = time.Duration(getEnvInt("IDLE_TIMEOUT_SECONDS", 0)) * time.Second
+
+	return *t
+}
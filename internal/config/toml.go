@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML parses the subset of TOML used by configuration files: tables, dotted table names, key = value pairs
+// of strings, integers, floats, booleans and single-line arrays of those, and comments. Inline tables, arrays of
+// tables, dotted keys, multi-line strings and arrays, and dates are not supported, failing with an unsupportedError
+// naming them.
+func parseTOML(data []byte) (interface{}, error) {
+	root := map[string]interface{}{}
+	table := root
+	for i, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(stripComment(raw))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: %w", i+1, unsupportedError{"arrays of tables"})
+			}
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid table header %s", i+1, line)
+			}
+			table = root
+			for _, name := range strings.Split(line[1:len(line)-1], ".") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					return nil, fmt.Errorf("line %d: invalid table name %s", i+1, line)
+				}
+				child, ok := table[name]
+				if !ok {
+					child = map[string]interface{}{}
+					table[name] = child
+				}
+				if table, ok = child.(map[string]interface{}); !ok {
+					return nil, fmt.Errorf("line %d: %s is not a table", i+1, name)
+				}
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !strings.HasPrefix(key, `"`) && strings.Contains(key, ".") {
+			return nil, fmt.Errorf("line %d: %w, use a [table]", i+1, unsupportedError{"dotted keys"})
+		}
+		key = strings.Trim(key, `"`)
+		if _, dup := table[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", i+1, key)
+		}
+		parsed, err := parseTOMLValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		table[key] = parsed
+	}
+	return root, nil
+}
+
+// parseTOMLValue parses a value: an array of scalars or a scalar.
+func parseTOMLValue(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, "{"):
+		return nil, unsupportedError{"inline tables"}
+	case strings.HasPrefix(text, `"""`), strings.HasPrefix(text, "'''"):
+		return nil, unsupportedError{"multi-line strings"}
+	}
+	if strings.HasPrefix(text, "[") {
+		if !strings.HasSuffix(text, "]") {
+			return nil, unsupportedError{"multi-line arrays"}
+		}
+		items, err := splitFlow(text[1 : len(text)-1])
+		if err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for _, item := range items {
+			value, err := parseTOMLScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+	return parseTOMLScalar(text)
+}
+
+// parseTOMLScalar parses a basic or literal string, a boolean or a number. Unlike YAML, strings must be quoted.
+func parseTOMLScalar(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", text)
+		}
+		return s, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") || strings.Contains(text[1:len(text)-1], "'") {
+			return nil, fmt.Errorf("invalid literal string %s", text)
+		}
+		return text[1 : len(text)-1], nil
+	case text == "true":
+		return true, nil
+	case text == "false":
+		return false, nil
+	}
+	digits := strings.ReplaceAll(text, "_", "")
+	if n, err := strconv.ParseInt(digits, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(digits, 64); err == nil {
+		return f, nil
+	}
+	if len(text) >= 10 && text[4] == '-' && text[7] == '-' {
+		return nil, unsupportedError{"dates and times"}
+	}
+	return nil, fmt.Errorf("invalid value %s, strings must be quoted", text)
+}
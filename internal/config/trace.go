@@ -0,0 +1,12 @@
+package config
+
+// TraceConfig holds the directory the Chrome traces recorded with --trace are written to.
+type TraceConfig struct {
+	Dir string
+}
+
+func (t *TraceConfig) Load() TraceConfig {
+	t.Dir = getEnv("TRACE_DIR", "traces")
+
+	return *t
+}
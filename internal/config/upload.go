@@ -0,0 +1,13 @@
+package config
+
+// UploadConfig holds the directory of the files the upload steps of the scenarios may set in the file inputs of
+// the page. An empty Dir disables the upload steps.
+type UploadConfig struct {
+	Dir string
+}
+
+func (u *UploadConfig) Load() UploadConfig {
+	u.Dir = getEnv("UPLOAD_DIR", "uploads")
+
+	return *u
+}
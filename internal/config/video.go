@@ -0,0 +1,15 @@
+package config
+
+// VideoConfig holds the directory the videos of the sessions recorded with --video are written to, and
+// their frame rate.
+type VideoConfig struct {
+	Dir string
+	FPS int
+}
+
+func (v *VideoConfig) Load() VideoConfig {
+	v.Dir = getEnv("VIDEO_DIR", "videos")
+	v.FPS = getEnvInt("VIDEO_FPS", 10)
+
+	return *v
+}
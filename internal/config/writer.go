@@ -0,0 +1,31 @@
+package config
+
+// WriterConfig sizes the pipeline storing the captured events: Workers insert batches of up to BatchSize
+// events, queued in a buffer of QueueSize events that blocks the run when full. Sink is the backend the events
+// are stored to: postgres, file, writing a file per test in SinkDir, stdout, mongodb, storing a document per
+// transaction in MongoCollection of MongoDatabase on the server of MongoURI, or none. MongoDatabase is empty when
+// not set, the database of MongoURI being used then.
+type WriterConfig struct {
+	Workers   int
+	BatchSize int
+	QueueSize int
+	Sink      string
+	SinkDir   string
+
+	MongoURI        string
+	MongoDatabase   string
+	MongoCollection string
+}
+
+func (w *WriterConfig) Load() WriterConfig {
+	w.Workers = getEnvInt("DB_WRITE_WORKERS", 4)
+	w.BatchSize = getEnvInt("DB_WRITE_BATCH_SIZE", 500)
+	w.QueueSize = getEnvInt("DB_WRITE_QUEUE_SIZE", 2000)
+	w.Sink = getEnv("EVENT_SINK", "postgres")
+	w.SinkDir = getEnv("EVENT_SINK_DIR", "events")
+	w.MongoURI = getEnv("MONGODB_URI", "")
+	w.MongoDatabase = getEnv("MONGODB_DATABASE", "")
+	w.MongoCollection = getEnv("MONGODB_COLLECTION", "transactions")
+
+	return *w
+}
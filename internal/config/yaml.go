@@ -0,0 +1,254 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a significant line of a YAML document, without its comment and indentation.
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// unsupportedError is the error of a construct of YAML or TOML that configuration files cannot use.
+type unsupportedError struct {
+	construct string
+}
+
+func (e unsupportedError) Error() string {
+	return e.construct + " are not supported"
+}
+
+// parseYAML parses the subset of YAML used by configuration files: nested mappings, block and flow sequences of
+// scalars, plain and quoted scalars, and comments. Sequences of mappings, anchors and aliases, block scalars, flow
+// mappings, tags and multiple documents are not supported, failing with an unsupportedError naming them.
+func parseYAML(data []byte) (interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(stripComment(raw), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "---" && len(lines) > 0 {
+			return nil, fmt.Errorf("line %d: %w", i+1, unsupportedError{"multiple documents"})
+		}
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", i+1)
+		}
+		lines = append(lines, yamlLine{number: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, next, err := parseYAMLBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[next].number)
+	}
+	return value, nil
+}
+
+// parseYAMLBlock parses the mapping or sequence starting at lines[i], whose entries are indented by indent,
+// returning it along with the index of the line following it.
+func parseYAMLBlock(lines []yamlLine, i, indent int) (interface{}, int, error) {
+	if isYAMLEntry(lines[i].text) {
+		var list []interface{}
+		// the sequence ends at the first line that is not one of its entries, e.g. the next key of the mapping
+		// holding a sequence at the indentation of its key
+		for ; i < len(lines) && lines[i].indent == indent && isYAMLEntry(lines[i].text); i++ {
+			l := lines[i]
+			item := strings.TrimSpace(strings.TrimPrefix(l.text, "-"))
+			if _, _, isKey := splitYAMLKey(item); isKey || item == "" {
+				return nil, i, fmt.Errorf("line %d: %w", l.number, unsupportedError{"sequences of mappings"})
+			}
+			if isYAMLEntry(item) {
+				return nil, i, fmt.Errorf("line %d: %w", l.number, unsupportedError{"nested block sequences"})
+			}
+			value, err := parseYAMLValue(item)
+			if err != nil {
+				return nil, i, fmt.Errorf("line %d: %w", l.number, err)
+			}
+			list = append(list, value)
+		}
+		return list, i, nil
+	}
+
+	mapping := map[string]interface{}{}
+	for i < len(lines) && lines[i].indent == indent {
+		l := lines[i]
+		key, rest, ok := splitYAMLKey(l.text)
+		if !ok {
+			return nil, i, fmt.Errorf("line %d: expected a key: value mapping", l.number)
+		}
+		if _, dup := mapping[key]; dup {
+			return nil, i, fmt.Errorf("line %d: duplicate key %q", l.number, key)
+		}
+		i++
+
+		if rest != "" {
+			value, err := parseYAMLValue(rest)
+			if err != nil {
+				return nil, i, fmt.Errorf("line %d: %w", l.number, err)
+			}
+			mapping[key] = value
+			continue
+		}
+		// a nested block is indented further, but a sequence may also sit at the indentation of its key
+		if i < len(lines) && (lines[i].indent > indent || lines[i].indent == indent && isYAMLEntry(lines[i].text)) {
+			value, next, err := parseYAMLBlock(lines, i, lines[i].indent)
+			if err != nil {
+				return nil, next, err
+			}
+			mapping[key], i = value, next
+			continue
+		}
+		mapping[key] = nil
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, i, fmt.Errorf("line %d: unexpected indentation", lines[i].number)
+	}
+	return mapping, i, nil
+}
+
+// isYAMLEntry tells whether the line is an entry of a block sequence.
+func isYAMLEntry(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits a "key: value" mapping entry, the value being empty for a nested block.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") || strings.HasPrefix(text, "[") {
+		return "", "", false
+	}
+	if strings.HasSuffix(text, ":") {
+		return strings.TrimSpace(strings.TrimSuffix(text, ":")), "", true
+	}
+	key, rest, ok = strings.Cut(text, ": ")
+	return strings.TrimSpace(key), strings.TrimSpace(rest), ok
+}
+
+// parseYAMLValue parses an inline value: a flow sequence of scalars or a scalar.
+func parseYAMLValue(text string) (interface{}, error) {
+	if strings.HasPrefix(text, "[") {
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("unterminated sequence %s", text)
+		}
+		items, err := splitFlow(text[1 : len(text)-1])
+		if err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for _, item := range items {
+			value, err := parseYAMLScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+	switch text[0] {
+	case '{':
+		return nil, unsupportedError{"flow mappings"}
+	case '|', '>':
+		return nil, unsupportedError{"block scalars (| and >)"}
+	case '&', '*':
+		return nil, unsupportedError{"anchors and aliases"}
+	case '!':
+		return nil, unsupportedError{"tags"}
+	}
+	return parseYAMLScalar(text)
+}
+
+// parseYAMLScalar parses a quoted or plain scalar, plain scalars being booleans, nulls and numbers when they
+// read as such.
+func parseYAMLScalar(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", text)
+		}
+		return s, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("invalid quoted string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	switch text {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return text, nil
+}
+
+// stripComment removes the comment ending the line, a # outside of quotes at its start or after a space. Only
+// the quotes starting a value quote, so the apostrophes of plain values do not.
+func stripComment(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.ContainsRune(" \t[,=", rune(line[i-1]))):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitFlow splits the items of a flow sequence on the commas outside of quotes, dropping a trailing comma.
+func splitFlow(text string) ([]string, error) {
+	var items []string
+	var quote rune
+	start := 0
+	for i, c := range text {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			return nil, unsupportedError{"nested sequences and mappings"}
+		case c == ',':
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quoted string in [%s]", text)
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" {
+		items = append(items, last)
+	}
+	for _, item := range items {
+		if item == "" {
+			return nil, fmt.Errorf("empty item in [%s]", text)
+		}
+	}
+	return items, nil
+}
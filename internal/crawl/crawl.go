@@ -0,0 +1,104 @@
+// Package crawl walks a site from a root page, following the links of every page to the pages of the same
+// origin, breadth first, and checks the status of every link found along the way.
+package crawl
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Page is a page of the crawl, found Depth links away from the root page.
+type Page struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// The states of the pages of a crawl: queued until they are crawled, then visited once loaded, or failed
+// when the browser could not load them.
+const (
+	StateQueued  = "queued"
+	StateVisited = "visited"
+	StateFailed  = "failed"
+)
+
+// Frontier is the queue of the pages left to crawl. The pages are taken in the order they were found, up to
+// maxPages, and only the pages of the origin of the root at most maxDepth links away from it are queued.
+type Frontier struct {
+	origin   string
+	maxPages int
+	maxDepth int
+	queue    []Page
+	seen     map[string]bool
+	taken    int
+}
+
+// NewFrontier creates the frontier of a crawl starting at root. A maxPages or maxDepth of 0 or less is unlimited.
+func NewFrontier(root string, maxPages, maxDepth int) (*Frontier, error) {
+	u, err := url.Parse(root)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid crawl root %q", root)
+	}
+	f := &Frontier{origin: u.Scheme + "://" + u.Host, maxPages: maxPages, maxDepth: maxDepth, seen: map[string]bool{}}
+	normalized, _ := Normalize(root)
+	f.seen[normalized] = true
+	f.queue = append(f.queue, Page{URL: normalized})
+	return f, nil
+}
+
+// Next takes the next page to crawl, returning false once the frontier is empty or maxPages were taken.
+func (f *Frontier) Next() (Page, bool) {
+	if len(f.queue) == 0 || (f.maxPages > 0 && f.taken >= f.maxPages) {
+		return Page{}, false
+	}
+	page := f.queue[0]
+	f.queue = f.queue[1:]
+	f.taken++
+	return page, true
+}
+
+// Add queues the links found on a page that are of the origin of the root, were not seen yet and are not
+// deeper than maxDepth, returning the pages it queued.
+func (f *Frontier) Add(from Page, links []string) []Page {
+	if f.maxDepth > 0 && from.Depth >= f.maxDepth {
+		return nil
+	}
+	var added []Page
+	for _, link := range links {
+		normalized, ok := Normalize(link)
+		if !ok || f.seen[normalized] || !f.SameOrigin(normalized) {
+			continue
+		}
+		f.seen[normalized] = true
+		page := Page{URL: normalized, Depth: from.Depth + 1}
+		f.queue = append(f.queue, page)
+		added = append(added, page)
+	}
+	return added
+}
+
+// Restore restores the frontier of an interrupted crawl: the pages it had seen, which are not queued again,
+// the pages left to crawl, in order, and the number of pages it had crawled, which count towards maxPages.
+func (f *Frontier) Restore(seen []Page, queued []Page, crawled int) {
+	for _, page := range seen {
+		f.seen[page.URL] = true
+	}
+	f.queue = append([]Page(nil), queued...)
+	f.taken = crawled
+}
+
+// SameOrigin tells whether the link is of the origin of the root.
+func (f *Frontier) SameOrigin(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && u.Scheme+"://"+u.Host == f.origin
+}
+
+// Normalize returns the link without its fragment, which points into the same document, and false for the
+// links that are not http or https, e.g. mailto: or javascript:.
+func Normalize(link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u.String(), true
+}
@@ -0,0 +1,123 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The ways the status of a link was found: by the browser when it crawled the page, or by a request of the checker.
+const (
+	CheckedByBrowser = "browser"
+	CheckedByHead    = "HEAD"
+	CheckedByGet     = "GET"
+)
+
+// Link is a link found on a page and the status it returned. Error is set when no response was received.
+type Link struct {
+	URL       string `json:"url"`
+	Status    int    `json:"status,omitempty"`
+	Error     string `json:"error,omitempty"`
+	CheckedBy string `json:"checked_by"`
+}
+
+// Broken tells whether the link is broken: it timed out or failed, or its page was not found, is gone or failed
+// on the server. Other client errors, e.g. 401 and 403, are often sent to robots and do not make a link broken.
+func (l Link) Broken() bool {
+	return l.Error != "" || l.Status == http.StatusNotFound || l.Status == http.StatusGone || l.Status >= 500
+}
+
+// Reason returns why a broken link is broken.
+func (l Link) Reason() string {
+	switch {
+	case l.Error == "timeout":
+		return "timeout"
+	case l.Error != "":
+		return "unreachable"
+	case l.Status == http.StatusNotFound:
+		return "not found"
+	case l.Status == http.StatusGone:
+		return "gone"
+	case l.Status >= 500:
+		return "server error"
+	}
+	return ""
+}
+
+// Checker finds the status of links, once per link. The status of the pages the browser crawled is the one
+// the browser received, the others are checked with a HEAD request, or a GET request when the server does
+// not allow HEAD, keeping to the politeness limits of hosts when set.
+type Checker struct {
+	client *http.Client
+	hosts  *Hosts
+	mu     sync.Mutex
+	links  map[string]Link
+}
+
+// NewChecker creates a Checker whose requests time out after timeout and are limited by hosts, unless nil.
+func NewChecker(timeout time.Duration, hosts *Hosts) *Checker {
+	return &Checker{client: &http.Client{Timeout: timeout}, hosts: hosts, links: map[string]Link{}}
+}
+
+// Visited records the status the browser received for a crawled page, or the error it failed with.
+func (c *Checker) Visited(url string, status int, err error) {
+	link := Link{URL: url, Status: status, CheckedBy: CheckedByBrowser}
+	if err != nil {
+		link.Error = err.Error()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.links[url] = link
+}
+
+// Check returns the status of a link, requesting it unless it was crawled or checked already.
+func (c *Checker) Check(ctx context.Context, url string) Link {
+	c.mu.Lock()
+	link, ok := c.links[url]
+	c.mu.Unlock()
+	if ok {
+		return link
+	}
+
+	link = c.request(ctx, http.MethodHead, url)
+	if link.Status == http.StatusMethodNotAllowed || link.Status == http.StatusNotImplemented {
+		link = c.request(ctx, http.MethodGet, url)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.links[url] = link
+	return link
+}
+
+// request requests a link, discarding the body of the response.
+func (c *Checker) request(ctx context.Context, method, url string) Link {
+	link := Link{URL: url, CheckedBy: method}
+	release, err := c.hosts.Acquire(ctx, url)
+	if err != nil {
+		link.Error = err.Error()
+		return link
+	}
+	defer release()
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		link.Error = err.Error()
+		return link
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		link.Error = err.Error()
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			link.Error = "timeout"
+		}
+		return link
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	link.Status = resp.StatusCode
+	return link
+}
@@ -0,0 +1,98 @@
+package crawl
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Politeness holds the limits a crawl keeps to towards every host, so it does not overload the sites it
+// crawls and checks: the page loads and link checks in progress at a time on a host, unlimited when 0, the
+// rate they are started at, unlimited when 0, and the random delay of up to Jitter added before each of them.
+type Politeness struct {
+	HostConcurrency int
+	HostRate        float64
+	Jitter          time.Duration
+}
+
+// Hosts keeps the page loads and link checks of a crawl to the Politeness limits of each host.
+type Hosts struct {
+	politeness Politeness
+	mu         sync.Mutex
+	hosts      map[string]*host
+}
+
+// host is the state of the requests to a host: the slots of the requests in progress and the earliest time
+// the next request may start.
+type host struct {
+	slots chan struct{}
+	next  time.Time
+}
+
+// NewHosts creates the Hosts keeping to politeness.
+func NewHosts(politeness Politeness) *Hosts {
+	return &Hosts{politeness: politeness, hosts: map[string]*host{}}
+}
+
+// Acquire waits for a request to the host of the link to be allowed to start, returning the function to call
+// once it is done. It returns the error of ctx when ctx is done first.
+func (h *Hosts) Acquire(ctx context.Context, link string) (func(), error) {
+	if h == nil {
+		return func() {}, nil
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return func() {}, nil
+	}
+
+	h.mu.Lock()
+	state, ok := h.hosts[u.Host]
+	if !ok {
+		state = &host{}
+		if h.politeness.HostConcurrency > 0 {
+			state.slots = make(chan struct{}, h.politeness.HostConcurrency)
+		}
+		h.hosts[u.Host] = state
+	}
+	h.mu.Unlock()
+
+	release := func() {}
+	if state.slots != nil {
+		select {
+		case state.slots <- struct{}{}:
+			release = func() { <-state.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// the requests to the host are spaced by the interval of the rate, in the order they got a slot
+	var wait time.Duration
+	if h.politeness.HostRate > 0 {
+		h.mu.Lock()
+		now := time.Now()
+		start := now
+		if state.next.After(now) {
+			start = state.next
+		}
+		state.next = start.Add(time.Duration(float64(time.Second) / h.politeness.HostRate))
+		h.mu.Unlock()
+		wait = start.Sub(now)
+	}
+	if h.politeness.Jitter > 0 {
+		wait += rand.N(h.politeness.Jitter)
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/inventory"
+
+	"github.com/google/uuid"
+)
+
+// InsertAPIEndpoint stores an endpoint of the APIs called by the page of a run as part of its API inventory.
+func InsertAPIEndpoint(logger *slog.Logger, db *sql.DB, testID uuid.UUID, e inventory.APIEndpoint) error {
+	if db == nil {
+		return nil
+	}
+	lists := map[string]interface{}{"request_types": e.RequestTypes, "response_types": e.ResponseTypes, "auth_headers": e.AuthHeaders,
+		"statuses": e.Statuses, "query_parameters": e.QueryParameters}
+	encoded := map[string]string{}
+	for name, list := range lists {
+		data, err := json.Marshal(list)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %v", name, err)
+		}
+		encoded[name] = string(data)
+	}
+
+	logger.Debug("Inserting into api_endpoints table", "test_id", testID.String(), "method", e.Method, "path", e.Path)
+	_, err := db.Exec(`INSERT INTO api_endpoints (test_id, method, origin, path, requests, request_types, response_types, auth_headers,
+		statuses, query_parameters) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		testID, e.Method, e.Origin, e.Path, e.Requests, encoded["request_types"], encoded["response_types"], encoded["auth_headers"],
+		encoded["statuses"], encoded["query_parameters"])
+	if err != nil {
+		return fmt.Errorf("failed to insert into api_endpoints table: %v", err)
+	}
+	return nil
+}
+
+// GetAPIEndpoints returns the API inventory of the given test, sorted by origin, path and method.
+func GetAPIEndpoints(db *sql.DB, testID uuid.UUID) ([]inventory.APIEndpoint, error) {
+	rows, err := db.Query(`SELECT method, origin, path, requests, request_types, response_types, auth_headers, statuses, query_parameters
+		FROM api_endpoints WHERE test_id = $1 ORDER BY origin, path, method`, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api_endpoints table: %v", err)
+	}
+	defer rows.Close()
+
+	var endpoints []inventory.APIEndpoint
+	for rows.Next() {
+		var e inventory.APIEndpoint
+		var requestTypes, responseTypes, auth, statuses, query []byte
+		if err = rows.Scan(&e.Method, &e.Origin, &e.Path, &e.Requests, &requestTypes, &responseTypes, &auth, &statuses, &query); err != nil {
+			return nil, fmt.Errorf("failed to scan api_endpoints row: %v", err)
+		}
+		for _, list := range []struct {
+			data []byte
+			into interface{}
+		}{{requestTypes, &e.RequestTypes}, {responseTypes, &e.ResponseTypes}, {auth, &e.AuthHeaders}, {statuses, &e.Statuses}, {query, &e.QueryParameters}} {
+			if err = json.Unmarshal(list.data, list.into); err != nil {
+				return nil, fmt.Errorf("failed to parse api endpoint: %v", err)
+			}
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, rows.Err()
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/browser"
+
+	"github.com/google/uuid"
+)
+
+// InsertFault stores a fault injected into a request of a run by the chaos mode.
+func InsertFault(logger *slog.Logger, db *sql.DB, testID uuid.UUID, fault browser.InjectedFault) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into faults table", "test_id", testID.String(), "url", fault.URL, "action", fault.Action)
+	_, err := db.Exec("INSERT INTO faults (test_id, url, action, detail) VALUES ($1, $2, $3, $4)",
+		testID, fault.URL, fault.Action, fault.Detail)
+	if err != nil {
+		return fmt.Errorf("failed to insert into faults table: %v", err)
+	}
+	return nil
+}
+
+// GetFaults returns the faults injected into the requests of the given test, in the order they were injected.
+func GetFaults(db *sql.DB, testID uuid.UUID) ([]browser.InjectedFault, error) {
+	rows, err := db.Query("SELECT url, action, detail FROM faults WHERE test_id = $1 ORDER BY created_at", testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query faults table: %v", err)
+	}
+	defer rows.Close()
+
+	var faults []browser.InjectedFault
+	for rows.Next() {
+		var f browser.InjectedFault
+		if err = rows.Scan(&f.URL, &f.Action, &f.Detail); err != nil {
+			return nil, fmt.Errorf("failed to scan faults row: %v", err)
+		}
+		faults = append(faults, f)
+	}
+	return faults, rows.Err()
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/browser"
+	"web-tester/internal/inventory"
+
+	"github.com/google/uuid"
+)
+
+// InsertCookie stores a cookie set in the browser during a run.
+func InsertCookie(logger *slog.Logger, db *sql.DB, testID uuid.UUID, cookie browser.Cookie) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into cookies table", "test_id", testID.String(), "name", cookie.Name, "domain", cookie.Domain)
+	var expires sql.NullTime
+	if !cookie.Expires.IsZero() {
+		expires = sql.NullTime{Time: cookie.Expires, Valid: true}
+	}
+	_, err := db.Exec(`INSERT INTO cookies (test_id, name, domain, path, expires, session, secure, http_only, same_site, size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		testID, cookie.Name, cookie.Domain, cookie.Path, expires, cookie.Session, cookie.Secure, cookie.HTTPOnly, cookie.SameSite, cookie.Size)
+	if err != nil {
+		return fmt.Errorf("failed to insert into cookies table: %v", err)
+	}
+	return nil
+}
+
+// InsertStorageItem stores a key set in the web storage of the page during a run.
+func InsertStorageItem(logger *slog.Logger, db *sql.DB, testID uuid.UUID, item browser.StorageItem) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into web_storage table", "test_id", testID.String(), "kind", item.Kind, "key", item.Key)
+	_, err := db.Exec("INSERT INTO web_storage (test_id, origin, kind, key, size) VALUES ($1, $2, $3, $4, $5)",
+		testID, item.Origin, item.Kind, item.Key, item.Size)
+	if err != nil {
+		return fmt.Errorf("failed to insert into web_storage table: %v", err)
+	}
+	return nil
+}
+
+// InsertStorageEntry stores a key of the web storage snapshot of a run, with its value.
+func InsertStorageEntry(logger *slog.Logger, db *sql.DB, testID uuid.UUID, entry browser.StorageEntry) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into storage_snapshot table", "test_id", testID.String(), "origin", entry.Origin, "key", entry.Key)
+	_, err := db.Exec("INSERT INTO storage_snapshot (test_id, origin, kind, key, value, looks) VALUES ($1, $2, $3, $4, $5, $6)",
+		testID, entry.Origin, entry.Kind, entry.Key, entry.Value, entry.Looks)
+	if err != nil {
+		return fmt.Errorf("failed to insert into storage_snapshot table: %v", err)
+	}
+	return nil
+}
+
+// GetCookies returns the cookies set during the given test.
+func GetCookies(db *sql.DB, testID uuid.UUID) ([]browser.Cookie, error) {
+	rows, err := db.Query(`SELECT name, domain, path, expires, session, secure, http_only, same_site, size
+		FROM cookies WHERE test_id = $1 ORDER BY domain, name`, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cookies table: %v", err)
+	}
+	defer rows.Close()
+
+	var cookies []browser.Cookie
+	for rows.Next() {
+		var c browser.Cookie
+		var expires sql.NullTime
+		if err = rows.Scan(&c.Name, &c.Domain, &c.Path, &expires, &c.Session, &c.Secure, &c.HTTPOnly, &c.SameSite, &c.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan cookies row: %v", err)
+		}
+		c.Expires = expires.Time
+		cookies = append(cookies, c)
+	}
+	return cookies, rows.Err()
+}
+
+// GetStorageItems returns the web storage keys set during the given test.
+func GetStorageItems(db *sql.DB, testID uuid.UUID) ([]browser.StorageItem, error) {
+	rows, err := db.Query("SELECT origin, kind, key, size FROM web_storage WHERE test_id = $1 ORDER BY origin, kind, key", testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query web_storage table: %v", err)
+	}
+	defer rows.Close()
+
+	var items []browser.StorageItem
+	for rows.Next() {
+		var item browser.StorageItem
+		if err = rows.Scan(&item.Origin, &item.Kind, &item.Key, &item.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan web_storage row: %v", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetStorageSnapshot returns the web storage snapshot of the given test.
+func GetStorageSnapshot(db *sql.DB, testID uuid.UUID) ([]browser.StorageEntry, error) {
+	rows, err := db.Query("SELECT origin, kind, key, value, looks FROM storage_snapshot WHERE test_id = $1 ORDER BY origin, kind, key", testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query storage_snapshot table: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []browser.StorageEntry
+	for rows.Next() {
+		var e browser.StorageEntry
+		if err = rows.Scan(&e.Origin, &e.Kind, &e.Key, &e.Value, &e.Looks); err != nil {
+			return nil, fmt.Errorf("failed to scan storage_snapshot row: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetDomains returns the domain inventory of the given test.
+func GetDomains(db *sql.DB, testID uuid.UUID) ([]inventory.Domain, error) {
+	rows, err := db.Query("SELECT domain, first_party, tracker, requests, bytes FROM domains WHERE test_id = $1 ORDER BY requests DESC, domain", testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query domains table: %v", err)
+	}
+	defer rows.Close()
+
+	var domains []inventory.Domain
+	for rows.Next() {
+		var d inventory.Domain
+		if err = rows.Scan(&d.Domain, &d.FirstParty, &d.Tracker, &d.Requests, &d.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan domains row: %v", err)
+		}
+		domains = append(domains, d)
+	}
+	return domains, rows.Err()
+}
+
+// InsertEdgeTiming stores the attribution of the latency of a registrable domain to its CDN edge and origin.
+func InsertEdgeTiming(logger *slog.Logger, db *sql.DB, testID uuid.UUID, edge inventory.Edge) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into edge_timing table", "test_id", testID.String(), "domain", edge.Domain)
+	_, err := db.Exec(`INSERT INTO edge_timing (test_id, domain, cdn, responses, hits, misses, edge_latency, origin_latency, server_time, server_timed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		testID, edge.Domain, edge.CDN, edge.Responses, edge.Hits, edge.Misses, edge.EdgeLatency, edge.OriginLatency, edge.ServerTime, edge.ServerTimed)
+	if err != nil {
+		return fmt.Errorf("failed to insert into edge_timing table: %v", err)
+	}
+	return nil
+}
+
+// GetEdgeTiming returns the latency attribution of every registrable domain of the given test, by decreasing response count.
+func GetEdgeTiming(db *sql.DB, testID uuid.UUID) ([]inventory.Edge, error) {
+	rows, err := db.Query(`SELECT domain, cdn, responses, hits, misses, edge_latency, origin_latency, server_time, server_timed
+		FROM edge_timing WHERE test_id = $1 ORDER BY responses DESC, domain`, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query edge_timing table: %v", err)
+	}
+	defer rows.Close()
+
+	var edges []inventory.Edge
+	for rows.Next() {
+		var e inventory.Edge
+		if err = rows.Scan(&e.Domain, &e.CDN, &e.Responses, &e.Hits, &e.Misses, &e.EdgeLatency, &e.OriginLatency, &e.ServerTime, &e.ServerTimed); err != nil {
+			return nil, fmt.Errorf("failed to scan edge_timing row: %v", err)
+		}
+		edges = append(edges, e)
+	}
+	return edges, rows.Err()
+}
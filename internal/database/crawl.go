@@ -0,0 +1,146 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+	"web-tester/internal/crawl"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// The statuses of a crawl.
+const (
+	CrawlRunning  = "running"
+	CrawlFinished = "finished"
+)
+
+// Crawl is a crawl of a site from its Root page, with the bounds it was started with.
+type Crawl struct {
+	CrawlID    uuid.UUID `json:"crawl_id"`
+	Root       string    `json:"root"`
+	SuiteID    string    `json:"suite_id"`
+	MaxPages   int       `json:"max_pages"`
+	MaxDepth   int       `json:"max_depth"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// CrawlPage is a page of the frontier of a crawl. Once crawled, TestID is the test of the page, Status the
+// status of its document and Links the links found on it, and LinksChecked is set once they were checked and
+// stored.
+type CrawlPage struct {
+	crawl.Page
+	State        string    `json:"state"`
+	TestID       uuid.UUID `json:"test_id,omitempty"`
+	Status       int       `json:"status,omitempty"`
+	Links        []string  `json:"links,omitempty"`
+	LinksChecked bool      `json:"links_checked"`
+}
+
+// InsertCrawl stores a crawl as it starts.
+func InsertCrawl(logger *slog.Logger, db *sql.DB, c Crawl) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into crawls table", "crawl_id", c.CrawlID.String(), "root", c.Root)
+	_, err := db.Exec("INSERT INTO crawls (crawl_id, root, suite_id, max_pages, max_depth, status) VALUES ($1, $2, $3, $4, $5, $6)",
+		c.CrawlID, c.Root, c.SuiteID, c.MaxPages, c.MaxDepth, CrawlRunning)
+	if err != nil {
+		return fmt.Errorf("failed to insert into crawls table: %v", err)
+	}
+	return nil
+}
+
+// FinishCrawl records that a crawl finished: every page of its frontier was crawled and their links checked.
+func FinishCrawl(logger *slog.Logger, db *sql.DB, crawlID uuid.UUID) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Updating crawls table", "crawl_id", crawlID.String())
+	if _, err := db.Exec("UPDATE crawls SET status = $2, finished_at = now() WHERE crawl_id = $1", crawlID, CrawlFinished); err != nil {
+		return fmt.Errorf("failed to update crawls table: %v", err)
+	}
+	return nil
+}
+
+// GetCrawl returns the crawl with the given ID, or the crawl one of whose pages was the given test.
+func GetCrawl(db *sql.DB, id uuid.UUID) (Crawl, error) {
+	var c Crawl
+	var finishedAt sql.NullTime
+	err := db.QueryRow(`SELECT crawl_id, root, suite_id, max_pages, max_depth, status, started_at, finished_at FROM crawls
+		WHERE crawl_id = $1 OR crawl_id IN (SELECT crawl_id FROM crawl_frontier WHERE test_id = $1)`, id).
+		Scan(&c.CrawlID, &c.Root, &c.SuiteID, &c.MaxPages, &c.MaxDepth, &c.Status, &c.StartedAt, &finishedAt)
+	if err != nil {
+		return c, fmt.Errorf("failed to query crawls table: %w", err)
+	}
+	c.FinishedAt = finishedAt.Time
+	return c, nil
+}
+
+// InsertCrawlPage queues a page in the frontier of a crawl, unless it is already in it.
+func InsertCrawlPage(logger *slog.Logger, db *sql.DB, crawlID uuid.UUID, page crawl.Page) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into crawl_frontier table", "crawl_id", crawlID.String(), "url", page.URL)
+	_, err := db.Exec(`INSERT INTO crawl_frontier (crawl_id, url, depth, state) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (crawl_id, url) DO NOTHING`, crawlID, page.URL, page.Depth, crawl.StateQueued)
+	if err != nil {
+		return fmt.Errorf("failed to insert into crawl_frontier table: %v", err)
+	}
+	return nil
+}
+
+// SetCrawlPage records the outcome of the crawl of a page of the frontier.
+func SetCrawlPage(logger *slog.Logger, db *sql.DB, crawlID uuid.UUID, page CrawlPage) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Updating crawl_frontier table", "crawl_id", crawlID.String(), "url", page.URL, "state", page.State)
+	_, err := db.Exec(`UPDATE crawl_frontier SET state = $3, test_id = $4, status = $5, links = $6, updated_at = now()
+		WHERE crawl_id = $1 AND url = $2`, crawlID, page.URL, page.State, page.TestID, page.Status, pq.Array(page.Links))
+	if err != nil {
+		return fmt.Errorf("failed to update crawl_frontier table: %v", err)
+	}
+	return nil
+}
+
+// SetLinksChecked records that the links of a crawled page were checked and stored.
+func SetLinksChecked(logger *slog.Logger, db *sql.DB, crawlID uuid.UUID, url string) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Updating crawl_frontier table", "crawl_id", crawlID.String(), "url", url, "links_checked", true)
+	_, err := db.Exec("UPDATE crawl_frontier SET links_checked = true, updated_at = now() WHERE crawl_id = $1 AND url = $2", crawlID, url)
+	if err != nil {
+		return fmt.Errorf("failed to update crawl_frontier table: %v", err)
+	}
+	return nil
+}
+
+// GetCrawlPages returns the frontier of a crawl, in the order the pages were queued.
+func GetCrawlPages(db *sql.DB, crawlID uuid.UUID) ([]CrawlPage, error) {
+	rows, err := db.Query(`SELECT url, depth, state, test_id, status, links, links_checked FROM crawl_frontier
+		WHERE crawl_id = $1 ORDER BY created_at, depth`, crawlID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl_frontier table: %v", err)
+	}
+	defer rows.Close()
+
+	var pages []CrawlPage
+	for rows.Next() {
+		var p CrawlPage
+		var testID uuid.NullUUID
+		var status sql.NullInt64
+		if err = rows.Scan(&p.URL, &p.Depth, &p.State, &testID, &status, pq.Array(&p.Links), &p.LinksChecked); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl_frontier row: %v", err)
+		}
+		p.TestID, p.Status = testID.UUID, int(status.Int64)
+		pages = append(pages, p)
+	}
+	return pages, rows.Err()
+}
@@ -7,37 +7,110 @@ import (
 	"log/slog"
 	"net/url"
 	"strings"
+	"time"
+	"web-tester/internal/audit"
+	"web-tester/internal/browser"
 	"web-tester/internal/config"
+	"web-tester/internal/inventory"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"github.com/chromedp/cdproto/network"
 	"github.com/google/uuid"
 )
 
-func InsertIntoDB(logger *slog.Logger, db *sql.DB, testID uuid.UUID, event struct {
-	RequestID network.RequestID
-	Type      string
-	URL       string
-	Content   interface{}
-	Body      []byte
-}) error {
+// Event is a captured request or response as stored in the events table.
+type Event struct {
+	RequestID    network.RequestID `json:"request_id"`
+	Type         string            `json:"type"`
+	URL          string            `json:"url"`
+	Content      interface{}       `json:"payload,omitempty"`
+	Body         []byte            `json:"body,omitempty"`
+	Status       int64             `json:"status,omitempty"`
+	ContentRange string            `json:"content_range,omitempty"`
+	Chunked      bool              `json:"chunked,omitempty"`
+	Parts        interface{}       `json:"parts,omitempty"`
+	Timing       browser.Timing    `json:"timing"`
+	BodySize     int               `json:"body_size,omitempty"`
+	BodyHash     string            `json:"body_hash,omitempty"`
+	BodyPath     string            `json:"body_path,omitempty"`
+	// BodyFetchStatus tells whether the body of a response was fetched and, when it was not, why, e.g. evicted
+	BodyFetchStatus string `json:"body_fetch_status,omitempty"`
+	// Source is the type of the worker that sent the request, or empty for the page
+	Source string `json:"source,omitempty"`
+	// FrameID and FrameURL are the frame that sent the request and the URL of its document
+	FrameID  string `json:"frame_id,omitempty"`
+	FrameURL string `json:"frame_url,omitempty"`
+}
+
+// eventColumns are the columns of the events table written for every event, in the order of eventArgs.
+const eventColumns = `test_id, type, domain, url, payload, body, status, content_range, chunked, parts,
+	dns_ms, connect_ms, tls_ms, ttfb_ms, download_ms, total_ms, encoded_bytes, body_size, body_hash, body_path, source,
+	frame_id, frame_url, body_fetch_status`
+
+// eventArgs returns the values of the event columns for an event.
+func eventArgs(logger *slog.Logger, testID uuid.UUID, event Event) ([]interface{}, error) {
 	eventJSON, err := json.Marshal(event.Content)
 	if err != nil {
-		logger.Error("failed to marshal event content: ", "error: ", err)
+		logger.Error("failed to marshal event content", "error", err)
 		eventJSON = []byte{}
 	}
 
+	partsJSON, err := json.Marshal(event.Parts)
+	if err != nil {
+		logger.Error("failed to marshal event parts", "error", err)
+		partsJSON = []byte("null")
+	}
+
 	parsedURL, err := url.Parse(event.URL)
 	if err != nil {
-		return fmt.Errorf("failed to parse URL: %v", err)
+		return nil, fmt.Errorf("failed to parse URL: %v", err)
 	}
 	host := parsedURL.Host
 	host = strings.Split(host, ":")[0]
 
-	logger.Debug("Inserting into events table: ", "testID: ", testID.String(), "type: ", event.Type, "domain: ", host)
-	_, err = db.Exec("INSERT INTO events (test_id, type, domain, payload, body) VALUES ($1, $2, $3, $4, $5)", testID, event.Type, host, string(eventJSON), event.Body)
+	t := event.Timing
+	return []interface{}{
+		testID, event.Type, host, event.URL, string(eventJSON), event.Body, event.Status, event.ContentRange, event.Chunked, string(partsJSON),
+		t.DNS, t.Connect, t.TLS, t.TTFB, t.Download, t.Total, t.EncodedBytes, event.BodySize, event.BodyHash, event.BodyPath, event.Source,
+		event.FrameID, event.FrameURL, event.BodyFetchStatus,
+	}, nil
+}
+
+// insertEventsQuery returns the statement inserting rows events, each taking columns arguments.
+func insertEventsQuery(rows, columns int) string {
+	var query strings.Builder
+	query.WriteString("INSERT INTO events (" + eventColumns + ") VALUES ")
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for j := 0; j < columns; j++ {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "$%d", i*columns+j+1)
+		}
+		query.WriteString(")")
+	}
+	return query.String()
+}
+
+// InsertIntoDB stores a captured event in the events table.
+// Like every write in this package, it is a no-op when db is nil, i.e. the database is disabled.
+// Runs storing many events use a Sink instead.
+func InsertIntoDB(logger *slog.Logger, db *sql.DB, testID uuid.UUID, event Event) error {
+	if db == nil {
+		return nil
+	}
+	args, err := eventArgs(logger, testID, event)
 	if err != nil {
+		return err
+	}
+
+	logger.Debug("Inserting into events table", "test_id", testID.String(), "type", event.Type, "url", event.URL)
+	if _, err = db.Exec(insertEventsQuery(1, len(args)), args...); err != nil {
 		return fmt.Errorf("failed to insert into events table: %v", err)
 	}
 	return nil
@@ -60,3 +133,157 @@ func Init(logger *slog.Logger, dbcfg config.DBConfig) (*sql.DB, error) {
 	logger.Info("Successfully connected to the database")
 	return db, nil
 }
+
+// InsertFinding stores a finding reported by an audit check.
+func InsertFinding(logger *slog.Logger, db *sql.DB, testID uuid.UUID, finding audit.Finding) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into findings table", "test_id", testID.String(), "check", finding.Check, "url", finding.URL)
+	_, err := db.Exec("INSERT INTO findings (test_id, check_name, severity, url, message, pages) VALUES ($1, $2, $3, $4, $5, $6)",
+		testID, finding.Check, finding.Severity, finding.URL, finding.Message, pq.Array(finding.Pages))
+	if err != nil {
+		return fmt.Errorf("failed to insert into findings table: %v", err)
+	}
+	return nil
+}
+
+// InsertDomain stores the traffic of a run to a registrable domain as part of its domain inventory.
+func InsertDomain(logger *slog.Logger, db *sql.DB, testID uuid.UUID, domain inventory.Domain) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into domains table", "test_id", testID.String(), "domain", domain.Domain)
+	_, err := db.Exec("INSERT INTO domains (test_id, domain, first_party, tracker, requests, bytes) VALUES ($1, $2, $3, $4, $5, $6)",
+		testID, domain.Domain, domain.FirstParty, domain.Tracker, domain.Requests, domain.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to insert into domains table: %v", err)
+	}
+	return nil
+}
+
+// InsertTLSDetails stores the TLS connection and certificate details of an origin contacted by a run.
+func InsertTLSDetails(logger *slog.Logger, db *sql.DB, testID uuid.UUID, details audit.TLSDetails) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into tls table", "test_id", testID.String(), "origin", details.Origin)
+	_, err := db.Exec("INSERT INTO tls (test_id, origin, protocol, cipher, subject, issuer, sans, valid_from, valid_to) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		testID, details.Origin, details.Protocol, details.Cipher, details.Subject, details.Issuer, pq.Array(details.SANs), details.ValidFrom, details.ValidTo)
+	if err != nil {
+		return fmt.Errorf("failed to insert into tls table: %v", err)
+	}
+	return nil
+}
+
+// InsertFuzzResult stores the outcome of replaying a request with a fuzzed parameter.
+func InsertFuzzResult(logger *slog.Logger, db *sql.DB, testID uuid.UUID, result audit.FuzzResult) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into fuzz_results table", "test_id", testID.String(), "url", result.URL, "parameter", result.Parameter)
+	_, err := db.Exec(`INSERT INTO fuzz_results (test_id, url, method, parameter, payload, baseline_status, status, baseline_length, length, reflected)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		testID, result.URL, result.Method, result.Parameter, result.Payload, result.BaselineStatus, result.Status, result.BaselineLength, result.Length, result.Reflected)
+	if err != nil {
+		return fmt.Errorf("failed to insert into fuzz_results table: %v", err)
+	}
+	return nil
+}
+
+// InsertEndpoint stores the result of probing a well-known endpoint as part of the target's profile.
+func InsertEndpoint(logger *slog.Logger, db *sql.DB, testID uuid.UUID, endpoint audit.Endpoint) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into profile table", "test_id", testID.String(), "endpoint", endpoint.Name, "present", endpoint.Present)
+	_, err := db.Exec("INSERT INTO profile (test_id, endpoint, url, status, present, content) VALUES ($1, $2, $3, $4, $5, $6)",
+		testID, endpoint.Name, endpoint.URL, endpoint.Status, endpoint.Present, endpoint.Content)
+	if err != nil {
+		return fmt.Errorf("failed to insert into profile table: %v", err)
+	}
+	return nil
+}
+
+// StoredEvent is an event as read back from the events table.
+type StoredEvent struct {
+	EventID      uuid.UUID       `json:"event_id"`
+	Type         string          `json:"type"`
+	Domain       string          `json:"domain"`
+	URL          string          `json:"url"`
+	Status       int64           `json:"status"`
+	Payload      json.RawMessage `json:"payload"`
+	Body         string          `json:"body,omitempty"`
+	EncodedBytes float64         `json:"encoded_bytes"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// Headers extracts the request or response headers from the stored CDP event payload.
+func (e StoredEvent) Headers() map[string]string {
+	var payload struct {
+		Request *struct {
+			Headers map[string]interface{} `json:"headers"`
+		} `json:"request"`
+		Response *struct {
+			Headers map[string]interface{} `json:"headers"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return nil
+	}
+
+	var raw map[string]interface{}
+	switch {
+	case payload.Response != nil:
+		raw = payload.Response.Headers
+	case payload.Request != nil:
+		raw = payload.Request.Headers
+	}
+	headers := make(map[string]string, len(raw))
+	for name, value := range raw {
+		headers[strings.ToLower(name)] = fmt.Sprint(value)
+	}
+	return headers
+}
+
+// GetEvents returns the events captured by the given test, in the order they were stored.
+func GetEvents(db *sql.DB, testID uuid.UUID) ([]StoredEvent, error) {
+	rows, err := db.Query(`SELECT event_id, type, domain, COALESCE(url, ''), COALESCE(status, 0), COALESCE(payload, 'null'), COALESCE(body, ''),
+		COALESCE(encoded_bytes, 0), created_at
+		FROM events WHERE test_id = $1 ORDER BY created_at`, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events table: %v", err)
+	}
+	defer rows.Close()
+
+	var events []StoredEvent
+	for rows.Next() {
+		var e StoredEvent
+		var payload []byte
+		if err = rows.Scan(&e.EventID, &e.Type, &e.Domain, &e.URL, &e.Status, &payload, &e.Body, &e.EncodedBytes, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan events row: %v", err)
+		}
+		e.Payload = payload
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetFindings returns the findings reported for the given test, with their remediation guidance.
+func GetFindings(db *sql.DB, testID uuid.UUID) ([]audit.Finding, error) {
+	rows, err := db.Query("SELECT check_name, severity, url, message, pages FROM findings WHERE test_id = $1 ORDER BY created_at", testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query findings table: %v", err)
+	}
+	defer rows.Close()
+
+	var findings []audit.Finding
+	for rows.Next() {
+		var f audit.Finding
+		if err = rows.Scan(&f.Check, &f.Severity, &f.URL, &f.Message, pq.Array(&f.Pages)); err != nil {
+			return nil, fmt.Errorf("failed to scan findings row: %v", err)
+		}
+		findings = append(findings, f)
+	}
+	return audit.Enrich(findings), rows.Err()
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// InsertDOM stores the rendered HTML of the page of a run, and its DOM snapshot when it was captured.
+func InsertDOM(logger *slog.Logger, db *sql.DB, testID uuid.UUID, html string, snapshot []byte) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into dom_snapshots table", "test_id", testID.String(), "html_bytes", len(html), "snapshot_bytes", len(snapshot))
+	var snapshotJSON interface{}
+	if len(snapshot) > 0 {
+		snapshotJSON = string(snapshot)
+	}
+	_, err := db.Exec("INSERT INTO dom_snapshots (test_id, html, snapshot) VALUES ($1, $2, $3)", testID, html, snapshotJSON)
+	if err != nil {
+		return fmt.Errorf("failed to insert into dom_snapshots table: %v", err)
+	}
+	return nil
+}
+
+// GetDOM returns the rendered HTML of the page of the given test, and its DOM snapshot, nil when it was not
+// captured. It returns sql.ErrNoRows when the test has neither.
+func GetDOM(db *sql.DB, testID uuid.UUID) (string, []byte, error) {
+	var html string
+	var snapshot []byte
+	err := db.QueryRow("SELECT COALESCE(html, ''), snapshot FROM dom_snapshots WHERE test_id = $1", testID).Scan(&html, &snapshot)
+	if err != nil {
+		return "", nil, err
+	}
+	return html, snapshot, nil
+}
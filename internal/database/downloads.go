@@ -0,0 +1,46 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/browser"
+
+	"github.com/google/uuid"
+)
+
+// InsertDownload stores a file downloaded by the page of a run. Its path is the reference of the file in the
+// artifact store once uploaded.
+func InsertDownload(logger *slog.Logger, db *sql.DB, testID uuid.UUID, d browser.Download) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into downloads table", "test_id", testID.String(), "url", d.URL, "bytes", d.Bytes)
+	_, err := db.Exec(`INSERT INTO downloads (test_id, guid, url, frame_id, filename, state, ref, bytes, sha256)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, NULLIF($9, ''))`,
+		testID, d.GUID, d.URL, d.FrameID, d.Filename, d.State, d.Path, d.Bytes, d.SHA256)
+	if err != nil {
+		return fmt.Errorf("failed to insert into downloads table: %v", err)
+	}
+	return nil
+}
+
+// GetDownloads returns the files downloaded by the page of the given test, in the order they began.
+func GetDownloads(db *sql.DB, testID uuid.UUID) ([]browser.Download, error) {
+	rows, err := db.Query(`SELECT guid, url, frame_id, filename, state, COALESCE(ref, ''), bytes, COALESCE(sha256, '')
+		FROM downloads WHERE test_id = $1 ORDER BY created_at`, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query downloads table: %v", err)
+	}
+	defer rows.Close()
+
+	var downloads []browser.Download
+	for rows.Next() {
+		var d browser.Download
+		if err = rows.Scan(&d.GUID, &d.URL, &d.FrameID, &d.Filename, &d.State, &d.Path, &d.Bytes, &d.SHA256); err != nil {
+			return nil, fmt.Errorf("failed to scan downloads row: %v", err)
+		}
+		downloads = append(downloads, d)
+	}
+	return downloads, rows.Err()
+}
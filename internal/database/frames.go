@@ -0,0 +1,24 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/browser"
+
+	"github.com/google/uuid"
+)
+
+// InsertFrame stores a frame of the frame tree of the page.
+func InsertFrame(logger *slog.Logger, db *sql.DB, testID uuid.UUID, frame browser.Frame) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into frames table", "test_id", testID.String(), "frame_id", frame.ID)
+	_, err := db.Exec("INSERT INTO frames (test_id, frame_id, parent_frame_id, url) VALUES ($1, $2, $3, $4)",
+		testID, frame.ID, frame.ParentID, frame.URL)
+	if err != nil {
+		return fmt.Errorf("failed to insert into frames table: %v", err)
+	}
+	return nil
+}
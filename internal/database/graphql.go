@@ -0,0 +1,30 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/graphql"
+
+	"github.com/google/uuid"
+)
+
+// InsertGraphQLOperation stores a GraphQL operation sent by a request of a run. Operations are indexed by name, so
+// the calls of an operation can be followed across runs although they all go to the same endpoint.
+func InsertGraphQLOperation(logger *slog.Logger, db *sql.DB, testID uuid.UUID, op graphql.Operation) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into graphql_operations table", "test_id", testID.String(), "request_id", op.RequestID, "operation", op.Name)
+	var variables sql.NullString
+	if len(op.Variables) > 0 {
+		variables = sql.NullString{String: string(op.Variables), Valid: true}
+	}
+	_, err := db.Exec(`INSERT INTO graphql_operations (test_id, request_id, url, position, operation_type, operation_name, query,
+		variables, persisted_hash) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		testID, op.RequestID, op.URL, op.Position, op.Type, op.Name, op.Query, variables, op.PersistedHash)
+	if err != nil {
+		return fmt.Errorf("failed to insert into graphql_operations table: %v", err)
+	}
+	return nil
+}
@@ -5,7 +5,456 @@ CREATE TABLE IF NOT EXISTS events (
     test_id uuid,
     type text,
     domain text,
+    url text,
     payload jsonb,
     body text,
+    status integer,
+    content_range text,
+    chunked boolean DEFAULT false,
+    parts jsonb,
+    dns_ms double precision,
+    connect_ms double precision,
+    tls_ms double precision,
+    ttfb_ms double precision,
+    download_ms double precision,
+    total_ms double precision,
+    encoded_bytes double precision,
+    body_size integer,
+    body_hash text,
+    body_path text,
+    source text DEFAULT '',
+    frame_id text,
+    frame_url text,
+    body_fetch_status text,
     created_at timestamp with time zone DEFAULT now()
-);
\ No newline at end of file
+);
+
+CREATE INDEX IF NOT EXISTS events_test_id_idx ON events (test_id);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    transaction_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    request_id text,
+    method text,
+    url text,
+    domain text,
+    resource_type text,
+    redirects jsonb,
+    status integer,
+    mime_type text,
+    finished boolean,
+    failed boolean,
+    error_text text,
+    canceled boolean,
+    blocked_reason text,
+    encoded_bytes double precision,
+    body_size integer,
+    total_ms double precision,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS graphql_operations (
+    operation_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    request_id text,
+    url text,
+    position integer,
+    operation_type text,
+    operation_name text,
+    query text,
+    variables jsonb,
+    persisted_hash text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS graphql_operations_name_idx ON graphql_operations (operation_name, created_at);
+
+CREATE TABLE IF NOT EXISTS findings (
+    finding_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    check_name text,
+    severity text,
+    url text,
+    message text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+
+CREATE TABLE IF NOT EXISTS profile (
+    profile_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    endpoint text,
+    url text,
+    status integer,
+    present boolean,
+    content text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS tests (
+    test_id uuid PRIMARY KEY,
+    target_url text,
+    schedule_id text,
+    started_at timestamp with time zone,
+    finished_at timestamp with time zone,
+    status text,
+    browser_version text,
+    tool_version text,
+    request_count integer,
+    response_count integer,
+    csp text,
+    device text,
+    cpu_throttle double precision,
+    suite_id text,
+    shard_index integer,
+    shard_total integer,
+    video text,
+    pdfs text[],
+    trace text,
+    statuses jsonb
+);
+
+CREATE INDEX IF NOT EXISTS tests_started_at_idx ON tests (started_at);
+
+CREATE TABLE IF NOT EXISTS assertions (
+    assertion_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    name text,
+    passed boolean,
+    message text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS domains (
+    domain_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    domain text,
+    first_party boolean,
+    tracker text,
+    requests integer,
+    bytes double precision,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS api_endpoints (
+    api_endpoint_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    method text,
+    origin text,
+    path text,
+    requests integer,
+    request_types jsonb,
+    response_types jsonb,
+    auth_headers jsonb,
+    statuses jsonb,
+    query_parameters jsonb,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS api_endpoints_path_idx ON api_endpoints (origin, path, method);
+
+CREATE TABLE IF NOT EXISTS edge_timing (
+    edge_timing_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    domain text,
+    cdn text,
+    responses integer,
+    hits integer,
+    misses integer,
+    edge_latency double precision,
+    origin_latency double precision,
+    server_time double precision,
+    server_timed integer,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS tls (
+    tls_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    origin text,
+    protocol text,
+    cipher text,
+    subject text,
+    issuer text,
+    sans text[],
+    valid_from timestamp with time zone,
+    valid_to timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS replays (
+    replay_result_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    replay_id uuid,
+    request_id text,
+    method text,
+    url text,
+    original_status integer,
+    status integer,
+    original_length integer,
+    length integer,
+    changed boolean,
+    duration_ms double precision,
+    error text,
+    body text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS replays_replay_id_idx ON replays (replay_id);
+
+CREATE TABLE IF NOT EXISTS fuzz_results (
+    fuzz_result_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    url text,
+    method text,
+    parameter text,
+    payload text,
+    baseline_status integer,
+    status integer,
+    baseline_length integer,
+    length integer,
+    reflected boolean,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS scripts (
+    script_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    target_url text,
+    url text,
+    hash text,
+    size integer,
+    content text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS scripts_target_url_idx ON scripts (target_url, url, created_at);
+
+CREATE TABLE IF NOT EXISTS script_changes (
+    script_change_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    url text,
+    previous_hash text,
+    hash text,
+    diff text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS cookies (
+    cookie_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    name text,
+    domain text,
+    path text,
+    expires timestamp with time zone,
+    session boolean,
+    secure boolean,
+    http_only boolean,
+    same_site text,
+    size integer,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS web_storage (
+    web_storage_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    origin text,
+    kind text,
+    key text,
+    size integer,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS milestones (
+    milestone_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    scenario text,
+    name text,
+    position integer,
+    elapsed_ms double precision,
+    since_previous_ms double precision,
+    requests integer,
+    total_requests integer,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS milestones_scenario_idx ON milestones (scenario, name, created_at);
+
+CREATE TABLE IF NOT EXISTS interactions (
+    interaction_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    position integer,
+    action text,
+    target text,
+    requests integer,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS metrics (
+    metric_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    url text,
+    ttfb_ms double precision,
+    fcp_ms double precision,
+    dom_content_loaded_ms double precision,
+    load_ms double precision,
+    lcp_ms double precision,
+    cls double precision,
+    fid_ms double precision,
+    inp_ms double precision,
+    script_duration_ms double precision,
+    task_duration_ms double precision,
+    layout_duration_ms double precision,
+    js_heap_used_bytes double precision,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS cache_comparisons (
+    cache_comparison_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    cold_requests integer,
+    warm_requests integer,
+    cold_from_cache integer,
+    warm_from_cache integer,
+    cold_bytes double precision,
+    warm_bytes double precision,
+    cold_load_ms double precision,
+    warm_load_ms double precision,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS critical_chain (
+    critical_chain_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    position integer,
+    depth integer,
+    url text,
+    resource_type text,
+    start_ms double precision,
+    end_ms double precision,
+    bytes double precision,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS coverage (
+    coverage_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    url text,
+    type text,
+    total_bytes double precision,
+    used_bytes double precision,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS faults (
+    fault_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    url text,
+    action text,
+    detail text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS storage_snapshot (
+    storage_snapshot_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    origin text,
+    kind text,
+    key text,
+    value text,
+    looks text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS frames (
+    frame_row_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    frame_id text,
+    parent_frame_id text,
+    url text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS dom_snapshots (
+    dom_snapshot_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    html text,
+    snapshot jsonb,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS review_queue (
+    review_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    url text,
+    status integer,
+    mime_type text,
+    body text,
+    body_path text,
+    body_size integer,
+    verdict text,
+    note text,
+    reviewed_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS links (
+    link_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    url text,
+    status integer,
+    error text,
+    checked_by text,
+    broken boolean,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS crawls (
+    crawl_id uuid PRIMARY KEY,
+    root text,
+    suite_id text,
+    max_pages integer,
+    max_depth integer,
+    status text,
+    started_at timestamp with time zone DEFAULT now(),
+    finished_at timestamp with time zone
+);
+
+CREATE TABLE IF NOT EXISTS crawl_frontier (
+    crawl_id uuid,
+    url text,
+    depth integer,
+    state text,
+    test_id uuid,
+    status integer,
+    links text[],
+    links_checked boolean DEFAULT false,
+    created_at timestamp with time zone DEFAULT now(),
+    updated_at timestamp with time zone DEFAULT now(),
+    PRIMARY KEY (crawl_id, url)
+);
+
+CREATE TABLE IF NOT EXISTS downloads (
+    download_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    guid text,
+    url text,
+    frame_id text,
+    filename text,
+    state text,
+    ref text,
+    bytes bigint,
+    sha256 text,
+    created_at timestamp with time zone DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS screenshots (
+    screenshot_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    test_id uuid,
+    label text,
+    url text,
+    mime_type text,
+    image bytea,
+    image_ref text,
+    created_at timestamp with time zone DEFAULT now()
+);
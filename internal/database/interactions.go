@@ -0,0 +1,43 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/explore"
+
+	"github.com/google/uuid"
+)
+
+// InsertInteraction stores a click or scroll made by the exploration of a run, with the requests it triggered.
+func InsertInteraction(logger *slog.Logger, db *sql.DB, testID uuid.UUID, i explore.Interaction) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into interactions table", "test_id", testID.String(), "action", i.Action, "target", i.Target)
+	_, err := db.Exec(`INSERT INTO interactions (test_id, position, action, target, requests) VALUES ($1, $2, $3, $4, $5)`,
+		testID, i.Position, i.Action, i.Target, i.Requests)
+	if err != nil {
+		return fmt.Errorf("failed to insert into interactions table: %v", err)
+	}
+	return nil
+}
+
+// GetInteractions returns the interactions made by the exploration of the given test, in order.
+func GetInteractions(db *sql.DB, testID uuid.UUID) ([]explore.Interaction, error) {
+	rows, err := db.Query(`SELECT position, action, target, requests FROM interactions WHERE test_id = $1 ORDER BY position`, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query interactions table: %v", err)
+	}
+	defer rows.Close()
+
+	var interactions []explore.Interaction
+	for rows.Next() {
+		var i explore.Interaction
+		if err = rows.Scan(&i.Position, &i.Action, &i.Target, &i.Requests); err != nil {
+			return nil, fmt.Errorf("failed to scan interactions row: %v", err)
+		}
+		interactions = append(interactions, i)
+	}
+	return interactions, rows.Err()
+}
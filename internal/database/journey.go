@@ -0,0 +1,54 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+	"web-tester/internal/journey"
+
+	"github.com/google/uuid"
+)
+
+// InsertMilestone stores a milestone reached by the journey of a run. Milestones are indexed by scenario
+// and name, so their timings can be followed across runs.
+func InsertMilestone(logger *slog.Logger, db *sql.DB, testID uuid.UUID, m journey.Milestone) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into milestones table", "test_id", testID.String(), "scenario", m.Scenario, "milestone", m.Name)
+	_, err := db.Exec(`INSERT INTO milestones (test_id, scenario, name, position, elapsed_ms, since_previous_ms, requests, total_requests)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		testID, m.Scenario, m.Name, m.Position, milliseconds(m.Elapsed), milliseconds(m.SincePrevious), m.Requests, m.TotalRequests)
+	if err != nil {
+		return fmt.Errorf("failed to insert into milestones table: %v", err)
+	}
+	return nil
+}
+
+// GetMilestones returns the milestones reached by the journey of the given test, in order.
+func GetMilestones(db *sql.DB, testID uuid.UUID) ([]journey.Milestone, error) {
+	rows, err := db.Query(`SELECT scenario, name, position, elapsed_ms, since_previous_ms, requests, total_requests
+		FROM milestones WHERE test_id = $1 ORDER BY position`, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query milestones table: %v", err)
+	}
+	defer rows.Close()
+
+	var milestones []journey.Milestone
+	for rows.Next() {
+		var m journey.Milestone
+		var elapsed, sincePrevious float64
+		if err = rows.Scan(&m.Scenario, &m.Name, &m.Position, &elapsed, &sincePrevious, &m.Requests, &m.TotalRequests); err != nil {
+			return nil, fmt.Errorf("failed to scan milestones row: %v", err)
+		}
+		m.Elapsed, m.SincePrevious = time.Duration(elapsed*float64(time.Millisecond)), time.Duration(sincePrevious*float64(time.Millisecond))
+		milestones = append(milestones, m)
+	}
+	return milestones, rows.Err()
+}
+
+// milliseconds returns a duration in milliseconds, the unit durations are stored in.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
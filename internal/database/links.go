@@ -0,0 +1,43 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/crawl"
+
+	"github.com/google/uuid"
+)
+
+// InsertLink stores a link found on the page of a test along with the status it returned.
+func InsertLink(logger *slog.Logger, db *sql.DB, testID uuid.UUID, link crawl.Link) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into links table", "test_id", testID.String(), "url", link.URL)
+	_, err := db.Exec("INSERT INTO links (test_id, url, status, error, checked_by, broken) VALUES ($1, $2, $3, $4, $5, $6)",
+		testID, link.URL, link.Status, link.Error, link.CheckedBy, link.Broken())
+	if err != nil {
+		return fmt.Errorf("failed to insert into links table: %v", err)
+	}
+	return nil
+}
+
+// GetBrokenLinks returns the broken links found on the page of a test.
+func GetBrokenLinks(db *sql.DB, testID uuid.UUID) ([]crawl.Link, error) {
+	rows, err := db.Query("SELECT url, status, error, checked_by FROM links WHERE test_id = $1 AND broken ORDER BY url", testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query links table: %v", err)
+	}
+	defer rows.Close()
+
+	var links []crawl.Link
+	for rows.Next() {
+		var l crawl.Link
+		if err = rows.Scan(&l.URL, &l.Status, &l.Error, &l.CheckedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan links row: %v", err)
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
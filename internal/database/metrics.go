@@ -0,0 +1,134 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/browser"
+
+	"github.com/google/uuid"
+)
+
+// InsertVitals stores the navigation timing and Core Web Vitals of a page loaded by a run.
+func InsertVitals(logger *slog.Logger, db *sql.DB, testID uuid.UUID, v browser.Vitals) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into metrics table", "test_id", testID.String(), "url", v.URL)
+	_, err := db.Exec(`INSERT INTO metrics (test_id, url, ttfb_ms, fcp_ms, dom_content_loaded_ms, load_ms, lcp_ms, cls, fid_ms, inp_ms,
+		script_duration_ms, task_duration_ms, layout_duration_ms, js_heap_used_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		testID, v.URL, v.TTFB, v.FCP, v.DOMContentLoaded, v.Load, v.LCP, v.CLS, v.FID, v.INP,
+		v.ScriptDuration, v.TaskDuration, v.LayoutDuration, v.JSHeapUsedSize)
+	if err != nil {
+		return fmt.Errorf("failed to insert into metrics table: %v", err)
+	}
+	return nil
+}
+
+// CacheComparison is the cold load of a run compared with a second, warm load of the target in the same browser.
+type CacheComparison struct {
+	Cold browser.LoadStats
+	Warm browser.LoadStats
+}
+
+// InsertCacheComparison stores the comparison of the cold and warm loads of a run.
+func InsertCacheComparison(logger *slog.Logger, db *sql.DB, testID uuid.UUID, c CacheComparison) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into cache_comparisons table", "test_id", testID.String())
+	_, err := db.Exec(`INSERT INTO cache_comparisons (test_id, cold_requests, warm_requests, cold_from_cache, warm_from_cache,
+		cold_bytes, warm_bytes, cold_load_ms, warm_load_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		testID, c.Cold.Requests, c.Warm.Requests, c.Cold.FromCache, c.Warm.FromCache, c.Cold.Bytes, c.Warm.Bytes, c.Cold.Load, c.Warm.Load)
+	if err != nil {
+		return fmt.Errorf("failed to insert into cache_comparisons table: %v", err)
+	}
+	return nil
+}
+
+// GetCacheComparison returns the comparison of the cold and warm loads of the given test, found is false
+// when the test did not compare them.
+func GetCacheComparison(db *sql.DB, testID uuid.UUID) (c CacheComparison, found bool, err error) {
+	err = db.QueryRow(`SELECT cold_requests, warm_requests, cold_from_cache, warm_from_cache, cold_bytes, warm_bytes, cold_load_ms, warm_load_ms
+		FROM cache_comparisons WHERE test_id = $1 ORDER BY created_at DESC LIMIT 1`, testID).
+		Scan(&c.Cold.Requests, &c.Warm.Requests, &c.Cold.FromCache, &c.Warm.FromCache, &c.Cold.Bytes, &c.Warm.Bytes, &c.Cold.Load, &c.Warm.Load)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, false, nil
+	}
+	if err != nil {
+		return c, false, fmt.Errorf("failed to query cache_comparisons table: %v", err)
+	}
+	return c, true, nil
+}
+
+// InsertCriticalChain stores the critical request chain of a run, one row per request in depth-first order.
+func InsertCriticalChain(logger *slog.Logger, db *sql.DB, testID uuid.UUID, chain []browser.ChainNode) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into critical_chain table", "test_id", testID.String(), "requests", len(chain))
+	for i, node := range chain {
+		_, err := db.Exec(`INSERT INTO critical_chain (test_id, position, depth, url, resource_type, start_ms, end_ms, bytes)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			testID, i, node.Depth, node.URL, node.ResourceType, node.Start, node.End, node.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to insert into critical_chain table: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetCriticalChain returns the critical request chain of the given test, in depth-first order.
+func GetCriticalChain(db *sql.DB, testID uuid.UUID) ([]browser.ChainNode, error) {
+	rows, err := db.Query("SELECT depth, url, resource_type, start_ms, end_ms, bytes FROM critical_chain WHERE test_id = $1 ORDER BY position", testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query critical_chain table: %v", err)
+	}
+	defer rows.Close()
+
+	var chain []browser.ChainNode
+	for rows.Next() {
+		var node browser.ChainNode
+		if err = rows.Scan(&node.Depth, &node.URL, &node.ResourceType, &node.Start, &node.End, &node.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan critical_chain row: %v", err)
+		}
+		chain = append(chain, node)
+	}
+	return chain, rows.Err()
+}
+
+// InsertCoverage stores how much of a script or stylesheet was used by the page.
+func InsertCoverage(logger *slog.Logger, db *sql.DB, testID uuid.UUID, c browser.ResourceCoverage) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into coverage table", "test_id", testID.String(), "url", c.URL)
+	_, err := db.Exec(`INSERT INTO coverage (test_id, url, type, total_bytes, used_bytes) VALUES ($1, $2, $3, $4, $5)`,
+		testID, c.URL, c.Type, c.Total, c.Used)
+	if err != nil {
+		return fmt.Errorf("failed to insert into coverage table: %v", err)
+	}
+	return nil
+}
+
+// GetCoverage returns the coverage of the scripts and stylesheets of the given test, largest unused size first.
+func GetCoverage(db *sql.DB, testID uuid.UUID) ([]browser.ResourceCoverage, error) {
+	rows, err := db.Query("SELECT url, type, total_bytes, used_bytes FROM coverage WHERE test_id = $1 ORDER BY total_bytes - used_bytes DESC, url", testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query coverage table: %v", err)
+	}
+	defer rows.Close()
+
+	var resources []browser.ResourceCoverage
+	for rows.Next() {
+		var c browser.ResourceCoverage
+		if err = rows.Scan(&c.URL, &c.Type, &c.Total, &c.Used); err != nil {
+			return nil, fmt.Errorf("failed to scan coverage row: %v", err)
+		}
+		resources = append(resources, c)
+	}
+	return resources, rows.Err()
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"web-tester/internal/bodystore"
+	"web-tester/internal/browser"
+	"web-tester/internal/config"
+	"web-tester/internal/mongo"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/google/uuid"
+)
+
+// mongoBatchSize is the number of documents inserted at most per insert command.
+const mongoBatchSize = 100
+
+// mongoBatchBytes is the encoded size of the documents inserted at most per insert command, keeping the command,
+// a single BSON document, under the 16MiB MongoDB allows. A larger document is inserted on its own.
+const mongoBatchBytes = 12 << 20
+
+// mongoMaxDocument is the encoded size of the largest document MongoDB stores.
+const mongoMaxDocument = 16 << 20
+
+// mongoMaxBody is the size of the largest body kept in a document, the larger ones being moved to the body store,
+// or dropped without one, so a transaction fits in a document.
+const mongoMaxBody = 4 << 20
+
+// defaultMongoDatabase is the database the documents are stored in when neither MONGODB_DATABASE nor the URI names
+// one.
+const defaultMongoDatabase = "web_tester"
+
+// MongoSink stores the events of a test in a MongoDB collection, one document per transaction: the request and
+// response of a request ID nested in a document along with the timings of the response. The documents are
+// inserted once the test is done, when Close is called. The bodies above mongoMaxBody are moved to the body store
+// and referenced by their body_path, or dropped without one.
+type MongoSink struct {
+	logger     *slog.Logger
+	client     *mongo.Client
+	database   string
+	collection string
+	testID     uuid.UUID
+	bodies     *bodystore.Store
+
+	mu           sync.Mutex
+	transactions map[network.RequestID]map[string]interface{}
+	order        []network.RequestID
+	failed       int
+}
+
+// NewMongoSink connects to the MongoDB server of writerCfg to store the events of the test, in the database of
+// MONGODB_DATABASE, or else of the URI, or else web_tester, moving the large bodies to bodies when it is not nil.
+func NewMongoSink(logger *slog.Logger, testID uuid.UUID, writerCfg config.WriterConfig, bodies *bodystore.Store) (*MongoSink, error) {
+	if writerCfg.MongoURI == "" {
+		return nil, fmt.Errorf("MONGODB_URI is not set")
+	}
+	client, err := mongo.Connect(writerCfg.MongoURI, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	database := writerCfg.MongoDatabase
+	if database == "" {
+		database = client.Database
+	}
+	if database == "" {
+		database = defaultMongoDatabase
+	}
+	return &MongoSink{
+		logger: logger, client: client, database: database, collection: writerCfg.MongoCollection, testID: testID,
+		bodies: bodies, transactions: map[network.RequestID]map[string]interface{}{},
+	}, nil
+}
+
+// Write adds the event to the document of its transaction.
+func (m *MongoSink) Write(event Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	doc, ok := m.transactions[event.RequestID]
+	if !ok {
+		doc = map[string]interface{}{
+			"test_id": m.testID.String(), "request_id": string(event.RequestID), "url": event.URL,
+			"source": event.Source, "frame_id": event.FrameID, "frame_url": event.FrameURL, "captured_at": time.Now().UTC(),
+		}
+		m.transactions[event.RequestID] = doc
+		m.order = append(m.order, event.RequestID)
+	}
+	payload, err := mongo.FromJSON(event.Content)
+	if err != nil {
+		m.logger.Error("failed to convert event payload", "url", event.URL, "error", err)
+		m.failed++
+		return
+	}
+	event = m.limitBody(event)
+	switch event.Type {
+	case "response":
+		parts, _ := mongo.FromJSON(event.Parts)
+		t := event.Timing
+		doc["response"] = map[string]interface{}{
+			"status": event.Status, "payload": payload, "body": event.Body, "body_size": event.BodySize,
+			"body_hash": event.BodyHash, "body_path": event.BodyPath, "body_fetch_status": event.BodyFetchStatus, "content_range": event.ContentRange,
+			"chunked": event.Chunked, "parts": parts,
+		}
+		doc["timings"] = map[string]interface{}{
+			"dns_ms": t.DNS, "connect_ms": t.Connect, "tls_ms": t.TLS, "ttfb_ms": t.TTFB,
+			"download_ms": t.Download, "total_ms": t.Total, "encoded_bytes": t.EncodedBytes,
+		}
+	default:
+		request := map[string]interface{}{"payload": payload, "body": event.Body}
+		if event.BodyPath != "" {
+			request["body_path"], request["body_hash"] = event.BodyPath, event.BodyHash
+		}
+		doc[event.Type] = request
+	}
+}
+
+// limitBody moves the body of the event to the body store when it is above mongoMaxBody, or drops it without one.
+func (m *MongoSink) limitBody(event Event) Event {
+	if len(event.Body) <= mongoMaxBody {
+		return event
+	}
+	if m.bodies == nil {
+		event.Body, event.BodyFetchStatus = nil, browser.BodyTooLarge
+		return event
+	}
+	ref, hash, err := m.bodies.Put(event.Body)
+	if err != nil {
+		m.logger.Error("failed to store body", "url", event.URL, "error", err)
+		event.Body, event.BodyFetchStatus = nil, browser.BodyTooLarge
+		return event
+	}
+	event.Body, event.BodyPath, event.BodyHash, event.BodyFetchStatus = nil, ref, hash, browser.BodyStored
+	return event
+}
+
+// Close inserts the documents of the transactions in batches of at most mongoBatchSize documents and
+// mongoBatchBytes bytes, and disconnects, returning the number of documents that could not be stored along with
+// the events whose payload could not be converted.
+func (m *MongoSink) Close() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.client.Close()
+
+	m.logger.Debug("Inserting into MongoDB collection", "test_id", m.testID.String(), "collection", m.collection, "documents", len(m.order))
+	var batch []interface{}
+	size := 0
+	for _, id := range m.order {
+		doc := m.transactions[id]
+		encoded, err := mongo.Marshal(doc)
+		if err == nil && len(encoded) > mongoMaxDocument {
+			err = fmt.Errorf("the document is %d bytes, above the %d bytes MongoDB stores", len(encoded), mongoMaxDocument)
+		}
+		if err != nil {
+			m.logger.Error("failed to encode MongoDB document", "request_id", string(id), "error", err)
+			m.failed++
+			continue
+		}
+		if len(batch) > 0 && (len(batch) == mongoBatchSize || size+len(encoded) > mongoBatchBytes) {
+			m.insert(batch)
+			batch, size = nil, 0
+		}
+		batch, size = append(batch, doc), size+len(encoded)
+	}
+	if len(batch) > 0 {
+		m.insert(batch)
+	}
+	return m.failed
+}
+
+// insert inserts a batch of documents, counting those that could not be.
+func (m *MongoSink) insert(batch []interface{}) {
+	inserted, err := m.client.Insert(m.database, m.collection, batch)
+	if err != nil {
+		m.logger.Error("failed to insert into MongoDB collection", "documents", len(batch), "error", err)
+		m.failed += len(batch) - inserted
+	}
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"unicode/utf8"
+	"web-tester/internal/browser"
+	"web-tester/internal/replay"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/google/uuid"
+)
+
+// storedResponse is the part of a response of a stored CDP event read back.
+type storedResponse struct {
+	URL      string          `json:"url"`
+	Status   int64           `json:"status"`
+	Headers  network.Headers `json:"headers"`
+	MimeType string          `json:"mimeType"`
+}
+
+// storedEvent is the part of a stored CDP request or response event read back. The payloads are not read into the
+// CDP events, whose enums reject the values the browser version they were captured with knows and cdproto does not.
+type storedEvent struct {
+	RequestID network.RequestID  `json:"requestId"`
+	Type      string             `json:"type"`
+	Timestamp *cdp.MonotonicTime `json:"timestamp"`
+	Request   *struct {
+		URL     string          `json:"url"`
+		Method  string          `json:"method"`
+		Headers network.Headers `json:"headers"`
+	} `json:"request"`
+	RedirectResponse *storedResponse `json:"redirectResponse"`
+	Response         *storedResponse `json:"response"`
+}
+
+// Transactions rebuilds the transactions of a run from its stored events, e.g. to replay them.
+func Transactions(events []StoredEvent) []browser.Transaction {
+	var requests []browser.Request
+	var responses []browser.Response
+	for _, e := range events {
+		var payload storedEvent
+		if json.Unmarshal(e.Payload, &payload) != nil {
+			continue
+		}
+		switch {
+		case e.Type == "request" && payload.Request != nil:
+			ev := &network.EventRequestWillBeSent{RequestID: payload.RequestID, Type: network.ResourceType(payload.Type),
+				Timestamp: payload.Timestamp, Request: &network.Request{URL: payload.Request.URL, Method: payload.Request.Method,
+					Headers: payload.Request.Headers}}
+			if r := payload.RedirectResponse; r != nil {
+				ev.RedirectResponse = &network.Response{URL: r.URL, Status: r.Status, Headers: r.Headers, MimeType: r.MimeType}
+			}
+			requests = append(requests, browser.Request{RequestID: ev.RequestID, Type: e.Type, URL: e.URL, Content: ev,
+				Body: []byte(e.Body)})
+		case e.Type == "response" && payload.Response != nil:
+			r := payload.Response
+			ev := &network.EventResponseReceived{RequestID: payload.RequestID, Type: network.ResourceType(payload.Type),
+				Timestamp: payload.Timestamp, Response: &network.Response{URL: r.URL, Status: r.Status, Headers: r.Headers,
+					MimeType: r.MimeType}}
+			responses = append(responses, browser.Response{RequestID: ev.RequestID, Type: e.Type, URL: e.URL, Content: ev,
+				Body: []byte(e.Body), Status: r.Status, Headers: r.Headers, MimeType: r.MimeType})
+		}
+	}
+	return browser.Correlate(requests, responses, nil)
+}
+
+// InsertReplay stores the response to a request of a run replayed, along with its comparison with the captured one.
+// Only textual bodies are stored.
+func InsertReplay(logger *slog.Logger, db *sql.DB, testID uuid.UUID, result replay.Result) error {
+	if db == nil {
+		return nil
+	}
+	var body sql.NullString
+	if utf8.Valid(result.Body) {
+		body = sql.NullString{String: string(result.Body), Valid: true}
+	}
+	logger.Debug("Inserting into replays table", "test_id", testID.String(), "replay_id", result.ReplayID.String(), "url", result.URL)
+	_, err := db.Exec(`INSERT INTO replays (test_id, replay_id, request_id, method, url, original_status, status, original_length,
+		length, changed, duration_ms, error, body) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		testID, result.ReplayID, result.RequestID, result.Method, result.URL, result.OriginalStatus, result.Status,
+		result.OriginalLength, result.Length, result.Changed, result.DurationMS, result.Error, body)
+	if err != nil {
+		return fmt.Errorf("failed to insert into replays table: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// EventFilter narrows the events returned by FindEvents. Its zero value matches every event of the test.
+type EventFilter struct {
+	Domain string
+	Type   string
+	Status int64
+	// Limit is the maximum number of events returned, unlimited when 0
+	Limit int
+}
+
+// ListTestRuns returns the most recent test run records, at most limit of them, newest first.
+func ListTestRuns(db *sql.DB, limit int) ([]TestRun, error) {
+	rows, err := db.Query("SELECT "+testRunColumns+" FROM tests ORDER BY started_at DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tests table: %v", err)
+	}
+	defer rows.Close()
+
+	var runs []TestRun
+	for rows.Next() {
+		run, err := scanTestRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tests row: %v", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// FindEvents returns the events of the given test matching the filter, in the order they were stored.
+func FindEvents(db *sql.DB, testID uuid.UUID, filter EventFilter) ([]StoredEvent, error) {
+	conditions := []string{"test_id = $1"}
+	args := []interface{}{testID}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, condition+" $"+strconv.Itoa(len(args)))
+	}
+	if filter.Domain != "" {
+		add("domain =", filter.Domain)
+	}
+	if filter.Type != "" {
+		add("type =", filter.Type)
+	}
+	if filter.Status != 0 {
+		add("status =", filter.Status)
+	}
+	query := `SELECT event_id, type, domain, COALESCE(url, ''), COALESCE(status, 0), COALESCE(payload, 'null'), COALESCE(body, ''),
+		COALESCE(encoded_bytes, 0), created_at
+		FROM events WHERE ` + strings.Join(conditions, " AND ") + " ORDER BY created_at"
+	if filter.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events table: %v", err)
+	}
+	defer rows.Close()
+
+	var events []StoredEvent
+	for rows.Next() {
+		var e StoredEvent
+		var payload []byte
+		if err = rows.Scan(&e.EventID, &e.Type, &e.Domain, &e.URL, &e.Status, &payload, &e.Body, &e.EncodedBytes, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan events row: %v", err)
+		}
+		e.Payload = payload
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetResponseBody returns the body of the response to the request of the given test, or the reference of the
+// body in the body store when it was too large for the database. It returns sql.ErrNoRows when the test has no
+// response to the request.
+func GetResponseBody(db *sql.DB, testID uuid.UUID, requestID string) (body []byte, ref string, err error) {
+	var path sql.NullString
+	err = db.QueryRow(`SELECT COALESCE(body, ''), body_path FROM events
+		WHERE test_id = $1 AND type = 'response' AND payload->>'requestId' = $2 ORDER BY created_at DESC LIMIT 1`,
+		testID, requestID).Scan(&body, &path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query events table: %w", err)
+	}
+	return body, path.String, nil
+}
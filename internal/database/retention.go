@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// runTables are the tables holding the rows of a test run, keyed by its test ID, deleted along with the run.
+// The tests table is deleted last.
+var runTables = []string{
+	"events", "transactions", "graphql_operations", "findings", "profile", "assertions", "domains", "api_endpoints",
+	"edge_timing", "tls", "fuzz_results", "replays", "scripts", "script_changes", "cookies", "web_storage", "milestones",
+	"metrics", "cache_comparisons", "critical_chain", "coverage", "faults", "storage_snapshot", "frames", "dom_snapshots",
+	"review_queue", "links", "screenshots", "downloads", "interactions", "tests",
+}
+
+// Pruned is what Prune deleted from the database.
+type Pruned struct {
+	Runs   int `json:"runs"`
+	Crawls int `json:"crawls"`
+	// Artifacts are the references of the videos, PDFs, traces, screenshots and downloads of the deleted runs, to
+	// be deleted from the artifact store
+	Artifacts []string `json:"artifacts,omitempty"`
+	// Bodies are the references of the bodies of the body store no remaining run refers to
+	Bodies []string `json:"bodies,omitempty"`
+	// Since is when the oldest run still in progress started, or the time of the prune when none is: the
+	// bodies handed out since then may belong to a run that did not store its events yet
+	Since time.Time `json:"since"`
+}
+
+// Prune deletes the test runs started before the given time along with every row of theirs, and the crawls
+// started before it with their frontier, in a single transaction. It returns the references of their artifacts,
+// which the caller deletes once the transaction is committed.
+func Prune(logger *slog.Logger, db *sql.DB, before time.Time) (Pruned, error) {
+	var pruned Pruned
+	tx, err := db.Begin()
+	if err != nil {
+		return pruned, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var testIDs []string
+	if err = tx.QueryRow("SELECT COALESCE(array_agg(test_id), '{}') FROM tests WHERE started_at < $1", before).
+		Scan(pq.Array(&testIDs)); err != nil {
+		return pruned, fmt.Errorf("failed to query tests table: %v", err)
+	}
+	pruned.Runs = len(testIDs)
+	if err = tx.QueryRow("SELECT COALESCE(MIN(started_at), now()) FROM tests WHERE status = $1 AND started_at >= $2",
+		StatusRunning, before).Scan(&pruned.Since); err != nil {
+		return pruned, fmt.Errorf("failed to query tests table: %v", err)
+	}
+
+	if len(testIDs) > 0 {
+		ids := pq.Array(testIDs)
+		err = tx.QueryRow(`SELECT COALESCE(array_agg(ref), '{}') FROM (
+			SELECT video AS ref FROM tests WHERE test_id = ANY($1::uuid[]) AND video IS NOT NULL
+			UNION SELECT trace FROM tests WHERE test_id = ANY($1::uuid[]) AND trace IS NOT NULL
+			UNION SELECT unnest(pdfs) FROM tests WHERE test_id = ANY($1::uuid[])
+			UNION SELECT image_ref FROM screenshots WHERE test_id = ANY($1::uuid[]) AND image_ref IS NOT NULL
+			UNION SELECT ref FROM downloads WHERE test_id = ANY($1::uuid[]) AND ref IS NOT NULL
+		) refs WHERE ref <> ''`, ids).Scan(pq.Array(&pruned.Artifacts))
+		if err != nil {
+			return pruned, fmt.Errorf("failed to query artifacts: %v", err)
+		}
+		// bodies are shared across runs by content, so only those no remaining run refers to are deleted
+		err = tx.QueryRow(`SELECT COALESCE(array_agg(ref), '{}') FROM (
+			SELECT body_path AS ref FROM events WHERE test_id = ANY($1::uuid[])
+			UNION SELECT body_path FROM review_queue WHERE test_id = ANY($1::uuid[])
+		) refs WHERE ref <> ''
+			AND NOT EXISTS (SELECT 1 FROM events e WHERE e.body_path = refs.ref AND NOT e.test_id = ANY($1::uuid[]))
+			AND NOT EXISTS (SELECT 1 FROM review_queue r WHERE r.body_path = refs.ref AND NOT r.test_id = ANY($1::uuid[]))`,
+			ids).Scan(pq.Array(&pruned.Bodies))
+		if err != nil {
+			return pruned, fmt.Errorf("failed to query bodies: %v", err)
+		}
+
+		for _, table := range runTables {
+			logger.Debug("Deleting from "+table+" table", "runs", len(testIDs))
+			if _, err = tx.Exec("DELETE FROM "+table+" WHERE test_id = ANY($1::uuid[])", ids); err != nil {
+				return pruned, fmt.Errorf("failed to delete from %s table: %v", table, err)
+			}
+		}
+	}
+
+	logger.Debug("Deleting from crawls table", "before", before)
+	if _, err = tx.Exec("DELETE FROM crawl_frontier WHERE crawl_id IN (SELECT crawl_id FROM crawls WHERE started_at < $1)", before); err != nil {
+		return pruned, fmt.Errorf("failed to delete from crawl_frontier table: %v", err)
+	}
+	res, err := tx.Exec("DELETE FROM crawls WHERE started_at < $1", before)
+	if err != nil {
+		return pruned, fmt.Errorf("failed to delete from crawls table: %v", err)
+	}
+	crawls, _ := res.RowsAffected()
+	pruned.Crawls = int(crawls)
+
+	if err = tx.Commit(); err != nil {
+		return pruned, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return pruned, nil
+}
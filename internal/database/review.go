@@ -0,0 +1,82 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Review verdicts.
+const (
+	VerdictOK  = "ok"
+	VerdictBad = "bad"
+)
+
+// ReviewItem is a response body sampled for a manual spot check. Verdict and Note are set once it was
+// reviewed, at ReviewedAt. A body moved to the body store is located by BodyPath instead of Body.
+type ReviewItem struct {
+	ReviewID   uuid.UUID  `json:"review_id"`
+	TestID     uuid.UUID  `json:"test_id"`
+	URL        string     `json:"url"`
+	Status     int64      `json:"status"`
+	MimeType   string     `json:"mime_type"`
+	Body       string     `json:"body,omitempty"`
+	BodyPath   string     `json:"body_path,omitempty"`
+	BodySize   int        `json:"body_size"`
+	Verdict    string     `json:"verdict,omitempty"`
+	Note       string     `json:"note,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// InsertReviewItem adds a sampled response body to the review queue.
+func InsertReviewItem(logger *slog.Logger, db *sql.DB, testID uuid.UUID, item ReviewItem) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into review_queue table", "test_id", testID.String(), "url", item.URL)
+	_, err := db.Exec(`INSERT INTO review_queue (test_id, url, status, mime_type, body, body_path, body_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		testID, item.URL, item.Status, item.MimeType, item.Body, item.BodyPath, item.BodySize)
+	if err != nil {
+		return fmt.Errorf("failed to insert into review_queue table: %v", err)
+	}
+	return nil
+}
+
+// GetPendingReviews returns up to limit items of the review queue that were not reviewed yet, oldest first.
+func GetPendingReviews(db *sql.DB, limit int) ([]ReviewItem, error) {
+	rows, err := db.Query(`SELECT review_id, test_id, url, status, mime_type, body, body_path, body_size, created_at
+		FROM review_queue WHERE reviewed_at IS NULL ORDER BY created_at LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review_queue table: %v", err)
+	}
+	defer rows.Close()
+
+	var items []ReviewItem
+	for rows.Next() {
+		var item ReviewItem
+		if err = rows.Scan(&item.ReviewID, &item.TestID, &item.URL, &item.Status, &item.MimeType, &item.Body, &item.BodyPath,
+			&item.BodySize, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan review_queue row: %v", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// SetReviewVerdict records the verdict of a review, returning sql.ErrNoRows when the item does not exist.
+func SetReviewVerdict(db *sql.DB, reviewID uuid.UUID, verdict, note string) error {
+	result, err := db.Exec("UPDATE review_queue SET verdict = $2, note = $3, reviewed_at = now() WHERE review_id = $1",
+		reviewID, verdict, note)
+	if err != nil {
+		return fmt.Errorf("failed to update review_queue table: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+	"web-tester/internal/assertion"
+	"web-tester/internal/browser"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Test run statuses stored in the tests table.
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// TestRun holds the context of a single run, stored in the tests table so events can be joined to it.
+type TestRun struct {
+	TestID         uuid.UUID `json:"test_id"`
+	TargetURL      string    `json:"target_url"`
+	ScheduleID     string    `json:"schedule_id,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	Status         string    `json:"status"`
+	BrowserVersion string    `json:"browser_version"`
+	ToolVersion    string    `json:"tool_version"`
+	RequestCount   int       `json:"request_count"`
+	ResponseCount  int       `json:"response_count"`
+	// CSP is the candidate Content-Security-Policy allowing the resources the run loaded
+	CSP string `json:"csp,omitempty"`
+	// Device is the name of the device preset the run emulated, if any
+	Device string `json:"device,omitempty"`
+	// CPUThrottle is the slowdown factor of the CPU during the run, zero when it was not throttled
+	CPUThrottle float64 `json:"cpu_throttle,omitempty"`
+	// SuiteID tags the runs of a URL list split across shards, ShardIndex and ShardTotal identify the shard
+	// that ran the test when the list was split
+	SuiteID    string `json:"suite_id,omitempty"`
+	ShardIndex int    `json:"shard_index,omitempty"`
+	ShardTotal int    `json:"shard_total,omitempty"`
+	// Video is the path of the video of the session, if it was recorded
+	Video string `json:"video,omitempty"`
+	// PDFs are the paths of the PDFs of the pages printed during the run
+	PDFs []string `json:"pdfs,omitempty"`
+	// Trace is the path of the Chrome trace of the page load, if it was recorded
+	Trace string `json:"trace,omitempty"`
+	// Statuses is the distribution of the statuses of the responses of the run, with its slowest and largest
+	// responses and the errors of its failed requests
+	Statuses *browser.StatusSummary `json:"statuses,omitempty"`
+}
+
+// StartTestRun inserts the test run record at the start of a run, with the running status.
+func StartTestRun(logger *slog.Logger, db *sql.DB, run TestRun) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into tests table", "test_id", run.TestID.String(), "target", run.TargetURL)
+	_, err := db.Exec(`INSERT INTO tests (test_id, target_url, schedule_id, started_at, status, tool_version, device, cpu_throttle,
+		suite_id, shard_index, shard_total)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, NULLIF($7, ''), NULLIF($8::double precision, 0), NULLIF($9, ''), $10, NULLIF($11, 0))`,
+		run.TestID, run.TargetURL, run.ScheduleID, run.StartedAt, StatusRunning, run.ToolVersion, run.Device, run.CPUThrottle,
+		run.SuiteID, run.ShardIndex, run.ShardTotal)
+	if err != nil {
+		return fmt.Errorf("failed to insert into tests table: %v", err)
+	}
+	return nil
+}
+
+// FinishTestRun finalizes the test run record with its end time, status, browser version, event counts, candidate CSP,
+// video, PDFs, trace and status summary.
+func FinishTestRun(logger *slog.Logger, db *sql.DB, run TestRun) error {
+	if db == nil {
+		return nil
+	}
+	statuses, err := json.Marshal(run.Statuses)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status summary: %v", err)
+	}
+	logger.Debug("Updating tests table", "test_id", run.TestID.String(), "status", run.Status)
+	_, err = db.Exec(`UPDATE tests SET finished_at = $2, status = $3, browser_version = $4, request_count = $5, response_count = $6,
+		csp = NULLIF($7, ''), video = NULLIF($8, ''), pdfs = $9, trace = NULLIF($10, ''), statuses = NULLIF($11, 'null')::jsonb
+		WHERE test_id = $1`,
+		run.TestID, run.FinishedAt, run.Status, run.BrowserVersion, run.RequestCount, run.ResponseCount, run.CSP, run.Video, pq.Array(run.PDFs),
+		run.Trace, string(statuses))
+	if err != nil {
+		return fmt.Errorf("failed to update tests table: %v", err)
+	}
+	return nil
+}
+
+// InsertAssertionResult stores the outcome of an assertion evaluated against the run.
+func InsertAssertionResult(logger *slog.Logger, db *sql.DB, testID uuid.UUID, result assertion.Result) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into assertions table", "test_id", testID.String(), "assertion", result.Name, "passed", result.Passed)
+	_, err := db.Exec("INSERT INTO assertions (test_id, name, passed, message) VALUES ($1, $2, $3, $4)",
+		testID, result.Name, result.Passed, result.Message)
+	if err != nil {
+		return fmt.Errorf("failed to insert into assertions table: %v", err)
+	}
+	return nil
+}
+
+// testRunColumns are the columns of the tests table read by scanTestRun.
+const testRunColumns = `test_id, target_url, schedule_id, started_at, finished_at, status, browser_version, tool_version, request_count,
+	response_count, csp, device, cpu_throttle, suite_id, shard_index, shard_total, video, pdfs, trace, statuses`
+
+// scanTestRun scans a row of testRunColumns.
+func scanTestRun(row interface{ Scan(...interface{}) error }) (TestRun, error) {
+	var run TestRun
+	var finishedAt sql.NullTime
+	var browserVersion, scheduleID, csp, device, suiteID, video, trace sql.NullString
+	var requestCount, responseCount, shardIndex, shardTotal sql.NullInt64
+	var cpuThrottle sql.NullFloat64
+	var statuses []byte
+
+	err := row.Scan(&run.TestID, &run.TargetURL, &scheduleID, &run.StartedAt, &finishedAt, &run.Status, &browserVersion, &run.ToolVersion,
+		&requestCount, &responseCount, &csp, &device, &cpuThrottle, &suiteID, &shardIndex, &shardTotal, &video, pq.Array(&run.PDFs), &trace,
+		&statuses)
+	if err != nil {
+		return run, err
+	}
+	if statuses != nil {
+		if err = json.Unmarshal(statuses, &run.Statuses); err != nil {
+			return run, fmt.Errorf("failed to parse status summary: %v", err)
+		}
+	}
+
+	run.FinishedAt, run.BrowserVersion, run.ScheduleID, run.CSP = finishedAt.Time, browserVersion.String, scheduleID.String, csp.String
+	run.Device, run.CPUThrottle = device.String, cpuThrottle.Float64
+	run.RequestCount, run.ResponseCount = int(requestCount.Int64), int(responseCount.Int64)
+	run.SuiteID, run.ShardIndex, run.ShardTotal = suiteID.String, int(shardIndex.Int64), int(shardTotal.Int64)
+	run.Video, run.Trace = video.String, trace.String
+	return run, nil
+}
+
+// GetTestRun returns the test run record of the given test.
+func GetTestRun(db *sql.DB, testID uuid.UUID) (TestRun, error) {
+	run, err := scanTestRun(db.QueryRow("SELECT "+testRunColumns+" FROM tests WHERE test_id = $1", testID))
+	if err != nil {
+		return run, fmt.Errorf("failed to query tests table: %w", err)
+	}
+	return run, nil
+}
+
+// GetSuiteRuns returns the test run records of the given suite, across its shards, in the order they started.
+func GetSuiteRuns(db *sql.DB, suiteID string) ([]TestRun, error) {
+	rows, err := db.Query("SELECT "+testRunColumns+" FROM tests WHERE suite_id = $1 ORDER BY started_at", suiteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tests table: %v", err)
+	}
+	defer rows.Close()
+
+	var runs []TestRun
+	for rows.Next() {
+		run, err := scanTestRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tests row: %v", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
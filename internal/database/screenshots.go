@@ -0,0 +1,46 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/browser"
+
+	"github.com/google/uuid"
+)
+
+// InsertScreenshot stores a screenshot of the page of a run.
+func InsertScreenshot(logger *slog.Logger, db *sql.DB, testID uuid.UUID, screenshot browser.Screenshot) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into screenshots table", "test_id", testID.String(), "label", screenshot.Label, "bytes", len(screenshot.Image))
+	_, err := db.Exec("INSERT INTO screenshots (test_id, label, url, mime_type, image, image_ref) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))",
+		testID, screenshot.Label, screenshot.URL, screenshot.MimeType, screenshot.Image, screenshot.Ref)
+	if err != nil {
+		return fmt.Errorf("failed to insert into screenshots table: %v", err)
+	}
+	return nil
+}
+
+// GetScreenshots returns the screenshots of the page of the given test, in the order they were taken. The image of
+// a screenshot uploaded to the artifact store is empty, and located by its Ref.
+func GetScreenshots(db *sql.DB, testID uuid.UUID) ([]browser.Screenshot, error) {
+	rows, err := db.Query("SELECT label, url, mime_type, image, image_ref FROM screenshots WHERE test_id = $1 ORDER BY created_at", testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query screenshots table: %v", err)
+	}
+	defer rows.Close()
+
+	var screenshots []browser.Screenshot
+	for rows.Next() {
+		var s browser.Screenshot
+		var ref sql.NullString
+		if err = rows.Scan(&s.Label, &s.URL, &s.MimeType, &s.Image, &ref); err != nil {
+			return nil, fmt.Errorf("failed to scan screenshots row: %v", err)
+		}
+		s.Ref = ref.String
+		screenshots = append(screenshots, s)
+	}
+	return screenshots, rows.Err()
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"web-tester/internal/audit"
+
+	"github.com/google/uuid"
+)
+
+// InsertScript stores the hash of a third-party script loaded by a run of a target. Its content is only
+// stored the first time its hash is seen for the URL, to diff it against later versions.
+func InsertScript(logger *slog.Logger, db *sql.DB, testID uuid.UUID, target string, script audit.Script) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into scripts table", "test_id", testID.String(), "url", script.URL)
+	_, err := db.Exec(`INSERT INTO scripts (test_id, target_url, url, hash, size, content)
+		SELECT $1, $2, $3, $4, $5, CASE WHEN EXISTS (SELECT 1 FROM scripts WHERE url = $3 AND hash = $4) THEN NULL ELSE $6 END`,
+		testID, target, script.URL, script.Hash, len(script.Content), script.Content)
+	if err != nil {
+		return fmt.Errorf("failed to insert into scripts table: %v", err)
+	}
+	return nil
+}
+
+// GetPreviousScript returns the hash and content of a third-party script as last loaded by a run of the target,
+// and whether it was loaded before.
+func GetPreviousScript(db *sql.DB, target, url string) (hash, content string, found bool, err error) {
+	err = db.QueryRow(`SELECT s.hash, COALESCE((SELECT c.content FROM scripts c WHERE c.url = s.url AND c.hash = s.hash AND c.content IS NOT NULL LIMIT 1), '')
+		FROM scripts s WHERE s.target_url = $1 AND s.url = $2 ORDER BY s.created_at DESC LIMIT 1`, target, url).Scan(&hash, &content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, fmt.Errorf("failed to query scripts table: %v", err)
+	}
+	return hash, content, true, nil
+}
+
+// InsertScriptChange stores the diff of a third-party script whose content changed since the previous run.
+func InsertScriptChange(logger *slog.Logger, db *sql.DB, testID uuid.UUID, url, previousHash, hash, diff string) error {
+	if db == nil {
+		return nil
+	}
+	logger.Debug("Inserting into script_changes table", "test_id", testID.String(), "url", url)
+	_, err := db.Exec("INSERT INTO script_changes (test_id, url, previous_hash, hash, diff) VALUES ($1, $2, $3, $4, $5)",
+		testID, url, previousHash, hash, diff)
+	if err != nil {
+		return fmt.Errorf("failed to insert into script_changes table: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"web-tester/internal/bodystore"
+	"web-tester/internal/config"
+
+	"github.com/google/uuid"
+)
+
+// The backends the captured events can be stored to, set in EVENT_SINK.
+const (
+	SinkPostgres = "postgres"
+	SinkFile     = "file"
+	SinkStdout   = "stdout"
+	SinkMongoDB  = "mongodb"
+	SinkNone     = "none"
+)
+
+// Sink stores the captured events of a test. Write may block while the sink is busy, and Close stores the
+// pending events, returning the number of events that could not be stored.
+type Sink interface {
+	Write(event Event)
+	Close() int
+}
+
+// NewSink creates the sink storing the events of the test to the backend set in writerCfg: the events table of
+// db, a JSON Lines file per test in the sink directory, stdout, a MongoDB collection, or nowhere. The bodies too
+// large for a MongoDB document are moved to bodies, when it is not nil.
+func NewSink(logger *slog.Logger, db *sql.DB, testID uuid.UUID, writerCfg config.WriterConfig, bodies *bodystore.Store) (Sink, error) {
+	switch writerCfg.Sink {
+	case SinkPostgres, "":
+		return NewPostgresSink(logger, db, testID, writerCfg.Workers, writerCfg.BatchSize, writerCfg.QueueSize), nil
+	case SinkFile:
+		return NewFileSink(logger, filepath.Join(writerCfg.SinkDir, "events-"+testID.String()+".ndjson"), testID)
+	case SinkStdout:
+		return NewStdoutSink(logger, testID), nil
+	case SinkMongoDB:
+		return NewMongoSink(logger, testID, writerCfg, bodies)
+	case SinkNone:
+		return NoopSink{}, nil
+	}
+	return nil, fmt.Errorf("unknown event sink %q", writerCfg.Sink)
+}
+
+// sinkRecord is an event as written by the JSON Lines sinks, along with the test it was captured in.
+type sinkRecord struct {
+	TestID uuid.UUID `json:"test_id"`
+	Event
+}
+
+// jsonLines writes the events of a test as JSON Lines, one event per line, counting the events it could not write.
+type jsonLines struct {
+	logger *slog.Logger
+	testID uuid.UUID
+	mu     *sync.Mutex
+	enc    *json.Encoder
+	failed int
+}
+
+func (j *jsonLines) Write(event Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.enc.Encode(sinkRecord{TestID: j.testID, Event: event}); err != nil {
+		j.logger.Error("failed to write event", "url", event.URL, "error", err)
+		j.failed++
+	}
+}
+
+// FileSink stores the events of a test in a JSON Lines file.
+type FileSink struct {
+	jsonLines
+	file *os.File
+}
+
+// NewFileSink creates a FileSink storing the events of the test in the file at path, creating its directory
+// when missing. The file is created or truncated.
+func NewFileSink(logger *slog.Logger, path string, testID uuid.UUID) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create event sink directory: %v", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event sink file: %v", err)
+	}
+	logger.Debug("storing events to file", "test_id", testID.String(), "path", path)
+	return &FileSink{jsonLines: jsonLines{logger: logger, testID: testID, mu: &sync.Mutex{}, enc: json.NewEncoder(f)}, file: f}, nil
+}
+
+// Close closes the file.
+func (f *FileSink) Close() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.file.Close(); err != nil {
+		f.logger.Error("failed to close event sink file", "error", err)
+	}
+	return f.failed
+}
+
+// stdoutMu keeps the lines of the tests writing to stdout at once from interleaving.
+var stdoutMu sync.Mutex
+
+// StdoutSink writes the events of a test to stdout as JSON Lines.
+type StdoutSink struct {
+	jsonLines
+}
+
+// NewStdoutSink creates a StdoutSink writing the events of the test.
+func NewStdoutSink(logger *slog.Logger, testID uuid.UUID) *StdoutSink {
+	return &StdoutSink{jsonLines{logger: logger, testID: testID, mu: &stdoutMu, enc: json.NewEncoder(os.Stdout)}}
+}
+
+// Close returns the number of events that could not be written.
+func (s *StdoutSink) Close() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failed
+}
+
+// NoopSink discards the events, e.g. for runs only interested in the findings and assertions.
+type NoopSink struct{}
+
+func (NoopSink) Write(Event) {}
+
+func (NoopSink) Close() int { return 0 }
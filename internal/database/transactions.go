@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"web-tester/internal/browser"
+
+	"github.com/google/uuid"
+)
+
+// StoredTransaction is a full HTTP transaction as read back from the transactions table.
+type StoredTransaction struct {
+	RequestID     string             `json:"request_id"`
+	Method        string             `json:"method"`
+	URL           string             `json:"url"`
+	Domain        string             `json:"domain"`
+	ResourceType  string             `json:"resource_type"`
+	Redirects     []browser.Redirect `json:"redirects,omitempty"`
+	Status        int64              `json:"status,omitempty"`
+	MimeType      string             `json:"mime_type,omitempty"`
+	Finished      bool               `json:"finished"`
+	Failed        bool               `json:"failed"`
+	ErrorText     string             `json:"error_text,omitempty"`
+	Canceled      bool               `json:"canceled,omitempty"`
+	BlockedReason string             `json:"blocked_reason,omitempty"`
+	EncodedBytes  float64            `json:"encoded_bytes"`
+	BodySize      int                `json:"body_size"`
+	TotalMS       float64            `json:"total_ms"`
+}
+
+// InsertTransaction stores a full HTTP transaction, one row per request ID, next to the raw request and
+// response events stored in the events table.
+func InsertTransaction(logger *slog.Logger, db *sql.DB, testID uuid.UUID, t browser.Transaction) error {
+	if db == nil {
+		return nil
+	}
+	row := StoredTransaction{RequestID: string(t.RequestID), Method: t.Method, URL: t.URL, ResourceType: t.ResourceType,
+		Redirects: t.Redirects, Finished: t.Finished, Failed: t.Failed()}
+	if u, err := url.Parse(t.URL); err == nil {
+		row.Domain = u.Hostname()
+	}
+	if t.Response != nil {
+		row.Status, row.MimeType = t.Response.Status, t.Response.MimeType
+		row.EncodedBytes, row.BodySize, row.TotalMS = t.Response.Timing.EncodedBytes, t.Response.BodySize, t.Response.Timing.Total
+	}
+	if t.Failure != nil {
+		row.ErrorText, row.Canceled, row.BlockedReason = t.Failure.ErrorText, t.Failure.Canceled, t.Failure.BlockedReason
+	}
+
+	redirects, err := json.Marshal(row.Redirects)
+	if err != nil {
+		return fmt.Errorf("failed to marshal redirects: %v", err)
+	}
+
+	logger.Debug("Inserting into transactions table", "test_id", testID.String(), "request_id", row.RequestID, "url", row.URL)
+	_, err = db.Exec(`INSERT INTO transactions (test_id, request_id, method, url, domain, resource_type, redirects, status, mime_type,
+		finished, failed, error_text, canceled, blocked_reason, encoded_bytes, body_size, total_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`,
+		testID, row.RequestID, row.Method, row.URL, row.Domain, row.ResourceType, string(redirects), row.Status, row.MimeType,
+		row.Finished, row.Failed, row.ErrorText, row.Canceled, row.BlockedReason, row.EncodedBytes, row.BodySize, row.TotalMS)
+	if err != nil {
+		return fmt.Errorf("failed to insert into transactions table: %v", err)
+	}
+	return nil
+}
+
+// GetTransactions returns the transactions of the given test, in the order they were stored.
+func GetTransactions(db *sql.DB, testID uuid.UUID) ([]StoredTransaction, error) {
+	rows, err := db.Query(`SELECT request_id, method, url, domain, resource_type, COALESCE(redirects, 'null'), status, mime_type,
+		finished, failed, error_text, canceled, blocked_reason, encoded_bytes, body_size, total_ms
+		FROM transactions WHERE test_id = $1 ORDER BY created_at`, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions table: %v", err)
+	}
+	defer rows.Close()
+
+	var transactions []StoredTransaction
+	for rows.Next() {
+		var t StoredTransaction
+		var redirects []byte
+		err = rows.Scan(&t.RequestID, &t.Method, &t.URL, &t.Domain, &t.ResourceType, &redirects, &t.Status, &t.MimeType,
+			&t.Finished, &t.Failed, &t.ErrorText, &t.Canceled, &t.BlockedReason, &t.EncodedBytes, &t.BodySize, &t.TotalMS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transactions row: %v", err)
+		}
+		if err = json.Unmarshal(redirects, &t.Redirects); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction redirects: %v", err)
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
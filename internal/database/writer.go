@@ -0,0 +1,126 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// maxBatchSize keeps the arguments of a batch insert under the 65535 parameters allowed by postgres.
+const maxBatchSize = 3000
+
+// PostgresSink stores the events of a test in the events table asynchronously: a buffered queue feeds workers inserting them in
+// batches, each batch with a single multi-row statement inside a transaction. Write blocks while the
+// queue is full, and Close flushes the queued events.
+type PostgresSink struct {
+	logger    *slog.Logger
+	db        *sql.DB
+	testID    uuid.UUID
+	batchSize int
+	events    chan Event
+	wg        sync.WaitGroup
+
+	mu sync.Mutex
+	// failed counts the events that could not be stored
+	failed int
+}
+
+// NewPostgresSink starts a PostgresSink storing the events of the test in db with the given number of workers,
+// batch size and queue size. Like every write in this package, writing is a no-op when db is nil.
+func NewPostgresSink(logger *slog.Logger, db *sql.DB, testID uuid.UUID, workers, batchSize, queueSize int) *PostgresSink {
+	workers = max(workers, 1)
+	batchSize = min(max(batchSize, 1), maxBatchSize)
+	w := &PostgresSink{logger: logger, db: db, testID: testID, batchSize: batchSize, events: make(chan Event, max(queueSize, 0))}
+	if db == nil {
+		return w
+	}
+	for i := 0; i < workers; i++ {
+		w.wg.Add(1)
+		go w.work()
+	}
+	return w
+}
+
+// Write queues an event to be stored, blocking while the queue is full.
+func (w *PostgresSink) Write(event Event) {
+	if w.db == nil {
+		return
+	}
+	w.events <- event
+}
+
+// Close stores the queued events and stops the workers. It returns the number of events that could not be stored.
+func (w *PostgresSink) Close() int {
+	close(w.events)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.failed
+}
+
+// work inserts the queued events in batches, flushing the last partial batch once the queue is closed.
+func (w *PostgresSink) work() {
+	defer w.wg.Done()
+	batch := make([]Event, 0, w.batchSize)
+	for event := range w.events {
+		batch = append(batch, event)
+		if len(batch) == w.batchSize {
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		w.flush(batch)
+	}
+}
+
+// flush inserts a batch of events, counting them as failed when the batch cannot be stored.
+func (w *PostgresSink) flush(batch []Event) {
+	var args []interface{}
+	rows, columns := 0, 0
+	for _, event := range batch {
+		eventArgs, err := eventArgs(w.logger, w.testID, event)
+		if err != nil {
+			w.logger.Error("failed to insert into database", "url", event.URL, "error", err)
+			w.fail(1)
+			continue
+		}
+		args = append(args, eventArgs...)
+		rows, columns = rows+1, len(eventArgs)
+	}
+	if rows == 0 {
+		return
+	}
+
+	w.logger.Debug("Inserting batch into events table", "test_id", w.testID.String(), "events", rows)
+	if err := w.insert(insertEventsQuery(rows, columns), args); err != nil {
+		w.logger.Error("failed to insert into database", "events", rows, "error", err)
+		w.fail(rows)
+	}
+}
+
+// insert runs a batch insert inside a transaction.
+func (w *PostgresSink) insert(query string, args []interface{}) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	if _, err = tx.Exec(query, args...); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert into events table: %v", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit events: %v", err)
+	}
+	return nil
+}
+
+func (w *PostgresSink) fail(events int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.failed += events
+}
@@ -0,0 +1,195 @@
+// Package diff compares two stored runs to surface regressions between them.
+package diff
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"web-tester/internal/audit"
+	"web-tester/internal/database"
+)
+
+// minSizeDelta is the minimum relative change of a response size reported as a delta.
+const minSizeDelta = 0.1
+
+// StatusChange is an endpoint whose response status changed between the runs.
+type StatusChange struct {
+	Endpoint string
+	Before   int64
+	After    int64
+}
+
+// SizeDelta is an endpoint whose response size changed by at least minSizeDelta between the runs.
+type SizeDelta struct {
+	Endpoint string
+	Before   float64
+	After    float64
+}
+
+// HeaderChange is a security header whose value changed on an endpoint between the runs.
+type HeaderChange struct {
+	Endpoint string
+	Header   string
+	Before   string
+	After    string
+}
+
+// Report holds the differences between a base run and a head run.
+type Report struct {
+	AddedEndpoints      []string
+	RemovedEndpoints    []string
+	StatusChanges       []StatusChange
+	SizeDeltas          []SizeDelta
+	NewThirdParties     []string
+	SecurityHeaderDiffs []HeaderChange
+}
+
+// endpoint returns the identity of a URL across runs: its scheme, host and path, without the query string.
+func endpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// responsesByEndpoint indexes the response events of a run by endpoint, keeping the first one seen.
+func responsesByEndpoint(events []database.StoredEvent) map[string]database.StoredEvent {
+	responses := map[string]database.StoredEvent{}
+	for _, e := range events {
+		if e.Type != "response" {
+			continue
+		}
+		if _, ok := responses[endpoint(e.URL)]; !ok {
+			responses[endpoint(e.URL)] = e
+		}
+	}
+	return responses
+}
+
+// thirdParties returns the domains contacted by a run that do not belong to the target's domain.
+func thirdParties(events []database.StoredEvent, target string) map[string]bool {
+	t, _ := url.Parse(target)
+	domain := ""
+	if t != nil {
+		domain = strings.TrimPrefix(t.Hostname(), "www.")
+	}
+
+	domains := map[string]bool{}
+	for _, e := range events {
+		if e.Domain == "" || e.Domain == domain || strings.HasSuffix(e.Domain, "."+domain) {
+			continue
+		}
+		domains[e.Domain] = true
+	}
+	return domains
+}
+
+// Compare reports the differences between the events of a base run and a head run of the same target.
+func Compare(base, head []database.StoredEvent, target string) Report {
+	report := Report{}
+	before, after := responsesByEndpoint(base), responsesByEndpoint(head)
+
+	for ep, a := range after {
+		b, ok := before[ep]
+		if !ok {
+			report.AddedEndpoints = append(report.AddedEndpoints, ep)
+			continue
+		}
+
+		if a.Status != b.Status {
+			report.StatusChanges = append(report.StatusChanges, StatusChange{Endpoint: ep, Before: b.Status, After: a.Status})
+		}
+		if delta := math.Abs(a.EncodedBytes - b.EncodedBytes); delta > 0 && delta >= minSizeDelta*math.Max(b.EncodedBytes, 1) {
+			report.SizeDeltas = append(report.SizeDeltas, SizeDelta{Endpoint: ep, Before: b.EncodedBytes, After: a.EncodedBytes})
+		}
+
+		beforeHeaders, afterHeaders := b.Headers(), a.Headers()
+		for _, h := range audit.SecurityHeaders {
+			if beforeHeaders[h] != afterHeaders[h] {
+				report.SecurityHeaderDiffs = append(report.SecurityHeaderDiffs, HeaderChange{Endpoint: ep, Header: h, Before: beforeHeaders[h], After: afterHeaders[h]})
+			}
+		}
+	}
+	for ep := range before {
+		if _, ok := after[ep]; !ok {
+			report.RemovedEndpoints = append(report.RemovedEndpoints, ep)
+		}
+	}
+
+	baseDomains := thirdParties(base, target)
+	for domain := range thirdParties(head, target) {
+		if !baseDomains[domain] {
+			report.NewThirdParties = append(report.NewThirdParties, domain)
+		}
+	}
+
+	sort.Strings(report.AddedEndpoints)
+	sort.Strings(report.RemovedEndpoints)
+	sort.Strings(report.NewThirdParties)
+	sort.Slice(report.StatusChanges, func(i, j int) bool { return report.StatusChanges[i].Endpoint < report.StatusChanges[j].Endpoint })
+	sort.Slice(report.SizeDeltas, func(i, j int) bool { return report.SizeDeltas[i].Endpoint < report.SizeDeltas[j].Endpoint })
+	sort.Slice(report.SecurityHeaderDiffs, func(i, j int) bool {
+		if report.SecurityHeaderDiffs[i].Endpoint != report.SecurityHeaderDiffs[j].Endpoint {
+			return report.SecurityHeaderDiffs[i].Endpoint < report.SecurityHeaderDiffs[j].Endpoint
+		}
+		return report.SecurityHeaderDiffs[i].Header < report.SecurityHeaderDiffs[j].Header
+	})
+	return report
+}
+
+// Empty reports whether the runs had no differences.
+func (r Report) Empty() bool {
+	return len(r.AddedEndpoints) == 0 && len(r.RemovedEndpoints) == 0 && len(r.StatusChanges) == 0 &&
+		len(r.SizeDeltas) == 0 && len(r.NewThirdParties) == 0 && len(r.SecurityHeaderDiffs) == 0
+}
+
+// Write prints the report in a human readable form.
+func (r Report) Write(w io.Writer) {
+	if r.Empty() {
+		fmt.Fprintln(w, "no differences")
+		return
+	}
+
+	section := func(title string, n int) bool {
+		if n == 0 {
+			return false
+		}
+		fmt.Fprintf(w, "\n%s (%d):\n", title, n)
+		return true
+	}
+
+	if section("new endpoints", len(r.AddedEndpoints)) {
+		for _, ep := range r.AddedEndpoints {
+			fmt.Fprintf(w, "  + %s\n", ep)
+		}
+	}
+	if section("removed endpoints", len(r.RemovedEndpoints)) {
+		for _, ep := range r.RemovedEndpoints {
+			fmt.Fprintf(w, "  - %s\n", ep)
+		}
+	}
+	if section("status code changes", len(r.StatusChanges)) {
+		for _, c := range r.StatusChanges {
+			fmt.Fprintf(w, "  %s: %d -> %d\n", c.Endpoint, c.Before, c.After)
+		}
+	}
+	if section("response size deltas", len(r.SizeDeltas)) {
+		for _, d := range r.SizeDeltas {
+			fmt.Fprintf(w, "  %s: %.0f -> %.0f bytes (%+.0f)\n", d.Endpoint, d.Before, d.After, d.After-d.Before)
+		}
+	}
+	if section("new third-party domains", len(r.NewThirdParties)) {
+		for _, d := range r.NewThirdParties {
+			fmt.Fprintf(w, "  + %s\n", d)
+		}
+	}
+	if section("changed security headers", len(r.SecurityHeaderDiffs)) {
+		for _, c := range r.SecurityHeaderDiffs {
+			fmt.Fprintf(w, "  %s %s: %q -> %q\n", c.Endpoint, c.Header, c.Before, c.After)
+		}
+	}
+}
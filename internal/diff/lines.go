@@ -0,0 +1,63 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxLineDiffCells bounds the size of the table computing a line diff, beyond which only a summary is returned.
+const maxLineDiffCells = 4_000_000
+
+// splitLines splits text in lines. Minified code, which holds few lines, is also split after each statement
+// so its diff stays readable.
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	if len(lines) >= 10 {
+		return lines
+	}
+	var split []string
+	for _, line := range lines {
+		split = append(split, strings.SplitAfter(line, ";")...)
+	}
+	return split
+}
+
+// Lines returns a diff of the lines of before and after, prefixing removed lines with "-" and added lines
+// with "+". Unchanged lines are left out.
+func Lines(before, after string) string {
+	a, b := splitLines(before), splitLines(after)
+	if len(a)*len(b) > maxLineDiffCells {
+		return fmt.Sprintf("too large to diff: %d -> %d lines, %d -> %d bytes\n", len(a), len(b), len(before), len(after))
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&out, "-%s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+%s\n", b[j])
+			j++
+		}
+	}
+	return out.String()
+}
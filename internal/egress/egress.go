@@ -0,0 +1,69 @@
+// Package egress restricts the network calls the tool makes by itself, outside of the browser,
+// to an allow-list of hosts. It backs the air-gapped mode required in locked-down environments.
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrBlocked is returned for the calls to hosts outside of the allow-list.
+var ErrBlocked = errors.New("egress blocked")
+
+// Guard is an http.RoundTripper only letting through the requests to allowed hosts, logging the others.
+type Guard struct {
+	logger *slog.Logger
+	next   http.RoundTripper
+	hosts  []string
+}
+
+// New creates a Guard allowing the hosts and their subdomains, sending the allowed requests through next,
+// or http.DefaultTransport when it is nil. Hosts may be given as URLs.
+func New(logger *slog.Logger, next http.RoundTripper, hosts ...string) *Guard {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	g := &Guard{logger: logger, next: next}
+	for _, h := range hosts {
+		if h = hostname(h); h != "" {
+			g.hosts = append(g.hosts, strings.TrimPrefix(h, "www."))
+		}
+	}
+	return g
+}
+
+// hostname returns the lowercase host of a URL or host[:port], without the port.
+func hostname(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	if h, _, err := net.SplitHostPort(s); err == nil {
+		return strings.ToLower(h)
+	}
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// Allowed reports whether calls to the host, given as a URL or host[:port], are allowed.
+func (g *Guard) Allowed(host string) bool {
+	host = hostname(host)
+	for _, h := range g.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundTrip sends the request when its host is allowed, otherwise logs the violation and returns ErrBlocked.
+func (g *Guard) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !g.Allowed(req.URL.Host) {
+		g.logger.Warn("egress violation blocked", "method", req.Method, "url", req.URL.String())
+		return nil, fmt.Errorf("%w: %s is not in the allow-list", ErrBlocked, req.URL.Hostname())
+	}
+	return g.next.RoundTrip(req)
+}
@@ -0,0 +1,166 @@
+// Package explore interacts with a loaded page on its own, scrolling it and clicking its buttons and menu toggles,
+// to trigger the lazy-loaded traffic a passive capture of the page load misses.
+package explore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Budget bounds the exploration of a page: the clicks and scrolls it makes at most, and the delay waited after
+// every one of them for the traffic it triggers. The elements whose label matches Skip are never clicked, e.g.
+// the logout and delete buttons.
+type Budget struct {
+	Interactions int
+	Scrolls      int
+	Delay        time.Duration
+	Skip         *regexp.Regexp
+}
+
+// Interaction is a click or scroll made by the exploration. Target is the element clicked, or the scroll offset
+// reached, and Requests the number of requests the page sent once it was made, until the next one.
+type Interaction struct {
+	Position int    `json:"position"`
+	Action   string `json:"action"`
+	Target   string `json:"target"`
+	Requests int    `json:"requests"`
+}
+
+// candidate is an element of the page the exploration may click, tagged with its ID in the page.
+type candidate struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+}
+
+// candidatesScript tags the visible clickable elements of the page not clicked yet with an ID and returns them.
+// Links and form submits are left out, since they navigate away from the page.
+const candidatesScript = `(() => {
+	window.__webTesterExplore = window.__webTesterExplore || 0;
+	const selector = 'button, input[type=button], summary, [role=button], [role=tab], [role=menuitem], [aria-expanded], [aria-haspopup]';
+	const found = [];
+	for (const el of document.querySelectorAll(selector)) {
+		if (el.hasAttribute('data-web-tester-explored') || el.disabled) continue;
+		if (el.closest('a[href]:not([href^="#"])')) continue;
+		if (el.form && (el.type === 'submit' || el.type === 'image')) continue;
+		const rect = el.getBoundingClientRect();
+		const style = getComputedStyle(el);
+		if (rect.width === 0 || rect.height === 0 || style.visibility === 'hidden' || style.display === 'none') continue;
+		if (!el.hasAttribute('data-web-tester-explore')) el.setAttribute('data-web-tester-explore', ++window.__webTesterExplore);
+		const label = (el.getAttribute('aria-label') || el.innerText || el.value || el.title || '').trim().replace(/\s+/g, ' ');
+		found.push({id: Number(el.getAttribute('data-web-tester-explore')), label: el.tagName.toLowerCase() + ' "' + label.slice(0, 80) + '"'});
+	}
+	return found;
+})()`
+
+// clickScript clicks the candidate with the given ID, marking it as explored, and tells whether it was still there.
+const clickScript = `(id => {
+	const el = document.querySelector('[data-web-tester-explore="' + id + '"]');
+	if (!el) return false;
+	el.setAttribute('data-web-tester-explored', '');
+	el.scrollIntoView({block: 'center'});
+	el.click();
+	return true;
+})(%d)`
+
+// scrollScript scrolls the page down by a screen and returns the scroll offset before and after.
+const scrollScript = `(() => {
+	const before = window.scrollY;
+	window.scrollBy(0, window.innerHeight);
+	return [before, window.scrollY];
+})()`
+
+// Run explores the page of the browser context ctx within the budget, scrolling it down to its end first, since
+// the content loaded by the scrolls may hold more elements to click, and then clicking its elements. count returns
+// the number of requests sent by the page so far. The exploration stops early once the page navigated away, and
+// the interactions made before an error are returned along with it.
+func Run(ctx context.Context, logger *slog.Logger, budget Budget, count func() int) ([]Interaction, error) {
+	var interactions []Interaction
+	var start string
+	if err := chromedp.Run(ctx, chromedp.Location(&start)); err != nil {
+		return nil, fmt.Errorf("failed to read the location of the page: %v", err)
+	}
+
+	// record waits for the traffic of the interaction, tells whether the page is still the one explored
+	record := func(action, target string, before int) (bool, error) {
+		if err := chromedp.Run(ctx, chromedp.Sleep(budget.Delay)); err != nil {
+			return false, err
+		}
+		i := Interaction{Position: len(interactions) + 1, Action: action, Target: target, Requests: count() - before}
+		logger.Debug("explored the page", "action", i.Action, "target", i.Target, "requests", i.Requests)
+		interactions = append(interactions, i)
+		var location string
+		if err := chromedp.Run(ctx, chromedp.Location(&location)); err != nil {
+			return false, err
+		}
+		return location == start, nil
+	}
+
+	for i := 0; i < budget.Scrolls; i++ {
+		var offsets []float64
+		before := count()
+		if err := chromedp.Run(ctx, chromedp.Evaluate(scrollScript, &offsets)); err != nil {
+			return interactions, fmt.Errorf("failed to scroll the page: %v", err)
+		}
+		if len(offsets) != 2 || offsets[1] <= offsets[0] {
+			break
+		}
+		same, err := record("scroll", fmt.Sprintf("%.0f", offsets[1]), before)
+		if err != nil {
+			return interactions, fmt.Errorf("failed to scroll the page: %v", err)
+		}
+		if !same {
+			logger.Info("the page navigated away, stopping the exploration")
+			return interactions, nil
+		}
+	}
+
+	skipped := map[int]bool{}
+	for clicks := 0; clicks < budget.Interactions; {
+		var candidates []candidate
+		if err := chromedp.Run(ctx, chromedp.Evaluate(candidatesScript, &candidates)); err != nil {
+			return interactions, fmt.Errorf("failed to find the elements to click: %v", err)
+		}
+		next, found := candidate{}, false
+		for _, c := range candidates {
+			if skipped[c.ID] {
+				continue
+			}
+			if budget.Skip != nil && budget.Skip.MatchString(c.Label) {
+				logger.Debug("skipping element", "target", c.Label)
+				skipped[c.ID] = true
+				continue
+			}
+			next, found = c, true
+			break
+		}
+		if !found {
+			break
+		}
+
+		var clicked bool
+		before := count()
+		if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(clickScript, next.ID), &clicked)); err != nil {
+			return interactions, fmt.Errorf("failed to click %s: %v", next.Label, err)
+		}
+		// an element removed since it was found is not clicked and does not use the budget
+		skipped[next.ID] = true
+		if !clicked {
+			continue
+		}
+		clicks++
+		same, err := record("click", next.Label, before)
+		if err != nil {
+			return interactions, fmt.Errorf("failed to click %s: %v", next.Label, err)
+		}
+		if !same {
+			logger.Info("the page navigated away, stopping the exploration", "target", next.Label)
+			return interactions, nil
+		}
+	}
+	return interactions, nil
+}
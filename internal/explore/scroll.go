@@ -0,0 +1,73 @@
+package explore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Scroll is the strategy scrolling a page to its bottom repeatedly, so its lazy-loaded images and content and its
+// infinite lists send their requests. The page is scrolled at most MaxIterations times, waiting Delay after every
+// scroll, and the scrolling stops once the height of the page did not grow and no request was sent for Stable
+// scrolls in a row.
+type Scroll struct {
+	MaxIterations int
+	Delay         time.Duration
+	Stable        int
+}
+
+// ScrollResult is the outcome of a scroll to the end of a page: the scrolls made, the final height of the page
+// and the requests they triggered. Stabilized tells whether the page stopped growing before MaxIterations.
+type ScrollResult struct {
+	Iterations int
+	Height     float64
+	Requests   int
+	Stabilized bool
+}
+
+// scrollToEndScript scrolls the page to its bottom and returns its height.
+const scrollToEndScript = `(() => {
+	const el = document.scrollingElement || document.documentElement;
+	window.scrollTo(0, el.scrollHeight);
+	return el.scrollHeight;
+})()`
+
+// pageHeightScript returns the height of the page.
+const pageHeightScript = `(document.scrollingElement || document.documentElement).scrollHeight`
+
+// ScrollToEnd scrolls the page of the browser context ctx to its bottom with the strategy until its height is
+// stable, then back to its top so the journey starts from the page as loaded. count returns the number of requests
+// sent by the page so far.
+func ScrollToEnd(ctx context.Context, logger *slog.Logger, strategy Scroll, count func() int) (ScrollResult, error) {
+	var result ScrollResult
+	start, stable := count(), 0
+	for result.Iterations < strategy.MaxIterations {
+		var before, after float64
+		sent := count()
+		if err := chromedp.Run(ctx, chromedp.Evaluate(scrollToEndScript, &before), chromedp.Sleep(strategy.Delay),
+			chromedp.Evaluate(pageHeightScript, &after)); err != nil {
+			return result, fmt.Errorf("failed to scroll to the end of the page: %v", err)
+		}
+		result.Iterations++
+		result.Height = after
+		logger.Debug("scrolled to the end of the page", "iteration", result.Iterations, "height", after, "requests", count()-sent)
+
+		if after > before || count() > sent {
+			stable = 0
+			continue
+		}
+		if stable++; stable >= strategy.Stable {
+			result.Stabilized = true
+			break
+		}
+	}
+	result.Requests = count() - start
+
+	if err := chromedp.Run(ctx, chromedp.Evaluate(`window.scrollTo(0, 0)`, nil)); err != nil {
+		return result, fmt.Errorf("failed to scroll back to the top of the page: %v", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,222 @@
+// Package graphql detects the GraphQL requests among the captured traffic and extracts their operations, so the
+// API calls of GraphQL-heavy applications can be told apart when they all go to the same endpoint.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Operation is a GraphQL operation sent by a request. Position is its index in a batch of operations, 0 when the
+// request sent a single one. Type is query, mutation or subscription, and Name empty for an anonymous operation.
+// Query is empty for an automatic persisted query, which only sends PersistedHash, the hash of its document.
+type Operation struct {
+	RequestID     string          `json:"request_id"`
+	URL           string          `json:"url"`
+	Position      int             `json:"position"`
+	Type          string          `json:"type"`
+	Name          string          `json:"name,omitempty"`
+	Query         string          `json:"query,omitempty"`
+	Variables     json.RawMessage `json:"variables,omitempty"`
+	PersistedHash string          `json:"persisted_hash,omitempty"`
+}
+
+// payload is a GraphQL request, as sent in a JSON body.
+type payload struct {
+	Query         *string         `json:"query"`
+	OperationName string          `json:"operationName"`
+	Variables     json.RawMessage `json:"variables"`
+	Extensions    struct {
+		PersistedQuery *struct {
+			SHA256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+// isOperation tells whether the payload is a GraphQL operation, with a query or a persisted query hash.
+func (p payload) isOperation() bool {
+	return p.Query != nil || p.Extensions.PersistedQuery != nil
+}
+
+// Parse returns the GraphQL operations sent by a request, none when it is not a GraphQL request. The operations
+// are read from a JSON body holding an operation or a batch of them, an application/graphql body, or the query
+// string of a GET request.
+func Parse(requestID, method, rawURL, contentType string, body []byte) []Operation {
+	var payloads []payload
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	switch trimmed := bytes.TrimSpace(body); {
+	case strings.TrimSpace(mediaType) == "application/graphql":
+		query := string(trimmed)
+		payloads = append(payloads, payload{Query: &query})
+	case len(trimmed) > 0 && trimmed[0] == '{':
+		var p payload
+		if json.Unmarshal(trimmed, &p) == nil && p.isOperation() {
+			payloads = append(payloads, p)
+		}
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		var batch []payload
+		if json.Unmarshal(trimmed, &batch) != nil {
+			return nil
+		}
+		for _, p := range batch {
+			// a batch is only GraphQL when all of its items are operations
+			if !p.isOperation() {
+				return nil
+			}
+		}
+		payloads = batch
+	case method == "GET":
+		if p, ok := fromQueryString(rawURL); ok {
+			payloads = append(payloads, p)
+		}
+	}
+
+	operations := make([]Operation, 0, len(payloads))
+	for i, p := range payloads {
+		op := Operation{RequestID: requestID, URL: rawURL, Position: i, Name: p.OperationName, Type: "query"}
+		if p.Query != nil {
+			op.Query = *p.Query
+			typ, name := operationOf(op.Query, p.OperationName)
+			op.Type = typ
+			if op.Name == "" {
+				op.Name = name
+			}
+		}
+		if p.Extensions.PersistedQuery != nil {
+			op.PersistedHash = p.Extensions.PersistedQuery.SHA256Hash
+		}
+		if v := bytes.TrimSpace(p.Variables); len(v) > 0 && !bytes.Equal(v, []byte("null")) {
+			op.Variables = v
+		}
+		operations = append(operations, op)
+	}
+	return operations
+}
+
+// fromQueryString reads a GraphQL operation from the query string of a GET request, where its variables and
+// extensions are JSON encoded.
+func fromQueryString(rawURL string) (payload, bool) {
+	var p payload
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return p, false
+	}
+	values := u.Query()
+	if values.Has("query") {
+		query := values.Get("query")
+		p.Query = &query
+	}
+	if extensions := values.Get("extensions"); extensions != "" {
+		if json.Unmarshal([]byte(extensions), &p.Extensions) != nil {
+			return p, false
+		}
+	}
+	if !p.isOperation() {
+		return p, false
+	}
+	p.OperationName = values.Get("operationName")
+	if variables := values.Get("variables"); json.Valid([]byte(variables)) {
+		p.Variables = json.RawMessage(variables)
+	}
+	return p, true
+}
+
+// operationOf returns the type and name of the operation of the document that runs, the one named name when the
+// document holds several, or else the first. The fragments of the document are skipped.
+func operationOf(document, name string) (string, string) {
+	type operation struct{ typ, name string }
+	var operations []operation
+	depth := 0
+	// keyword is the operation keyword just read at the top level, waiting for the name that may follow
+	keyword := ""
+	for i := 0; i < len(document); {
+		c := document[i]
+		switch {
+		case c == '#':
+			for i < len(document) && document[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(document[i:], `"""`):
+			end := strings.Index(document[i+3:], `"""`)
+			if end < 0 {
+				return "query", ""
+			}
+			i += end + 6
+			continue
+		case c == '"':
+			i++
+			for i < len(document) && document[i] != '"' {
+				if document[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			continue
+		case c == '{':
+			// the selection set of a top level definition ends it
+			if depth == 0 {
+				switch keyword {
+				case "":
+					// a selection set without keyword is the shorthand of an anonymous query
+					operations = append(operations, operation{typ: "query"})
+				case "query", "mutation", "subscription":
+					operations = append(operations, operation{typ: keyword})
+				}
+				keyword = ""
+			}
+			depth++
+		case c == '(' || c == '[':
+			depth++
+		case c == '@':
+			// a directive is not the name of the operation
+			for i++; i < len(document) && isNameChar(document[i]); i++ {
+			}
+			continue
+		case c == '}' || c == ')' || c == ']':
+			depth--
+		case isNameStart(c):
+			start := i
+			for i < len(document) && isNameChar(document[i]) {
+				i++
+			}
+			word := document[start:i]
+			if depth != 0 {
+				continue
+			}
+			switch {
+			case keyword == "" && (word == "query" || word == "mutation" || word == "subscription" || word == "fragment"):
+				keyword = word
+			case keyword != "" && keyword != "fragment" && keyword != "named":
+				operations = append(operations, operation{typ: keyword, name: word})
+				// the rest of the definition, e.g. its variables and directives, is skipped until its selection set
+				keyword = "named"
+			}
+			continue
+		}
+		i++
+	}
+
+	if len(operations) == 0 {
+		return "query", ""
+	}
+	for _, op := range operations {
+		if name != "" && op.name == name {
+			return op.typ, op.name
+		}
+	}
+	return operations[0].typ, operations[0].name
+}
+
+// isNameStart tells whether c starts a GraphQL name.
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isNameChar tells whether c continues a GraphQL name.
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
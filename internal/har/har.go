@@ -0,0 +1,173 @@
+// Package har imports HTTP Archive files, the traffic captured elsewhere, e.g. exported from the browser's DevTools or
+// recorded by a proxy, into the events of a run as stored, so it can be replayed or compared with a run like a
+// captured one.
+package har
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"web-tester/internal/database"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// resourceTypes are the resource types of the browser, the DevTools export of a HAR tagging every entry with one.
+var resourceTypes = []network.ResourceType{
+	network.ResourceTypeDocument, network.ResourceTypeStylesheet, network.ResourceTypeImage, network.ResourceTypeMedia,
+	network.ResourceTypeFont, network.ResourceTypeScript, network.ResourceTypeTextTrack, network.ResourceTypeXHR,
+	network.ResourceTypeFetch, network.ResourceTypePrefetch, network.ResourceTypeEventSource, network.ResourceTypeWebSocket,
+	network.ResourceTypeManifest, network.ResourceTypeSignedExchange, network.ResourceTypePing,
+	network.ResourceTypeCSPViolationReport, network.ResourceTypePreflight, network.ResourceTypeOther,
+}
+
+// header is a header of a request or response of a HAR.
+type header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// entry is a request of a HAR with its response. The fields prefixed with an underscore are the custom fields of the
+// DevTools export.
+type entry struct {
+	ResourceType string `json:"_resourceType"`
+	Request      struct {
+		Method   string   `json:"method"`
+		URL      string   `json:"url"`
+		Headers  []header `json:"headers"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int64    `json:"status"`
+		Headers []header `json:"headers"`
+		Content struct {
+			Size     int64  `json:"size"`
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+		HeadersSize  int64   `json:"headersSize"`
+		BodySize     int64   `json:"bodySize"`
+		TransferSize float64 `json:"_transferSize"`
+	} `json:"response"`
+}
+
+// Read reads a HAR into the request and response events of a run, as stored, in the order of its entries. The events
+// hold the CDP payloads of a capture with the fields a HAR has, and their request IDs are har-1, har-2 and so on.
+// The entries without a response, e.g. the failed requests, only have a request event.
+func Read(r io.Reader) ([]database.StoredEvent, error) {
+	var archive struct {
+		Log struct {
+			Entries []entry `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("failed to decode HAR: %v", err)
+	}
+
+	var events []database.StoredEvent
+	for i, e := range archive.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse URL of HAR entry %d: %v", i+1, err)
+		}
+		requestID, resourceType := "har-"+strconv.Itoa(i+1), resourceType(e)
+
+		request := database.StoredEvent{Type: "request", Domain: u.Hostname(), URL: e.Request.URL}
+		if e.Request.PostData != nil {
+			request.Body = e.Request.PostData.Text
+		}
+		if request.Payload, err = json.Marshal(map[string]interface{}{"requestId": requestID, "type": resourceType,
+			"request": map[string]interface{}{"url": e.Request.URL, "method": e.Request.Method, "headers": headers(e.Request.Headers)}}); err != nil {
+			return nil, err
+		}
+		events = append(events, request)
+
+		if e.Response.Status == 0 {
+			continue
+		}
+		response := database.StoredEvent{Type: "response", Domain: u.Hostname(), URL: e.Request.URL, Status: e.Response.Status,
+			Body: e.Response.Content.Text, EncodedBytes: encodedBytes(e)}
+		if e.Response.Content.Encoding == "base64" {
+			body, err := base64.StdEncoding.DecodeString(e.Response.Content.Text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode body of HAR entry %d: %v", i+1, err)
+			}
+			response.Body = string(body)
+		}
+		if response.Payload, err = json.Marshal(map[string]interface{}{"requestId": requestID, "type": resourceType,
+			"response": map[string]interface{}{"url": e.Request.URL, "status": e.Response.Status,
+				"headers": headers(e.Response.Headers), "mimeType": e.Response.Content.MimeType}}); err != nil {
+			return nil, err
+		}
+		events = append(events, response)
+	}
+	return events, nil
+}
+
+// headers returns the headers of a HAR as CDP headers. The values of a header repeated are joined with commas, or
+// semicolons for the cookies, the way a client sends them.
+func headers(list []header) network.Headers {
+	h := network.Headers{}
+	for _, header := range list {
+		previous, ok := h[header.Name].(string)
+		switch {
+		case !ok:
+			h[header.Name] = header.Value
+		case strings.EqualFold(header.Name, "Cookie"):
+			h[header.Name] = previous + "; " + header.Value
+		default:
+			h[header.Name] = previous + ", " + header.Value
+		}
+	}
+	return h
+}
+
+// resourceType returns the resource type of an entry: the one the DevTools export tagged it with, or else the one
+// its response media type tells, the requests of JSON and XML being taken for fetch requests.
+func resourceType(e entry) string {
+	for _, t := range resourceTypes {
+		if strings.EqualFold(e.ResourceType, t.String()) {
+			return t.String()
+		}
+	}
+	media, _, _ := strings.Cut(strings.ToLower(e.Response.Content.MimeType), ";")
+	switch {
+	case media == "text/html" || media == "application/xhtml+xml":
+		return network.ResourceTypeDocument.String()
+	case media == "text/css":
+		return network.ResourceTypeStylesheet.String()
+	case strings.Contains(media, "javascript") || strings.Contains(media, "ecmascript"):
+		return network.ResourceTypeScript.String()
+	case strings.HasPrefix(media, "image/"):
+		return network.ResourceTypeImage.String()
+	case strings.HasPrefix(media, "font/") || strings.Contains(media, "font"):
+		return network.ResourceTypeFont.String()
+	case strings.HasPrefix(media, "audio/") || strings.HasPrefix(media, "video/"):
+		return network.ResourceTypeMedia.String()
+	case strings.Contains(media, "json") || strings.Contains(media, "xml"):
+		return network.ResourceTypeFetch.String()
+	}
+	return network.ResourceTypeOther.String()
+}
+
+// encodedBytes returns the bytes an entry took on the wire: its transfer size when the DevTools export recorded it,
+// or else the sizes of its response headers and body, or the size of its content.
+func encodedBytes(e entry) float64 {
+	switch r := e.Response; {
+	case r.TransferSize > 0:
+		return r.TransferSize
+	case r.HeadersSize >= 0 && r.BodySize >= 0:
+		return float64(r.HeadersSize + r.BodySize)
+	case r.BodySize >= 0:
+		return float64(r.BodySize)
+	}
+	return float64(e.Response.Content.Size)
+}
@@ -0,0 +1,179 @@
+package inventory
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"web-tester/internal/browser"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// APIEndpoint is an endpoint of the APIs called by the page, from its XHR and fetch requests: a method and a path
+// template, whose segments holding IDs are collapsed into parameters, e.g. GET /users/{id}/orders. Origin is the
+// scheme and host of the endpoint. RequestTypes and ResponseTypes are the content types of its request and response
+// bodies, AuthHeaders the authentication headers its requests carried, e.g. "authorization: bearer", Statuses the
+// statuses it answered with and QueryParameters the names of the parameters of its query strings, all sorted.
+type APIEndpoint struct {
+	Method          string   `json:"method"`
+	Origin          string   `json:"origin"`
+	Path            string   `json:"path"`
+	Requests        int      `json:"requests"`
+	RequestTypes    []string `json:"request_types,omitempty"`
+	ResponseTypes   []string `json:"response_types,omitempty"`
+	AuthHeaders     []string `json:"auth_headers,omitempty"`
+	Statuses        []int    `json:"statuses,omitempty"`
+	QueryParameters []string `json:"query_parameters,omitempty"`
+}
+
+// authHeaders are the request headers carrying credentials, besides Authorization whose scheme is kept.
+var authHeaders = []string{"X-Api-Key", "Api-Key", "X-Auth-Token", "X-Access-Token", "X-Csrf-Token", "X-Xsrf-Token"}
+
+var (
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexSegment     = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	numericSegment = regexp.MustCompile(`^\d+$`)
+	dateSegment    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	// tokenSegment matches the long opaque tokens mixing letters and digits, e.g. the IDs of MongoDB or Stripe
+	tokenSegment = regexp.MustCompile(`^[A-Za-z0-9_-]{20,}$`)
+)
+
+// isIDSegment tells whether a path segment holds an ID rather than a name of the API.
+func isIDSegment(segment string) bool {
+	switch {
+	case numericSegment.MatchString(segment), uuidSegment.MatchString(segment), hexSegment.MatchString(segment),
+		dateSegment.MatchString(segment):
+		return true
+	case tokenSegment.MatchString(segment):
+		return strings.ContainsAny(segment, "0123456789")
+	}
+	return false
+}
+
+// TemplatePath collapses the segments of a path holding IDs into parameters, named {id}, {id2} and so on in order.
+func TemplatePath(path string) string {
+	segments := strings.Split(path, "/")
+	params := 0
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		if !isIDSegment(segment) {
+			continue
+		}
+		params++
+		if params == 1 {
+			segments[i] = "{id}"
+		} else {
+			segments[i] = "{id" + strconv.Itoa(params) + "}"
+		}
+	}
+	if template := strings.Join(segments, "/"); template != "" {
+		return template
+	}
+	return "/"
+}
+
+// IsAPI tells whether a transaction is an API call, an XHR or fetch request.
+func IsAPI(t browser.Transaction) bool {
+	return t.ResourceType == network.ResourceTypeXHR.String() || t.ResourceType == network.ResourceTypeFetch.String()
+}
+
+// BuildAPI aggregates the XHR and fetch transactions of a run into the endpoints of the APIs they called, sorted by
+// origin, path and method.
+func BuildAPI(transactions []browser.Transaction) []APIEndpoint {
+	type sets struct {
+		requestTypes, responseTypes, auth, query map[string]bool
+		statuses                                 map[int]bool
+	}
+	index := map[string]*APIEndpoint{}
+	seen := map[string]*sets{}
+	for _, t := range transactions {
+		if !IsAPI(t) {
+			continue
+		}
+		u, err := url.Parse(t.URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		e := APIEndpoint{Method: t.Method, Origin: u.Scheme + "://" + u.Host, Path: TemplatePath(u.EscapedPath())}
+		key := e.Method + " " + e.Origin + e.Path
+		endpoint, ok := index[key]
+		if !ok {
+			endpoint = &e
+			index[key] = endpoint
+			seen[key] = &sets{requestTypes: map[string]bool{}, responseTypes: map[string]bool{}, auth: map[string]bool{},
+				query: map[string]bool{}, statuses: map[int]bool{}}
+		}
+		s := seen[key]
+		endpoint.Requests++
+
+		if contentType := mediaType(t.Request.Header("Content-Type")); contentType != "" {
+			s.requestTypes[contentType] = true
+		}
+		if authorization := t.Request.Header("Authorization"); authorization != "" {
+			scheme, _, _ := strings.Cut(authorization, " ")
+			s.auth["authorization: "+strings.ToLower(scheme)] = true
+		}
+		for _, name := range authHeaders {
+			if t.Request.Header(name) != "" {
+				s.auth[strings.ToLower(name)] = true
+			}
+		}
+		for name := range u.Query() {
+			s.query[name] = true
+		}
+		if t.Response != nil {
+			if t.Response.MimeType != "" {
+				s.responseTypes[t.Response.MimeType] = true
+			}
+			if t.Response.Status > 0 {
+				s.statuses[int(t.Response.Status)] = true
+			}
+		}
+	}
+
+	endpoints := make([]APIEndpoint, 0, len(index))
+	for key, e := range index {
+		s := seen[key]
+		e.RequestTypes, e.ResponseTypes = sortedKeys(s.requestTypes), sortedKeys(s.responseTypes)
+		e.AuthHeaders, e.QueryParameters = sortedKeys(s.auth), sortedKeys(s.query)
+		for status := range s.statuses {
+			e.Statuses = append(e.Statuses, status)
+		}
+		sort.Ints(e.Statuses)
+		endpoints = append(endpoints, *e)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		a, b := endpoints[i], endpoints[j]
+		if a.Origin != b.Origin {
+			return a.Origin < b.Origin
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+	return endpoints
+}
+
+// mediaType returns the media type of a Content-Type header, without its parameters.
+func mediaType(contentType string) string {
+	media, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(media))
+}
+
+// sortedKeys returns the keys of a set, sorted.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,203 @@
+package inventory
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"web-tester/internal/browser"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// Cache statuses of a response at the edge.
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// Edge attributes the latency of the responses from a registrable domain to its CDN edge and its origin.
+// Responses served from the edge cache measure the edge latency, the others the origin latency, and the
+// durations the servers report in their Server-Timing header measure the time spent in the servers.
+// Latencies are average times to first byte, in milliseconds.
+type Edge struct {
+	Domain string
+	// CDN is the CDN recognized from the response headers, empty when none was
+	CDN       string
+	Responses int
+	// Hits and Misses count the responses whose cache status was reported, by x-cache, cf-cache-status
+	// and the like, or inferred from a non-zero age
+	Hits   int
+	Misses int
+	// EdgeLatency and OriginLatency are the average times to first byte of the hits and of the misses
+	EdgeLatency   float64
+	OriginLatency float64
+	// ServerTime is the average server time of the responses reporting a Server-Timing header, its total
+	// metric when present, otherwise the sum of its durations
+	ServerTime float64
+	// ServerTimed counts the responses reporting a Server-Timing header
+	ServerTimed int
+}
+
+// HitRatio returns the share of the responses with a known cache status that were hits.
+func (e Edge) HitRatio() float64 {
+	if e.Hits+e.Misses == 0 {
+		return 0
+	}
+	return float64(e.Hits) / float64(e.Hits+e.Misses)
+}
+
+// cdnHeaders are response headers telling the CDN that served a response, in the order they are checked.
+var cdnHeaders = []struct{ header, cdn string }{
+	{"cf-ray", "Cloudflare"},
+	{"x-amz-cf-id", "CloudFront"},
+	{"x-fastly-request-id", "Fastly"},
+	{"x-akamai-request-id", "Akamai"},
+	{"x-vercel-id", "Vercel"},
+	{"x-nf-request-id", "Netlify"},
+	{"x-azure-ref", "Azure Front Door"},
+	{"x-goog-cache-status", "Google Cloud CDN"},
+}
+
+// cacheStatusHeaders are response headers reporting whether the edge served a response from its cache.
+var cacheStatusHeaders = []string{"cf-cache-status", "x-vercel-cache", "x-nf-cache-status", "x-goog-cache-status", "x-cache-status", "x-cache"}
+
+// CDNOf returns the CDN that served a response, recognized from its headers, or an empty string.
+func CDNOf(r browser.Response) string {
+	for _, h := range cdnHeaders {
+		if r.Header(h.header) != "" {
+			return h.cdn
+		}
+	}
+	if server := strings.ToLower(r.Header("server")); strings.Contains(server, "akamai") {
+		return "Akamai"
+	}
+	if via := strings.ToLower(r.Header("via")); strings.Contains(via, "varnish") {
+		return "Varnish"
+	}
+	return ""
+}
+
+// CacheStatus returns whether the edge served a response from its cache, CacheHit or CacheMiss, or an
+// empty string when the response does not tell. Headers listing the status of several cache layers, such
+// as "MISS, HIT" on Fastly, report the edge closest to the client last.
+func CacheStatus(r browser.Response) string {
+	for _, name := range cacheStatusHeaders {
+		value := r.Header(name)
+		if value == "" {
+			continue
+		}
+		layers := strings.Split(value, ",")
+		status := strings.ToUpper(strings.TrimSpace(layers[len(layers)-1]))
+		switch {
+		case strings.Contains(status, "HIT"), status == "STALE", status == "UPDATING", status == "REVALIDATED":
+			return CacheHit
+		case strings.Contains(status, "MISS"), status == "EXPIRED", status == "BYPASS", status == "DYNAMIC", status == "PASS":
+			return CacheMiss
+		}
+	}
+	if age, err := strconv.Atoi(strings.TrimSpace(r.Header("age"))); err == nil && age > 0 {
+		return CacheHit
+	}
+	return ""
+}
+
+// ServerTime returns the server time reported by the Server-Timing header of a response, in milliseconds:
+// its total metric when present, otherwise the sum of the durations of its metrics.
+func ServerTime(r browser.Response) (float64, bool) {
+	header := r.Header("server-timing")
+	if header == "" {
+		return 0, false
+	}
+	var sum, total float64
+	hasTotal := false
+	// the header may be repeated, which the browser reports joined with newlines
+	for _, metric := range strings.FieldsFunc(header, func(c rune) bool { return c == ',' || c == '\n' }) {
+		params := strings.Split(metric, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		for _, param := range params[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(key, "dur") {
+				continue
+			}
+			dur, err := strconv.ParseFloat(strings.Trim(value, `"`), 64)
+			if err != nil {
+				continue
+			}
+			sum += dur
+			if name == "total" {
+				total, hasTotal = dur, true
+			}
+		}
+	}
+	if hasTotal {
+		return total, true
+	}
+	return sum, true
+}
+
+// BuildEdge attributes the latency of the responses of a run to the CDN edges and origins of their
+// registrable domains, sorted by decreasing response count. Responses served from the browser's own
+// caches, which never reached the network, are left out.
+func BuildEdge(responses []browser.Response) []Edge {
+	type sums struct {
+		edge, origin, server float64
+	}
+	index := map[string]*Edge{}
+	totals := map[string]*sums{}
+	for _, r := range responses {
+		domain := domainOf(r.URL)
+		if domain == "" || fromBrowser(r) {
+			continue
+		}
+		e, ok := index[domain]
+		if !ok {
+			e = &Edge{Domain: domain}
+			index[domain], totals[domain] = e, &sums{}
+		}
+		e.Responses++
+		if cdn := CDNOf(r); cdn != "" && e.CDN == "" {
+			e.CDN = cdn
+		}
+		switch CacheStatus(r) {
+		case CacheHit:
+			e.Hits++
+			totals[domain].edge += r.Timing.TTFB
+		case CacheMiss:
+			e.Misses++
+			totals[domain].origin += r.Timing.TTFB
+		}
+		if server, ok := ServerTime(r); ok {
+			e.ServerTimed++
+			totals[domain].server += server
+		}
+	}
+
+	edges := make([]Edge, 0, len(index))
+	for domain, e := range index {
+		t := totals[domain]
+		if e.Hits > 0 {
+			e.EdgeLatency = t.edge / float64(e.Hits)
+		}
+		if e.Misses > 0 {
+			e.OriginLatency = t.origin / float64(e.Misses)
+		}
+		if e.ServerTimed > 0 {
+			e.ServerTime = t.server / float64(e.ServerTimed)
+		}
+		edges = append(edges, *e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Responses != edges[j].Responses {
+			return edges[i].Responses > edges[j].Responses
+		}
+		return edges[i].Domain < edges[j].Domain
+	})
+	return edges
+}
+
+// fromBrowser reports whether a response was served by the browser itself, from its disk cache, a
+// prefetch or a service worker, without reaching the CDN.
+func fromBrowser(r browser.Response) bool {
+	ev, ok := r.Content.(*network.EventResponseReceived)
+	return ok && ev.Response != nil && (ev.Response.FromDiskCache || ev.Response.FromPrefetchCache || ev.Response.FromServiceWorker)
+}
@@ -0,0 +1,141 @@
+// Package inventory aggregates the traffic of a run by registrable domain (eTLD+1), telling first-party
+// domains from third-party ones and flagging known trackers, and by API endpoint.
+package inventory
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"web-tester/internal/browser"
+)
+
+// Domain is the traffic of a run to a registrable domain. Tracker is the category of the tracker
+// the domain is known as, and is empty when it is not a known tracker or trackers are not matched.
+type Domain struct {
+	Domain     string
+	FirstParty bool
+	Tracker    string
+	Requests   int
+	Bytes      float64
+}
+
+// multiLabelSuffixes are the common public suffixes made of two labels, under which the registrable domain
+// has three labels. It is not the full public suffix list, but covers the suffixes most sites are found under.
+var multiLabelSuffixes = map[string]bool{
+	"co.uk": true, "org.uk": true, "ac.uk": true, "gov.uk": true,
+	"com.au": true, "net.au": true, "org.au": true,
+	"com.br": true, "net.br": true, "org.br": true, "gov.br": true,
+	"co.jp": true, "ne.jp": true, "or.jp": true,
+	"co.nz": true, "co.za": true, "co.in": true, "co.kr": true,
+	"com.ar": true, "com.mx": true, "com.cn": true, "com.tr": true, "com.sg": true, "com.hk": true, "com.tw": true,
+	"cloudfront.net": true, "herokuapp.com": true, "github.io": true, "azurewebsites.net": true,
+	"appspot.com": true, "blogspot.com": true, "netlify.app": true, "vercel.app": true, "pages.dev": true,
+}
+
+// Trackers is the bundled tracker list, mapping the registrable domains of well-known trackers to their category.
+var Trackers = map[string]string{
+	"doubleclick.net":       "advertising",
+	"googlesyndication.com": "advertising",
+	"googleadservices.com":  "advertising",
+	"adnxs.com":             "advertising",
+	"adsrvr.org":            "advertising",
+	"amazon-adsystem.com":   "advertising",
+	"criteo.com":            "advertising",
+	"criteo.net":            "advertising",
+	"outbrain.com":          "advertising",
+	"pubmatic.com":          "advertising",
+	"quantserve.com":        "advertising",
+	"rubiconproject.com":    "advertising",
+	"taboola.com":           "advertising",
+	"demdex.net":            "analytics",
+	"google-analytics.com":  "analytics",
+	"googletagmanager.com":  "analytics",
+	"hotjar.com":            "analytics",
+	"clarity.ms":            "analytics",
+	"mixpanel.com":          "analytics",
+	"scorecardresearch.com": "analytics",
+	"segment.io":            "analytics",
+	"nr-data.net":           "monitoring",
+	"sentry.io":             "monitoring",
+	"facebook.net":          "social",
+	"licdn.com":             "social",
+	"ads-twitter.com":       "social",
+	"cookielaw.org":         "consent",
+	"onetrust.com":          "consent",
+	"hs-analytics.net":      "marketing",
+	"klaviyo.com":           "marketing",
+}
+
+// RegistrableDomain returns the eTLD+1 of a host, e.g. "example.co.uk" for "www.example.co.uk".
+// IP addresses are returned as is.
+func RegistrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) <= 2 {
+		return host
+	}
+	n := 2
+	if multiLabelSuffixes[strings.Join(labels[len(labels)-2:], ".")] {
+		n = 3
+	}
+	return strings.Join(labels[len(labels)-n:], ".")
+}
+
+// domainOf returns the registrable domain of a URL, or an empty string for URLs without a host, e.g. data: URLs.
+func domainOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return RegistrableDomain(u.Hostname())
+}
+
+// Build aggregates the requests and responses of a run by registrable domain, sorted by decreasing request
+// count. Domains sharing the target's registrable domain are first-party. Known trackers are flagged when
+// matchTrackers is set.
+func Build(target string, requests []browser.Request, responses []browser.Response, matchTrackers bool) []Domain {
+	first := domainOf(target)
+	index := map[string]*Domain{}
+	get := func(rawURL string) *Domain {
+		domain := domainOf(rawURL)
+		if domain == "" {
+			return nil
+		}
+		d, ok := index[domain]
+		if !ok {
+			d = &Domain{Domain: domain, FirstParty: domain == first}
+			if matchTrackers && !d.FirstParty {
+				d.Tracker = Trackers[domain]
+			}
+			index[domain] = d
+		}
+		return d
+	}
+
+	for _, r := range requests {
+		if d := get(r.URL); d != nil {
+			d.Requests++
+		}
+	}
+	for _, r := range responses {
+		if d := get(r.URL); d != nil {
+			d.Bytes += r.Timing.EncodedBytes
+		}
+	}
+
+	domains := make([]Domain, 0, len(index))
+	for _, d := range index {
+		domains = append(domains, *d)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if domains[i].Requests != domains[j].Requests {
+			return domains[i].Requests > domains[j].Requests
+		}
+		return domains[i].Domain < domains[j].Domain
+	})
+	return domains
+}
@@ -0,0 +1,103 @@
+package issues
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// githubPageSize is the number of issues listed per page, the maximum allowed by the API.
+const githubPageSize = 100
+
+// fingerprintMarker carries the fingerprint of the finding in the body of a GitHub issue, hidden when rendered.
+var fingerprintMarker = regexp.MustCompile(`<!-- web-tester fingerprint: ([0-9a-f]+) -->`)
+
+// GitHub opens issues in a GitHub repository, given as owner/name, with a token allowed to write its issues.
+type GitHub struct {
+	client *http.Client
+	api    string
+	repo   string
+	token  string
+}
+
+// NewGitHub creates a GitHub tracker calling the REST API at api, e.g. https://api.github.com or the
+// /api/v3 endpoint of a GitHub Enterprise Server.
+func NewGitHub(client *http.Client, api, repo, token string) *GitHub {
+	return &GitHub{client: client, api: strings.TrimSuffix(api, "/"), repo: repo, token: token}
+}
+
+func (g *GitHub) Name() string {
+	return "github"
+}
+
+// OpenFingerprints lists the open issues labelled by the tool, reading the fingerprints from their bodies.
+func (g *GitHub) OpenFingerprints(ctx context.Context) (map[string]bool, error) {
+	fingerprints := map[string]bool{}
+	for page := 1; ; page++ {
+		var issues []struct {
+			Body string `json:"body"`
+		}
+		url := fmt.Sprintf("%s/repos/%s/issues?state=open&labels=%s&per_page=%d&page=%d", g.api, g.repo, Label, githubPageSize, page)
+		if err := g.do(ctx, http.MethodGet, url, nil, &issues); err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			if m := fingerprintMarker.FindStringSubmatch(issue.Body); m != nil {
+				fingerprints[m[1]] = true
+			}
+		}
+		if len(issues) < githubPageSize {
+			return fingerprints, nil
+		}
+	}
+}
+
+// Create opens the issue with the tool's label.
+func (g *GitHub) Create(ctx context.Context, issue Issue) (string, error) {
+	body := map[string]interface{}{
+		"title":  issue.Title,
+		"body":   fmt.Sprintf("%s\n<!-- web-tester fingerprint: %s -->\n", issue.Body, issue.Fingerprint),
+		"labels": []string{Label},
+	}
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := g.do(ctx, http.MethodPost, fmt.Sprintf("%s/repos/%s/issues", g.api, g.repo), body, &created); err != nil {
+		return "", err
+	}
+	return created.HTMLURL, nil
+}
+
+// do calls the API, decoding the JSON response into out.
+func (g *GitHub) do(ctx context.Context, method, url string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return fmt.Errorf("failed to encode github request: %v", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, &body)
+	if err != nil {
+		return fmt.Errorf("failed to build github request: %v", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call github: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("github returned %s for %s %s", resp.Status, method, url)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode github response: %v", err)
+	}
+	return nil
+}
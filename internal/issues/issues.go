@@ -0,0 +1,94 @@
+// Package issues opens issues in the trackers of the teams triaging the findings of the runs, e.g. GitHub
+// or Jira, so scheduled runs flow into their existing workflows.
+package issues
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"web-tester/internal/audit"
+
+	"github.com/google/uuid"
+)
+
+// Label is the label of every issue opened by the tool, used to find the open ones.
+const Label = "web-tester"
+
+// severityRanks orders the severities of the findings.
+var severityRanks = map[string]int{audit.SeverityInfo: 0, audit.SeverityLow: 1, audit.SeverityMedium: 2, audit.SeverityHigh: 3}
+
+// Issue is an issue to open for a finding. Fingerprint identifies the finding across runs.
+type Issue struct {
+	Title       string
+	Body        string
+	Fingerprint string
+}
+
+// Tracker is an issue tracker issues can be opened in.
+type Tracker interface {
+	// Name is the name of the tracker in the logs, e.g. "github"
+	Name() string
+	// OpenFingerprints returns the fingerprints of the issues opened by the tool that are still open
+	OpenFingerprints(ctx context.Context) (map[string]bool, error)
+	// Create opens the issue, returning its URL
+	Create(ctx context.Context, issue Issue) (string, error)
+}
+
+// Fingerprint identifies a finding across runs by its check, resource and message.
+func Fingerprint(f audit.Finding) string {
+	sum := sha256.Sum256([]byte(f.Check + "\n" + f.URL + "\n" + f.Message))
+	return hex.EncodeToString(sum[:8])
+}
+
+// NewIssue describes a finding of a test as an issue.
+func NewIssue(testID uuid.UUID, target string, f audit.Finding) Issue {
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s\n\n", f.Message)
+	fmt.Fprintf(&body, "- Check: %s\n- Severity: %s\n- URL: %s\n- Target: %s\n- Test: %s\n", f.Check, f.Severity, f.URL, target, testID)
+	if len(f.Pages) > 0 {
+		fmt.Fprintf(&body, "- Pages: %s\n", strings.Join(f.Pages, ", "))
+	}
+	if r := f.Remediation; r != nil {
+		fmt.Fprintf(&body, "\nRemediation: %s\n", r.Summary)
+		for _, ref := range r.References {
+			fmt.Fprintf(&body, "- %s\n", ref)
+		}
+	}
+	return Issue{Title: fmt.Sprintf("[%s] %s: %s", f.Severity, f.Check, f.URL), Body: body.String(), Fingerprint: Fingerprint(f)}
+}
+
+// Open opens an issue in every tracker for each finding of at least minSeverity that has no open issue yet,
+// returning the number of issues that could not be opened. Findings repeated in a run open a single issue.
+func Open(ctx context.Context, logger *slog.Logger, trackers []Tracker, minSeverity string, testID uuid.UUID, target string, findings []audit.Finding) int {
+	failed := 0
+	for _, tracker := range trackers {
+		open, err := tracker.OpenFingerprints(ctx)
+		if err != nil {
+			logger.Error("failed to list open issues", "tracker", tracker.Name(), "error", err)
+			failed++
+			continue
+		}
+		for _, f := range findings {
+			if severityRanks[f.Severity] < severityRanks[minSeverity] {
+				continue
+			}
+			issue := NewIssue(testID, target, f)
+			if open[issue.Fingerprint] {
+				logger.Debug("issue already open", "tracker", tracker.Name(), "fingerprint", issue.Fingerprint)
+				continue
+			}
+			url, err := tracker.Create(ctx, issue)
+			if err != nil {
+				logger.Error("failed to open issue", "tracker", tracker.Name(), "fingerprint", issue.Fingerprint, "error", err)
+				failed++
+				continue
+			}
+			open[issue.Fingerprint] = true
+			logger.Info("opened issue", "tracker", tracker.Name(), "url", url, "check", f.Check)
+		}
+	}
+	return failed
+}
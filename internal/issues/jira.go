@@ -0,0 +1,116 @@
+package issues
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// jiraPageSize is the number of issues searched per page.
+const jiraPageSize = 100
+
+// fingerprintLabel is the prefix of the label carrying the fingerprint of the finding of a Jira issue.
+const fingerprintLabel = Label + "-"
+
+// Jira opens issues in a Jira project, authenticating with a user and an API token.
+type Jira struct {
+	client    *http.Client
+	baseURL   string
+	project   string
+	issueType string
+	user      string
+	token     string
+}
+
+// NewJira creates a Jira tracker opening issues of issueType, e.g. "Bug", in the project with the given key.
+func NewJira(client *http.Client, baseURL, project, issueType, user, token string) *Jira {
+	return &Jira{client: client, baseURL: strings.TrimSuffix(baseURL, "/"), project: project, issueType: issueType, user: user, token: token}
+}
+
+func (j *Jira) Name() string {
+	return "jira"
+}
+
+// OpenFingerprints searches the unresolved issues labelled by the tool, reading the fingerprints from their labels.
+func (j *Jira) OpenFingerprints(ctx context.Context) (map[string]bool, error) {
+	fingerprints := map[string]bool{}
+	jql := fmt.Sprintf(`project = "%s" AND labels = "%s" AND statusCategory != Done`, j.project, Label)
+	for startAt := 0; ; startAt += jiraPageSize {
+		var result struct {
+			Total  int `json:"total"`
+			Issues []struct {
+				Fields struct {
+					Labels []string `json:"labels"`
+				} `json:"fields"`
+			} `json:"issues"`
+		}
+		query := url.Values{"jql": {jql}, "fields": {"labels"}, "startAt": {fmt.Sprint(startAt)}, "maxResults": {fmt.Sprint(jiraPageSize)}}
+		if err := j.do(ctx, http.MethodGet, j.baseURL+"/rest/api/2/search?"+query.Encode(), nil, &result); err != nil {
+			return nil, err
+		}
+		for _, issue := range result.Issues {
+			for _, label := range issue.Fields.Labels {
+				if strings.HasPrefix(label, fingerprintLabel) {
+					fingerprints[strings.TrimPrefix(label, fingerprintLabel)] = true
+				}
+			}
+		}
+		if len(result.Issues) == 0 || startAt+len(result.Issues) >= result.Total {
+			return fingerprints, nil
+		}
+	}
+}
+
+// Create opens the issue with the tool's label and the label of its fingerprint.
+func (j *Jira) Create(ctx context.Context, issue Issue) (string, error) {
+	body := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": j.project},
+			"issuetype":   map[string]string{"name": j.issueType},
+			"summary":     issue.Title,
+			"description": issue.Body,
+			"labels":      []string{Label, fingerprintLabel + issue.Fingerprint},
+		},
+	}
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := j.do(ctx, http.MethodPost, j.baseURL+"/rest/api/2/issue", body, &created); err != nil {
+		return "", err
+	}
+	return j.baseURL + "/browse/" + created.Key, nil
+}
+
+// do calls the API, decoding the JSON response into out.
+func (j *Jira) do(ctx context.Context, method, url string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return fmt.Errorf("failed to encode jira request: %v", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, &body)
+	if err != nil {
+		return fmt.Errorf("failed to build jira request: %v", err)
+	}
+	req.SetBasicAuth(j.user, j.token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call jira: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("jira returned %s for %s %s", resp.Status, method, url)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode jira response: %v", err)
+	}
+	return nil
+}
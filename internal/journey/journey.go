@@ -0,0 +1,109 @@
+// Package journey runs the user journey of a scenario on a loaded page, timing it between its milestones.
+package journey
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+	"web-tester/internal/config"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Milestone is a point reached by a journey. Elapsed and TotalRequests are measured from the start of the
+// journey, SincePrevious and Requests from the previous milestone, or the start for the first one.
+type Milestone struct {
+	Scenario      string        `json:"scenario"`
+	Name          string        `json:"name"`
+	Position      int           `json:"position"`
+	Elapsed       time.Duration `json:"elapsed"`
+	SincePrevious time.Duration `json:"since_previous"`
+	Requests      int           `json:"requests"`
+	TotalRequests int           `json:"total_requests"`
+}
+
+// Run runs the steps of the scenario on the page of the browser context ctx, returning the milestones
+// reached. The files of the upload steps are taken from uploadDir, and count returns the number of requests
+// sent by the page so far. When a step fails, the milestones reached before it are returned along with the error.
+func Run(ctx context.Context, logger *slog.Logger, scenario config.Scenario, uploadDir string, count func() int) ([]Milestone, error) {
+	var milestones []Milestone
+	start, startCount := time.Now(), count()
+	previous, previousCount := start, startCount
+
+	for i, step := range scenario.Steps {
+		if step.Action == "milestone" {
+			now, sent := time.Now(), count()
+			m := Milestone{Scenario: scenario.Name, Name: step.Name, Position: len(milestones) + 1,
+				Elapsed: now.Sub(start).Round(time.Millisecond), SincePrevious: now.Sub(previous).Round(time.Millisecond), Requests: sent - previousCount, TotalRequests: sent - startCount}
+			logger.Info("journey milestone reached", "milestone", m.Name, "elapsed", m.Elapsed, "since_previous", m.SincePrevious, "requests", m.Requests)
+			milestones = append(milestones, m)
+			previous, previousCount = now, sent
+			continue
+		}
+
+		action, err := stepAction(step, uploadDir)
+		if err != nil {
+			return milestones, fmt.Errorf("step %d: %v", i+1, err)
+		}
+		logger.Info("running journey step", "step", i+1, "action", step.Action)
+		if err = chromedp.Run(ctx, action); err != nil {
+			return milestones, fmt.Errorf("step %d (%s) failed: %v", i+1, step.Action, err)
+		}
+	}
+	return milestones, nil
+}
+
+// stepAction returns the browser action of a step.
+func stepAction(step config.Step, uploadDir string) (chromedp.Action, error) {
+	switch step.Action {
+	case "navigate":
+		return chromedp.Navigate(step.URL), nil
+	case "click":
+		return chromedp.Click(step.Selector, chromedp.NodeVisible), nil
+	case "type":
+		return chromedp.SendKeys(step.Selector, step.Value, chromedp.NodeVisible), nil
+	case "upload":
+		files, err := uploadFiles(uploadDir, step.Files)
+		if err != nil {
+			return nil, err
+		}
+		return chromedp.SetUploadFiles(step.Selector, files, chromedp.NodeReady), nil
+	case "wait_visible":
+		return chromedp.WaitVisible(step.Selector), nil
+	case "sleep":
+		return chromedp.Sleep(time.Duration(step.Seconds * float64(time.Second))), nil
+	}
+	return nil, fmt.Errorf("unknown action %q", step.Action)
+}
+
+// uploadFiles resolves the files of an upload step in the upload directory. The scenarios may come from the API,
+// so the files must be inside the directory, keeping the other files of the host from being sent to the target.
+func uploadFiles(dir string, files []string) ([]string, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("uploads are disabled, set UPLOAD_DIR")
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("upload step without files")
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve upload directory: %v", err)
+	}
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		if filepath.IsAbs(f) || !filepath.IsLocal(f) {
+			return nil, fmt.Errorf("upload file %q must be a relative path inside the upload directory", f)
+		}
+		path := filepath.Join(root, f)
+		if info, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("upload file %q: %v", f, err)
+		} else if info.IsDir() {
+			return nil, fmt.Errorf("upload file %q is a directory", f)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"web-tester/internal/config"
+)
+
+// New creates the logger of the configuration, writing to w unless the configuration names a file. The file is
+// appended to and left open for the life of the process. The logger is also made the default one, so the
+// messages of the standard log package, e.g. those of chromedp, are written along with the others.
+func New(cfg config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	level, err := config.ParseLogLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	modules, err := config.ParseLogModules(cfg.Modules)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.File != "" {
+		if w, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return nil, fmt.Errorf("failed to open log file: %v", err)
+		}
+	}
+
+	// the wrapped handler lets every record through that a module may want, the module levels filtering them
+	lowest := level
+	for _, l := range modules {
+		lowest = min(lowest, l)
+	}
+	opts := &slog.HandlerOptions{Level: lowest}
+	var handler slog.Handler
+	switch cfg.Format {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, use json or text", cfg.Format)
+	}
+
+	logger := slog.New(&moduleHandler{Handler: handler, level: level, modules: modules, lowest: lowest})
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// moduleHandler filters the records by the level of the package logging them, the default level applying to
+// the packages without one.
+type moduleHandler struct {
+	slog.Handler
+	level   slog.Level
+	modules map[string]slog.Level
+	// lowest is the lowest of the levels, below which no record is handled
+	lowest slog.Level
+}
+
+func (h *moduleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.lowest && h.Handler.Enabled(ctx, level)
+}
+
+func (h *moduleHandler) Handle(ctx context.Context, r slog.Record) error {
+	level, ok := h.modules[module(r.PC)]
+	if !ok {
+		level = h.level
+	}
+	if r.Level < level {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *moduleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &moduleHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level, modules: h.modules, lowest: h.lowest}
+}
+
+func (h *moduleHandler) WithGroup(name string) slog.Handler {
+	return &moduleHandler{Handler: h.Handler.WithGroup(name), level: h.level, modules: h.modules, lowest: h.lowest}
+}
+
+// module returns the name of the package of the function at pc, e.g. database for
+// web-tester/internal/database.InsertEvents, or main for the command.
+func module(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	name := frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+	name, _, _ = strings.Cut(name, ".")
+	return name
+}
@@ -0,0 +1,302 @@
+package mongo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// D is a BSON document whose keys keep their order, as commands need their name as first key.
+type D []E
+
+// E is an element of a D.
+type E struct {
+	Key   string
+	Value interface{}
+}
+
+// Binary is BSON binary data of the generic subtype.
+type Binary []byte
+
+// Get returns the value of the element of the document with the key, nil when missing.
+func (d D) Get(key string) interface{} {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+// FromJSON converts a value to the nested documents, arrays and scalars of its JSON encoding, so any value,
+// e.g. the CDP payloads of the events, can be stored as a queryable document.
+func FromJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err = dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// Marshal encodes a document. Values may be D, maps with string keys, which are encoded with their keys sorted,
+// slices, strings, numbers, booleans, nil, Binary, []byte, time.Time and json.Number.
+func Marshal(doc interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeDocument(&buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeDocument(buf *bytes.Buffer, doc interface{}) error {
+	var elements D
+	switch d := doc.(type) {
+	case D:
+		elements = d
+	case map[string]interface{}:
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			elements = append(elements, E{k, d[k]})
+		}
+	default:
+		return fmt.Errorf("cannot encode %T as a BSON document", doc)
+	}
+
+	start := buf.Len()
+	buf.Write([]byte{0, 0, 0, 0})
+	for _, e := range elements {
+		if err := writeElement(buf, e.Key, e.Value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(0)
+	binary.LittleEndian.PutUint32(buf.Bytes()[start:], uint32(buf.Len()-start))
+	return nil
+}
+
+func writeElement(buf *bytes.Buffer, key string, value interface{}) error {
+	header := func(kind byte) {
+		buf.WriteByte(kind)
+		buf.WriteString(key)
+		buf.WriteByte(0)
+	}
+	switch v := value.(type) {
+	case nil:
+		header(0x0A)
+	case bool:
+		header(0x08)
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case string:
+		header(0x02)
+		binary.Write(buf, binary.LittleEndian, int32(len(v)+1))
+		buf.WriteString(v)
+		buf.WriteByte(0)
+	case int:
+		header(0x12)
+		binary.Write(buf, binary.LittleEndian, int64(v))
+	case int32:
+		header(0x10)
+		binary.Write(buf, binary.LittleEndian, v)
+	case int64:
+		header(0x12)
+		binary.Write(buf, binary.LittleEndian, v)
+	case float64:
+		header(0x01)
+		binary.Write(buf, binary.LittleEndian, math.Float64bits(v))
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return writeElement(buf, key, i)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		return writeElement(buf, key, f)
+	case time.Time:
+		header(0x09)
+		binary.Write(buf, binary.LittleEndian, v.UnixMilli())
+	case Binary:
+		header(0x05)
+		binary.Write(buf, binary.LittleEndian, int32(len(v)))
+		buf.WriteByte(0)
+		buf.Write(v)
+	case []byte:
+		return writeElement(buf, key, Binary(v))
+	case D, map[string]interface{}:
+		header(0x03)
+		return writeDocument(buf, v)
+	case []interface{}:
+		header(0x04)
+		array := make(D, len(v))
+		for i, item := range v {
+			array[i] = E{strconv.Itoa(i), item}
+		}
+		return writeDocument(buf, array)
+	case []D:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return writeElement(buf, key, items)
+	default:
+		return fmt.Errorf("cannot encode %T as BSON", value)
+	}
+	return nil
+}
+
+// Unmarshal decodes a document. Embedded documents are decoded as D, arrays as []interface{}, integers as
+// int32 or int64, binary data as Binary and object IDs as their 12 bytes.
+func Unmarshal(data []byte) (D, error) {
+	doc, n, err := readDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(data) {
+		return nil, fmt.Errorf("trailing bytes after BSON document")
+	}
+	return doc, nil
+}
+
+func readDocument(data []byte) (D, int, error) {
+	if len(data) < 5 {
+		return nil, 0, fmt.Errorf("truncated BSON document")
+	}
+	size := int(binary.LittleEndian.Uint32(data))
+	if size < 5 || size > len(data) || data[size-1] != 0 {
+		return nil, 0, fmt.Errorf("invalid BSON document size %d", size)
+	}
+	doc := D{}
+	pos := 4
+	for pos < size-1 {
+		kind := data[pos]
+		pos++
+		end := bytes.IndexByte(data[pos:size], 0)
+		if end < 0 {
+			return nil, 0, fmt.Errorf("unterminated BSON key")
+		}
+		key := string(data[pos : pos+end])
+		pos += end + 1
+		value, n, err := readValue(kind, data[pos:size-1])
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %v", key, err)
+		}
+		pos += n
+		doc = append(doc, E{key, value})
+	}
+	return doc, size, nil
+}
+
+func readValue(kind byte, data []byte) (interface{}, int, error) {
+	need := func(n int) error {
+		if len(data) < n {
+			return fmt.Errorf("truncated BSON value")
+		}
+		return nil
+	}
+	switch kind {
+	case 0x01:
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), 8, nil
+	case 0x02:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		n := int(binary.LittleEndian.Uint32(data))
+		if n < 1 || 4+n > len(data) {
+			return nil, 0, fmt.Errorf("invalid BSON string")
+		}
+		return string(data[4 : 4+n-1]), 4 + n, nil
+	case 0x03:
+		return readDocument(data)
+	case 0x04:
+		doc, n, err := readDocument(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		array := make([]interface{}, len(doc))
+		for i, e := range doc {
+			array[i] = e.Value
+		}
+		return array, n, nil
+	case 0x05:
+		if err := need(5); err != nil {
+			return nil, 0, err
+		}
+		n := int(binary.LittleEndian.Uint32(data))
+		if n < 0 || 5+n > len(data) {
+			return nil, 0, fmt.Errorf("invalid BSON binary")
+		}
+		return Binary(data[5 : 5+n]), 5 + n, nil
+	case 0x07:
+		if err := need(12); err != nil {
+			return nil, 0, err
+		}
+		return data[:12], 12, nil
+	case 0x08:
+		if err := need(1); err != nil {
+			return nil, 0, err
+		}
+		return data[0] == 1, 1, nil
+	case 0x09:
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return time.UnixMilli(int64(binary.LittleEndian.Uint64(data))).UTC(), 8, nil
+	case 0x0A:
+		return nil, 0, nil
+	case 0x10:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		return int32(binary.LittleEndian.Uint32(data)), 4, nil
+	case 0x11, 0x12:
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return int64(binary.LittleEndian.Uint64(data)), 8, nil
+	case 0x13:
+		if err := need(16); err != nil {
+			return nil, 0, err
+		}
+		return data[:16], 16, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported BSON type 0x%02x", kind)
+}
+
+// number returns the value of a numeric BSON value, as the servers send e.g. ok as a double or an integer.
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case bool:
+		if n {
+			return 1
+		}
+	}
+	return 0
+}
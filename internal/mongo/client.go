@@ -0,0 +1,262 @@
+// Package mongo is a minimal MongoDB client speaking the OP_MSG wire protocol, enough to insert documents: it
+// connects to the first host of a mongodb:// URI, over TLS when tls=true, and authenticates with SCRAM-SHA-256
+// when the URI holds credentials.
+package mongo
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// opMsg is the op code of OP_MSG messages.
+const opMsg = 2013
+
+// maxMessageSize is the largest reply read, the default limit of the servers.
+const maxMessageSize = 48 * 1000 * 1000
+
+// Client is a connection to a MongoDB server. Commands are sent one at a time.
+type Client struct {
+	mu        sync.Mutex
+	conn      net.Conn
+	reader    *bufio.Reader
+	requestID int32
+	// Database is the database of the URI, empty when it has none
+	Database string
+}
+
+// Connect connects to the server of the URI and authenticates, giving up after timeout.
+func Connect(uri string, timeout time.Duration) (*Client, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MongoDB URI: %v", err)
+	}
+	if u.Scheme != "mongodb" {
+		return nil, fmt.Errorf("unsupported MongoDB URI scheme %q, only mongodb:// is supported", u.Scheme)
+	}
+	host := strings.Split(u.Host, ",")[0]
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "27017")
+	}
+	query := u.Query()
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if query.Get("tls") == "true" || query.Get("ssl") == "true" {
+		serverName, _, _ := net.SplitHostPort(host)
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: serverName})
+	} else {
+		conn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %v", err)
+	}
+	c := &Client{conn: conn, reader: bufio.NewReader(conn), Database: strings.TrimPrefix(u.Path, "/")}
+
+	if u.User != nil {
+		password, _ := u.User.Password()
+		authSource := query.Get("authSource")
+		if authSource == "" {
+			authSource = c.Database
+		}
+		if authSource == "" {
+			authSource = "admin"
+		}
+		conn.SetDeadline(time.Now().Add(timeout))
+		if err = c.authenticate(u.User.Username(), password, authSource); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+	}
+	return c, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Insert inserts documents into the collection of the database, returning the number of documents inserted.
+func (c *Client) Insert(database, collection string, documents []interface{}) (int, error) {
+	reply, err := c.Command(database, D{{"insert", collection}, {"documents", documents}, {"ordered", false}})
+	if err != nil {
+		return 0, err
+	}
+	inserted := int(number(reply.Get("n")))
+	if writeErrors, ok := reply.Get("writeErrors").([]interface{}); ok && len(writeErrors) > 0 {
+		message := ""
+		if first, ok := writeErrors[0].(D); ok {
+			message, _ = first.Get("errmsg").(string)
+		}
+		return inserted, fmt.Errorf("failed to insert %d documents: %s", len(writeErrors), message)
+	}
+	return inserted, nil
+}
+
+// Command runs a command against the database, returning its reply, or an error when it failed.
+func (c *Client) Command(database string, command D) (D, error) {
+	command = append(command, E{"$db", database})
+	body, err := Marshal(command)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requestID++
+	header := make([]byte, 21)
+	binary.LittleEndian.PutUint32(header[0:], uint32(len(header)+len(body)))
+	binary.LittleEndian.PutUint32(header[4:], uint32(c.requestID))
+	binary.LittleEndian.PutUint32(header[12:], opMsg)
+	// flag bits at 16 are 0 and the body is a section of kind 0 at 20
+	if _, err = c.conn.Write(append(header, body...)); err != nil {
+		return nil, fmt.Errorf("failed to send MongoDB command: %v", err)
+	}
+
+	reply, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if number(reply.Get("ok")) != 1 {
+		message, _ := reply.Get("errmsg").(string)
+		return reply, fmt.Errorf("MongoDB command failed: %s", message)
+	}
+	return reply, nil
+}
+
+// read reads the body of an OP_MSG reply.
+func (c *Client) read() (D, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return nil, fmt.Errorf("failed to read MongoDB reply: %v", err)
+	}
+	size := int(binary.LittleEndian.Uint32(header))
+	if size < 21 || size > maxMessageSize {
+		return nil, fmt.Errorf("invalid MongoDB reply size %d", size)
+	}
+	if op := binary.LittleEndian.Uint32(header[12:]); op != opMsg {
+		return nil, fmt.Errorf("unexpected MongoDB reply op code %d", op)
+	}
+	message := make([]byte, size-16)
+	if _, err := io.ReadFull(c.reader, message); err != nil {
+		return nil, fmt.Errorf("failed to read MongoDB reply: %v", err)
+	}
+	// the flag bits, then the body section of kind 0 followed by the checksum when flagged
+	if message[4] != 0 {
+		return nil, fmt.Errorf("unexpected MongoDB reply section kind %d", message[4])
+	}
+	doc, _, err := readDocument(message[5:])
+	return doc, err
+}
+
+// authenticate runs the SCRAM-SHA-256 conversation of RFC 7677.
+func (c *Client) authenticate(username, password, authSource string) error {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	clientNonce := base64.StdEncoding.EncodeToString(nonce)
+	username = strings.NewReplacer("=", "=3D", ",", "=2C").Replace(username)
+	clientFirstBare := "n=" + username + ",r=" + clientNonce
+
+	reply, err := c.Command(authSource, D{
+		{"saslStart", int32(1)}, {"mechanism", "SCRAM-SHA-256"}, {"payload", Binary("n,," + clientFirstBare)},
+		{"options", D{{"skipEmptyExchange", true}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to authenticate to MongoDB: %v", err)
+	}
+	serverFirst := string(binaryValue(reply.Get("payload")))
+	fields := scramFields(serverFirst)
+	salt, err := base64.StdEncoding.DecodeString(fields["s"])
+	iterations := 0
+	fmt.Sscan(fields["i"], &iterations)
+	if err != nil || !strings.HasPrefix(fields["r"], clientNonce) || iterations < 1 {
+		return fmt.Errorf("failed to authenticate to MongoDB: invalid server challenge")
+	}
+
+	salted := pbkdf2(password, salt, iterations)
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	clientFinal := "c=biws,r=" + fields["r"]
+	authMessage := clientFirstBare + "," + serverFirst + "," + clientFinal
+	signature := hmacSHA256(storedKey[:], authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ signature[i]
+	}
+
+	reply, err = c.Command(authSource, D{
+		{"saslContinue", int32(1)}, {"conversationId", reply.Get("conversationId")},
+		{"payload", Binary(clientFinal + ",p=" + base64.StdEncoding.EncodeToString(proof))},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to authenticate to MongoDB: %v", err)
+	}
+	serverKey := hmacSHA256(salted, "Server Key")
+	expected := base64.StdEncoding.EncodeToString(hmacSHA256(serverKey, authMessage))
+	if scramFields(string(binaryValue(reply.Get("payload"))))["v"] != expected {
+		return fmt.Errorf("failed to authenticate to MongoDB: invalid server signature")
+	}
+	for done, _ := reply.Get("done").(bool); !done; done, _ = reply.Get("done").(bool) {
+		reply, err = c.Command(authSource, D{
+			{"saslContinue", int32(1)}, {"conversationId", reply.Get("conversationId")}, {"payload", Binary{}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to authenticate to MongoDB: %v", err)
+		}
+	}
+	return nil
+}
+
+// scramFields parses the comma separated key=value attributes of a SCRAM message.
+func scramFields(message string) map[string]string {
+	fields := map[string]string{}
+	for _, attribute := range strings.Split(message, ",") {
+		if key, value, ok := strings.Cut(attribute, "="); ok {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+func binaryValue(v interface{}) []byte {
+	b, _ := v.(Binary)
+	return b
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// pbkdf2 derives the salted password with PBKDF2-HMAC-SHA256, a single block being the size of the key.
+func pbkdf2(password string, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
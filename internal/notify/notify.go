@@ -0,0 +1,134 @@
+// Package notify posts the summary of every finished run to webhooks, e.g. Slack or Teams incoming webhooks
+// or an incident pipeline, so failures reach the teams without anyone watching the runs.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"web-tester/internal/assertion"
+	"web-tester/internal/audit"
+
+	"github.com/google/uuid"
+)
+
+// SignatureHeader is the header holding the HMAC-SHA256 of the body of a notification, hex encoded and prefixed
+// with "sha256=", when a secret is set, so receivers can check a notification was sent by web-tester.
+const SignatureHeader = "X-Web-Tester-Signature"
+
+// Summary is the summary of a finished run posted to the webhooks. Text is a one-line summary, which Slack and
+// Teams incoming webhooks display as the message.
+type Summary struct {
+	Text          string             `json:"text"`
+	TestID        uuid.UUID          `json:"test_id"`
+	Target        string             `json:"target"`
+	Status        string             `json:"status"`
+	Error         string             `json:"error,omitempty"`
+	ScheduleID    string             `json:"schedule_id,omitempty"`
+	SuiteID       string             `json:"suite_id,omitempty"`
+	StartedAt     time.Time          `json:"started_at"`
+	FinishedAt    time.Time          `json:"finished_at"`
+	DurationMS    int64              `json:"duration_ms"`
+	Requests      int                `json:"requests"`
+	Responses     int                `json:"responses"`
+	Findings      map[string]int     `json:"findings"`
+	Assertions    []assertion.Result `json:"assertions"`
+	Failed        int                `json:"failed_assertions"`
+	StorageErrors int                `json:"storage_errors"`
+}
+
+// SetText sets the one-line summary of the run from the other fields.
+func (s *Summary) SetText() {
+	var text strings.Builder
+	fmt.Fprintf(&text, "web-tester %s %s in %s", s.Status, s.Target, time.Duration(s.DurationMS)*time.Millisecond)
+	if s.Error != "" {
+		fmt.Fprintf(&text, ": %s", s.Error)
+	}
+	fmt.Fprintf(&text, ", %d requests", s.Requests)
+	if len(s.Assertions) > 0 {
+		fmt.Fprintf(&text, ", %d/%d assertions failed", s.Failed, len(s.Assertions))
+	}
+	for _, severity := range []string{audit.SeverityHigh, audit.SeverityMedium, audit.SeverityLow, audit.SeverityInfo} {
+		if count := s.Findings[severity]; count > 0 {
+			fmt.Fprintf(&text, ", %d %s findings", count, severity)
+		}
+	}
+	fmt.Fprintf(&text, " (test %s)", s.TestID)
+	s.Text = text.String()
+}
+
+// Notifier posts the summaries of the runs to webhooks.
+type Notifier struct {
+	client *http.Client
+	urls   []string
+	secret string
+}
+
+// New creates a Notifier posting to the webhook urls with client, signing the notifications with secret when set.
+func New(client *http.Client, urls []string, secret string) *Notifier {
+	return &Notifier{client: client, urls: urls, secret: secret}
+}
+
+// Notify posts the summary to every webhook at once, returning the number of webhooks it could not be posted to.
+func (n *Notifier) Notify(ctx context.Context, logger *slog.Logger, summary Summary) int {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		logger.Error("failed to encode notification", "error", err)
+		return len(n.urls)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := 0
+	for _, url := range n.urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := n.post(ctx, url, body); err != nil {
+				logger.Error("failed to notify webhook", "url", url, "error", err)
+				mu.Lock()
+				failed++
+				mu.Unlock()
+				return
+			}
+			logger.Debug("notified webhook", "url", url, "test_id", summary.TestID)
+		}()
+	}
+	wg.Wait()
+	return failed
+}
+
+// post posts a notification to a webhook.
+func (n *Notifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook answered %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+// Package openapi synthesizes an OpenAPI 3 document from the API traffic captured by a run: its paths and methods
+// come from the API inventory, and its parameter and body schemas are inferred from the samples of the requests
+// and responses. The document is a starting point for the undocumented APIs of the tested app, not a contract.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"web-tester/internal/database"
+	"web-tester/internal/inventory"
+)
+
+// Version is the version of the OpenAPI specification of the documents.
+const Version = "3.0.3"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Servers    []Server                         `json:"servers"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components *Components                      `json:"components,omitempty"`
+}
+
+// Info describes the API of a document.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is the base URL of the paths of a document.
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Operation is a method of a path.
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter of an operation.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is the body of the requests of an operation, by media type.
+type RequestBody struct {
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType is the schema of a body of a media type.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Response is a response of an operation, with its body by media type.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Components holds the security schemes the operations refer to.
+type Components struct {
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme is an HTTP authentication scheme or an API key sent in a header.
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// Sample is a request of the page to an API, along with its response when one was captured.
+type Sample struct {
+	Method       string
+	URL          string
+	RequestType  string
+	RequestBody  []byte
+	Status       int
+	ResponseType string
+	ResponseBody []byte
+}
+
+// Samples pairs the XHR and fetch requests of the stored events of a run with their responses, by request ID.
+func Samples(events []database.StoredEvent) []Sample {
+	var payload struct {
+		RequestID string `json:"requestId"`
+		Type      string `json:"type"`
+		Request   *struct {
+			Method string `json:"method"`
+		} `json:"request"`
+		Response *struct {
+			MimeType string `json:"mimeType"`
+		} `json:"response"`
+	}
+	var samples []Sample
+	index := map[string]int{}
+	for _, e := range events {
+		payload.Request, payload.Response = nil, nil
+		if json.Unmarshal(e.Payload, &payload) != nil || (payload.Type != "XHR" && payload.Type != "Fetch") {
+			continue
+		}
+		switch {
+		case e.Type == "request" && payload.Request != nil:
+			// a request ID sent again follows a redirect, whose final request the response belongs to
+			index[payload.RequestID] = len(samples)
+			samples = append(samples, Sample{Method: payload.Request.Method, URL: e.URL, RequestType: e.Headers()["content-type"],
+				RequestBody: []byte(e.Body)})
+		case e.Type == "response" && payload.Response != nil:
+			if i, ok := index[payload.RequestID]; ok {
+				samples[i].Status, samples[i].ResponseType, samples[i].ResponseBody = int(e.Status), payload.Response.MimeType, []byte(e.Body)
+			}
+		}
+	}
+	return samples
+}
+
+// Generate returns the OpenAPI document of the endpoints of the API inventory served from origin, with the schemas
+// inferred from the samples.
+func Generate(title, origin string, endpoints []inventory.APIEndpoint, samples []Sample) Document {
+	doc := Document{OpenAPI: Version, Info: Info{Title: title, Version: "0.0.0",
+		Description: "Synthesized from the traffic captured by web-tester. Review it before relying on it."},
+		Servers: []Server{{URL: origin}}, Paths: map[string]map[string]*Operation{}}
+
+	byEndpoint := map[string][]Sample{}
+	for _, s := range samples {
+		if u, err := url.Parse(s.URL); err == nil {
+			key := s.Method + " " + u.Scheme + "://" + u.Host + inventory.TemplatePath(u.EscapedPath())
+			byEndpoint[key] = append(byEndpoint[key], s)
+		}
+	}
+
+	schemes := map[string]SecurityScheme{}
+	for _, e := range endpoints {
+		if e.Origin != origin {
+			continue
+		}
+		samples := byEndpoint[e.Method+" "+e.Origin+e.Path]
+		op := &Operation{OperationID: operationID(e.Method, e.Path), Responses: map[string]Response{}}
+		op.Parameters = append(pathParameters(e.Path, samples), queryParameters(e.QueryParameters, samples)...)
+		op.RequestBody = requestBody(samples)
+		op.Responses = responses(e.Statuses, samples)
+		for _, header := range e.AuthHeaders {
+			name, scheme := securityScheme(header)
+			schemes[name] = scheme
+			op.Security = append(op.Security, map[string][]string{name: {}})
+		}
+
+		item, ok := doc.Paths[e.Path]
+		if !ok {
+			item = map[string]*Operation{}
+			doc.Paths[e.Path] = item
+		}
+		item[strings.ToLower(e.Method)] = op
+	}
+	if len(schemes) > 0 {
+		doc.Components = &Components{SecuritySchemes: schemes}
+	}
+	return doc
+}
+
+// parameterName matches the parameters of a path template.
+var parameterName = regexp.MustCompile(`^\{(.+)\}$`)
+
+// pathParameters returns the parameters of a path template, typed after their values in the samples.
+func pathParameters(path string, samples []Sample) []Parameter {
+	var params []Parameter
+	for i, segment := range strings.Split(path, "/") {
+		m := parameterName.FindStringSubmatch(segment)
+		if m == nil {
+			continue
+		}
+		var values []string
+		for _, s := range samples {
+			if u, err := url.Parse(s.URL); err == nil {
+				if segments := strings.Split(u.EscapedPath(), "/"); i < len(segments) {
+					value, _ := url.PathUnescape(segments[i])
+					values = append(values, value)
+				}
+			}
+		}
+		params = append(params, Parameter{Name: m[1], In: "path", Required: true, Schema: scalarSchema(values)})
+	}
+	return params
+}
+
+// queryParameters returns the query parameters of an operation, required when every sample sent them.
+func queryParameters(names []string, samples []Sample) []Parameter {
+	params := make([]Parameter, 0, len(names))
+	for _, name := range names {
+		var values []string
+		present := 0
+		for _, s := range samples {
+			if u, err := url.Parse(s.URL); err == nil && u.Query().Has(name) {
+				present++
+				values = append(values, u.Query().Get(name))
+			}
+		}
+		params = append(params, Parameter{Name: name, In: "query", Required: len(samples) > 0 && present == len(samples),
+			Schema: scalarSchema(values)})
+	}
+	return params
+}
+
+// scalarSchema returns the schema of the values of a parameter: integer, number or boolean when all of them read
+// as such, otherwise string, with the format they share.
+func scalarSchema(values []string) *Schema {
+	var schema *Schema
+	for _, v := range values {
+		var s *Schema
+		switch {
+		case v == "true" || v == "false":
+			s = &Schema{Type: "boolean"}
+		case isInteger(v):
+			s = &Schema{Type: "integer"}
+		case isNumber(v):
+			s = &Schema{Type: "number"}
+		default:
+			s = &Schema{Type: "string", Format: stringFormat(v)}
+		}
+		schema = Merge(schema, s)
+	}
+	// parameters are strings on the wire, so values of different types still read as strings
+	if schema == nil || schema.Type == "" {
+		return &Schema{Type: "string"}
+	}
+	return schema
+}
+
+func isInteger(v string) bool {
+	_, err := strconv.ParseInt(v, 10, 64)
+	return err == nil
+}
+
+func isNumber(v string) bool {
+	_, err := strconv.ParseFloat(v, 64)
+	return err == nil
+}
+
+// requestBody returns the request body of an operation by media type, nil when no sample sent one.
+func requestBody(samples []Sample) *RequestBody {
+	content := map[string]MediaType{}
+	for _, s := range samples {
+		if len(s.RequestBody) == 0 {
+			continue
+		}
+		media := mediaType(s.RequestType)
+		content[media] = MediaType{Schema: Merge(content[media].Schema, bodySchema(media, s.RequestBody))}
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	finish(content)
+	return &RequestBody{Content: content}
+}
+
+// responses returns the responses of an operation by status, with their bodies by media type.
+func responses(statuses []int, samples []Sample) map[string]Response {
+	result := map[string]Response{}
+	for _, status := range statuses {
+		result[strconv.Itoa(status)] = Response{Description: description(status)}
+	}
+	for _, s := range samples {
+		if s.Status == 0 {
+			continue
+		}
+		code := strconv.Itoa(s.Status)
+		r, ok := result[code]
+		if !ok {
+			r = Response{Description: description(s.Status)}
+		}
+		if len(s.ResponseBody) > 0 {
+			if r.Content == nil {
+				r.Content = map[string]MediaType{}
+			}
+			media := mediaType(s.ResponseType)
+			r.Content[media] = MediaType{Schema: Merge(r.Content[media].Schema, bodySchema(media, s.ResponseBody))}
+		}
+		result[code] = r
+	}
+	for _, r := range result {
+		finish(r.Content)
+	}
+	if len(result) == 0 {
+		result["default"] = Response{Description: "No response was captured"}
+	}
+	return result
+}
+
+// description returns the description of a response status.
+func description(status int) string {
+	if text := http.StatusText(status); text != "" {
+		return text
+	}
+	return "Status " + strconv.Itoa(status)
+}
+
+// bodySchema returns the schema of a body: inferred from JSON bodies, an object of strings for forms, and a
+// string, binary unless textual, for the others.
+func bodySchema(media string, body []byte) *Schema {
+	switch {
+	case media == "application/json" || strings.HasSuffix(media, "+json"):
+		if s := Infer(body); s != nil {
+			return s
+		}
+	case media == "application/x-www-form-urlencoded":
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+			for name := range form {
+				s.Properties[name] = &Schema{Type: "string"}
+				s.Required = append(s.Required, name)
+			}
+			sort.Strings(s.Required)
+			return s
+		}
+	case strings.HasPrefix(media, "text/") || media == "application/xml" || media == "application/graphql":
+		return &Schema{Type: "string"}
+	}
+	return &Schema{Type: "string", Format: "binary"}
+}
+
+// finish resolves the schemas of the bodies once all of their samples were merged.
+func finish(content map[string]MediaType) {
+	for media, m := range content {
+		content[media] = MediaType{Schema: m.Schema.finish()}
+	}
+}
+
+// mediaType returns the media type of a Content-Type header, without its parameters, and
+// application/octet-stream when there is none.
+func mediaType(contentType string) string {
+	media, _, _ := strings.Cut(contentType, ";")
+	if media = strings.ToLower(strings.TrimSpace(media)); media != "" {
+		return media
+	}
+	return "application/octet-stream"
+}
+
+// securityScheme returns the name and the security scheme of an authentication header of the inventory, e.g.
+// "authorization: bearer" or "x-api-key".
+func securityScheme(header string) (string, SecurityScheme) {
+	if scheme, ok := strings.CutPrefix(header, "authorization: "); ok {
+		if scheme == "bearer" || scheme == "basic" {
+			return scheme + "Auth", SecurityScheme{Type: "http", Scheme: scheme}
+		}
+		return "authorization", SecurityScheme{Type: "apiKey", In: "header", Name: "Authorization"}
+	}
+	return header, SecurityScheme{Type: "apiKey", In: "header", Name: header}
+}
+
+// operationID returns the ID of an operation, from its method and the words of its path in camel case, e.g.
+// getApiUsersIdOrders.
+func operationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, word := range strings.FieldsFunc(path, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		id.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return id.String()
+}
@@ -0,0 +1,165 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Schema is the subset of the OpenAPI schema object inferred from JSON samples. An empty schema allows any value,
+// which is what the samples of different types merge into.
+type Schema struct {
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	// null is set while the only samples seen were null, whose type is not known yet
+	null bool
+}
+
+var (
+	uuidFormat     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	dateFormat     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	dateTimeFormat = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})$`)
+	emailFormat    = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// Infer returns the schema of a JSON document, nil when it is not valid JSON.
+func Infer(data []byte) *Schema {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil
+	}
+	return inferValue(value)
+}
+
+// inferValue returns the schema of a decoded JSON value.
+func inferValue(value interface{}) *Schema {
+	switch v := value.(type) {
+	case nil:
+		return &Schema{null: true}
+	case bool:
+		return &Schema{Type: "boolean"}
+	case json.Number:
+		if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return &Schema{Type: "integer"}
+		}
+		return &Schema{Type: "number"}
+	case string:
+		return &Schema{Type: "string", Format: stringFormat(v)}
+	case []interface{}:
+		s := &Schema{Type: "array"}
+		for _, item := range v {
+			s.Items = Merge(s.Items, inferValue(item))
+		}
+		if s.Items == nil {
+			s.Items = &Schema{}
+		}
+		return s
+	case map[string]interface{}:
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for name, property := range v {
+			s.Properties[name] = inferValue(property)
+			s.Required = append(s.Required, name)
+		}
+		sort.Strings(s.Required)
+		return s
+	}
+	return &Schema{}
+}
+
+// stringFormat returns the format of a string value, when it has one of the common ones.
+func stringFormat(s string) string {
+	switch {
+	case uuidFormat.MatchString(s):
+		return "uuid"
+	case dateTimeFormat.MatchString(s):
+		return "date-time"
+	case dateFormat.MatchString(s):
+		return "date"
+	case emailFormat.MatchString(s):
+		return "email"
+	}
+	return ""
+}
+
+// Merge returns the schema allowing the values of both schemas. The properties of objects are merged, only those
+// of both being required, integers widen to numbers, null makes the other schema nullable, and other differing
+// types merge into the empty schema.
+func Merge(a, b *Schema) *Schema {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.null && b.null:
+		return a
+	case a.null:
+		merged := *b
+		merged.Nullable = true
+		return &merged
+	case b.null:
+		merged := *a
+		merged.Nullable = true
+		return &merged
+	}
+
+	nullable := a.Nullable || b.Nullable
+	if a.Type != b.Type {
+		if (a.Type == "integer" || a.Type == "number") && (b.Type == "integer" || b.Type == "number") {
+			return &Schema{Type: "number", Nullable: nullable}
+		}
+		return &Schema{}
+	}
+
+	merged := &Schema{Type: a.Type, Nullable: nullable}
+	if a.Format == b.Format {
+		merged.Format = a.Format
+	}
+	switch a.Type {
+	case "array":
+		merged.Items = Merge(a.Items, b.Items)
+	case "object":
+		merged.Properties = map[string]*Schema{}
+		for name, property := range a.Properties {
+			merged.Properties[name] = Merge(property, b.Properties[name])
+		}
+		for name, property := range b.Properties {
+			if _, ok := merged.Properties[name]; !ok {
+				merged.Properties[name] = property
+			}
+		}
+		required := map[string]bool{}
+		for _, name := range a.Required {
+			required[name] = true
+		}
+		for _, name := range b.Required {
+			if required[name] {
+				merged.Required = append(merged.Required, name)
+			}
+		}
+		sort.Strings(merged.Required)
+	}
+	return merged
+}
+
+// finish resolves the schemas only null was seen for into the empty schema, which allows null too.
+func (s *Schema) finish() *Schema {
+	if s == nil {
+		return nil
+	}
+	if s.null {
+		return &Schema{Nullable: true}
+	}
+	for name, property := range s.Properties {
+		s.Properties[name] = property.finish()
+	}
+	s.Items = s.Items.finish()
+	return s
+}
@@ -0,0 +1,241 @@
+// Package redact masks the credentials and personal data of the captured traffic before it is stored or streamed:
+// the values of the headers, JSON fields and form or query parameters named by the rules, and the text matching
+// their pattern. The traffic is redacted once audited, so the checks still see it as captured.
+package redact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// Mask replaces the redacted values.
+const Mask = "[REDACTED]"
+
+// Rules are the redaction rules. Headers are the names of the headers whose values are masked. Fields are the
+// paths of the JSON fields whose values are masked, e.g. password or user.email, matching the fields whose path ends
+// with them, the arrays being left out of the paths; the fields of a single name also match the form and query
+// parameters of that name. The text matching Pattern is masked everywhere, e.g. card numbers or email addresses.
+type Rules struct {
+	Headers []string
+	Fields  []string
+	Pattern *regexp.Regexp
+}
+
+// Empty tells whether the rules redact nothing.
+func (r Rules) Empty() bool {
+	return len(r.Headers) == 0 && len(r.Fields) == 0 && r.Pattern == nil
+}
+
+// Text masks the text matching the pattern.
+func (r Rules) Text(text string) string {
+	if r.Pattern == nil {
+		return text
+	}
+	return r.Pattern.ReplaceAllString(text, Mask)
+}
+
+// URL masks the query parameters named by the fields of a URL, and the text matching the pattern.
+func (r Rules) URL(rawURL string) string {
+	if r.Empty() {
+		return rawURL
+	}
+	if u, err := url.Parse(rawURL); err == nil && u.RawQuery != "" {
+		if query, changed := r.form(u.Query()); changed {
+			u.RawQuery = query.Encode()
+			rawURL = u.String()
+		}
+	}
+	return r.Text(rawURL)
+}
+
+// Body masks the fields of a JSON body or the parameters of a form body named by the fields, and the text matching
+// the pattern. A JSON body redacted is encoded again, with its object keys sorted.
+func (r Rules) Body(body []byte) []byte {
+	if r.Empty() || len(body) == 0 {
+		return body
+	}
+	if trimmed := bytes.TrimSpace(body); len(r.Fields) > 0 && len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.UseNumber()
+		if decoder.Decode(&value) == nil {
+			if value, changed := r.value(value, nil); changed {
+				var out bytes.Buffer
+				encoder := json.NewEncoder(&out)
+				encoder.SetEscapeHTML(false)
+				if encoder.Encode(value) == nil {
+					body = bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+				}
+			}
+		}
+	} else if len(r.Fields) > 0 && bytes.ContainsRune(body, '=') {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			if values, changed := r.form(values); changed {
+				body = []byte(values.Encode())
+			}
+		}
+	}
+	if r.Pattern == nil {
+		return body
+	}
+	return r.Pattern.ReplaceAll(body, []byte(Mask))
+}
+
+// Value masks a value stored under a name, e.g. a web storage entry: whole when the name is one of the fields of a
+// single name, else as a body.
+func (r Rules) Value(name, value string) string {
+	if r.Empty() {
+		return value
+	}
+	if r.field([]string{name}) {
+		return Mask
+	}
+	return string(r.Body([]byte(value)))
+}
+
+// Content masks the headers, URLs and post data of a CDP request or response event, returning a redacted copy of
+// it. The other events are returned as they are.
+func (r Rules) Content(content interface{}) interface{} {
+	if r.Empty() {
+		return content
+	}
+	switch ev := content.(type) {
+	case *network.EventRequestWillBeSent:
+		redacted := *ev
+		redacted.DocumentURL = r.URL(ev.DocumentURL)
+		if ev.Request != nil {
+			request := *ev.Request
+			request.URL, request.Headers = r.URL(request.URL), r.headers(request.Headers)
+			request.PostDataEntries = nil
+			for _, entry := range ev.Request.PostDataEntries {
+				data, err := base64.StdEncoding.DecodeString(entry.Bytes)
+				if err != nil {
+					continue
+				}
+				request.PostDataEntries = append(request.PostDataEntries,
+					&network.PostDataEntry{Bytes: base64.StdEncoding.EncodeToString(r.Body(data))})
+			}
+			redacted.Request = &request
+		}
+		if ev.RedirectResponse != nil {
+			redacted.RedirectResponse = r.response(ev.RedirectResponse)
+		}
+		return &redacted
+	case *network.EventResponseReceived:
+		redacted := *ev
+		if ev.Response != nil {
+			redacted.Response = r.response(ev.Response)
+		}
+		return &redacted
+	}
+	return content
+}
+
+// response returns a redacted copy of a CDP response, along with the request headers it carries.
+func (r Rules) response(resp *network.Response) *network.Response {
+	redacted := *resp
+	redacted.URL = r.URL(resp.URL)
+	redacted.Headers, redacted.RequestHeaders = r.headers(resp.Headers), r.headers(resp.RequestHeaders)
+	return &redacted
+}
+
+// headers returns a copy of CDP headers with the values of the headers named by the rules masked, and the text
+// matching the pattern in the others.
+func (r Rules) headers(headers network.Headers) network.Headers {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(network.Headers, len(headers))
+	for name, value := range headers {
+		switch v := value.(type) {
+		case string:
+			if r.header(name) {
+				redacted[name] = Mask
+			} else {
+				redacted[name] = r.Text(v)
+			}
+		default:
+			redacted[name] = value
+		}
+	}
+	return redacted
+}
+
+// header tells whether the value of the header is masked.
+func (r Rules) header(name string) bool {
+	for _, h := range r.Headers {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// form masks the values of the parameters named by the fields, telling whether any was.
+func (r Rules) form(values url.Values) (url.Values, bool) {
+	changed := false
+	for name, list := range values {
+		if !r.field([]string{name}) {
+			continue
+		}
+		for i := range list {
+			list[i] = Mask
+		}
+		changed = true
+	}
+	return values, changed
+}
+
+// value masks the fields of a decoded JSON value at path named by the fields, telling whether any was.
+func (r Rules) value(value interface{}, path []string) (interface{}, bool) {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, field := range v {
+			fieldPath := append(path[:len(path):len(path)], name)
+			if r.field(fieldPath) {
+				v[name], changed = Mask, true
+				continue
+			}
+			var c bool
+			if v[name], c = r.value(field, fieldPath); c {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			var c bool
+			if v[i], c = r.value(item, path); c {
+				changed = true
+			}
+		}
+	}
+	return value, changed
+}
+
+// field tells whether the field at path is named by the fields, its path ending with one of them.
+func (r Rules) field(path []string) bool {
+	for _, f := range r.Fields {
+		names := strings.Split(f, ".")
+		if len(names) > len(path) {
+			continue
+		}
+		matched := true
+		for i, name := range names {
+			if !strings.EqualFold(name, path[len(path)-len(names)+i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
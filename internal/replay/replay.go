@@ -0,0 +1,182 @@
+// Package replay re-issues captured requests with net/http, with their original headers and bodies, optionally
+// mutated, e.g. sent to another host or with the token of another user, and compares the responses with the captured
+// ones, for regression and access-control testing. The stored events being redacted, their masked headers are not
+// sent and the credentials must be given again as mutated headers.
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"web-tester/internal/browser"
+	"web-tester/internal/redact"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/google/uuid"
+)
+
+// maxBody is the maximum number of bytes of a replayed response body read.
+const maxBody = 1 << 20
+
+// Mutation is the change made to the replayed requests. Host replaces their host, e.g. to replay the traffic of
+// production against staging, and Headers replace their headers, a header with an empty value being removed, e.g.
+// to replay them with the token of another user or without credentials.
+type Mutation struct {
+	Host    string
+	Headers http.Header
+}
+
+// Filter selects the transactions replayed: those of the resource types Types, all of them when empty, of the
+// method Method when set, and whose URL matches Match when set.
+type Filter struct {
+	Types  []string
+	Method string
+	Match  *regexp.Regexp
+}
+
+// Result is the response to a replayed request, compared with the captured one. OriginalStatus is 0 when no
+// response was captured. Changed is set when the status differs, or the body when the original one was captured and
+// not redacted, a redacted body never matching the live one.
+type Result struct {
+	ReplayID       uuid.UUID `json:"replay_id"`
+	RequestID      string    `json:"request_id"`
+	Method         string    `json:"method"`
+	URL            string    `json:"url"`
+	OriginalStatus int       `json:"original_status"`
+	Status         int       `json:"status"`
+	OriginalLength int       `json:"original_length"`
+	Length         int       `json:"length"`
+	Changed        bool      `json:"changed"`
+	DurationMS     float64   `json:"duration_ms"`
+	Error          string    `json:"error,omitempty"`
+	Body           []byte    `json:"-"`
+}
+
+// Select returns the transactions selected by the filter.
+func Select(transactions []browser.Transaction, filter Filter) []browser.Transaction {
+	var selected []browser.Transaction
+	for _, t := range transactions {
+		if len(filter.Types) > 0 && !containsFold(filter.Types, t.ResourceType) {
+			continue
+		}
+		if filter.Method != "" && !strings.EqualFold(filter.Method, t.Method) {
+			continue
+		}
+		if filter.Match != nil && !filter.Match.MatchString(t.URL) {
+			continue
+		}
+		selected = append(selected, t)
+	}
+	return selected
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run replays the transactions in order with the mutation, at most maxRequests of them, and returns their results
+// tagged with a new replay ID, their URLs, errors and bodies redacted by the rules, as they are logged. The client
+// should not follow redirects, the browser having captured every hop.
+func Run(ctx context.Context, logger *slog.Logger, client *http.Client, transactions []browser.Transaction, m Mutation, redaction redact.Rules, maxRequests int) []Result {
+	replayID := uuid.New()
+	results := make([]Result, 0, len(transactions))
+	for i, t := range transactions {
+		if i >= maxRequests {
+			logger.Info("replay request budget exhausted", "max_requests", maxRequests)
+			break
+		}
+		result := send(ctx, client, t, m)
+		result.ReplayID = replayID
+		sentURL := result.URL
+		result.URL = redaction.URL(sentURL)
+		result.Error = redaction.Text(strings.ReplaceAll(result.Error, sentURL, result.URL))
+		result.Body = redaction.Body(result.Body)
+		if result.Error != "" {
+			logger.Error("failed to replay request", "url", result.URL, "error", result.Error)
+		} else {
+			logger.Info("replayed request", "method", result.Method, "url", result.URL, "status", result.Status,
+				"original_status", result.OriginalStatus, "changed", result.Changed)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// send replays a transaction with the mutation and compares its response with the captured one.
+func send(ctx context.Context, client *http.Client, t browser.Transaction, m Mutation) Result {
+	result := Result{RequestID: string(t.RequestID), Method: t.Method, URL: t.URL}
+	if t.Response != nil {
+		result.OriginalStatus, result.OriginalLength = int(t.Response.Status), len(t.Response.Body)
+	}
+	ev, ok := t.Request.Content.(*network.EventRequestWillBeSent)
+	if !ok || ev.Request == nil {
+		result.Error = "the request was not captured"
+		return result
+	}
+
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if m.Host != "" {
+		u.Host = m.Host
+		result.URL = u.String()
+	}
+	req, err := http.NewRequestWithContext(ctx, t.Method, u.String(), bytes.NewReader(t.Request.Body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for name, value := range ev.Request.Headers {
+		// pseudo and framing headers are set by the client
+		if strings.HasPrefix(name, ":") || strings.EqualFold(name, "Content-Length") || strings.EqualFold(name, "Host") {
+			continue
+		}
+		// the masked values of the redacted headers would only be rejected, their credentials being lost
+		if v := fmt.Sprint(value); v != redact.Mask {
+			req.Header.Set(name, v)
+		}
+	}
+	for name, values := range m.Headers {
+		if len(values) == 0 || values[0] == "" {
+			req.Header.Del(name)
+		} else {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	result.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status, result.Length, result.Body = resp.StatusCode, len(body), body
+	result.Changed = result.OriginalStatus != 0 && result.Status != result.OriginalStatus
+	if t.Response != nil && len(t.Response.Body) > 0 && !bytes.Contains(t.Response.Body, []byte(redact.Mask)) &&
+		!bytes.Equal(t.Response.Body, body) {
+		result.Changed = true
+	}
+	return result
+}
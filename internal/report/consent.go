@@ -0,0 +1,215 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"web-tester/internal/browser"
+	"web-tester/internal/database"
+	"web-tester/internal/inventory"
+)
+
+// ConsentProfile is the regulation a consent report is written for. Flagged holds the categories of cookies,
+// storage and trackers that should not be active before the visitor consents, or opts out, under the regulation.
+type ConsentProfile struct {
+	Name    string
+	Title   string
+	Rule    string
+	Flagged map[string]bool
+}
+
+// categoryUnclassified is the category of the third-party cookies and storage of unknown vendors, which
+// need a review as they may well be tracking the visitor.
+const categoryUnclassified = "unclassified"
+
+// ConsentProfiles are the regulation profiles a consent report can be rendered for.
+var ConsentProfiles = map[string]ConsentProfile{
+	"gdpr": {
+		Name:  "gdpr",
+		Title: "GDPR / ePrivacy Directive",
+		Rule: "Cookies, storage and trackers that are not strictly necessary need the visitor's prior consent, " +
+			"so none of the flagged items should be set or contacted before the visitor opts in.",
+		Flagged: map[string]bool{"advertising": true, "analytics": true, "social": true, "marketing": true, categoryUnclassified: true},
+	},
+	"ccpa": {
+		Name:  "ccpa",
+		Title: "CCPA / CPRA",
+		Rule: "Selling or sharing personal information for cross-context behavioral advertising must honor the visitor's " +
+			"\"Do Not Sell or Share\" opt-out and Global Privacy Control, so the flagged items need to be disabled once the visitor opts out.",
+		Flagged: map[string]bool{"advertising": true, "social": true, "marketing": true},
+	},
+}
+
+// knownVendor is the vendor and category of a cookie or storage key, recognized by its name prefix.
+type knownVendor struct {
+	prefix   string
+	vendor   string
+	category string
+}
+
+// knownVendors are the name prefixes of the cookies and storage keys set by common vendors.
+var knownVendors = []knownVendor{
+	{"_ga", "Google Analytics", "analytics"},
+	{"_gid", "Google Analytics", "analytics"},
+	{"_gat", "Google Analytics", "analytics"},
+	{"_gcl_", "Google Ads", "advertising"},
+	{"IDE", "Google Ads", "advertising"},
+	{"test_cookie", "Google Ads", "advertising"},
+	{"_fbp", "Meta", "social"},
+	{"_fbc", "Meta", "social"},
+	{"_hj", "Hotjar", "analytics"},
+	{"_clck", "Microsoft Clarity", "analytics"},
+	{"_clsk", "Microsoft Clarity", "analytics"},
+	{"_uetsid", "Microsoft Advertising", "advertising"},
+	{"_uetvid", "Microsoft Advertising", "advertising"},
+	{"mp_", "Mixpanel", "analytics"},
+	{"ajs_", "Segment", "analytics"},
+	{"amplitude", "Amplitude", "analytics"},
+	{"_pin_unauth", "Pinterest", "social"},
+	{"_ttp", "TikTok", "social"},
+	{"li_", "LinkedIn", "social"},
+	{"bcookie", "LinkedIn", "social"},
+	{"hubspotutk", "HubSpot", "marketing"},
+	{"__hs", "HubSpot", "marketing"},
+	{"__kla_id", "Klaviyo", "marketing"},
+	{"OptanonConsent", "OneTrust", "consent"},
+	{"OptanonAlertBoxClosed", "OneTrust", "consent"},
+	{"CookieConsent", "Cookiebot", "consent"},
+	{"__cf_bm", "Cloudflare", "necessary"},
+	{"cf_clearance", "Cloudflare", "necessary"},
+	{"__cfruid", "Cloudflare", "necessary"},
+}
+
+// ConsentEntry is a cookie, storage key or tracker observed during the run.
+type ConsentEntry struct {
+	Name       string
+	Domain     string
+	Vendor     string
+	Category   string
+	ThirdParty bool
+	Flagged    bool
+	Details    string
+}
+
+// ConsentData is what consent report templates are executed with. The run does not interact with consent
+// banners, so everything it observed was set or contacted before the visitor consented.
+type ConsentData struct {
+	Run      database.TestRun
+	Profile  ConsentProfile
+	Cookies  []ConsentEntry
+	Storage  []ConsentEntry
+	Trackers []ConsentEntry
+	// Flagged counts the entries flagged under the profile
+	Flagged int
+}
+
+// NewConsent gathers the data of a consent report on a run for the given regulation profile.
+func NewConsent(run database.TestRun, profile ConsentProfile, cookies []browser.Cookie, storage []browser.StorageItem, domains []inventory.Domain) ConsentData {
+	data := ConsentData{Run: run, Profile: profile}
+	site := ""
+	if u, err := url.Parse(run.TargetURL); err == nil {
+		site = inventory.RegistrableDomain(u.Hostname())
+	}
+	trackers := map[string]string{}
+	for _, d := range domains {
+		trackers[d.Domain] = d.Tracker
+	}
+
+	for _, c := range cookies {
+		domain := inventory.RegistrableDomain(strings.TrimPrefix(c.Domain, "."))
+		entry := data.classify(c.Name, domain, domain != site, trackers[domain])
+		entry.Details = cookieDetails(c)
+		data.Cookies = append(data.Cookies, entry)
+	}
+
+	for _, item := range storage {
+		domain := ""
+		if u, err := url.Parse(item.Origin); err == nil {
+			domain = inventory.RegistrableDomain(u.Hostname())
+		}
+		entry := data.classify(item.Key, domain, domain != site, "")
+		entry.Details = fmt.Sprintf("%sStorage, %d characters", item.Kind, item.Size)
+		data.Storage = append(data.Storage, entry)
+	}
+
+	for _, d := range domains {
+		if d.Tracker == "" {
+			continue
+		}
+		entry := ConsentEntry{Name: d.Domain, Domain: d.Domain, Vendor: d.Domain, Category: d.Tracker, ThirdParty: !d.FirstParty,
+			Flagged: profile.Flagged[d.Tracker], Details: fmt.Sprintf("%d requests", d.Requests)}
+		if entry.Flagged {
+			data.Flagged++
+		}
+		data.Trackers = append(data.Trackers, entry)
+	}
+
+	// flagged entries first, then by domain and name
+	for _, entries := range [][]ConsentEntry{data.Cookies, data.Storage, data.Trackers} {
+		sort.SliceStable(entries, func(i, j int) bool {
+			if entries[i].Flagged != entries[j].Flagged {
+				return entries[i].Flagged
+			}
+			if entries[i].Domain != entries[j].Domain {
+				return entries[i].Domain < entries[j].Domain
+			}
+			return entries[i].Name < entries[j].Name
+		})
+	}
+	return data
+}
+
+// classify returns the entry of a cookie or storage key, categorized by its vendor when its name is known,
+// or by the tracker category of its domain. Third-party entries of unknown vendors are unclassified.
+func (d *ConsentData) classify(name, domain string, thirdParty bool, tracker string) ConsentEntry {
+	entry := ConsentEntry{Name: name, Domain: domain, ThirdParty: thirdParty}
+	for _, known := range knownVendors {
+		if strings.HasPrefix(name, known.prefix) {
+			entry.Vendor, entry.Category = known.vendor, known.category
+			break
+		}
+	}
+	switch {
+	case entry.Category != "":
+	case tracker != "":
+		entry.Vendor, entry.Category = domain, tracker
+	case thirdParty:
+		entry.Category = categoryUnclassified
+	}
+
+	entry.Flagged = d.Profile.Flagged[entry.Category]
+	if entry.Flagged {
+		d.Flagged++
+	}
+	return entry
+}
+
+// cookieDetails describes the lifetime and attributes of a cookie.
+func cookieDetails(c browser.Cookie) string {
+	details := []string{"session"}
+	if !c.Session && !c.Expires.IsZero() {
+		details = []string{"expires " + c.Expires.Format("2006-01-02")}
+	}
+	if c.Secure {
+		details = append(details, "Secure")
+	}
+	if c.HTTPOnly {
+		details = append(details, "HttpOnly")
+	}
+	if c.SameSite != "" {
+		details = append(details, "SameSite="+c.SameSite)
+	}
+	return strings.Join(details, ", ")
+}
+
+// RenderConsent writes the consent report in the HTML or Markdown format, with the template at templatePath
+// when it is set, otherwise the embedded consent template of the format.
+func RenderConsent(w io.Writer, format, templatePath string, data ConsentData) error {
+	name, ok := consentTemplates[format]
+	if !ok {
+		return fmt.Errorf("unsupported consent report format %q, expected %s or %s", format, FormatHTML, FormatMarkdown)
+	}
+	return execute(w, format, name, templatePath, data)
+}
@@ -0,0 +1,120 @@
+package report
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"web-tester/internal/browser"
+	"web-tester/internal/database"
+)
+
+// gigabyte is the size data plans are priced by.
+const gigabyte = 1e9
+
+// DataPrice is the price of a gigabyte of data for a region and connection type, e.g. "in/mobile".
+type DataPrice struct {
+	Label string
+	PerGB float64
+}
+
+// ParsePrices parses data prices from label=price pairs.
+func ParsePrices(pairs []string) ([]DataPrice, error) {
+	var prices []DataPrice
+	for _, pair := range pairs {
+		label, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(label) == "" {
+			return nil, fmt.Errorf("invalid data price %q, expected label=price", pair)
+		}
+		perGB, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || perGB < 0 {
+			return nil, fmt.Errorf("invalid data price %q: price must be a positive number", pair)
+		}
+		prices = append(prices, DataPrice{Label: strings.TrimSpace(label), PerGB: perGB})
+	}
+	return prices, nil
+}
+
+// Cost is the data cost of a page to its users: the bytes transferred on a first visit and on a repeat
+// visit with the caches filled by the first one, priced for every configured region and connection type.
+type Cost struct {
+	FirstVisit  float64
+	RepeatVisit float64
+	// RepeatMeasured is set when the repeat visit bytes were measured by a warm load rather than estimated
+	RepeatMeasured bool
+	Currency       string
+	Prices         []VisitCost
+}
+
+// VisitCost is the cost of a first and a repeat visit at a data price.
+type VisitCost struct {
+	Label       string
+	PerGB       float64
+	FirstVisit  float64
+	RepeatVisit float64
+}
+
+// NewCost estimates the cost to user of a run from its stored responses. The repeat visit bytes are those
+// of the warm load when the run compared cache loads, otherwise the bytes of the responses that are not
+// fresh in the cache on a repeat visit according to their headers.
+func NewCost(events []database.StoredEvent, warm *browser.LoadStats, prices []DataPrice, currency string) Cost {
+	cost := Cost{Currency: currency}
+	for _, e := range events {
+		if e.Type != "response" {
+			continue
+		}
+		cost.FirstVisit += e.EncodedBytes
+		if !reusable(e.Headers()) {
+			cost.RepeatVisit += e.EncodedBytes
+		}
+	}
+	if warm != nil {
+		cost.RepeatVisit, cost.RepeatMeasured = warm.Bytes, true
+	}
+
+	for _, p := range prices {
+		cost.Prices = append(cost.Prices, VisitCost{Label: p.Label, PerGB: p.PerGB,
+			FirstVisit: cost.FirstVisit / gigabyte * p.PerGB, RepeatVisit: cost.RepeatVisit / gigabyte * p.PerGB})
+	}
+	return cost
+}
+
+// reusable reports whether a response is served from the cache without a network round trip on a repeat
+// visit, i.e. it may be stored and has a freshness lifetime. Heuristic freshness is not taken into account.
+func reusable(headers map[string]string) bool {
+	var cacheControl, expires, date string
+	for name, value := range headers {
+		switch strings.ToLower(name) {
+		case "cache-control":
+			cacheControl = strings.ToLower(value)
+		case "expires":
+			expires = value
+		case "date":
+			date = value
+		}
+	}
+
+	maxAge := -1
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch name {
+		case "no-store", "no-cache":
+			return false
+		case "max-age":
+			if age, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				maxAge = age
+			}
+		}
+	}
+	// max-age takes precedence over Expires
+	if maxAge >= 0 {
+		return maxAge > 0
+	}
+
+	expiresAt, err := http.ParseTime(expires)
+	if err != nil {
+		return false
+	}
+	served, err := http.ParseTime(date)
+	return err != nil || expiresAt.After(served)
+}
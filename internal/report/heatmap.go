@@ -0,0 +1,191 @@
+package report
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"web-tester/internal/database"
+)
+
+// Heatmap settings: the run is split into at most heatmapBuckets buckets of at least heatmapMinBucket
+// milliseconds, and the heatmapDomains domains transferring the most bytes get a row of their own.
+const (
+	heatmapBuckets   = 40
+	heatmapMinBucket = 100.0
+	heatmapDomains   = 12
+	// heatmapLabelEvery is the number of buckets between two labels of the time axis
+	heatmapLabelEvery = 5
+)
+
+// Heatmap is the request activity of a run over time: the requests sent and the bytes received in each
+// bucket of the run, per domain.
+type Heatmap struct {
+	// Bucket is the duration of a bucket in milliseconds
+	Bucket float64
+	// Offsets are the start of each bucket in milliseconds since the first event, and Labels label the time
+	// axis every heatmapLabelEvery buckets
+	Offsets []float64
+	Labels  []HeatmapLabel
+	// Total is the activity of all the domains, and Rows the activity of the busiest domains, followed by
+	// the Other row of the remaining domains
+	Total HeatmapRow
+	Rows  []HeatmapRow
+}
+
+// AllRows returns the Total row followed by the domain rows.
+func (h Heatmap) AllRows() []HeatmapRow {
+	return append([]HeatmapRow{h.Total}, h.Rows...)
+}
+
+// HeatmapLabel labels the Span buckets of the time axis it spans with the start of the first, in seconds.
+type HeatmapLabel struct {
+	Text string
+	Span int
+}
+
+// HeatmapRow is the activity of a domain, or of all the domains of the Other row.
+type HeatmapRow struct {
+	Domain   string
+	Requests int
+	Bytes    float64
+	Cells    []HeatmapCell
+}
+
+// HeatmapCell is the activity of a domain in a bucket. Intensity is from 0 to 1, relative to the domain
+// bucket of the heatmap with the most bytes, or the most requests when no bytes were recorded. The
+// intensity of the Total row is relative to its own busiest bucket.
+type HeatmapCell struct {
+	Requests  int
+	Bytes     float64
+	Intensity float64
+}
+
+// NewHeatmap buckets the stored requests by the time they were sent and the responses by the time they were
+// received, returning nil when the events have no timestamps.
+func NewHeatmap(events []database.StoredEvent) *Heatmap {
+	type sample struct {
+		domain  string
+		at      float64
+		request bool
+		bytes   float64
+	}
+	var samples []sample
+	start, end := math.Inf(1), math.Inf(-1)
+	for _, e := range events {
+		if e.Type != "request" && e.Type != "response" {
+			continue
+		}
+		var payload struct {
+			Timestamp float64 `json:"timestamp"`
+		}
+		if err := json.Unmarshal(e.Payload, &payload); err != nil || payload.Timestamp == 0 {
+			continue
+		}
+		// the timestamps are in seconds of the browser's monotonic clock
+		at := payload.Timestamp * 1000
+		samples = append(samples, sample{domain: e.Domain, at: at, request: e.Type == "request", bytes: e.EncodedBytes})
+		start, end = math.Min(start, at), math.Max(end, at)
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	bucket := math.Max(heatmapMinBucket, math.Ceil((end-start)/heatmapBuckets/heatmapMinBucket)*heatmapMinBucket)
+	buckets := int((end-start)/bucket) + 1
+
+	// the busiest domains get their own row, the others share the Other row
+	domainBytes := map[string]float64{}
+	for _, s := range samples {
+		domainBytes[s.domain] += s.bytes
+	}
+	domains := make([]string, 0, len(domainBytes))
+	for domain := range domainBytes {
+		domains = append(domains, domain)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if domainBytes[domains[i]] != domainBytes[domains[j]] {
+			return domainBytes[domains[i]] > domainBytes[domains[j]]
+		}
+		return domains[i] < domains[j]
+	})
+	rowOf := map[string]int{}
+	for i, domain := range domains {
+		rowOf[domain] = min(i, heatmapDomains)
+	}
+
+	newRow := func(domain string) HeatmapRow {
+		return HeatmapRow{Domain: domain, Cells: make([]HeatmapCell, buckets)}
+	}
+	heatmap := &Heatmap{Bucket: bucket, Total: newRow("All domains")}
+	for i := 0; i < buckets; i++ {
+		heatmap.Offsets = append(heatmap.Offsets, float64(i)*bucket)
+		if i%heatmapLabelEvery == 0 {
+			text := strconv.FormatFloat(float64(i)*bucket/1000, 'f', 1, 64) + "s"
+			heatmap.Labels = append(heatmap.Labels, HeatmapLabel{Text: text, Span: min(heatmapLabelEvery, buckets-i)})
+		}
+	}
+	for i := 0; i < min(len(domains), heatmapDomains); i++ {
+		heatmap.Rows = append(heatmap.Rows, newRow(domains[i]))
+	}
+	if len(domains) > heatmapDomains {
+		heatmap.Rows = append(heatmap.Rows, newRow("Other"))
+	}
+
+	for _, s := range samples {
+		i := int((s.at - start) / bucket)
+		for _, row := range []*HeatmapRow{&heatmap.Total, &heatmap.Rows[rowOf[s.domain]]} {
+			if s.request {
+				row.Requests++
+				row.Cells[i].Requests++
+			}
+			row.Bytes += s.bytes
+			row.Cells[i].Bytes += s.bytes
+		}
+	}
+
+	// the domain rows share their scale, so the bursts of a domain stand out against the others
+	maxBytes, maxRequests := 0.0, 0
+	for _, row := range heatmap.Rows {
+		for _, cell := range row.Cells {
+			maxBytes, maxRequests = math.Max(maxBytes, cell.Bytes), max(maxRequests, cell.Requests)
+		}
+	}
+	setIntensity(&heatmap.Total, heatmap.Total.maxBytes(), heatmap.Total.maxRequests())
+	for i := range heatmap.Rows {
+		setIntensity(&heatmap.Rows[i], maxBytes, maxRequests)
+	}
+	return heatmap
+}
+
+// setIntensity sets the intensity of the cells of the row relative to the busiest bucket.
+func setIntensity(row *HeatmapRow, maxBytes float64, maxRequests int) {
+	for i := range row.Cells {
+		cell := &row.Cells[i]
+		switch {
+		case maxBytes > 0:
+			cell.Intensity = cell.Bytes / maxBytes
+		case maxRequests > 0:
+			cell.Intensity = float64(cell.Requests) / float64(maxRequests)
+		}
+		cell.Intensity = math.Round(cell.Intensity*100) / 100
+	}
+}
+
+// maxBytes returns the bytes of the bucket of the row with the most bytes.
+func (r HeatmapRow) maxBytes() float64 {
+	m := 0.0
+	for _, cell := range r.Cells {
+		m = math.Max(m, cell.Bytes)
+	}
+	return m
+}
+
+// maxRequests returns the requests of the bucket of the row with the most requests.
+func (r HeatmapRow) maxRequests() int {
+	m := 0
+	for _, cell := range r.Cells {
+		m = max(m, cell.Requests)
+	}
+	return m
+}
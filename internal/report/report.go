@@ -0,0 +1,215 @@
+// Package report renders the results of a stored run as an HTML or Markdown report, and the cookies and
+// trackers it observed as a consent report. The default templates
+// are embedded, and organizations can provide their own template to brand and restructure the report.
+package report
+
+import (
+	"embed"
+	"encoding/base64"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"web-tester/internal/audit"
+	"web-tester/internal/browser"
+	"web-tester/internal/crawl"
+	"web-tester/internal/database"
+	"web-tester/internal/explore"
+	"web-tester/internal/inventory"
+	"web-tester/internal/journey"
+)
+
+// Report formats.
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+	FormatSARIF    = "sarif"
+)
+
+// Formats are the formats a report can be rendered in.
+var Formats = []string{FormatHTML, FormatMarkdown, FormatSARIF}
+
+//go:embed templates
+var templates embed.FS
+
+// defaultTemplates maps each format to its embedded template.
+var defaultTemplates = map[string]string{
+	FormatHTML:     "templates/report.html.tmpl",
+	FormatMarkdown: "templates/report.md.tmpl",
+}
+
+// consentTemplates maps each format to its embedded consent report template.
+var consentTemplates = map[string]string{
+	FormatHTML:     "templates/consent.html.tmpl",
+	FormatMarkdown: "templates/consent.md.tmpl",
+}
+
+// Data is what report templates are executed with.
+type Data struct {
+	Run      database.TestRun
+	Events   []database.StoredEvent
+	Findings []audit.Finding
+	Summary  Summary
+	// Milestones are the milestones reached by the journey of the run, if it ran a scenario
+	Milestones []journey.Milestone
+	// Interactions are the clicks and scrolls made by the exploration of the run, if it explored the page
+	Interactions []explore.Interaction
+	// CriticalChain is the critical request chain of the page load, in depth-first order
+	CriticalChain []browser.ChainNode
+	// Coverage is the usage of the scripts and stylesheets of the page, if the run measured it
+	Coverage []browser.ResourceCoverage
+	// Cost is the data cost of the page to its users
+	Cost Cost
+	// Edge attributes the latency of each registrable domain to its CDN edge and origin
+	Edge []inventory.Edge
+	// Faults are the faults the chaos mode injected into the requests of the run
+	Faults []browser.InjectedFault
+	// Storage is the web storage snapshot of the run, if it took one
+	Storage []browser.StorageEntry
+	// Activity is the heatmap of the requests and bytes of the run over time, per domain
+	Activity *Heatmap
+	// BrokenLinks are the broken links found on the page, if it was crawled
+	BrokenLinks []crawl.Link
+	// Waterfall lays out the requests of each page of the run on its timeline
+	Waterfall []WaterfallPage
+	// Screenshots are the screenshots of the page taken during the run, embedded in the HTML report
+	Screenshots []browser.Screenshot
+}
+
+// Summary holds the aggregate numbers of a run.
+type Summary struct {
+	Requests  int
+	Responses int
+	Bytes     float64
+	Duration  string
+	// StatusClasses counts the responses per status class, e.g. "2xx"
+	StatusClasses []Count
+	// Severities counts the findings per severity
+	Severities []Count
+}
+
+// Count is a labelled count.
+type Count struct {
+	Label string
+	Count int
+}
+
+// New gathers the data of a report on a run.
+func New(run database.TestRun, events []database.StoredEvent, findings []audit.Finding) Data {
+	summary := Summary{}
+	if !run.FinishedAt.IsZero() {
+		summary.Duration = run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond).String()
+	}
+
+	classes := map[string]int{}
+	for _, e := range events {
+		switch e.Type {
+		case "request":
+			summary.Requests++
+		case "response":
+			summary.Responses++
+			summary.Bytes += e.EncodedBytes
+			if e.Status > 0 {
+				classes[fmt.Sprintf("%dxx", e.Status/100)]++
+			}
+		}
+	}
+	summary.StatusClasses = sortedCounts(classes)
+
+	severities := map[string]int{}
+	for _, f := range findings {
+		severities[f.Severity]++
+	}
+	summary.Severities = sortedCounts(severities)
+
+	return Data{Run: run, Events: events, Findings: findings, Summary: summary, Activity: NewHeatmap(events)}
+}
+
+// sortedCounts returns the counts sorted by label.
+func sortedCounts(counts map[string]int) []Count {
+	sorted := make([]Count, 0, len(counts))
+	for label, count := range counts {
+		sorted = append(sorted, Count{Label: label, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Label < sorted[j].Label })
+	return sorted
+}
+
+// funcs are the functions available to report templates, on top of the template package builtins.
+var funcs = map[string]interface{}{
+	"bytes": func(n float64) string {
+		switch {
+		case n >= 1<<20:
+			return fmt.Sprintf("%.1f MiB", n/(1<<20))
+		case n >= 1<<10:
+			return fmt.Sprintf("%.1f KiB", n/(1<<10))
+		}
+		return fmt.Sprintf("%.0f B", n)
+	},
+	"percent": func(ratio float64) string {
+		return fmt.Sprintf("%.0f%%", ratio*100)
+	},
+	"add":  func(a, b int) int { return a + b },
+	"addf": func(a, b float64) float64 { return a + b },
+	"dataURI": func(mimeType string, data []byte) htmltemplate.URL {
+		return htmltemplate.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data))
+	},
+	"upper":  strings.ToUpper,
+	"repeat": strings.Repeat,
+	"truncate": func(n int, s string) string {
+		if len(s) <= n {
+			return s
+		}
+		return s[:n] + "…"
+	},
+}
+
+// Render writes the report in the given format. The template at templatePath is used when it is set,
+// otherwise the embedded template of the format. HTML templates are escaped as HTML. SARIF reports only
+// hold the findings and are not templated.
+func Render(w io.Writer, format, templatePath string, data Data) error {
+	if format == FormatSARIF {
+		return writeSARIF(w, data)
+	}
+
+	name, ok := defaultTemplates[format]
+	if !ok {
+		return fmt.Errorf("unsupported report format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+	return execute(w, format, name, templatePath, data)
+}
+
+// execute executes the template at templatePath when it is set, otherwise the embedded template name,
+// escaping it as HTML for the HTML format.
+func execute(w io.Writer, format, name, templatePath string, data interface{}) error {
+	var text []byte
+	var err error
+	if templatePath != "" {
+		name = templatePath
+		text, err = os.ReadFile(templatePath)
+	} else {
+		text, err = templates.ReadFile(name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read report template: %v", err)
+	}
+
+	if format == FormatHTML {
+		tmpl, err := htmltemplate.New(filepath.Base(name)).Funcs(funcs).Parse(string(text))
+		if err != nil {
+			return fmt.Errorf("failed to parse report template: %v", err)
+		}
+		return tmpl.Execute(w, data)
+	}
+
+	tmpl, err := texttemplate.New(filepath.Base(name)).Funcs(funcs).Parse(string(text))
+	if err != nil {
+		return fmt.Errorf("failed to parse report template: %v", err)
+	}
+	return tmpl.Execute(w, data)
+}
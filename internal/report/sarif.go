@@ -0,0 +1,118 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"web-tester/internal/audit"
+	"web-tester/internal/version"
+)
+
+// sarifVersion and sarifSchema identify the version of SARIF written.
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifLevels maps the severities of findings to SARIF result levels.
+var sarifLevels = map[string]string{
+	audit.SeverityHigh:   "error",
+	audit.SeverityMedium: "warning",
+	audit.SeverityLow:    "note",
+	audit.SeverityInfo:   "note",
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID         string                 `json:"id"`
+	Help       *sarifMessage          `json:"help,omitempty"`
+	HelpURI    string                 `json:"helpUri,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+// writeSARIF writes the findings of the report as a SARIF log, with a rule per check carrying its remediation.
+func writeSARIF(w io.Writer, data Data) error {
+	rules := map[string]sarifRule{}
+	results := []sarifResult{}
+	for _, f := range data.Findings {
+		if _, ok := rules[f.Check]; !ok {
+			rule := sarifRule{ID: f.Check}
+			if r := f.Remediation; r != nil {
+				rule.Help = &sarifMessage{Text: r.Summary}
+				if len(r.References) > 0 {
+					rule.HelpURI = r.References[0]
+				}
+				var tags []string
+				for _, tag := range []string{r.CWE, r.OWASP} {
+					if tag != "" {
+						tags = append(tags, tag)
+					}
+				}
+				if len(tags) > 0 {
+					rule.Properties = map[string]interface{}{"tags": tags}
+				}
+			}
+			rules[f.Check] = rule
+		}
+
+		results = append(results, sarifResult{
+			RuleID:    f.Check,
+			Level:     sarifLevels[f.Severity],
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifact{URI: f.URL}}}},
+		})
+	}
+
+	driver := sarifDriver{Name: "web-tester", Version: version.Version}
+	for _, rule := range rules {
+		driver.Rules = append(driver.Rules, rule)
+	}
+	sort.Slice(driver.Rules, func(i, j int) bool { return driver.Rules[i].ID < driver.Rules[j].ID })
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}}})
+}
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"web-tester/internal/audit"
+	"web-tester/internal/database"
+)
+
+// Suite aggregates the runs of a suite across its shards, e.g. to gate a CI pipeline once every shard
+// job finished.
+type Suite struct {
+	SuiteID string `json:"suite_id"`
+	// ShardTotal is the number of shards the URL list was split into, and MissingShards the shards that
+	// did not record any run, e.g. because their job failed to start
+	ShardTotal    int            `json:"shard_total"`
+	MissingShards []int          `json:"missing_shards,omitempty"`
+	Tests         int            `json:"tests"`
+	Statuses      map[string]int `json:"statuses"`
+	Findings      map[string]int `json:"findings"`
+	Runs          []SuiteRun     `json:"runs"`
+}
+
+// SuiteRun is a run of a suite.
+type SuiteRun struct {
+	TestID   string `json:"test_id"`
+	Target   string `json:"target_url"`
+	Shard    int    `json:"shard"`
+	Status   string `json:"status"`
+	Findings int    `json:"findings"`
+}
+
+// NewSuite aggregates the runs of a suite and their findings, keyed by test ID.
+func NewSuite(suiteID string, runs []database.TestRun, findings map[string][]audit.Finding) Suite {
+	suite := Suite{SuiteID: suiteID, Statuses: map[string]int{}, Findings: map[string]int{}, Runs: []SuiteRun{}}
+	seen := map[int]bool{}
+	for _, run := range runs {
+		testID := run.TestID.String()
+		suite.Tests++
+		suite.Statuses[run.Status]++
+		suite.ShardTotal = max(suite.ShardTotal, run.ShardTotal)
+		seen[run.ShardIndex] = true
+		for _, f := range findings[testID] {
+			suite.Findings[f.Severity]++
+		}
+		suite.Runs = append(suite.Runs, SuiteRun{TestID: testID, Target: run.TargetURL, Shard: run.ShardIndex,
+			Status: run.Status, Findings: len(findings[testID])})
+	}
+	for i := 0; i < suite.ShardTotal; i++ {
+		if !seen[i] {
+			suite.MissingShards = append(suite.MissingShards, i)
+		}
+	}
+	return suite
+}
+
+// Passed reports whether every shard recorded its runs and every run completed.
+func (s Suite) Passed() bool {
+	return s.Tests > 0 && len(s.MissingShards) == 0 && s.Statuses[database.StatusCompleted] == s.Tests
+}
@@ -0,0 +1,179 @@
+package report
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"web-tester/internal/database"
+)
+
+// waterfallRows is the number of requests drawn at most in the waterfall of a page.
+const waterfallRows = 150
+
+// WaterfallPage is the waterfall of the requests of a page of the run: the page loaded first, then each
+// page the main frame navigated to, e.g. during the journey.
+type WaterfallPage struct {
+	URL string
+	// Duration is the time from the request of the page to the end of its last request, in milliseconds
+	Duration float64
+	Rows     []WaterfallRow
+	// Hidden counts the requests left out of Rows beyond waterfallRows
+	Hidden int
+}
+
+// WaterfallRow is a request of a page. Start is in milliseconds since the request of the page, Wait is the
+// time until the response headers were received and Download the time to receive the body, both in
+// milliseconds. Offset, WaitWidth and DownloadWidth place the bars of the request in percent of the duration
+// of the page.
+type WaterfallRow struct {
+	URL          string
+	ResourceType string
+	Status       int64
+	Failed       bool
+	Bytes        float64
+	Start        float64
+	Wait         float64
+	Download     float64
+
+	Offset        float64
+	WaitWidth     float64
+	DownloadWidth float64
+}
+
+// NewWaterfall lays out the stored requests of a run on the timeline of the page they were sent from, with the
+// timing of their responses and the duration of their transactions. It returns nil when the events have no
+// timestamps.
+func NewWaterfall(events []database.StoredEvent, transactions []database.StoredTransaction) []WaterfallPage {
+	type entry struct {
+		url, resourceType, frameID string
+		start, headersEnd, end     float64
+		status                     int64
+		bytes                      float64
+	}
+	entries := map[string]*entry{}
+	for _, e := range events {
+		var payload struct {
+			RequestID string  `json:"requestId"`
+			Timestamp float64 `json:"timestamp"`
+			Type      string  `json:"type"`
+			FrameID   string  `json:"frameId"`
+			Response  *struct {
+				Timing *struct {
+					RequestTime       float64 `json:"requestTime"`
+					ReceiveHeadersEnd float64 `json:"receiveHeadersEnd"`
+				} `json:"timing"`
+			} `json:"response"`
+		}
+		if err := json.Unmarshal(e.Payload, &payload); err != nil || payload.RequestID == "" {
+			continue
+		}
+		switch e.Type {
+		case "request":
+			// a redirected request is sent again with the same ID, and starts with its first request
+			if payload.Timestamp == 0 {
+				continue
+			}
+			existing := entries[payload.RequestID]
+			if existing == nil {
+				existing = &entry{}
+				entries[payload.RequestID] = existing
+			}
+			if start := payload.Timestamp * 1000; existing.start == 0 || start < existing.start {
+				existing.url, existing.resourceType, existing.frameID, existing.start = e.URL, payload.Type, payload.FrameID, start
+			}
+		case "response":
+			if payload.Response == nil || payload.Response.Timing == nil {
+				continue
+			}
+			existing := entries[payload.RequestID]
+			if existing == nil {
+				existing = &entry{url: e.URL, resourceType: payload.Type, frameID: payload.FrameID}
+				entries[payload.RequestID] = existing
+			}
+			existing.status, existing.bytes = e.Status, e.EncodedBytes
+			timing := payload.Response.Timing
+			existing.headersEnd = timing.RequestTime*1000 + timing.ReceiveHeadersEnd
+			if existing.start == 0 {
+				existing.start = timing.RequestTime * 1000
+			}
+		}
+	}
+
+	byID := map[string]database.StoredTransaction{}
+	for _, t := range transactions {
+		byID[t.RequestID] = t
+	}
+
+	var ids []string
+	for id, e := range entries {
+		if e.start == 0 {
+			continue
+		}
+		e.end = math.Max(e.start, e.headersEnd)
+		if t, ok := byID[id]; ok && t.TotalMS > 0 {
+			e.end = math.Max(e.end, e.start+t.TotalMS)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if entries[ids[i]].start != entries[ids[j]].start {
+			return entries[ids[i]].start < entries[ids[j]].start
+		}
+		return ids[i] < ids[j]
+	})
+
+	// a page starts with each document of the main frame, the frame of the first document
+	mainFrame := ""
+	for _, id := range ids {
+		if entries[id].resourceType == "Document" {
+			mainFrame = entries[id].frameID
+			break
+		}
+	}
+	var pages []WaterfallPage
+	var starts []float64
+	for _, id := range ids {
+		e := entries[id]
+		if (e.resourceType == "Document" && e.frameID == mainFrame) || len(pages) == 0 {
+			pages = append(pages, WaterfallPage{URL: e.url})
+			starts = append(starts, e.start)
+		}
+		page := &pages[len(pages)-1]
+		row := WaterfallRow{URL: e.url, ResourceType: e.resourceType, Status: e.status, Bytes: e.bytes, Start: e.start - starts[len(pages)-1]}
+		if t, ok := byID[id]; ok {
+			row.Status, row.Failed, row.Bytes = t.Status, t.Failed, t.EncodedBytes
+		}
+		// the start of a request and its response timing are read from two clocks, which may disagree slightly
+		if e.headersEnd > 0 {
+			row.Wait = math.Max(e.headersEnd-e.start, 0)
+		}
+		row.Download = math.Max(e.end-e.start-row.Wait, 0)
+		page.Duration = math.Max(page.Duration, row.Start+row.Wait+row.Download)
+		if len(page.Rows) < waterfallRows {
+			page.Rows = append(page.Rows, row)
+		} else {
+			page.Hidden++
+		}
+	}
+
+	for i := range pages {
+		page := &pages[i]
+		duration := math.Max(page.Duration, 1)
+		for j := range page.Rows {
+			row := &page.Rows[j]
+			row.Offset = round(100 * row.Start / duration)
+			row.WaitWidth = round(100 * row.Wait / duration)
+			row.DownloadWidth = round(100 * row.Download / duration)
+		}
+		page.Duration = math.Round(page.Duration)
+	}
+	return pages
+}
+
+// round rounds a percentage to two decimals.
+func round(percent float64) float64 {
+	return math.Round(percent*100) / 100
+}
@@ -0,0 +1,340 @@
+// Package rpc exposes a JSON-RPC 2.0 control protocol over stdio, so test harnesses written in other languages
+// can drive web-tester without the REST server: submit tests with their scenario, wait for them to complete
+// and fetch their results. Requests and responses are JSON objects, one per line.
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+	"web-tester/internal/assertion"
+	"web-tester/internal/audit"
+	"web-tester/internal/browser"
+	"web-tester/internal/database"
+	"web-tester/internal/inventory"
+	"web-tester/internal/runner"
+	"web-tester/internal/server"
+
+	"github.com/google/uuid"
+)
+
+// maxMessage bounds the size of a request, which may hold a long scenario.
+const maxMessage = 10 << 20
+
+// null is the ID of the responses to the requests whose ID could not be read.
+var null = json.RawMessage("null")
+
+// Error codes of the protocol: the JSON-RPC 2.0 ones, then the codes of the errors of web-tester.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeUnknownTest    = -32000
+	CodeTimeout        = -32001
+	CodeNoDatabase     = -32002
+)
+
+// Error is the error of a failed request.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// request is a JSON-RPC request, or a notification without ID.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// message is a response to a request, or a notification sent by the server when Method is set.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// testParams are the params of the methods on a submitted test.
+type testParams struct {
+	TestID         uuid.UUID `json:"test_id"`
+	TimeoutSeconds float64   `json:"timeout_seconds,omitempty"`
+}
+
+// TestStatus is the status of a submitted test, with its outcome once it finished.
+type TestStatus struct {
+	TestID           uuid.UUID          `json:"test_id"`
+	Status           string             `json:"status"`
+	Error            string             `json:"error,omitempty"`
+	FailedAssertions []assertion.Result `json:"failed_assertions,omitempty"`
+	StorageErrors    int                `json:"storage_errors,omitempty"`
+}
+
+// TestResults are the stored results of a test.
+type TestResults struct {
+	TestID       uuid.UUID                    `json:"test_id"`
+	Run          database.TestRun             `json:"run"`
+	Events       []database.StoredEvent       `json:"events"`
+	Transactions []database.StoredTransaction `json:"transactions"`
+	APIEndpoints []inventory.APIEndpoint      `json:"api_endpoints"`
+	Findings     []audit.Finding              `json:"findings"`
+}
+
+// test is a submitted test, done being closed once it finished.
+type test struct {
+	status TestStatus
+	done   chan struct{}
+}
+
+// Server runs the tests submitted over the protocol, up to workers at a time.
+type Server struct {
+	logger  *slog.Logger
+	db      *sql.DB
+	runner  *runner.Runner
+	workers chan struct{}
+
+	mu    sync.Mutex
+	tests map[uuid.UUID]*test
+	// out writes the messages, one at a time
+	outMu sync.Mutex
+	out   *json.Encoder
+	// running tracks the tests in progress, waited for before Serve returns
+	running sync.WaitGroup
+}
+
+// New creates a Server running up to workers tests at a time with r, reading their results back from db.
+func New(logger *slog.Logger, db *sql.DB, r *runner.Runner, workers int) *Server {
+	return &Server{logger: logger, db: db, runner: r, workers: make(chan struct{}, max(workers, 1)), tests: map[uuid.UUID]*test{}}
+}
+
+// Serve reads the requests from in and writes the responses and notifications to out until in is closed,
+// then waits for the submitted tests to finish. Requests are handled concurrently, so a test.wait does not
+// block the requests sent after it.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.out = json.NewEncoder(out)
+	var handlers sync.WaitGroup
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxMessage)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.write(message{ID: null, Error: &Error{Code: CodeParseError, Message: "invalid json: " + err.Error()}})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			id := req.ID
+			if id == nil {
+				id = null
+			}
+			s.write(message{ID: id, Error: &Error{Code: CodeInvalidRequest, Message: `expected a "2.0" jsonrpc request with a method`}})
+			continue
+		}
+
+		handlers.Add(1)
+		go func() {
+			defer handlers.Done()
+			result, err := s.handle(ctx, req)
+			// notifications are not answered
+			if req.ID == nil {
+				return
+			}
+			if err != nil {
+				var rpcErr *Error
+				if !errors.As(err, &rpcErr) {
+					rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+				}
+				s.write(message{ID: req.ID, Error: rpcErr})
+				return
+			}
+			s.write(message{ID: req.ID, Result: result})
+		}()
+	}
+
+	handlers.Wait()
+	s.running.Wait()
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read requests: %v", err)
+	}
+	return nil
+}
+
+// handle runs the method of a request.
+func (s *Server) handle(ctx context.Context, req request) (interface{}, error) {
+	switch req.Method {
+	case "test.submit":
+		var params server.TestRequest
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		opts, err := params.Options()
+		if err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		return s.submit(opts)
+
+	case "test.status", "test.wait", "test.results":
+		var params testParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		t, ok := s.test(params.TestID)
+		if !ok {
+			return nil, &Error{Code: CodeUnknownTest, Message: fmt.Sprintf("unknown test %s", params.TestID)}
+		}
+		switch req.Method {
+		case "test.status":
+			return s.status(t), nil
+		case "test.wait":
+			return s.wait(ctx, t, params.TimeoutSeconds)
+		}
+		return s.results(params.TestID)
+	}
+	return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+}
+
+// unmarshalParams decodes the params of a request.
+func unmarshalParams(raw json.RawMessage, params interface{}) error {
+	if len(raw) == 0 {
+		return &Error{Code: CodeInvalidParams, Message: "params are required"}
+	}
+	if err := json.Unmarshal(raw, params); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+// submit starts a test as soon as a worker is free, returning its status. The test.finished notification
+// is sent once it finished.
+func (s *Server) submit(opts runner.Options) (TestStatus, error) {
+	testID, err := uuid.NewV7()
+	if err != nil {
+		return TestStatus{}, fmt.Errorf("failed to create test id: %v", err)
+	}
+	t := &test{status: TestStatus{TestID: testID, Status: server.StatusQueued}, done: make(chan struct{})}
+	s.mu.Lock()
+	s.tests[testID] = t
+	s.mu.Unlock()
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.workers <- struct{}{}
+		defer func() { <-s.workers }()
+		s.run(t, opts)
+	}()
+	s.logger.Info("submitted test", "test_id", testID, "target", opts.Target)
+	return s.status(t), nil
+}
+
+// run runs a submitted test, then notifies its outcome.
+func (s *Server) run(t *test, opts runner.Options) {
+	s.mu.Lock()
+	t.status.Status = database.StatusRunning
+	testID := t.status.TestID
+	s.mu.Unlock()
+
+	client := browser.NewWithTestID(opts.Target, testID)
+	defer client.Cancel()
+	result, err := s.runner.Run(client, opts)
+
+	s.mu.Lock()
+	t.status.Status, t.status.FailedAssertions, t.status.StorageErrors = result.Status, result.FailedAssertions, result.StorageErrors
+	if err != nil {
+		t.status.Error = err.Error()
+		if t.status.Status == "" {
+			t.status.Status = database.StatusFailed
+		}
+	}
+	s.mu.Unlock()
+	close(t.done)
+
+	s.write(message{Method: "test.finished", Params: s.status(t)})
+}
+
+// test returns the submitted test with the given ID.
+func (s *Server) test(testID uuid.UUID) (*test, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tests[testID]
+	return t, ok
+}
+
+// status returns the current status of a test.
+func (s *Server) status(t *test) TestStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return t.status
+}
+
+// wait waits for a test to finish, for up to timeout seconds when above 0, returning its status.
+func (s *Server) wait(ctx context.Context, t *test, timeout float64) (TestStatus, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout*float64(time.Second)))
+		defer cancel()
+	}
+	select {
+	case <-t.done:
+		return s.status(t), nil
+	case <-ctx.Done():
+		return TestStatus{}, &Error{Code: CodeTimeout, Message: fmt.Sprintf("test %s did not finish in time", t.status.TestID)}
+	}
+}
+
+// results returns the stored results of a test.
+func (s *Server) results(testID uuid.UUID) (TestResults, error) {
+	if s.db == nil {
+		return TestResults{}, &Error{Code: CodeNoDatabase, Message: "results are read from the database, which is not available"}
+	}
+	results := TestResults{TestID: testID}
+	var err error
+	if results.Run, err = database.GetTestRun(s.db, testID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return TestResults{}, &Error{Code: CodeUnknownTest, Message: fmt.Sprintf("test %s has no stored results yet", testID)}
+		}
+		return TestResults{}, fmt.Errorf("failed to get test run: %v", err)
+	}
+	if results.Events, err = database.GetEvents(s.db, testID); err != nil {
+		return TestResults{}, fmt.Errorf("failed to get events: %v", err)
+	}
+	if results.Transactions, err = database.GetTransactions(s.db, testID); err != nil {
+		return TestResults{}, fmt.Errorf("failed to get transactions: %v", err)
+	}
+	if results.APIEndpoints, err = database.GetAPIEndpoints(s.db, testID); err != nil {
+		return TestResults{}, fmt.Errorf("failed to get api endpoints: %v", err)
+	}
+	if results.Findings, err = database.GetFindings(s.db, testID); err != nil {
+		return TestResults{}, fmt.Errorf("failed to get findings: %v", err)
+	}
+	return results, nil
+}
+
+// write writes a message to the output.
+func (s *Server) write(m message) {
+	m.JSONRPC = "2.0"
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	if err := s.out.Encode(m); err != nil {
+		s.logger.Error("failed to write rpc message", "error", err)
+	}
+}
@@ -0,0 +1,1249 @@
+// Package runner runs a single test against a target: it drives the browser, stores the captured
+// traffic, runs the audit checks and evaluates the assertions.
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"web-tester/internal/artifacts"
+	"web-tester/internal/assertion"
+	"web-tester/internal/audit"
+	"web-tester/internal/bodystore"
+	"web-tester/internal/browser"
+	"web-tester/internal/config"
+	"web-tester/internal/database"
+	"web-tester/internal/diff"
+	"web-tester/internal/egress"
+	"web-tester/internal/explore"
+	"web-tester/internal/graphql"
+	"web-tester/internal/inventory"
+	"web-tester/internal/issues"
+	"web-tester/internal/journey"
+	"web-tester/internal/notify"
+	"web-tester/internal/redact"
+	"web-tester/internal/sampling"
+	"web-tester/internal/scope"
+	"web-tester/internal/sink"
+	"web-tester/internal/telemetry"
+	"web-tester/internal/version"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/google/uuid"
+)
+
+// DefaultWaitTime is how long the browser waits on the target after navigating when no wait time is set.
+const DefaultWaitTime = 5 * time.Second
+
+// DefaultVideoFPS is the frame rate of the videos of the sessions when none is set.
+const DefaultVideoFPS = 10
+
+// Options holds the per-test options. ScheduleID tags tests started by a schedule. A non-empty Filter
+// replaces the runner's default capture filter. CompareCache loads the target a second time once the test
+// is done, comparing the warm load with the cold one. A non-empty TraceDir records a Chrome trace of the page
+// load in the file trace-<test-id>.json of the directory. Coverage measures the unused bytes of every script
+// and stylesheet. A Device with a name is emulated while loading the target, and a CPUThrottle above 1 slows
+// down the CPU of the page by that factor. A non-nil State is the storage state the browser starts from, and
+// a non-empty SaveState is the path the storage state is saved to once the page and journey ran. UserAgent
+// and Locale override the user agent and preferred language of the browser when set, and Geolocation and
+// Timezone its position and timezone. SuiteID, ShardIndex and ShardTotal tag the tests of a sharded URL list.
+// A non-empty VideoDir records a video of the session at VideoFPS frames per second in the file
+// video-<test-id>.avi of the directory. A non-empty PDFDir prints the page to pdf-<test-id>.pdf in the directory
+// once loaded, and to pdf-<test-id>-journey.pdf once the journey ran. Scenario, when set, is run instead of the scenario of the runner.
+// Links collects the links of the page once loaded into the Links of the result, for the crawl to follow and check.
+// Scroll scrolls the page to its end once loaded, with the scroll strategy of the runner, until its lazy-loaded
+// content and infinite lists stop growing. Explore scrolls the page and clicks its buttons and menu toggles once the journey ran, within the exploration
+// budget of the runner, to capture the traffic they trigger.
+// A non-empty TraceParent is the W3C traceparent of the caller, whose trace the spans of the test join.
+type Options struct {
+	Target       string
+	WaitTime     time.Duration
+	ScheduleID   string
+	Filter       browser.Filter
+	CompareCache bool
+	TraceDir     string
+	Coverage     bool
+	Device       browser.Device
+	CPUThrottle  float64
+	State        *browser.State
+	SaveState    string
+	UserAgent    string
+	Locale       string
+	Geolocation  *browser.Geolocation
+	Timezone     string
+	SuiteID      string
+	ShardIndex   int
+	ShardTotal   int
+	VideoDir     string
+	VideoFPS     int
+	PDFDir       string
+	Scenario     *config.Scenario
+	Links        bool
+	Scroll       bool
+	Explore      bool
+	TraceParent  string
+}
+
+// Result is the outcome of a test. StorageErrors counts the results that could not be stored. PageStatus is
+// the status of the document of the page, 0 when none was received, and Links the links of the page when
+// collected. Assertions are the results of every assertion and Findings counts the findings per severity.
+type Result struct {
+	TestID           uuid.UUID
+	Status           string
+	Assertions       []assertion.Result
+	FailedAssertions []assertion.Result
+	Findings         map[string]int
+	StorageErrors    int
+	PageStatus       int
+	Links            []string
+}
+
+// Runner runs tests, storing their results in the database. A nil db disables storage.
+type Runner struct {
+	logger     *slog.Logger
+	db         *sql.DB
+	assertions config.Assertions
+	auditCfg   config.AuditConfig
+	sink       sink.Sink
+	redaction  redact.Rules
+	egressCfg  config.EgressConfig
+	limits     browser.Limits
+	sampling   config.SamplingConfig
+	bodyLimit  int
+	bodies     *bodystore.Store
+	filter     browser.Filter
+	scope      scope.Rules
+	writer     config.WriterConfig
+	scenario   config.Scenario
+	trackers   []issues.Tracker
+	// minSeverity is the lowest severity of the findings issues are opened for
+	minSeverity string
+	notifier    *notify.Notifier
+	// basic and login authenticate the browser of every test when set
+	basic *browser.Credentials
+	login *browser.Login
+	// mocks serve the requests matching them from fixtures in every test
+	mocks []browser.Mock
+	// faults are injected into the requests matching them in every test
+	faults []browser.Fault
+	// artifacts receives the screenshots, PDFs, videos and traces of every test when set
+	artifacts artifacts.Store
+	// tracer records the spans of the steps of every test when set
+	tracer *telemetry.Tracer
+	// timeouts bound the steps of every test
+	timeouts browser.Timeouts
+	// scroll is the strategy of the scrolls to the end of the pages of the tests run with Scroll
+	scroll explore.Scroll
+	// consent dismisses the consent banner of the page of every test when set
+	consent *browser.Consent
+	// exploration bounds the explorations of the tests run with Explore
+	exploration explore.Budget
+	// uploadDir holds the files the upload steps of the journeys may set, uploads being disabled when empty
+	uploadDir string
+	// downloadDir receives the files downloaded by the page of every test, in a directory per test, when set
+	downloadDir string
+}
+
+// New creates a Runner storing results in db, checking runs against the assertions and configuring
+// the audit checks with auditCfg.
+func New(logger *slog.Logger, db *sql.DB, assertions config.Assertions, auditCfg config.AuditConfig) *Runner {
+	return &Runner{logger: logger, db: db, assertions: assertions, auditCfg: auditCfg}
+}
+
+// StreamTo makes every test stream its captured events to the sink.
+func (r *Runner) StreamTo(s sink.Sink) {
+	r.sink = s
+}
+
+// Redact masks the credentials and personal data of the captured traffic with the rules before it is stored or
+// streamed. The checks run on the traffic as captured.
+func (r *Runner) Redact(rules redact.Rules) {
+	r.redaction = rules
+}
+
+// Restrict applies the air-gapped mode of the egress configuration: the checks relying on external
+// services are skipped, and the checks' own calls are limited to the target and the allowed hosts.
+func (r *Runner) Restrict(egressCfg config.EgressConfig) {
+	r.egressCfg = egressCfg
+}
+
+// LimitBrowser sets the resource limits of the browser of every test, killing it and failing the test when exceeded.
+func (r *Runner) LimitBrowser(limits browser.Limits) {
+	r.limits = limits
+}
+
+// Timeout sets the timeouts of every test, the deadline of the run replacing the browser's own when set.
+func (r *Runner) Timeout(timeouts browser.Timeouts) {
+	r.timeouts = timeouts
+}
+
+// Sample bounds the events stored by every test. The checks and assertions still see every event.
+func (r *Runner) Sample(samplingCfg config.SamplingConfig) {
+	r.sampling = samplingCfg
+}
+
+// LimitBodies keeps the response bodies larger than limit bytes out of memory and of the database,
+// writing them to the store instead, or dropping them when store is nil.
+func (r *Runner) LimitBodies(limit int, store *bodystore.Store) {
+	r.bodyLimit, r.bodies = limit, store
+}
+
+// Archive uploads the screenshots, PDFs, videos and traces of every test to the artifact store, keeping only
+// their references in the database.
+func (r *Runner) Archive(store artifacts.Store) {
+	r.artifacts = store
+}
+
+// CaptureDownloads keeps the files downloaded by the page of every test, e.g. from the clicks of a journey, in
+// a directory per test under dir, hashing them and storing them as artifacts.
+func (r *Runner) CaptureDownloads(dir string) {
+	r.downloadDir = dir
+}
+
+// Instrument records the steps of every test as OpenTelemetry spans with tracer.
+func (r *Runner) Instrument(tracer *telemetry.Tracer) {
+	r.tracer = tracer
+}
+
+// Filter sets the default filter selecting the events captured by the tests.
+func (r *Runner) Filter(filter browser.Filter) {
+	r.filter = filter
+}
+
+// Scope restricts the requests persisted by the tests, along with their responses, to the URLs in scope.
+func (r *Runner) Scope(rules scope.Rules) {
+	r.scope = rules
+}
+
+// BatchWrites sets the pipeline storing the events of every test. Without it, events are stored one by one.
+func (r *Runner) BatchWrites(writerCfg config.WriterConfig) {
+	r.writer = writerCfg
+}
+
+// OpenIssues makes every test open an issue in the trackers for each finding of at least minSeverity that
+// has no open issue yet.
+func (r *Runner) OpenIssues(trackers []issues.Tracker, minSeverity string) {
+	r.trackers, r.minSeverity = trackers, minSeverity
+}
+
+// Notify makes every test post its summary to the webhooks of the notifier once it finished or failed.
+func (r *Runner) Notify(notifier *notify.Notifier) {
+	r.notifier = notifier
+}
+
+// Authenticate makes every test answer the authentication challenges of its target with the basic
+// credentials and log in with the form login before the capture begins, when they are set.
+func (r *Runner) Authenticate(basic *browser.Credentials, login *browser.Login) {
+	r.basic, r.login = basic, login
+}
+
+// Mock makes every test serve the requests matching the mocks from their fixtures instead of the network.
+func (r *Runner) Mock(mocks []browser.Mock) {
+	r.mocks = mocks
+}
+
+// InjectFaults turns every test into a resilience test, injecting the faults into the requests matching
+// them. The injected faults are stored, and the page's degradation shows in its findings and assertions.
+func (r *Runner) InjectFaults(faults []browser.Fault) {
+	r.faults = faults
+}
+
+// Journey sets the scenario run on the page of every test once it has loaded, timing it between its milestones.
+func (r *Runner) Journey(scenario config.Scenario) {
+	r.scenario = scenario
+}
+
+// HandleConsent makes every test dismiss the consent banner of its page with the choice of the consent handling.
+func (r *Runner) HandleConsent(c *browser.Consent) {
+	r.consent = c
+}
+
+// InfiniteScroll sets the strategy of the scrolls to the end of the pages of the tests run with Scroll.
+func (r *Runner) InfiniteScroll(strategy explore.Scroll) {
+	r.scroll = strategy
+}
+
+// Explore sets the budget of the explorations of the tests run with Explore.
+func (r *Runner) Explore(budget explore.Budget) {
+	r.exploration = budget
+}
+
+// AllowUploads lets the upload steps of the scenarios set the files of dir in the file inputs of the page.
+func (r *Runner) AllowUploads(dir string) {
+	r.uploadDir = dir
+}
+
+// Run runs a test with a browser client created for opts.Target. The client's test ID identifies the
+// test, so callers that need it before the test finishes create the client themselves with browser.New.
+func (r *Runner) Run(client *browser.Browser, opts Options) (Result, error) {
+	ctx, span := r.tracer.Start(telemetry.Extract(context.Background(), opts.TraceParent), "test.run",
+		telemetry.String("test_id", client.TestID().String()), telemetry.String("target", opts.Target))
+	result, err := r.run(ctx, client, opts)
+	// the events are streamed as they are received, wait for those still queued for the sink
+	if dropped := client.DrainStream(); dropped > 0 {
+		r.logger.Warn("events not streamed", "dropped", dropped)
+	}
+	span.SetAttributes(telemetry.String("status", result.Status), telemetry.Int("storage_errors", result.StorageErrors))
+	span.RecordError(err)
+	span.End()
+	return result, err
+}
+
+// run runs a test, recording its steps as children of the span of ctx.
+func (r *Runner) run(ctx context.Context, client *browser.Browser, opts Options) (Result, error) {
+	logger, db := r.logger, r.db
+	target := opts.Target
+	if opts.WaitTime <= 0 {
+		opts.WaitTime = DefaultWaitTime
+	}
+	if r.sink != nil {
+		client.StreamTo(r.sink)
+	}
+	client.Redact(r.redaction)
+	if r.tracer != nil {
+		client.Instrument(ctx, r.tracer)
+	}
+	client.SetLimits(r.limits)
+	client.SetTimeouts(r.timeouts)
+	client.LimitBodies(r.bodyLimit, r.bodies)
+	if opts.Filter.Empty() {
+		opts.Filter = r.filter
+	}
+	client.SetFilter(opts.Filter)
+	var tracePath string
+	if opts.TraceDir != "" {
+		tracePath = filepath.Join(opts.TraceDir, "trace-"+client.TestID().String()+".json")
+		logger.Info("tracing the page load", "path", tracePath)
+		client.Trace(tracePath)
+	}
+	var videoPath string
+	if opts.VideoDir != "" {
+		if opts.VideoFPS <= 0 {
+			opts.VideoFPS = DefaultVideoFPS
+		}
+		videoPath = filepath.Join(opts.VideoDir, "video-"+client.TestID().String()+".avi")
+		logger.Info("recording the session", "path", videoPath)
+		client.Record(videoPath, opts.VideoFPS)
+	}
+	if opts.Coverage {
+		client.MeasureCoverage()
+	}
+	if r.downloadDir != "" {
+		client.CaptureDownloads(filepath.Join(r.downloadDir, client.TestID().String()))
+	}
+	if opts.Device.Name != "" {
+		logger.Info("emulating device", "device", opts.Device.Name)
+		client.Emulate(opts.Device)
+	}
+	if opts.State != nil {
+		logger.Info("starting from storage state", "cookies", len(opts.State.Cookies), "origins", len(opts.State.Origins))
+		client.StartFrom(*opts.State)
+	}
+	if opts.UserAgent != "" || opts.Locale != "" {
+		logger.Info("overriding user agent and locale", "user_agent", opts.UserAgent, "locale", opts.Locale)
+		client.Identify(opts.UserAgent, opts.Locale)
+	}
+	if opts.Geolocation != nil || opts.Timezone != "" {
+		logger.Info("overriding location", "geolocation", opts.Geolocation, "timezone", opts.Timezone)
+		client.Locate(opts.Geolocation, opts.Timezone)
+	}
+	if r.consent != nil {
+		client.HandleConsent(r.consent)
+	}
+	if r.basic != nil || r.login != nil {
+		client.Authenticate(r.basic, r.login)
+	}
+	if len(r.mocks) > 0 {
+		client.Mock(r.mocks)
+	}
+	if len(r.faults) > 0 {
+		client.InjectFaults(r.faults)
+	}
+	if opts.CPUThrottle > 1 {
+		logger.Info("throttling cpu", "rate", opts.CPUThrottle)
+		client.ThrottleCPU(opts.CPUThrottle)
+	}
+
+	result := Result{TestID: client.TestID()}
+	run := database.TestRun{TestID: client.TestID(), TargetURL: target, ScheduleID: opts.ScheduleID, StartedAt: time.Now(), ToolVersion: version.Version,
+		Device: opts.Device.Name, CPUThrottle: opts.CPUThrottle, SuiteID: opts.SuiteID, ShardIndex: opts.ShardIndex, ShardTotal: opts.ShardTotal,
+		Trace: tracePath}
+	if err := database.StartTestRun(logger, db, run); err != nil {
+		logger.Error("failed to start test run", "error", err)
+		result.StorageErrors++
+	}
+
+	var events = browser.NewEventStore()
+	var console = browser.ConsoleMessages{}
+
+	client.ListenToEvents(logger, events)
+	client.ListenToConsole(logger, &console)
+
+	_, span := r.tracer.Start(ctx, "browser.navigate", telemetry.String("url", target))
+	err := client.Run(logger, opts.WaitTime)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		logger.Error("failed to run browser", "error", err)
+		result.Status = database.StatusFailed
+		run.Video = r.saveVideo(client, videoPath)
+		err = fmt.Errorf("failed to run browser: %w", err)
+		r.finishTestRun(run, &result, err)
+		return result, err
+	}
+
+	if r.consent != nil {
+		if button := client.ConsentDismissed(); button != "" {
+			logger.Info("consent banner dismissed", "policy", r.consent.Policy, "button", button)
+		} else {
+			logger.Info("no consent banner found", "policy", r.consent.Policy)
+		}
+	}
+
+	// the vitals are read before the journey navigates away from the page
+	vitals, err := client.WebVitals()
+	if err != nil {
+		logger.Error("failed to collect web vitals", "error", err)
+	} else if err = database.InsertVitals(logger, db, client.TestID(), vitals); err != nil {
+		logger.Error("failed to insert web vitals into database", "error", err)
+		result.StorageErrors++
+	}
+
+	if opts.Coverage {
+		r.collectCoverage(client, &result)
+	}
+
+	r.savePDF(client, opts.PDFDir, "", &run)
+	r.saveScreenshot(client, "loaded", &result)
+
+	// the page is scrolled before its links are collected, so the links of the content loaded on demand are too
+	if opts.Scroll {
+		logger.Info("scrolling to the end of the page", "max_iterations", r.scroll.MaxIterations)
+		scrolled, err := explore.ScrollToEnd(client.GetCtx(), logger, r.scroll, client.RequestCount)
+		if err != nil {
+			logger.Error("failed to scroll the page", "error", err)
+		}
+		logger.Info("scrolled to the end of the page", "iterations", scrolled.Iterations, "height", scrolled.Height,
+			"requests", scrolled.Requests, "stabilized", scrolled.Stabilized)
+	}
+
+	if opts.Links {
+		if result.Links, err = client.Links(); err != nil {
+			logger.Error("failed to collect links", "error", err)
+		}
+	}
+
+	// a failed journey step fails the test like an assertion, keeping the milestones reached before it
+	var journeyErr error
+	scenario := r.scenario
+	if opts.Scenario != nil {
+		scenario = *opts.Scenario
+	}
+	if len(scenario.Steps) > 0 {
+		logger.Info("running the journey of the scenario", "scenario", scenario.Name)
+		var milestones []journey.Milestone
+		milestones, journeyErr = journey.Run(client.GetCtx(), logger, scenario, r.uploadDir, client.RequestCount)
+		if journeyErr != nil {
+			logger.Error("journey failed", "error", journeyErr)
+		}
+		for _, m := range milestones {
+			if err = database.InsertMilestone(logger, db, client.TestID(), m); err != nil {
+				logger.Error("failed to insert milestone into database", "error", err)
+				result.StorageErrors++
+			}
+		}
+		r.savePDF(client, opts.PDFDir, "-journey", &run)
+		r.saveScreenshot(client, "journey", &result)
+	}
+
+	// the exploration runs last since its clicks may leave the page in any state, and does not fail the test
+	if opts.Explore {
+		r.explore(client, &result)
+	}
+
+	if opts.SaveState != "" {
+		r.saveState(client, opts.SaveState)
+	}
+	run.Video = r.saveVideo(client, videoPath)
+	r.saveDownloads(client, &result)
+
+	// request bodies may need to be fetched from the browser, so do it while the context is still alive
+	requests := events.Requests()
+	for i := range requests {
+		if err = requests[i].SetBody(client.ContextOf(requests[i].RequestID)); err != nil {
+			logger.Error("failed to set request body", "request_id", requests[i].RequestID, "error", err)
+		}
+	}
+
+	run.BrowserVersion, err = client.Version()
+	if err != nil {
+		logger.Error("failed to get browser version", "error", err)
+	}
+
+	title, err := client.Title()
+	if err != nil {
+		logger.Error("failed to get page title", "error", err)
+	}
+
+	hints, err := client.ResourceHints()
+	if err != nil {
+		logger.Error("failed to collect resource hints", "error", err)
+	}
+
+	pwaStatus, err := client.PWAStatus()
+	if err != nil {
+		logger.Error("failed to collect pwa status", "error", err)
+	}
+
+	cookies, err := client.Cookies()
+	if err != nil {
+		logger.Error("failed to collect cookies", "error", err)
+	}
+
+	storageItems, err := client.WebStorage()
+	if err != nil {
+		logger.Error("failed to collect web storage", "error", err)
+	}
+	var snapshot []browser.StorageEntry
+	if r.auditCfg.StorageSnapshot {
+		if snapshot, err = client.SnapshotStorage(); err != nil {
+			logger.Error("failed to snapshot web storage", "error", err)
+		}
+	}
+	var renderedHTML string
+	if r.auditCfg.RenderedHTML {
+		if renderedHTML, err = client.RenderedHTML(); err != nil {
+			logger.Error("failed to capture rendered html", "error", err)
+		}
+	}
+	var domSnapshot []byte
+	if r.auditCfg.DOMSnapshot {
+		if domSnapshot, err = client.DOMSnapshot(); err != nil {
+			logger.Error("failed to capture dom snapshot", "error", err)
+		}
+	}
+
+	// the bodies are fetched as the responses finish loading, wait for those still queued before storing them
+	_, span = r.tracer.Start(ctx, "browser.drain_bodies")
+	finished := client.DrainFinishers()
+	span.SetAttributes(telemetry.Int("dropped", finished.Dropped), telemetry.Int("late", finished.Late))
+	span.End()
+	if finished.Dropped > 0 || finished.Late > 0 {
+		logger.Warn("response bodies not fetched", "dropped", finished.Dropped, "late", finished.Late)
+	}
+
+	// the browser may have been killed for exceeding its limits after navigating
+	if err = client.Err(); err != nil {
+		logger.Error("browser stopped", "error", err)
+		result.Status = database.StatusFailed
+		err = fmt.Errorf("browser stopped: %w", err)
+		r.finishTestRun(run, &result, err)
+		return result, err
+	}
+
+	logger.Info("browser ran successfully, starting database input")
+	workerRequests := 0
+	for _, req := range requests {
+		if req.Source != "" {
+			workerRequests++
+		}
+	}
+	if workerRequests > 0 {
+		logger.Info("requests sent by workers", "count", workerRequests)
+	}
+
+	// a response is stored along with its request, so out of scope and sampled out requests drop their response too
+	responses := events.Responses()
+	_, span = r.tracer.Start(ctx, "db.insert_events", telemetry.Int("requests", len(requests)), telemetry.Int("responses", len(responses)))
+	writer, err := database.NewSink(logger, db, client.TestID(), r.writer, r.bodies)
+	if err != nil {
+		logger.Error("failed to create event sink", "error", err)
+		result.StorageErrors++
+		writer = database.NoopSink{}
+	}
+	sampler := sampling.New(r.sampling)
+	sampled := map[network.RequestID]bool{}
+	for _, req := range requests {
+		resp, _ := events.Response(req.RequestID)
+		contentType := resp.MimeType
+		if ev, ok := req.Content.(*network.EventRequestWillBeSent); ok && contentType == "" {
+			contentType = ev.Type.String()
+		}
+		if !r.scope.InScope(req.URL) || !sampler.Keep(req.URL, contentType) {
+			continue
+		}
+		sampled[req.RequestID] = true
+
+		writer.Write(r.redactEvent(database.Event{
+			RequestID: req.RequestID, Type: req.Type, URL: req.URL, Content: req.Content, Body: req.Body, Source: req.Source,
+			FrameID: string(req.FrameID), FrameURL: frameURL(client, req.FrameID, req.FrameURL),
+		}))
+	}
+
+	for _, resp := range responses {
+		if (sampler.Enabled() || !r.scope.Empty()) && !sampled[resp.RequestID] {
+			continue
+		}
+		writer.Write(r.redactEvent(database.Event{
+			RequestID: resp.RequestID, Type: resp.Type, URL: resp.URL, Content: resp.Content, Body: resp.Body,
+			Status: resp.Status, ContentRange: resp.ContentRange, Chunked: resp.Chunked, Parts: resp.Parts, Timing: resp.Timing,
+			BodySize: resp.BodySize, BodyHash: resp.BodyHash, BodyPath: resp.BodyPath, BodyFetchStatus: resp.BodyStatus, Source: resp.Source,
+			FrameID: string(resp.FrameID), FrameURL: frameURL(client, resp.FrameID, resp.FrameURL),
+		}))
+	}
+	storageErrors := writer.Close()
+	result.StorageErrors += storageErrors
+	span.SetAttributes(telemetry.Int("storage_errors", storageErrors))
+	span.End()
+
+	// a transaction merges the events of a request ID, and is stored when its request is
+	_, span = r.tracer.Start(ctx, "db.insert_transactions")
+	transactions := browser.Correlate(requests, events.Responses(), events.Failures())
+	operations := 0
+	for _, t := range transactions {
+		if !sampled[t.RequestID] {
+			continue
+		}
+		if err = database.InsertTransaction(logger, db, client.TestID(), r.redactTransaction(t)); err != nil {
+			logger.Error("failed to insert transaction into database", "error", err)
+			result.StorageErrors++
+		}
+		operations += r.saveGraphQL(client, t, &result)
+	}
+	if operations > 0 {
+		logger.Info("graphql operations captured", "operations", operations)
+	}
+	span.SetAttributes(telemetry.Int("transactions", len(transactions)))
+	span.End()
+
+	chain := browser.CriticalChain(transactions, vitals.FCP)
+	longest := 0.0
+	for _, node := range chain {
+		longest = max(longest, node.End)
+	}
+	logger.Info("critical request chain", "requests", len(chain), "longest_ms", longest)
+	if err = database.InsertCriticalChain(logger, db, client.TestID(), chain); err != nil {
+		logger.Error("failed to insert critical request chain into database", "error", err)
+		result.StorageErrors++
+	}
+
+	if seen, dropped := sampler.Stats(); dropped > 0 {
+		logger.Info("sampled stored events", "requests", seen, "dropped", dropped)
+	}
+
+	captured := events.Responses()
+	result.PageStatus = pageStatus(client, captured)
+	if r.sampling.ReviewBodies > 0 {
+		result.StorageErrors += r.queueReviews(client.TestID(), captured)
+	}
+
+	stats := browser.Summarize(captured)
+	logger.Info("run timing stats", "responses", stats.Count, "p50_ms", stats.P50, "p95_ms", stats.P95, "total_bytes", stats.TotalBytes)
+
+	_, span = r.tracer.Start(ctx, "audit.checks")
+	var findings []audit.Finding
+
+	if doc, ok := audit.MainDocument(captured); ok {
+		logger.Info("auditing security headers of the main document")
+		headerFindings, grade := audit.AuditSecurityHeaders(doc)
+		findings = append(findings, headerFindings...)
+		logger.Info("security headers summary", "url", grade.URL, "grade", grade.Grade, "score", grade.Score, "missing", grade.Missing)
+
+		logger.Info("checking for mixed content")
+		findings = append(findings, audit.CheckMixedContentRequests(doc, requests)...)
+	} else {
+		logger.Warn("no main document response captured, skipping the security header and mixed content checks")
+	}
+
+	if r.auditCfg.SecretScan {
+		logger.Info("scanning captured traffic for secrets and personal data")
+		findings = append(findings, audit.ScanSecrets(logger, transactions)...)
+	}
+
+	logger.Info("validating cache revalidation of captured responses")
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	if r.egressCfg.AirGapped {
+		httpClient.Transport = egress.New(logger, nil, append([]string{target}, r.egressCfg.Allow...)...)
+	}
+	findings = append(findings, audit.ValidateCaching(context.Background(), logger, httpClient, captured)...)
+
+	logger.Info("auditing favicon, manifest and pwa installability")
+	findings = append(findings, audit.CheckPWAStatus(context.Background(), logger, httpClient, target, pwaStatus)...)
+
+	logger.Info("capturing tls details of the contacted origins")
+	tlsDetails := audit.CollectTLS(captured)
+	for _, d := range tlsDetails {
+		logger.Info("tls details", "origin", d.Origin, "protocol", d.Protocol, "cipher", d.Cipher, "issuer", d.Issuer, "valid_to", d.ValidTo)
+		if err = database.InsertTLSDetails(logger, db, client.TestID(), d); err != nil {
+			logger.Error("failed to insert tls details into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	findings = append(findings, audit.CheckCertificateExpiry(tlsDetails, r.auditCfg.CertExpiryDays, time.Now())...)
+
+	// the preload list, the CT logs and third-party DNS records are external services
+	if r.egressCfg.AirGapped {
+		logger.Info("air-gapped mode, skipping hsts preload, certificate transparency and dangling dns checks")
+	} else {
+		logger.Info("checking hsts preload eligibility and certificate transparency logs")
+		origins := audit.TargetOrigins(target, captured)
+		findings = append(findings, audit.CheckHSTS(context.Background(), logger, httpClient, origins, captured)...)
+		findings = append(findings, audit.CheckCertificateTransparency(context.Background(), logger, httpClient, origins, r.auditCfg.CTExpectedIssuers, r.auditCfg.CTRecentDays)...)
+
+		logger.Info("resolving third-party domains for dangling cnames")
+		findings = append(findings, audit.CheckDanglingDNS(context.Background(), logger, net.DefaultResolver, target, requests, hints)...)
+	}
+
+	if db != nil {
+		logger.Info("comparing third-party scripts with the previous runs")
+		findings = append(findings, r.watchScripts(client.TestID(), target, captured, &result)...)
+	}
+
+	// active testing sends mutated requests to the target, so it is opt-in
+	if r.auditCfg.Fuzz {
+		logger.Info("replaying captured api requests with fuzzed parameters")
+		fuzzResults, fuzzFindings := audit.Fuzz(context.Background(), logger, httpClient, target, requests, r.auditCfg.FuzzMaxRequests)
+		for _, res := range fuzzResults {
+			if err = database.InsertFuzzResult(logger, db, client.TestID(), res); err != nil {
+				logger.Error("failed to insert fuzz result into database", "error", err)
+				result.StorageErrors++
+			}
+		}
+		findings = append(findings, fuzzFindings...)
+	}
+
+	span.End()
+	findings = audit.OnPage(findings, target)
+	if r.auditCfg.Dedup {
+		findings = audit.Dedup(findings)
+	}
+	_, span = r.tracer.Start(ctx, "db.insert_findings", telemetry.Int("findings", len(findings)))
+	result.StorageErrors += r.storeFindings(client.TestID(), findings)
+	span.End()
+	result.Findings = map[string]int{}
+	for _, f := range findings {
+		result.Findings[f.Severity]++
+	}
+	if len(r.trackers) > 0 {
+		if failed := issues.Open(context.Background(), logger, r.trackers, r.minSeverity, client.TestID(), target, findings); failed > 0 {
+			logger.Warn("failed to open issues for some findings", "failed", failed)
+		}
+	}
+
+	logger.Info("building the domain inventory")
+	thirdParties, trackers := 0, 0
+	for _, d := range inventory.Build(target, requests, captured, r.auditCfg.MatchTrackers) {
+		if !d.FirstParty {
+			thirdParties++
+		}
+		if d.Tracker != "" {
+			trackers++
+			logger.Info("tracker contacted", "domain", d.Domain, "category", d.Tracker, "requests", d.Requests)
+		}
+		if err = database.InsertDomain(logger, db, client.TestID(), d); err != nil {
+			logger.Error("failed to insert domain into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	logger.Info("domain inventory", "third_parties", thirdParties, "trackers", trackers)
+
+	apiEndpoints := inventory.BuildAPI(transactions)
+	for _, e := range apiEndpoints {
+		if err = database.InsertAPIEndpoint(logger, db, client.TestID(), e); err != nil {
+			logger.Error("failed to insert api endpoint into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	if len(apiEndpoints) > 0 {
+		logger.Info("api inventory", "endpoints", len(apiEndpoints))
+	}
+
+	for _, e := range inventory.BuildEdge(captured) {
+		if e.Hits+e.Misses > 0 || e.ServerTimed > 0 {
+			logger.Info("edge timing", "domain", e.Domain, "cdn", e.CDN, "hit_ratio", e.HitRatio(),
+				"edge_latency_ms", e.EdgeLatency, "origin_latency_ms", e.OriginLatency, "server_time_ms", e.ServerTime)
+		}
+		if err = database.InsertEdgeTiming(logger, db, client.TestID(), e); err != nil {
+			logger.Error("failed to insert edge timing into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+
+	for _, f := range client.Frames() {
+		if !f.Main() {
+			logger.Info("iframe", "frame_id", f.ID, "url", f.URL)
+		}
+		if err = database.InsertFrame(logger, db, client.TestID(), f); err != nil {
+			logger.Error("failed to insert frame into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+
+	// cookies and storage are the subject of the consent report, along with the trackers of the inventory
+	logger.Info("storing cookies and web storage", "cookies", len(cookies), "storage_keys", len(storageItems))
+	for _, c := range cookies {
+		if err = database.InsertCookie(logger, db, client.TestID(), c); err != nil {
+			logger.Error("failed to insert cookie into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	for _, item := range storageItems {
+		if err = database.InsertStorageItem(logger, db, client.TestID(), item); err != nil {
+			logger.Error("failed to insert storage item into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	for _, entry := range snapshot {
+		if entry.Looks != "" {
+			logger.Info("web storage value", "origin", entry.Origin, "kind", entry.Kind, "key", entry.Key, "looks", entry.Looks)
+		}
+		entry.Value = r.redaction.Value(entry.Key, entry.Value)
+		if err = database.InsertStorageEntry(logger, db, client.TestID(), entry); err != nil {
+			logger.Error("failed to insert storage snapshot into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	if renderedHTML != "" || domSnapshot != nil {
+		logger.Info("storing rendered dom", "html_bytes", len(renderedHTML), "snapshot_bytes", len(domSnapshot))
+		err = database.InsertDOM(logger, db, client.TestID(), r.redaction.Text(renderedHTML), r.redaction.Body(domSnapshot))
+		if err != nil {
+			logger.Error("failed to insert dom into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+
+	logger.Info("probing well-known endpoints of the target")
+	endpoints, err := audit.DiscoverWellKnown(context.Background(), logger, httpClient, target)
+	if err != nil {
+		logger.Error("failed to probe well-known endpoints", "error", err)
+	}
+	for _, e := range endpoints {
+		if err = database.InsertEndpoint(logger, db, client.TestID(), e); err != nil {
+			logger.Error("failed to insert endpoint into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+
+	run.RequestCount, run.ResponseCount = len(requests), len(captured)
+	statuses := browser.SummarizeStatuses(captured, events.Failures())
+	run.Statuses = &statuses
+	logger.Info("response status summary", "1xx", statuses.Informational, "2xx", statuses.Success, "3xx", statuses.Redirect,
+		"4xx", statuses.ClientError, "5xx", statuses.ServerError, "failed", statuses.Failed, "errors", statuses.Errors)
+	for _, s := range statuses.Slowest {
+		logger.Info("slow response", "url", s.URL, "status", s.Status, "total_ms", s.TotalMS)
+	}
+	for _, s := range statuses.Largest {
+		logger.Info("large response", "url", s.URL, "status", s.Status, "bytes", s.Bytes)
+	}
+	if len(r.mocks) > 0 {
+		logger.Info("requests served from fixtures", "mocked", client.Mocked())
+	}
+	for _, f := range client.Faults() {
+		logger.Info("fault injected", "url", f.URL, "action", f.Action, "detail", f.Detail)
+		if err = database.InsertFault(logger, db, client.TestID(), f); err != nil {
+			logger.Error("failed to insert fault into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	run.CSP = audit.GenerateCSP(target, requests)
+	logger.Info("candidate content security policy", "csp", run.CSP)
+
+	results := assertion.Evaluate(r.assertions, assertion.Run{Title: title, Requests: requests, Responses: captured, ConsoleErrors: console.Errors()})
+	if journeyErr != nil {
+		results = append(results, assertion.Result{Name: "journey", Passed: false, Message: journeyErr.Error()})
+	}
+	for _, res := range results {
+		if err = database.InsertAssertionResult(logger, db, client.TestID(), res); err != nil {
+			logger.Error("failed to insert assertion result into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+
+	result.Status, result.Assertions = database.StatusCompleted, results
+	if result.FailedAssertions = assertion.Failed(results); len(result.FailedAssertions) > 0 {
+		for _, res := range result.FailedAssertions {
+			logger.Error("assertion failed", "assertion", res.Name, "message", res.Message)
+		}
+		result.Status = database.StatusFailed
+	}
+
+	// the warm load is not captured, so it comes last and leaves the stored results alone
+	if opts.CompareCache {
+		r.compareCache(client, opts.WaitTime, browser.SummarizeLoad(requests, captured, vitals.Load), &result)
+	}
+
+	if vitals.URL != "" {
+		logger.Info("web vitals summary", "url", vitals.URL, "ttfb_ms", vitals.TTFB, "fcp_ms", vitals.FCP, "lcp_ms", vitals.LCP,
+			"cls", vitals.CLS, "inp_ms", vitals.INP, "dom_content_loaded_ms", vitals.DOMContentLoaded, "load_ms", vitals.Load, "ratings", vitals.Ratings())
+	}
+
+	r.finishTestRun(run, &result, nil)
+	return result, nil
+}
+
+// watchScripts stores the hashes of the third-party scripts of a run, reporting the scripts whose content
+// changed since the previous run of the target and storing the diff of the change.
+func (r *Runner) watchScripts(testID uuid.UUID, target string, responses []browser.Response, result *Result) []audit.Finding {
+	var findings []audit.Finding
+	for _, script := range audit.ThirdPartyScripts(target, responses) {
+		previousHash, previousContent, found, err := database.GetPreviousScript(r.db, target, script.URL)
+		if err != nil {
+			r.logger.Error("failed to get previous script", "url", script.URL, "error", err)
+		}
+		if found && previousHash != script.Hash {
+			findings = append(findings, audit.ScriptChanged(script, previousHash))
+			if err = database.InsertScriptChange(r.logger, r.db, testID, script.URL, previousHash, script.Hash, diff.Lines(previousContent, script.Content)); err != nil {
+				r.logger.Error("failed to insert script change into database", "error", err)
+				result.StorageErrors++
+			}
+		}
+		if err = database.InsertScript(r.logger, r.db, testID, target, script); err != nil {
+			r.logger.Error("failed to insert script into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	return findings
+}
+
+// compareCache loads the target again with the caches filled by the cold load, logging and storing how
+// many requests, bytes and milliseconds the caches saved.
+func (r *Runner) compareCache(client *browser.Browser, waitTime time.Duration, cold browser.LoadStats, result *Result) {
+	r.logger.Info("reloading the target with a warm cache")
+	warm, err := client.ReloadWarm(waitTime)
+	if err != nil {
+		r.logger.Error("failed to reload the target with a warm cache", "error", err)
+		return
+	}
+
+	comparison := database.CacheComparison{Cold: cold, Warm: warm}
+	saved := 0.0
+	if cold.Bytes > 0 {
+		saved = 100 * (cold.Bytes - warm.Bytes) / cold.Bytes
+	}
+	r.logger.Info("cache comparison", "cold_requests", cold.Requests, "warm_requests", warm.Requests,
+		"warm_from_cache", warm.FromCache, "cold_bytes", cold.Bytes, "warm_bytes", warm.Bytes, "bytes_saved_percent", saved,
+		"cold_load_ms", cold.Load, "warm_load_ms", warm.Load)
+	if err = database.InsertCacheComparison(r.logger, r.db, client.TestID(), comparison); err != nil {
+		r.logger.Error("failed to insert cache comparison into database", "error", err)
+		result.StorageErrors++
+	}
+}
+
+// savePDF prints the page to pdf-<test-id><suffix>.pdf in dir, when set, adding the file to the PDFs of the run.
+// A page that could not be printed does not fail the test.
+func (r *Runner) savePDF(client *browser.Browser, dir, suffix string, run *database.TestRun) {
+	if dir == "" {
+		return
+	}
+	path := filepath.Join(dir, "pdf-"+client.TestID().String()+suffix+".pdf")
+	location, err := client.PrintPDF(path)
+	if err != nil {
+		r.logger.Error("failed to save pdf", "error", err)
+		return
+	}
+	r.logger.Info("pdf saved", "path", path, "url", location)
+	run.PDFs = append(run.PDFs, path)
+}
+
+// saveScreenshot stores a screenshot of the page, labelled with the step of the run, when screenshots are
+// enabled. A page that could not be captured does not fail the test.
+func (r *Runner) saveScreenshot(client *browser.Browser, label string, result *Result) {
+	if !r.auditCfg.Screenshots {
+		return
+	}
+	screenshot, err := client.Screenshot(label)
+	if err != nil {
+		r.logger.Error("failed to take screenshot", "label", label, "error", err)
+		return
+	}
+	if r.artifacts != nil {
+		key := "screenshots/" + client.TestID().String() + "-" + label + ".jpg"
+		if screenshot.Ref, err = r.artifacts.Put(key, screenshot.MimeType, screenshot.Image); err != nil {
+			r.logger.Error("failed to upload screenshot", "label", label, "error", err)
+			result.StorageErrors++
+		} else {
+			screenshot.Image = nil
+		}
+	}
+	if err = database.InsertScreenshot(r.logger, r.db, client.TestID(), screenshot); err != nil {
+		r.logger.Error("failed to insert screenshot into database", "error", err)
+		result.StorageErrors++
+	}
+}
+
+// saveGraphQL stores the GraphQL operations sent by a transaction, when it is an XHR or fetch, returning how many.
+func (r *Runner) saveGraphQL(client *browser.Browser, t browser.Transaction, result *Result) int {
+	if !inventory.IsAPI(t) {
+		return 0
+	}
+	operations := graphql.Parse(string(t.RequestID), t.Method, t.URL, t.Request.Header("Content-Type"), t.Request.Body)
+	for _, op := range operations {
+		op.URL, op.Query = r.redaction.URL(op.URL), r.redaction.Text(op.Query)
+		if len(op.Variables) > 0 {
+			op.Variables = r.redaction.Body(op.Variables)
+		}
+		if err := database.InsertGraphQLOperation(r.logger, r.db, client.TestID(), op); err != nil {
+			r.logger.Error("failed to insert graphql operation into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	return len(operations)
+}
+
+// redactEvent masks the credentials and personal data of an event before it is stored.
+func (r *Runner) redactEvent(e database.Event) database.Event {
+	e.URL, e.FrameURL = r.redaction.URL(e.URL), r.redaction.URL(e.FrameURL)
+	e.Content, e.Body = r.redaction.Content(e.Content), r.redaction.Body(e.Body)
+	return e
+}
+
+// redactTransaction masks the query parameters and the text matching the pattern of the redaction in the URLs of a
+// transaction before it is stored.
+func (r *Runner) redactTransaction(t browser.Transaction) browser.Transaction {
+	t.URL = r.redaction.URL(t.URL)
+	if t.Redirects != nil {
+		redirects := make([]browser.Redirect, len(t.Redirects))
+		for i, redirect := range t.Redirects {
+			redirect.URL, redirect.Location = r.redaction.URL(redirect.URL), r.redaction.URL(redirect.Location)
+			redirects[i] = redirect
+		}
+		t.Redirects = redirects
+	}
+	return t
+}
+
+// explore explores the page within the exploration budget, storing the interactions made. A failed exploration
+// keeps the interactions made before it and does not fail the test.
+func (r *Runner) explore(client *browser.Browser, result *Result) {
+	r.logger.Info("exploring the page", "max_interactions", r.exploration.Interactions, "max_scrolls", r.exploration.Scrolls)
+	interactions, err := explore.Run(client.GetCtx(), r.logger, r.exploration, client.RequestCount)
+	if err != nil {
+		r.logger.Error("exploration failed", "error", err)
+	}
+	requests := 0
+	for _, i := range interactions {
+		requests += i.Requests
+		if err = database.InsertInteraction(r.logger, r.db, client.TestID(), i); err != nil {
+			r.logger.Error("failed to insert interaction into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	r.logger.Info("explored the page", "interactions", len(interactions), "requests", requests)
+}
+
+// saveDownloads stores the files downloaded by the page, uploading them to the artifact store when set.
+func (r *Runner) saveDownloads(client *browser.Browser, result *Result) {
+	for _, d := range client.Downloads() {
+		r.logger.Info("file downloaded", "url", d.URL, "path", d.Path, "state", d.State, "bytes", d.Bytes, "sha256", d.SHA256)
+		if r.artifacts != nil && d.Path != "" {
+			key := "downloads/" + client.TestID().String() + "/" + filepath.Base(d.Path)
+			if ref, err := artifacts.Upload(r.artifacts, d.Path, key, "application/octet-stream"); err != nil {
+				r.logger.Error("failed to upload download", "path", d.Path, "error", err)
+				result.StorageErrors++
+			} else {
+				d.Path = ref
+			}
+		}
+		if err := database.InsertDownload(r.logger, r.db, client.TestID(), d); err != nil {
+			r.logger.Error("failed to insert download into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+}
+
+// saveVideo stops the recording of the session and writes its video, returning its path, or an empty string
+// when the session was not recorded or its video could not be written.
+func (r *Runner) saveVideo(client *browser.Browser, path string) string {
+	if path == "" {
+		return ""
+	}
+	frames, err := client.StopRecording(r.logger)
+	if err != nil {
+		r.logger.Error("failed to save video", "error", err)
+		return ""
+	}
+	r.logger.Info("video saved", "path", path, "frames", frames)
+	return path
+}
+
+// storeFindings logs the findings reported by the checks and inserts them into the database,
+// returning the number of findings that could not be stored.
+func (r *Runner) storeFindings(testID uuid.UUID, findings []audit.Finding) int {
+	failed := 0
+	for _, f := range findings {
+		r.logger.Warn("finding", "check", f.Check, "url", f.URL, "message", f.Message)
+		if err := database.InsertFinding(r.logger, r.db, testID, f); err != nil {
+			r.logger.Error("failed to insert finding into database", "error", err)
+			failed++
+		}
+	}
+	return failed
+}
+
+// finishTestRun finalizes the test run record with the status of the result, then notifies the webhooks of the
+// run, with the error it failed with, if any.
+func (r *Runner) finishTestRun(run database.TestRun, result *Result, runErr error) {
+	r.archive(&run, result)
+	run.Status, run.FinishedAt = result.Status, time.Now()
+	if err := database.FinishTestRun(r.logger, r.db, run); err != nil {
+		r.logger.Error("failed to finish test run", "error", err)
+		result.StorageErrors++
+	}
+	if r.notifier == nil {
+		return
+	}
+
+	summary := notify.Summary{TestID: run.TestID, Target: run.TargetURL, Status: run.Status, ScheduleID: run.ScheduleID, SuiteID: run.SuiteID,
+		StartedAt: run.StartedAt, FinishedAt: run.FinishedAt, DurationMS: run.FinishedAt.Sub(run.StartedAt).Milliseconds(),
+		Requests: run.RequestCount, Responses: run.ResponseCount, Findings: result.Findings, Assertions: result.Assertions,
+		Failed: len(result.FailedAssertions), StorageErrors: result.StorageErrors}
+	if runErr != nil {
+		summary.Error = runErr.Error()
+	}
+	summary.SetText()
+	if failed := r.notifier.Notify(context.Background(), r.logger, summary); failed > 0 {
+		r.logger.Warn("failed to notify some webhooks", "failed", failed)
+	}
+}
+
+// archive uploads the video, PDFs and trace of the run to the artifact store, when set, replacing their paths
+// in the run record with their references. A file that could not be uploaded keeps its path.
+func (r *Runner) archive(run *database.TestRun, result *Result) {
+	if r.artifacts == nil {
+		return
+	}
+	upload := func(path, prefix, contentType string) string {
+		if path == "" {
+			return ""
+		}
+		if _, err := os.Stat(path); err != nil {
+			// e.g. the trace of a browser that failed before it was written
+			return ""
+		}
+		ref, err := artifacts.Upload(r.artifacts, path, prefix+"/"+filepath.Base(path), contentType)
+		if err != nil {
+			r.logger.Error("failed to upload artifact", "path", path, "error", err)
+			result.StorageErrors++
+			return path
+		}
+		r.logger.Info("artifact uploaded", "path", path, "ref", ref)
+		return ref
+	}
+	run.Video = upload(run.Video, "videos", "video/x-msvideo")
+	for i := range run.PDFs {
+		run.PDFs[i] = upload(run.PDFs[i], "pdfs", "application/pdf")
+	}
+	run.Trace = upload(run.Trace, "traces", "application/json")
+}
+
+// collectCoverage logs and stores the coverage of the scripts and stylesheets of the loaded page.
+func (r *Runner) collectCoverage(client *browser.Browser, result *Result) {
+	resources, err := client.Coverage()
+	if err != nil {
+		r.logger.Error("failed to collect coverage", "error", err)
+		return
+	}
+
+	var total, unused float64
+	for _, c := range resources {
+		total += c.Total
+		unused += c.Unused()
+		if err = database.InsertCoverage(r.logger, r.db, client.TestID(), c); err != nil {
+			r.logger.Error("failed to insert coverage into database", "error", err)
+			result.StorageErrors++
+		}
+	}
+	r.logger.Info("coverage", "resources", len(resources), "total_bytes", total, "unused_bytes", unused)
+}
+
+// saveState saves the storage state of the browser to path, so later runs can start from it.
+func (r *Runner) saveState(client *browser.Browser, path string) {
+	state, err := client.ExportState()
+	if err != nil {
+		r.logger.Error("failed to export storage state", "error", err)
+		return
+	}
+	if err = browser.SaveState(path, state); err != nil {
+		r.logger.Error("failed to save storage state", "error", err)
+		return
+	}
+	r.logger.Info("saved storage state", "path", path, "cookies", len(state.Cookies), "origins", len(state.Origins))
+}
+
+// frameURL returns the URL of the document of the frame of an event, or when it was unknown as the event was
+// captured, e.g. for a frame that had not navigated yet, the last document the frame navigated to.
+func frameURL(client *browser.Browser, frameID cdp.FrameID, captured string) string {
+	if captured != "" || frameID == "" {
+		return captured
+	}
+	return client.FrameURL(frameID)
+}
+
+// queueReviews adds REVIEW_SAMPLE_BODIES response bodies of the run, picked at random among the in-scope
+// text bodies of the REVIEW_SAMPLE_TYPES media types, to the review queue, returning the number of bodies
+// that could not be stored.
+func (r *Runner) queueReviews(testID uuid.UUID, responses []browser.Response) int {
+	var candidates []browser.Response
+	for _, resp := range responses {
+		if (len(resp.Body) == 0 && resp.BodyPath == "") || !utf8.Valid(resp.Body) || !r.scope.InScope(resp.URL) {
+			continue
+		}
+		mediaType, _, _ := strings.Cut(strings.ToLower(resp.MimeType), ";")
+		if len(r.sampling.ReviewTypes) > 0 && !slices.Contains(r.sampling.ReviewTypes, strings.TrimSpace(mediaType)) {
+			continue
+		}
+		candidates = append(candidates, resp)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].URL < candidates[j].URL })
+
+	picked := sampling.Pick(candidates, r.sampling.ReviewBodies, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	r.logger.Info("queueing response bodies for review", "candidates", len(candidates), "picked", len(picked))
+	failed := 0
+	for _, resp := range picked {
+		item := database.ReviewItem{URL: r.redaction.URL(resp.URL), Status: resp.Status, MimeType: resp.MimeType,
+			Body: string(r.redaction.Body(resp.Body)), BodyPath: resp.BodyPath, BodySize: resp.BodySize}
+		if err := database.InsertReviewItem(r.logger, r.db, testID, item); err != nil {
+			r.logger.Error("failed to insert review item into database", "error", err)
+			failed++
+		}
+	}
+	return failed
+}
+
+// pageStatus returns the status of the first document received by the main frame, which is the page's once
+// the redirects were followed.
+func pageStatus(client *browser.Browser, responses []browser.Response) int {
+	var mainFrame cdp.FrameID
+	for _, frame := range client.Frames() {
+		if frame.Main() {
+			mainFrame = frame.ID
+			break
+		}
+	}
+	var first *network.EventResponseReceived
+	for _, resp := range responses {
+		ev, ok := resp.Content.(*network.EventResponseReceived)
+		if !ok || ev.Type != network.ResourceTypeDocument || resp.Source != "" || (mainFrame != "" && resp.FrameID != mainFrame) {
+			continue
+		}
+		if first == nil || ev.Timestamp.Time().Before(first.Timestamp.Time()) {
+			first = ev
+		}
+	}
+	if first == nil {
+		return 0
+	}
+	return int(first.Response.Status)
+}
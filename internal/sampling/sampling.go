@@ -0,0 +1,85 @@
+// Package sampling bounds the number of events stored for very chatty targets, keeping every Nth request
+// and capping the requests stored per content type and per domain, and picks the response bodies spot checked
+// in the review queue.
+package sampling
+
+import (
+	"math/rand/v2"
+	"net/url"
+	"sort"
+	"strings"
+	"web-tester/internal/config"
+)
+
+// Sampler decides which requests of a run are stored. A Sampler holds the counts of a single run.
+type Sampler struct {
+	cfg       config.SamplingConfig
+	seen      int
+	perType   map[string]int
+	perDomain map[string]int
+	dropped   int
+}
+
+// New creates a Sampler for a run.
+func New(cfg config.SamplingConfig) *Sampler {
+	return &Sampler{cfg: cfg, perType: map[string]int{}, perDomain: map[string]int{}}
+}
+
+// Enabled reports whether any sampling option is set, i.e. whether some requests may not be stored.
+func (s *Sampler) Enabled() bool {
+	return s.cfg.EveryN > 1 || s.cfg.MaxPerType > 0 || s.cfg.MaxPerDomain > 0
+}
+
+// Keep reports whether the request to rawURL, whose response has the given content type, is stored.
+// It must be called once per request, in the order the requests were captured.
+func (s *Sampler) Keep(rawURL, contentType string) bool {
+	n := s.seen
+	s.seen++
+	if s.cfg.EveryN > 1 && n%s.cfg.EveryN != 0 {
+		s.dropped++
+		return false
+	}
+
+	// media types are compared without their parameters, e.g. charset
+	contentType, _, _ = strings.Cut(strings.ToLower(contentType), ";")
+	contentType = strings.TrimSpace(contentType)
+	if s.cfg.MaxPerType > 0 && s.perType[contentType] >= s.cfg.MaxPerType {
+		s.dropped++
+		return false
+	}
+
+	domain := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		domain = u.Hostname()
+	}
+	if s.cfg.MaxPerDomain > 0 && s.perDomain[domain] >= s.cfg.MaxPerDomain {
+		s.dropped++
+		return false
+	}
+
+	s.perType[contentType]++
+	s.perDomain[domain]++
+	return true
+}
+
+// Stats returns the number of requests seen and the number of them that were not stored.
+func (s *Sampler) Stats() (seen, dropped int) {
+	return s.seen, s.dropped
+}
+
+// Pick returns n of the items picked at random, or all of them, in their order, when there are at most n.
+func Pick[T any](items []T, n int, rnd *rand.Rand) []T {
+	if n <= 0 {
+		return nil
+	}
+	if len(items) <= n {
+		return items
+	}
+	indexes := rnd.Perm(len(items))[:n]
+	sort.Ints(indexes)
+	picked := make([]T, 0, n)
+	for _, i := range indexes {
+		picked = append(picked, items[i])
+	}
+	return picked
+}
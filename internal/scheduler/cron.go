@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expression is a parsed cron expression with the standard five fields:
+// minute, hour, day of month, month and day of week.
+type Expression struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// fieldBounds are the allowed ranges of the five fields, in order.
+var fieldBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// Parse parses a cron expression. Each field accepts "*", values, ranges ("1-5"), steps ("*/15", "0-30/10")
+// and comma separated lists of those. Day of week 7 is accepted as Sunday.
+func Parse(expr string) (Expression, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Expression{}, fmt.Errorf("cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseField(field, fieldBounds[i][0], fieldBounds[i][1])
+		if err != nil {
+			return Expression{}, fmt.Errorf("invalid cron field %q: %v", field, err)
+		}
+		sets[i] = set
+	}
+	// 7 is an alias for Sunday
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return Expression{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseField returns the set of values matched by a field as a bitmask.
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	if field == "" {
+		return 0, fmt.Errorf("empty field")
+	}
+	if max == 6 {
+		max = 7
+	}
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			loPart, hiPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(loPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", loPart)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiPart); err != nil {
+					return 0, fmt.Errorf("invalid value %q", hiPart)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("range %d-%d out of bounds %d-%d", lo, hi, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// Matches reports whether the expression fires at the minute of t. As in cron, when both the day of month
+// and the day of week are restricted, either of them matching is enough.
+func (e Expression) Matches(t time.Time) bool {
+	if e.minute&(1<<uint(t.Minute())) == 0 || e.hour&(1<<uint(t.Hour())) == 0 || e.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	domMatch := e.dom&(1<<uint(t.Day())) != 0
+	dowMatch := e.dow&(1<<uint(t.Weekday())) != 0
+	if e.domAny || e.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
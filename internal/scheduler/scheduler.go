@@ -0,0 +1,103 @@
+// Package scheduler re-runs configured targets on cron-style schedules.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"web-tester/internal/browser"
+	"web-tester/internal/config"
+	"web-tester/internal/runner"
+
+	"github.com/google/uuid"
+)
+
+// Enqueuer queues tests to be run.
+type Enqueuer interface {
+	Enqueue(opts runner.Options) (uuid.UUID, error)
+}
+
+// schedule is a configured schedule with its parsed expression.
+type schedule struct {
+	config.Schedule
+	expr Expression
+}
+
+// Scheduler queues a test for each schedule whenever its expression matches the current minute.
+type Scheduler struct {
+	logger    *slog.Logger
+	queue     Enqueuer
+	schedules []schedule
+}
+
+// New creates a Scheduler queueing the configured schedules on queue.
+func New(logger *slog.Logger, queue Enqueuer, schedules []config.Schedule) (*Scheduler, error) {
+	s := &Scheduler{logger: logger, queue: queue}
+	for _, sc := range schedules {
+		if sc.ID == "" || sc.Target == "" {
+			return nil, fmt.Errorf("schedule %q must have an id and a target", sc.ID)
+		}
+		expr, err := Parse(sc.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %v", sc.ID, err)
+		}
+		s.schedules = append(s.schedules, schedule{Schedule: sc, expr: expr})
+	}
+	return s, nil
+}
+
+// Run checks the schedules at the start of every minute until ctx is canceled.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.logger.Info("starting scheduler", "schedules", len(s.schedules))
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+			s.tick(next)
+		}
+	}
+}
+
+// tick queues the tests of the schedules matching t.
+func (s *Scheduler) tick(t time.Time) {
+	for _, sc := range s.schedules {
+		if !sc.expr.Matches(t) {
+			continue
+		}
+		opts := runner.Options{
+			Target:     sc.Target,
+			WaitTime:   time.Duration(sc.WaitSeconds * float64(time.Second)),
+			ScheduleID: sc.ID,
+		}
+		// the state is read on every run, so a refreshed state file is picked up
+		if sc.StorageState != "" {
+			state, err := browser.LoadState(sc.StorageState)
+			if err != nil {
+				s.logger.Error("failed to load storage state of schedule", "schedule", sc.ID, "error", err)
+				continue
+			}
+			opts.State = &state
+		}
+		if sc.Session != "" {
+			state, err := browser.LoadSession(sc.Session)
+			if err != nil {
+				s.logger.Error("failed to load session of schedule", "schedule", sc.ID, "error", err)
+				continue
+			}
+			if state != nil && opts.State == nil {
+				opts.State = state
+			}
+			opts.SaveState = sc.Session
+		}
+		testID, err := s.queue.Enqueue(opts)
+		if err != nil {
+			s.logger.Error("failed to queue scheduled test", "schedule", sc.ID, "error", err)
+			continue
+		}
+		s.logger.Info("queued scheduled test", "schedule", sc.ID, "test_id", testID)
+	}
+}
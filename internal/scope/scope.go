@@ -0,0 +1,109 @@
+// Package scope restricts a run to the URLs matching include and exclude rules. Rules are globs, where
+// "*" matches any characters, or regular expressions when prefixed with "re:".
+//
+// Globs starting with "/" match the path of the URL, e.g. "/logout", and the others match the URL
+// without its scheme, e.g. "api.example.com/*". Regular expressions match anywhere in the full URL.
+package scope
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Rules decide which URLs are in scope: the URLs matching an include rule, or any URL when there is none,
+// and matching no exclude rule.
+type Rules struct {
+	include []rule
+	exclude []rule
+}
+
+// rule is a compiled rule, along with the part of URLs it matches.
+type rule struct {
+	re   *regexp.Regexp
+	part urlPart
+}
+
+// urlPart is the part of URLs a rule matches.
+type urlPart int
+
+const (
+	fullURL urlPart = iota
+	withoutScheme
+	pathOnly
+)
+
+// Parse compiles the include and exclude rules.
+func Parse(include, exclude []string) (Rules, error) {
+	var rules Rules
+	var err error
+	if rules.include, err = compile(include); err != nil {
+		return Rules{}, err
+	}
+	if rules.exclude, err = compile(exclude); err != nil {
+		return Rules{}, err
+	}
+	return rules, nil
+}
+
+// compile compiles the rules.
+func compile(patterns []string) ([]rule, error) {
+	var rules []rule
+	for _, pattern := range patterns {
+		if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid scope rule %q: %v", pattern, err)
+			}
+			rules = append(rules, rule{re: re, part: fullURL})
+			continue
+		}
+
+		glob := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+		part := withoutScheme
+		if strings.HasPrefix(pattern, "/") {
+			part = pathOnly
+		}
+		rules = append(rules, rule{re: regexp.MustCompile("^" + glob + "$"), part: part})
+	}
+	return rules, nil
+}
+
+// Empty reports whether there are no rules, i.e. every URL is in scope.
+func (r Rules) Empty() bool {
+	return len(r.include) == 0 && len(r.exclude) == 0
+}
+
+// InScope reports whether the URL is in scope.
+func (r Rules) InScope(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return r.Empty()
+	}
+	for _, rule := range r.exclude {
+		if rule.matches(rawURL, u) {
+			return false
+		}
+	}
+	if len(r.include) == 0 {
+		return true
+	}
+	for _, rule := range r.include {
+		if rule.matches(rawURL, u) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the rule matches the URL.
+func (r rule) matches(rawURL string, u *url.URL) bool {
+	switch r.part {
+	case pathOnly:
+		return r.re.MatchString(u.Path)
+	case withoutScheme:
+		return r.re.MatchString(strings.TrimPrefix(rawURL, u.Scheme+"://"))
+	}
+	return r.re.MatchString(rawURL)
+}
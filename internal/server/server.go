@@ -0,0 +1,324 @@
+// Package server exposes a REST API to trigger tests on demand and inspect their results.
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+	"web-tester/internal/audit"
+	"web-tester/internal/browser"
+	"web-tester/internal/config"
+	"web-tester/internal/database"
+	"web-tester/internal/inventory"
+	"web-tester/internal/runner"
+
+	"github.com/google/uuid"
+)
+
+// StatusQueued is the status of a test waiting in the job queue.
+const StatusQueued = "queued"
+
+// ErrQueueFull is returned when a test is queued while the job queue is full.
+var ErrQueueFull = errors.New("job queue is full")
+
+// job is a test waiting to be run by a worker.
+type job struct {
+	testID uuid.UUID
+	opts   runner.Options
+}
+
+// Server runs the tests submitted through the API on a pool of workers fed by a bounded job queue.
+type Server struct {
+	logger *slog.Logger
+	db     *sql.DB
+	runner *runner.Runner
+	jobs   chan job
+
+	mu       sync.Mutex
+	statuses map[uuid.UUID]string
+}
+
+// TestRequest is the body of POST /tests, and of the test.submit method of the JSON-RPC protocol. Scenario
+// is run on the page instead of the scenario of SCENARIO_FILE when set. Scroll scrolls the page to its end once
+// loaded with the SCROLL_* strategy, and Explore explores the page within the EXPLORE_* budget once the journey ran.
+type TestRequest struct {
+	Target       string               `json:"target"`
+	WaitSeconds  float64              `json:"wait_seconds"`
+	Filter       *browser.Filter      `json:"filter,omitempty"`
+	CompareCache bool                 `json:"compare_cache,omitempty"`
+	Coverage     bool                 `json:"coverage,omitempty"`
+	Device       string               `json:"device,omitempty"`
+	CPUThrottle  float64              `json:"cpu_throttle,omitempty"`
+	UserAgent    string               `json:"user_agent,omitempty"`
+	Locale       string               `json:"locale,omitempty"`
+	Geolocation  *browser.Geolocation `json:"geolocation,omitempty"`
+	Timezone     string               `json:"timezone,omitempty"`
+	Scenario     *config.Scenario     `json:"scenario,omitempty"`
+	Scroll       bool                 `json:"scroll,omitempty"`
+	Explore      bool                 `json:"explore,omitempty"`
+	TraceParent  string               `json:"traceparent,omitempty"`
+}
+
+// Options returns the options of the requested test.
+func (req TestRequest) Options() (runner.Options, error) {
+	if req.Target == "" {
+		return runner.Options{}, errors.New("target is required")
+	}
+	opts := runner.Options{Target: req.Target, WaitTime: time.Duration(req.WaitSeconds * float64(time.Second)), CompareCache: req.CompareCache,
+		Coverage: req.Coverage, CPUThrottle: req.CPUThrottle, UserAgent: req.UserAgent, Locale: req.Locale,
+		Geolocation: req.Geolocation, Timezone: req.Timezone, Scenario: req.Scenario, Scroll: req.Scroll, Explore: req.Explore,
+		TraceParent: req.TraceParent}
+	if req.Filter != nil {
+		opts.Filter = *req.Filter
+	}
+	if req.Device != "" {
+		device, err := browser.LookupDevice(req.Device)
+		if err != nil {
+			return runner.Options{}, err
+		}
+		opts.Device = device
+	}
+	return opts, nil
+}
+
+// testResponse is the body returned by the API for a test.
+type testResponse struct {
+	TestID       uuid.UUID                    `json:"test_id"`
+	Status       string                       `json:"status"`
+	Run          *database.TestRun            `json:"run,omitempty"`
+	Events       []database.StoredEvent       `json:"events,omitempty"`
+	Transactions []database.StoredTransaction `json:"transactions,omitempty"`
+	APIEndpoints []inventory.APIEndpoint      `json:"api_endpoints,omitempty"`
+	Findings     []audit.Finding              `json:"findings,omitempty"`
+}
+
+// New creates a Server running tests with r, reading their results back from db.
+func New(logger *slog.Logger, db *sql.DB, r *runner.Runner, queueSize int) *Server {
+	return &Server{logger: logger, db: db, runner: r, jobs: make(chan job, queueSize), statuses: map[uuid.UUID]string{}}
+}
+
+// Start launches the workers running queued tests until ctx is canceled.
+func (s *Server) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-s.jobs:
+					s.run(j)
+				}
+			}
+		}()
+	}
+}
+
+// run runs a queued test, tracking its status. The browser is only created here so its
+// timeout does not run while the test waits in the queue.
+func (s *Server) run(j job) {
+	client := browser.NewWithTestID(j.opts.Target, j.testID)
+	defer client.Cancel()
+	s.setStatus(j.testID, database.StatusRunning)
+
+	result, err := s.runner.Run(client, j.opts)
+	if err != nil {
+		s.logger.Error("test failed", "test_id", j.testID, "error", err)
+	}
+	s.setStatus(j.testID, result.Status)
+}
+
+func (s *Server) setStatus(testID uuid.UUID, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[testID] = status
+}
+
+func (s *Server) status(testID uuid.UUID) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.statuses[testID]
+	return status, ok
+}
+
+// Handler returns the API routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tests", s.createTest)
+	mux.HandleFunc("GET /tests/{id}", s.getTest)
+	mux.HandleFunc("GET /tests/{id}/dom", s.getDOM)
+	return mux
+}
+
+// ListenAndServe serves the API on addr until ctx is canceled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("serving api", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Enqueue queues a test and returns its test ID, or ErrQueueFull when the job queue is full.
+func (s *Server) Enqueue(opts runner.Options) (uuid.UUID, error) {
+	testID, err := uuid.NewV7()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create test id: %v", err)
+	}
+
+	// set the status before queueing so a fast worker's running status is not overwritten
+	s.setStatus(testID, StatusQueued)
+	select {
+	case s.jobs <- job{testID: testID, opts: opts}:
+	default:
+		s.mu.Lock()
+		delete(s.statuses, testID)
+		s.mu.Unlock()
+		return uuid.Nil, ErrQueueFull
+	}
+
+	s.logger.Info("queued test", "test_id", testID, "target", opts.Target)
+	return testID, nil
+}
+
+// createTest queues a test for the requested target and returns its test ID.
+func (s *Server) createTest(w http.ResponseWriter, r *http.Request) {
+	req := TestRequest{}
+	// the test joins the trace of the client when it sends its traceparent
+	req.TraceParent = r.Header.Get("traceparent")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	opts, err := req.Options()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	testID, err := s.Enqueue(opts)
+	if errors.Is(err, ErrQueueFull) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, testResponse{TestID: testID, Status: StatusQueued})
+}
+
+// getTest returns the status of a test along with its run record, captured events, transactions and findings once stored.
+func (s *Server) getTest(w http.ResponseWriter, r *http.Request) {
+	testID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid test id")
+		return
+	}
+
+	resp := testResponse{TestID: testID}
+	status, known := s.status(testID)
+	resp.Status = status
+
+	if s.db != nil {
+		run, err := database.GetTestRun(s.db, testID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			s.logger.Error("failed to get test run", "test_id", testID, "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get test run")
+			return
+		default:
+			known, resp.Run, resp.Status = true, &run, run.Status
+		}
+
+		if resp.Events, err = database.GetEvents(s.db, testID); err != nil {
+			s.logger.Error("failed to get events", "test_id", testID, "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get events")
+			return
+		}
+		if resp.Transactions, err = database.GetTransactions(s.db, testID); err != nil {
+			s.logger.Error("failed to get transactions", "test_id", testID, "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get transactions")
+			return
+		}
+		if resp.APIEndpoints, err = database.GetAPIEndpoints(s.db, testID); err != nil {
+			s.logger.Error("failed to get api endpoints", "test_id", testID, "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get api endpoints")
+			return
+		}
+		if resp.Findings, err = database.GetFindings(s.db, testID); err != nil {
+			s.logger.Error("failed to get findings", "test_id", testID, "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to get findings")
+			return
+		}
+	}
+
+	if !known {
+		writeError(w, http.StatusNotFound, "test not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// getDOM returns the HTML of the page of a test as rendered after its scripts ran, or its DOM snapshot
+// with ?snapshot=true.
+func (s *Server) getDOM(w http.ResponseWriter, r *http.Request) {
+	testID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid test id")
+		return
+	}
+	if s.db == nil {
+		writeError(w, http.StatusServiceUnavailable, "the database is not available")
+		return
+	}
+
+	html, snapshot, err := database.GetDOM(s.db, testID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "no rendered dom was stored for this test")
+		return
+	}
+	if err != nil {
+		s.logger.Error("failed to get dom", "test_id", testID, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to get dom")
+		return
+	}
+
+	if r.URL.Query().Get("snapshot") == "true" {
+		if snapshot == nil {
+			writeError(w, http.StatusNotFound, "no dom snapshot was captured for this test, see DOM_SNAPSHOT")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(snapshot)
+		return
+	}
+	// the page is served as text so its scripts do not run on the API's origin
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(html))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,141 @@
+// Package signing signs the files exported by the tool, e.g. reports, with an Ed25519 key, so captures
+// used as audit evidence can be proven untampered.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Algorithm is the signature algorithm of the signatures.
+const Algorithm = "ed25519"
+
+// Extension is appended to the path of a signed file to get the path of its detached signature.
+const Extension = ".sig"
+
+// ErrInvalid is returned when a file does not match its signature.
+var ErrInvalid = errors.New("invalid signature")
+
+// Signature is the detached signature of a file. The signature covers the contents of the file, SHA256
+// and the public key are informative.
+type Signature struct {
+	Algorithm string    `json:"algorithm"`
+	PublicKey []byte    `json:"public_key"`
+	SHA256    string    `json:"sha256"`
+	Signature []byte    `json:"signature"`
+	SignedAt  time.Time `json:"signed_at"`
+}
+
+// LoadPrivateKey reads an Ed25519 private key from a PKCS #8 PEM file, as generated by
+// `openssl genpkey -algorithm ed25519`.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is a %T, not an ed25519 key", key)
+	}
+	return private, nil
+}
+
+// LoadPublicKey reads an Ed25519 public key from a PKIX PEM file, as extracted by `openssl pkey -pubout`.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %v", err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is a %T, not an ed25519 key", key)
+	}
+	return public, nil
+}
+
+// readPEM returns the contents of the first PEM block of the file, which must be of the given type.
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s is not a PEM encoded %s", path, blockType)
+	}
+	return block.Bytes, nil
+}
+
+// Sign signs data with the private key.
+func Sign(key ed25519.PrivateKey, data []byte) Signature {
+	digest := sha256.Sum256(data)
+	return Signature{
+		Algorithm: Algorithm,
+		PublicKey: key.Public().(ed25519.PublicKey),
+		SHA256:    hex.EncodeToString(digest[:]),
+		Signature: ed25519.Sign(key, data),
+		SignedAt:  time.Now().UTC(),
+	}
+}
+
+// Verify checks that data was signed with the private key of the trusted public key. The public key of the
+// signature is not trusted, as whoever tampered with the file could have signed it again with their own key.
+func Verify(trusted ed25519.PublicKey, data []byte, sig Signature) error {
+	if sig.Algorithm != Algorithm {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalid, sig.Algorithm)
+	}
+	if !ed25519.Verify(trusted, data, sig.Signature) {
+		return fmt.Errorf("%w: the file was modified or not signed by the trusted key", ErrInvalid)
+	}
+	return nil
+}
+
+// SignFile writes the detached signature of the file next to it, at its path with Extension appended.
+func SignFile(key ed25519.PrivateKey, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file to sign: %v", err)
+	}
+	encoded, err := json.MarshalIndent(Sign(key, data), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal signature: %v", err)
+	}
+	sigPath := path + Extension
+	if err = os.WriteFile(sigPath, append(encoded, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write signature: %v", err)
+	}
+	return sigPath, nil
+}
+
+// VerifyFile checks the file against its detached signature at sigPath with the trusted public key.
+func VerifyFile(trusted ed25519.PublicKey, path, sigPath string) (Signature, error) {
+	var sig Signature
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return sig, fmt.Errorf("failed to read signed file: %v", err)
+	}
+	encoded, err := os.ReadFile(sigPath)
+	if err != nil {
+		return sig, fmt.Errorf("failed to read signature: %v", err)
+	}
+	if err = json.Unmarshal(encoded, &sig); err != nil {
+		return sig, fmt.Errorf("failed to parse signature: %v", err)
+	}
+	return sig, Verify(trusted, data, sig)
+}
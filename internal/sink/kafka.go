@@ -0,0 +1,137 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// kafkaBatchSize and kafkaLinger bound the records published by a request to the REST proxy, and how long a
+// record waits for others to be published with it.
+const (
+	kafkaBatchSize = 100
+	kafkaLinger    = 100 * time.Millisecond
+)
+
+// Kafka is a sink publishing every record to a Kafka topic through a Kafka REST Proxy, e.g. the Confluent REST
+// Proxy, as it is captured. Records are keyed by their request ID, so the events of a transaction land on the same
+// partition, in order. They are published in the background in small batches, and Close returns an error when
+// some could not be published.
+type Kafka struct {
+	client   *http.Client
+	endpoint string
+
+	mu      sync.Mutex
+	pending []kafkaRecord
+	failed  int
+	lastErr error
+	wake    chan struct{}
+	done    chan struct{}
+	closed  chan struct{}
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Record `json:"value"`
+}
+
+// NewKafka creates a Kafka sink publishing to the topic through the REST proxy at proxyURL with client.
+func NewKafka(client *http.Client, proxyURL, topic string) *Kafka {
+	k := &Kafka{
+		client: client, endpoint: strings.TrimSuffix(proxyURL, "/") + "/topics/" + topic,
+		wake: make(chan struct{}, 1), done: make(chan struct{}), closed: make(chan struct{}),
+	}
+	go k.publish()
+	return k
+}
+
+// Write queues the record to be published.
+func (k *Kafka) Write(record Record) error {
+	k.mu.Lock()
+	k.pending = append(k.pending, kafkaRecord{Key: record.RequestID, Value: record})
+	full := len(k.pending) >= kafkaBatchSize
+	k.mu.Unlock()
+	if full {
+		select {
+		case k.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// publish publishes the queued records every kafkaLinger, or as soon as a batch is full, until Close is called.
+func (k *Kafka) publish() {
+	defer close(k.closed)
+	ticker := time.NewTicker(kafkaLinger)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-k.wake:
+		case <-k.done:
+			k.flush()
+			return
+		}
+		k.flush()
+	}
+}
+
+// flush publishes the queued records in batches.
+func (k *Kafka) flush() {
+	k.mu.Lock()
+	pending := k.pending
+	k.pending = nil
+	k.mu.Unlock()
+
+	for start := 0; start < len(pending); start += kafkaBatchSize {
+		batch := pending[start:min(start+kafkaBatchSize, len(pending))]
+		if err := k.post(batch); err != nil {
+			k.mu.Lock()
+			k.failed += len(batch)
+			k.lastErr = err
+			k.mu.Unlock()
+		}
+	}
+}
+
+// post publishes a batch of records with a request to the REST proxy.
+func (k *Kafka) post(batch []kafkaRecord) error {
+	body, err := json.Marshal(map[string]interface{}{"records": batch})
+	if err != nil {
+		return fmt.Errorf("failed to encode records: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, k.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kafka REST proxy answered %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// Close publishes the queued records and stops publishing.
+func (k *Kafka) Close() error {
+	close(k.done)
+	<-k.closed
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.failed > 0 {
+		return fmt.Errorf("failed to publish %d records to kafka: %v", k.failed, k.lastErr)
+	}
+	return nil
+}
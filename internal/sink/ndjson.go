@@ -0,0 +1,49 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// NDJSON is a sink writing one JSON document per line, suitable for piping into jq or log shippers.
+type NDJSON struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewNDJSON creates an NDJSON sink writing to the file at path, or to stdout when path is "-".
+// The file is created or truncated.
+func NewNDJSON(path string) (*NDJSON, error) {
+	if path == "-" {
+		return &NDJSON{enc: json.NewEncoder(os.Stdout)}, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ndjson output: %v", err)
+	}
+	return &NDJSON{enc: json.NewEncoder(f), closer: f}, nil
+}
+
+// Write encodes the record as a single line.
+func (n *NDJSON) Write(record Record) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.enc.Encode(record); err != nil {
+		return fmt.Errorf("failed to write ndjson record: %v", err)
+	}
+	return nil
+}
+
+// Close closes the underlying file, if any.
+func (n *NDJSON) Close() error {
+	if n.closer == nil {
+		return nil
+	}
+	return n.closer.Close()
+}
@@ -0,0 +1,50 @@
+// Package sink provides outputs that captured events are streamed to as they happen.
+package sink
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Record is a single captured event written to a sink.
+type Record struct {
+	TestID    uuid.UUID   `json:"test_id"`
+	Type      string      `json:"type"`
+	RequestID string      `json:"request_id"`
+	URL       string      `json:"url,omitempty"`
+	Time      time.Time   `json:"time"`
+	Content   interface{} `json:"content,omitempty"`
+}
+
+// Sink receives captured events as they happen. Implementations must be safe for concurrent use.
+type Sink interface {
+	Write(record Record) error
+	Close() error
+}
+
+// Multi is a sink writing every record to each of its sinks.
+type Multi []Sink
+
+// Write writes the record to every sink, returning the errors of the sinks that failed.
+func (m Multi) Write(record Record) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Write(record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every sink, returning the errors of the sinks that failed.
+func (m Multi) Close() error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,312 @@
+// Package telemetry traces the steps of the runs with OpenTelemetry spans, exported to an OTLP collector over
+// HTTP in the OTLP JSON encoding, so operators can see where the time of large runs goes. Spans are carried by
+// contexts, and the W3C traceparent of a span propagates the trace across processes.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The limits of the exporter: spans are exported once exportBatch spans are pending or every exportInterval,
+// and dropped beyond maxPending spans when the collector cannot keep up.
+const (
+	exportBatch    = 512
+	exportInterval = 5 * time.Second
+	maxPending     = 8192
+)
+
+// Attr is an attribute of a span.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key, value string) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attr {
+	return Attr{Key: key, Value: int64(value)}
+}
+
+// Tracer records spans and exports them to an OTLP collector in the background. A nil Tracer records nothing,
+// so the code it instruments does not need to check whether tracing is enabled.
+type Tracer struct {
+	logger   *slog.Logger
+	client   *http.Client
+	endpoint string
+	headers  map[string]string
+	service  string
+
+	mu      sync.Mutex
+	pending []*Span
+	dropped int
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// New creates a Tracer exporting the spans of service to the traces endpoint of the OTLP collector at endpoint,
+// e.g. http://localhost:4318, with the headers, e.g. to authenticate.
+func New(logger *slog.Logger, client *http.Client, endpoint, service string, headers map[string]string) *Tracer {
+	t := &Tracer{
+		logger: logger, client: client, endpoint: strings.TrimSuffix(endpoint, "/") + "/v1/traces", headers: headers, service: service,
+		wake: make(chan struct{}, 1), done: make(chan struct{}), stopped: make(chan struct{}),
+	}
+	go t.export()
+	return t
+}
+
+// Span is an operation of a run, from its start to the call to End. The methods of a nil Span do nothing.
+type Span struct {
+	tracer  *Tracer
+	traceID [16]byte
+	spanID  [8]byte
+	parent  [8]byte
+	name    string
+	kind    int
+	start   time.Time
+	end     time.Time
+
+	mu     sync.Mutex
+	attrs  []Attr
+	errMsg string
+	ended  bool
+}
+
+// spanKey is the key of the span of a context.
+type spanKey struct{}
+
+// remote is the span of another process, extracted from its traceparent.
+type remote struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+// Start starts a span named name, child of the span of ctx, if any, returning the context carrying it.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{tracer: t, name: name, kind: 1, start: time.Now(), attrs: attrs}
+	switch parent := ctx.Value(spanKey{}).(type) {
+	case *Span:
+		s.traceID, s.parent = parent.traceID, parent.spanID
+	case remote:
+		s.traceID, s.parent, s.kind = parent.traceID, parent.spanID, 2
+	default:
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// RecordError marks the span as failed with err, unless nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errMsg = err.Error()
+}
+
+// End ends the span, queuing it for export.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended, s.end = true, time.Now()
+	s.mu.Unlock()
+
+	t := s.tracer
+	t.mu.Lock()
+	if len(t.pending) >= maxPending {
+		t.dropped++
+	} else {
+		t.pending = append(t.pending, s)
+	}
+	full := len(t.pending) >= exportBatch
+	t.mu.Unlock()
+	if full {
+		select {
+		case t.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Traceparent returns the W3C traceparent of the span of ctx, to propagate its trace to another process, or an
+// empty string when ctx has no span.
+func Traceparent(ctx context.Context) string {
+	switch s := ctx.Value(spanKey{}).(type) {
+	case *Span:
+		return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.spanID[:]) + "-01"
+	case remote:
+		return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.spanID[:]) + "-01"
+	}
+	return ""
+}
+
+// Extract returns ctx carrying the span of another process from its W3C traceparent, so the spans started
+// from it join its trace. ctx is returned as is when the traceparent is empty or invalid.
+func Extract(ctx context.Context, traceparent string) context.Context {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	var r remote
+	if _, err := hex.Decode(r.traceID[:], []byte(parts[1])); err != nil || r.traceID == [16]byte{} {
+		return ctx
+	}
+	if _, err := hex.Decode(r.spanID[:], []byte(parts[2])); err != nil || r.spanID == [8]byte{} {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, r)
+}
+
+// Shutdown exports the pending spans and stops exporting.
+func (t *Tracer) Shutdown() {
+	if t == nil {
+		return
+	}
+	close(t.done)
+	<-t.stopped
+}
+
+// export exports the pending spans every exportInterval, or as soon as a batch is full, until Shutdown is called.
+func (t *Tracer) export() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.wake:
+		case <-t.done:
+			t.flush()
+			return
+		}
+		t.flush()
+	}
+}
+
+// flush exports the pending spans in batches.
+func (t *Tracer) flush() {
+	t.mu.Lock()
+	pending, dropped := t.pending, t.dropped
+	t.pending, t.dropped = nil, 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		t.logger.Warn("dropped spans the collector could not keep up with", "spans", dropped)
+	}
+
+	for start := 0; start < len(pending); start += exportBatch {
+		batch := pending[start:min(start+exportBatch, len(pending))]
+		if err := t.post(batch); err != nil {
+			t.logger.Error("failed to export spans", "spans", len(batch), "error", err)
+		}
+	}
+}
+
+// post exports a batch of spans.
+func (t *Tracer) post(batch []*Span) error {
+	spans := make([]map[string]interface{}, len(batch))
+	for i, s := range batch {
+		spans[i] = s.otlp()
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource":   map[string]interface{}{"attributes": otlpAttributes([]Attr{String("service.name", t.service)})},
+			"scopeSpans": []interface{}{map[string]interface{}{"scope": map[string]string{"name": "web-tester"}, "spans": spans}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("collector answered %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// otlp returns the span in the OTLP JSON encoding, where IDs are hex encoded and 64-bit integers are strings.
+func (s *Span) otlp() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span := map[string]interface{}{
+		"traceId": hex.EncodeToString(s.traceID[:]), "spanId": hex.EncodeToString(s.spanID[:]), "name": s.name, "kind": s.kind,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10), "endTimeUnixNano": strconv.FormatInt(s.end.UnixNano(), 10),
+		"attributes": otlpAttributes(s.attrs),
+	}
+	if s.parent != [8]byte{} {
+		span["parentSpanId"] = hex.EncodeToString(s.parent[:])
+	}
+	if s.errMsg != "" {
+		span["status"] = map[string]interface{}{"code": 2, "message": s.errMsg}
+	}
+	return span
+}
+
+func otlpAttributes(attrs []Attr) []map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(attrs))
+	for _, a := range attrs {
+		var value map[string]interface{}
+		switch v := a.Value.(type) {
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, map[string]interface{}{"key": a.Key, "value": value})
+	}
+	return encoded
+}
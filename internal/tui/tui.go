@@ -0,0 +1,153 @@
+// Package tui provides a terminal UI to browse the events and findings of a stored run.
+// It is prompt driven and only needs a plain terminal, so it also works over SSH.
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"web-tester/internal/audit"
+	"web-tester/internal/database"
+)
+
+// maxBodyPreview is the maximum number of bytes of a body shown in the detail pane.
+const maxBodyPreview = 4096
+
+const clearScreen = "\033[H\033[2J"
+
+const help = `commands:
+  list                 show the request list
+  filter <text>        only list events whose type, status or URL contains text ("filter" clears it)
+  show <n>             show headers and body of event n
+  findings             show the findings of the run
+  help                 show this help
+  quit                 exit`
+
+// TUI holds the state of an inspection session.
+type TUI struct {
+	out      io.Writer
+	events   []database.StoredEvent
+	findings []audit.Finding
+	filter   string
+}
+
+// New creates a TUI over the events and findings of a run, writing to out.
+func New(out io.Writer, events []database.StoredEvent, findings []audit.Finding) *TUI {
+	return &TUI{out: out, events: events, findings: findings}
+}
+
+// Run reads commands from in until it is exhausted or the user quits.
+func (t *TUI) Run(in io.Reader) error {
+	t.list()
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(t.out, "\n> ")
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		switch cmd {
+		case "", "list", "l":
+			t.list()
+		case "filter", "f":
+			t.filter = strings.TrimSpace(arg)
+			t.list()
+		case "show", "s":
+			n, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil || n < 0 || n >= len(t.events) {
+				fmt.Fprintf(t.out, "invalid event number %q\n", arg)
+				continue
+			}
+			t.show(n)
+		case "findings":
+			t.showFindings()
+		case "help", "h", "?":
+			fmt.Fprintln(t.out, help)
+		case "quit", "q", "exit":
+			return nil
+		default:
+			fmt.Fprintf(t.out, "unknown command %q, type help for the list of commands\n", cmd)
+		}
+	}
+}
+
+// matches reports whether the event matches the current filter.
+func (t *TUI) matches(e database.StoredEvent) bool {
+	if t.filter == "" {
+		return true
+	}
+	f := strings.ToLower(t.filter)
+	return strings.Contains(strings.ToLower(e.URL), f) || strings.Contains(e.Type, f) || strconv.FormatInt(e.Status, 10) == f
+}
+
+// list prints the events matching the filter, numbered by their position in the run.
+func (t *TUI) list() {
+	fmt.Fprint(t.out, clearScreen)
+	shown := 0
+	for i, e := range t.events {
+		if !t.matches(e) {
+			continue
+		}
+		status := ""
+		if e.Status > 0 {
+			status = strconv.FormatInt(e.Status, 10)
+		}
+		fmt.Fprintf(t.out, "%4d  %-8s %3s  %s\n", i, e.Type, status, e.URL)
+		shown++
+	}
+	fmt.Fprintf(t.out, "\n%d of %d events", shown, len(t.events))
+	if t.filter != "" {
+		fmt.Fprintf(t.out, " matching %q", t.filter)
+	}
+	fmt.Fprintf(t.out, ", %d findings. Type help for commands.\n", len(t.findings))
+}
+
+// show prints the detail pane of an event: its headers and body.
+func (t *TUI) show(n int) {
+	e := t.events[n]
+	fmt.Fprint(t.out, clearScreen)
+	fmt.Fprintf(t.out, "#%d %s %s\n", n, e.Type, e.URL)
+	if e.Status > 0 {
+		fmt.Fprintf(t.out, "status: %d\n", e.Status)
+	}
+
+	fmt.Fprintln(t.out, "\nheaders:")
+	headers := e.Headers()
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(t.out, "  %s: %v\n", name, headers[name])
+	}
+
+	fmt.Fprintln(t.out, "\nbody:")
+	body := e.Body
+	if len(body) > maxBodyPreview {
+		body = body[:maxBodyPreview] + fmt.Sprintf("\n... (%d more bytes)", len(e.Body)-maxBodyPreview)
+	}
+	if body == "" {
+		body = "(empty)"
+	}
+	fmt.Fprintln(t.out, body)
+}
+
+// showFindings prints the findings view.
+func (t *TUI) showFindings() {
+	fmt.Fprint(t.out, clearScreen)
+	if len(t.findings) == 0 {
+		fmt.Fprintln(t.out, "no findings")
+		return
+	}
+	for _, f := range t.findings {
+		fmt.Fprintf(t.out, "[%s] %s  %s\n    %s\n", f.Severity, f.Check, f.URL, f.Message)
+		if len(f.Pages) > 1 {
+			fmt.Fprintf(t.out, "    on %d pages\n", len(f.Pages))
+		}
+	}
+}
@@ -0,0 +1,6 @@
+// Package version holds the version of the web-tester tool.
+package version
+
+// Version is the tool version, overridden at build time with
+// -ldflags "-X web-tester/internal/version.Version=<version>".
+var Version = "dev"
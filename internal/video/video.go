@@ -0,0 +1,189 @@
+// Package video writes the frames of a screencast as a Motion JPEG AVI file, playable in browsers and
+// common video players, along with the timestamps of its frames to synchronize it with the request timeline.
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Frame is a JPEG screenshot of the page taken at Time.
+type Frame struct {
+	JPEG []byte
+	Time time.Time
+}
+
+// Timeline holds the timestamps of the frames of a video, so its playback can be matched with the requests
+// of the run. Offsets are from StartedAt, the time of the first frame.
+type Timeline struct {
+	StartedAt time.Time       `json:"started_at"`
+	FPS       int             `json:"fps"`
+	Duration  float64         `json:"duration_ms"`
+	Frames    []TimelineFrame `json:"frames"`
+}
+
+// TimelineFrame is a frame captured by the screencast, shown from Offset in the video.
+type TimelineFrame struct {
+	Time   time.Time `json:"time"`
+	Offset float64   `json:"offset_ms"`
+}
+
+// resample returns the frames shown at each tick of a video of fps frames per second: the last frame
+// captured at or before the tick, so the video plays the frames at the time they were captured. The last
+// frame is shown for one tick.
+func resample(frames []Frame, fps int) [][]byte {
+	start := frames[0].Time
+	end := frames[len(frames)-1].Time
+	tick := time.Second / time.Duration(fps)
+	var ticks [][]byte
+	next := 0
+	for t := time.Duration(0); start.Add(t).Before(end.Add(tick)); t += tick {
+		for next+1 < len(frames) && !frames[next+1].Time.After(start.Add(t)) {
+			next++
+		}
+		ticks = append(ticks, frames[next].JPEG)
+	}
+	return ticks
+}
+
+// NewTimeline returns the timeline of the frames played at fps frames per second.
+func NewTimeline(frames []Frame, fps int) Timeline {
+	if len(frames) == 0 {
+		return Timeline{FPS: fps, Frames: []TimelineFrame{}}
+	}
+	timeline := Timeline{StartedAt: frames[0].Time, FPS: fps}
+	for _, f := range frames {
+		timeline.Frames = append(timeline.Frames, TimelineFrame{Time: f.Time, Offset: milliseconds(f.Time.Sub(frames[0].Time))})
+	}
+	timeline.Duration = milliseconds(time.Duration(len(resample(frames, fps))) * time.Second / time.Duration(fps))
+	return timeline
+}
+
+// milliseconds returns the duration in milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteFile writes the frames as a Motion JPEG AVI video of fps frames per second to path, and their
+// timeline next to it, at path with the .json extension.
+func WriteFile(path string, frames []Frame, fps int) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create video directory: %v", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create video file: %v", err)
+	}
+	w := bufio.NewWriter(file)
+	if err = WriteAVI(w, frames, fps); err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write video file: %v", err)
+	}
+
+	timeline, err := json.MarshalIndent(NewTimeline(frames, fps), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal video timeline: %v", err)
+	}
+	if err = os.WriteFile(path+".json", timeline, 0o644); err != nil {
+		return fmt.Errorf("failed to write video timeline: %v", err)
+	}
+	return nil
+}
+
+// WriteAVI writes the frames as a Motion JPEG AVI video of fps frames per second. The size of the video is
+// the size of the first frame.
+func WriteAVI(w io.Writer, frames []Frame, fps int) error {
+	if len(frames) == 0 {
+		return errors.New("no frames to write")
+	}
+	if fps <= 0 {
+		return fmt.Errorf("invalid frame rate %d", fps)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(frames[0].JPEG))
+	if err != nil {
+		return fmt.Errorf("failed to decode first frame: %v", err)
+	}
+	width, height := uint32(config.Width), uint32(config.Height)
+	ticks := resample(frames, fps)
+
+	// the movie list holds a chunk per frame, indexed by their offset from the start of its type
+	var movi, index bytes.Buffer
+	movi.WriteString("movi")
+	var maxSize uint32
+	for _, jpg := range ticks {
+		size := uint32(len(jpg))
+		maxSize = max(maxSize, size)
+		index.WriteString("00dc")
+		le(&index, 0x10, uint32(movi.Len()), size) // AVIIF_KEYFRAME
+		movi.WriteString("00dc")
+		le(&movi, size)
+		movi.Write(jpg)
+		if size%2 == 1 {
+			movi.WriteByte(0)
+		}
+	}
+
+	var avih bytes.Buffer
+	le(&avih, uint32(time.Second/time.Microsecond)/uint32(fps), maxSize*uint32(fps), 0, 0x10, // AVIF_HASINDEX
+		uint32(len(ticks)), 0, 1, maxSize, width, height, 0, 0, 0, 0)
+
+	var strh bytes.Buffer
+	strh.WriteString("vidsMJPG")
+	le(&strh, 0, 0, 0, 1, uint32(fps), 0, uint32(len(ticks)), maxSize, 0xffffffff, 0)
+	binary.Write(&strh, binary.LittleEndian, [4]uint16{0, 0, uint16(width), uint16(height)})
+
+	var strf bytes.Buffer
+	le(&strf, 40, width, height)
+	binary.Write(&strf, binary.LittleEndian, [2]uint16{1, 24})
+	strf.WriteString("MJPG")
+	le(&strf, width*height*3, 0, 0, 0, 0)
+
+	strl := list("strl", chunk("strh", strh.Bytes()), chunk("strf", strf.Bytes()))
+	hdrl := list("hdrl", chunk("avih", avih.Bytes()), strl)
+	riff := list("AVI ", hdrl, chunk("LIST", movi.Bytes()), chunk("idx1", index.Bytes()))
+
+	_, err = w.Write(append([]byte("RIFF"), riff[4:]...))
+	return err
+}
+
+// le writes 32-bit little endian values.
+func le(buf *bytes.Buffer, values ...uint32) {
+	for _, v := range values {
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+}
+
+// chunk returns a RIFF chunk of the given type, padded to an even size.
+func chunk(fourcc string, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(fourcc)
+	le(&buf, uint32(len(data)))
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// list returns a RIFF list of the given type holding the chunks.
+func list(listType string, chunks ...[]byte) []byte {
+	data := []byte(listType)
+	for _, c := range chunks {
+		data = append(data, c...)
+	}
+	return chunk("LIST", data)
+}
@@ -0,0 +1,181 @@
+// Package webtester runs web-tester captures from other Go programs: it loads a target in a headless browser,
+// captures its traffic, runs the audit checks and evaluates the assertions, like the web-tester command, and
+// returns the outcome instead of an exit code.
+//
+//	r := webtester.New(logger, nil, webtester.Assertions{No5xx: true})
+//	result, err := r.Run(ctx, webtester.Options{Target: "https://example.com"})
+//
+// The audit checks are configured from the environment, like the command.
+package webtester
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+	"web-tester/internal/browser"
+	"web-tester/internal/config"
+	"web-tester/internal/database"
+	"web-tester/internal/runner"
+	"web-tester/internal/sink"
+
+	"github.com/google/uuid"
+)
+
+// DefaultWaitTime is how long the browser waits on the target after navigating when no wait time is set.
+const DefaultWaitTime = runner.DefaultWaitTime
+
+// The statuses of a finished test.
+const (
+	StatusCompleted = database.StatusCompleted
+	StatusFailed    = database.StatusFailed
+)
+
+// ErrLaunch and ErrNavigation are wrapped by the errors of Run for a browser that could not be started and
+// for a target that could not be loaded.
+var (
+	ErrLaunch     = browser.ErrLaunch
+	ErrNavigation = browser.ErrNavigation
+)
+
+// Assertions holds the expectations a test is checked against once the capture finishes. Unset expectations
+// are not evaluated.
+type Assertions struct {
+	TitleMatches    string
+	RequestOccurred []string
+	No5xx           bool
+	NoConsoleErrors bool
+	// MaxPageWeight is the most bytes the responses of the page may weigh
+	MaxPageWeight float64
+}
+
+// Options holds the options of a test. ScheduleID and SuiteID tag the test, e.g. to aggregate the tests of a
+// suite. UserAgent, Locale and Timezone override those of the browser when set. Links collects the links of the
+// page once loaded into the Links of the result.
+type Options struct {
+	Target     string
+	WaitTime   time.Duration
+	ScheduleID string
+	SuiteID    string
+	UserAgent  string
+	Locale     string
+	Timezone   string
+	Links      bool
+}
+
+// AssertionResult is the outcome of an assertion.
+type AssertionResult struct {
+	Name    string
+	Passed  bool
+	Message string
+}
+
+// Result is the outcome of a test. StorageErrors counts the results that could not be stored. PageStatus is
+// the status of the document of the page, 0 when none was received, and Links the links of the page when
+// collected. Findings counts the findings of the audit checks per severity.
+type Result struct {
+	TestID           uuid.UUID
+	Status           string
+	Assertions       []AssertionResult
+	FailedAssertions []AssertionResult
+	Findings         map[string]int
+	StorageErrors    int
+	PageStatus       int
+	Links            []string
+}
+
+// Event is a captured event, streamed to the sinks as it happens.
+type Event struct {
+	TestID    uuid.UUID
+	Type      string
+	RequestID string
+	URL       string
+	Time      time.Time
+	Content   interface{}
+}
+
+// Sink receives the captured events as they happen. Implementations must be safe for concurrent use.
+type Sink interface {
+	Write(event Event) error
+	Close() error
+}
+
+// Runner runs tests. It is safe to run several tests at once.
+type Runner struct {
+	runner *runner.Runner
+}
+
+// New creates a Runner storing the results in db, nil to disable storage, and checking the tests against the
+// assertions.
+func New(logger *slog.Logger, db *sql.DB, assertions Assertions) *Runner {
+	auditConfig := &config.AuditConfig{}
+	r := runner.New(logger, db, config.Assertions{
+		TitleMatches:    assertions.TitleMatches,
+		RequestOccurred: assertions.RequestOccurred,
+		No5xx:           assertions.No5xx,
+		NoConsoleErrors: assertions.NoConsoleErrors,
+		MaxPageWeight:   assertions.MaxPageWeight,
+	}, auditConfig.Load())
+	return &Runner{runner: r}
+}
+
+// StreamTo makes every test stream its captured events to s.
+func (r *Runner) StreamTo(s Sink) {
+	r.runner.StreamTo(sinkAdapter{s})
+}
+
+// Run runs a test against the target of opts, stopping the browser when ctx is done. The error wraps
+// ErrLaunch or ErrNavigation when the browser could not be started or the target could not be loaded; failed
+// assertions are not an error but are listed in the FailedAssertions of the result.
+func (r *Runner) Run(ctx context.Context, opts Options) (Result, error) {
+	testID, err := uuid.NewV7()
+	if err != nil {
+		return Result{}, err
+	}
+	client := browser.NewWithTestID(opts.Target, testID)
+	defer client.Cancel()
+	stop := context.AfterFunc(ctx, client.Cancel)
+	defer stop()
+
+	res, err := r.runner.Run(client, runner.Options{
+		Target:     opts.Target,
+		WaitTime:   opts.WaitTime,
+		ScheduleID: opts.ScheduleID,
+		SuiteID:    opts.SuiteID,
+		UserAgent:  opts.UserAgent,
+		Locale:     opts.Locale,
+		Timezone:   opts.Timezone,
+		Links:      opts.Links,
+	})
+	result := Result{
+		TestID:        res.TestID,
+		Status:        res.Status,
+		Findings:      res.Findings,
+		StorageErrors: res.StorageErrors,
+		PageStatus:    res.PageStatus,
+		Links:         res.Links,
+	}
+	for _, a := range res.Assertions {
+		result.Assertions = append(result.Assertions, AssertionResult(a))
+	}
+	for _, a := range res.FailedAssertions {
+		result.FailedAssertions = append(result.FailedAssertions, AssertionResult(a))
+	}
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return result, err
+}
+
+// sinkAdapter streams the records of the runner to a Sink as events.
+type sinkAdapter struct {
+	sink Sink
+}
+
+func (s sinkAdapter) Write(record sink.Record) error {
+	return s.sink.Write(Event(record))
+}
+
+func (s sinkAdapter) Close() error {
+	return s.sink.Close()
+}